@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Core affinity needs the CPU core of every per-frame scope (CoreId), which some
+// exporters record. A thread's core in a frame is the core it spent most time on; a
+// migration is a change of that core between frames, plus every extra core a thread
+// touched within one frame. Migrations cost cache warmth, which hurts the main thread
+// most, while worker threads that share one core serialize work meant to run in parallel.
+
+//
+// Logical cores that are SMT siblings (hyperthreads) share one physical core's execution
+// units, so two heavy threads on siblings slow each other down without either migrating.
+// Siblings are numbered adjacently (0-1, 2-3) on consoles and Windows; Linux usually
+// numbers them split (0 and N/2).
+
+const (
+	hoppingMigrationsPerFrame = 0.2  // Main-thread migrations per frame above which it counts as bouncing
+	pinnedCoreShare           = 0.9  // Share of a thread's time on one core to count as pinned
+	heavyThreadBudgetShare    = 0.25 // Share of the frame budget a thread must use in a frame to count as heavy
+	smtSharedFrameShare       = 0.5  // Share of over-budget frames two heavy threads must share a physical core in, and more often than in other frames
+)
+
+// ThreadAffinity is the core usage of one thread
+type ThreadAffinity struct {
+	ThreadName         string          `json:"threadName"`
+	Stage              string          `json:"stage"`
+	Frames             int             `json:"frames"`
+	Cores              []int           `json:"cores"`
+	DominantCore       int             `json:"dominantCore"`
+	DominantCoreShare  float64         `json:"dominantCorePercent"`
+	Migrations         int             `json:"migrations"`
+	MigrationsPerFrame float64         `json:"migrationsPerFrame"`
+	AvgFrameMsMigrated float64         `json:"avgFrameMsWhenMigrated,omitempty"`
+	AvgFrameMsStayed   float64         `json:"avgFrameMsWhenStayed,omitempty"`
+	BusyMsPerFrame     float64         `json:"busyMsPerFrame"`
+	Evidence           *IssueEvidence  `json:"evidence,omitempty"`
+	coreMs             map[int]float64 // Time per core over the capture
+}
+
+// SMTContention is a pair of heavy threads that run on sibling logical cores of one
+// physical core in over-budget frames
+type SMTContention struct {
+	Threads            []string       `json:"threads"`
+	PhysicalCore       int            `json:"physicalCore"`
+	LogicalCores       []int          `json:"logicalCores"`
+	OverBudgetFrames   int            `json:"overBudgetFramesShared"`
+	OverBudgetShare    float64        `json:"overBudgetPercent"` // Of all over-budget frames
+	InBudgetFrames     int            `json:"inBudgetFramesShared"`
+	AvgFrameMsShared   float64        `json:"avgFrameMsWhenShared"`
+	AvgFrameMsSeparate float64        `json:"avgFrameMsWhenSeparate,omitempty"`
+	Evidence           *IssueEvidence `json:"evidence,omitempty"`
+	frames             map[int]bool
+	overBudgetTotal    int
+	sharedMs           float64
+	cores              map[int]bool
+}
+
+// physicalCore maps a logical core to its physical core for a sibling layout
+func physicalCore(core, width int, layout string, logicalCores int) int {
+	if layout == "split" {
+		return core % max((logicalCores+width-1)/width, 1)
+	}
+	return core / width
+}
+
+// frameCores returns the time each thread spent on each core in a frame
+func frameCores(frame FrameProFrame) map[int]map[int]float64 {
+	cores := make(map[int]map[int]float64)
+	for _, fn := range frame.Functions {
+		if fn.CoreID == nil {
+			continue
+		}
+		if cores[fn.ThreadID] == nil {
+			cores[fn.ThreadID] = make(map[int]float64)
+		}
+		cores[fn.ThreadID][*fn.CoreID] += fn.TimeMs
+	}
+	return cores
+}
+
+// dominantCore returns the core with the most time, the lowest on ties
+func dominantCore(coreMs map[int]float64) int {
+	best, bestMs := -1, -1.0
+	for core, ms := range coreMs {
+		if ms > bestMs || ms == bestMs && core < best {
+			best, bestMs = core, ms
+		}
+	}
+	return best
+}
+
+func coreAffinityHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	smtWidth := 2
+	if n, ok := args["smt_width"].(float64); ok && n >= 1 {
+		smtWidth = int(n)
+	}
+	smtLayout := "adjacent"
+	if layout, ok := args["smt_layout"].(string); ok && layout != "" {
+		if layout != "adjacent" && layout != "split" {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown smt_layout %q: use adjacent or split", layout)), nil
+		}
+		smtLayout = layout
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, data)
+	budgetMs := 1000 / targetFPS
+	if ms, ok := args["budget_ms"].(float64); ok && ms > 0 {
+		budgetMs = ms
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	stages := threadStages(data)
+	times := frameTimes(data)
+	threads := make(map[int]*ThreadAffinity)
+	lastCore := make(map[int]int)
+	migratedFrames := make(map[int]map[int]int) // Thread -> frame number -> migrations
+	migratedMs := make(map[int][2]float64)      // Thread -> frame time sum when migrated, when stayed
+	for i, frame := range data.Frames {
+		cores := frameCores(frame)
+		for id, coreMs := range cores {
+			thread, exists := threads[id]
+			if !exists {
+				thread = &ThreadAffinity{Stage: stages[id], coreMs: make(map[int]float64)}
+				threads[id] = thread
+				migratedFrames[id] = make(map[int]int)
+			}
+			thread.Frames++
+			for core, ms := range coreMs {
+				thread.coreMs[core] += ms
+				thread.BusyMsPerFrame += ms
+			}
+
+			migrations := len(coreMs) - 1
+			core := dominantCore(coreMs)
+			if previous, seen := lastCore[id]; seen && previous != core {
+				migrations++
+			}
+			lastCore[id] = core
+			thread.Migrations += migrations
+			sums := migratedMs[id]
+			if migrations > 0 {
+				migratedFrames[id][frame.FrameNumber] = migrations
+				sums[0] += times[i]
+			} else {
+				sums[1] += times[i]
+			}
+			migratedMs[id] = sums
+		}
+	}
+	if len(threads) == 0 {
+		return mcp.NewToolResultError("This capture has no core IDs (CoreId) on its per-frame scopes. FramePro's standard JSON exports don't record them; core affinity needs an exporter that writes the core each scope ran on"), nil
+	}
+	for _, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			if thread := threads[fn.ThreadID]; thread != nil && thread.ThreadName == "" {
+				thread.ThreadName = fn.ThreadName
+			}
+		}
+	}
+
+	affinities := []*ThreadAffinity{}
+	for id, thread := range threads {
+		var total float64
+		for core, ms := range thread.coreMs {
+			thread.Cores = append(thread.Cores, core)
+			total += ms
+		}
+		sort.Ints(thread.Cores)
+		thread.DominantCore = dominantCore(thread.coreMs)
+		if total > 0 {
+			thread.DominantCoreShare = thread.coreMs[thread.DominantCore] / total * 100
+		}
+		thread.MigrationsPerFrame = float64(thread.Migrations) / float64(thread.Frames)
+		thread.BusyMsPerFrame /= float64(thread.Frames)
+		if migrated := len(migratedFrames[id]); migrated > 0 {
+			thread.AvgFrameMsMigrated = migratedMs[id][0] / float64(migrated)
+			if stayed := thread.Frames - migrated; stayed > 0 {
+				thread.AvgFrameMsStayed = migratedMs[id][1] / float64(stayed)
+			}
+			frames := migratedFrames[id]
+			thread.Evidence = frameEvidence(data, func(frame FrameProFrame) float64 {
+				return float64(frames[frame.FrameNumber])
+			}, 0)
+		}
+		affinities = append(affinities, thread)
+	}
+	sort.Slice(affinities, func(i, j int) bool {
+		return affinities[i].BusyMsPerFrame > affinities[j].BusyMsPerFrame
+	})
+
+	analysis := []string{}
+	suggestions := []string{}
+	for _, thread := range affinities {
+		if thread.Stage != stageMain || thread.MigrationsPerFrame < hoppingMigrationsPerFrame || !ruleEnabled("detect/core-hopping") {
+			continue
+		}
+		line := fmt.Sprintf("%s bounces across %d cores: %.2f migrations per frame, %.0f%% of its time on core %d",
+			thread.ThreadName, len(thread.Cores), thread.MigrationsPerFrame, thread.DominantCoreShare, thread.DominantCore)
+		if thread.AvgFrameMsStayed > 0 {
+			line += fmt.Sprintf("; frames average %.2fms when it migrates vs %.2fms when it stays", thread.AvgFrameMsMigrated, thread.AvgFrameMsStayed)
+		}
+		analysis = append(analysis, line)
+		suggestions = append(suggestions, fmt.Sprintf("Pin %s to one core (or a pair of cores) so it keeps its caches warm", thread.ThreadName))
+	}
+
+	// Threads that are nearly always on the same core as another busy thread
+	byCore := make(map[int][]*ThreadAffinity)
+	for _, thread := range affinities {
+		if thread.DominantCoreShare >= pinnedCoreShare*100 && ruleEnabled("detect/core-hopping") {
+			byCore[thread.DominantCore] = append(byCore[thread.DominantCore], thread)
+		}
+	}
+	shared := []map[string]interface{}{}
+	coreIDs := []int{}
+	for core := range byCore {
+		coreIDs = append(coreIDs, core)
+	}
+	sort.Ints(coreIDs)
+	for _, core := range coreIDs {
+		group := byCore[core]
+		if len(group) < 2 {
+			continue
+		}
+		names := []string{}
+		workers := 0
+		var busy float64
+		for _, thread := range group {
+			names = append(names, thread.ThreadName)
+			busy += thread.BusyMsPerFrame
+			if thread.Stage == stageWorkers {
+				workers++
+			}
+		}
+		shared = append(shared, map[string]interface{}{"core": core, "threads": names, "busyMsPerFrame": busy})
+		analysis = append(analysis, fmt.Sprintf("%d threads are pinned to core %d (%.2fms of work per frame): %s", len(group), core, busy, strings.Join(names, ", ")))
+		if workers >= 2 {
+			suggestions = append(suggestions, fmt.Sprintf("Workers %s share core %d, so their jobs run one after another: give each worker its own core", strings.Join(names, ", "), core))
+		} else {
+			suggestions = append(suggestions, fmt.Sprintf("Move one of %s off core %d", strings.Join(names, " and "), core))
+		}
+	}
+
+	smt := []*SMTContention{}
+	if ruleEnabled("detect/smt-contention") {
+		smt = smtContention(data, threads, times, budgetMs, smtWidth, smtLayout)
+	}
+	for _, pair := range smt {
+		line := fmt.Sprintf("%s share physical core %d (logical cores %s) in %d of %d over-budget frames (%.0f%%)",
+			strings.Join(pair.Threads, " and "), pair.PhysicalCore, joinInts(pair.LogicalCores), pair.OverBudgetFrames,
+			pair.overBudgetTotal, pair.OverBudgetShare)
+		if pair.AvgFrameMsSeparate > 0 {
+			line += fmt.Sprintf("; frames average %.2fms when they share it vs %.2fms apart", pair.AvgFrameMsShared, pair.AvgFrameMsSeparate)
+		}
+		analysis = append(analysis, line)
+		suggestions = append(suggestions, fmt.Sprintf("Keep %s on different physical cores: with %d-way SMT (%s numbering) they compete for the execution units of core %d",
+			strings.Join(pair.Threads, " and "), smtWidth, smtLayout, pair.PhysicalCore))
+	}
+
+	summary := fmt.Sprintf("No core affinity problems across %d threads with core IDs", len(affinities))
+	if len(analysis) > 0 {
+		summary = fmt.Sprintf("%d core affinity problems across %d threads with core IDs", len(analysis), len(affinities))
+	}
+
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"framesAnalyzed":  len(data.Frames),
+		"threads":         affinities,
+		"sharedCores":     shared,
+		"smtContention":   smt,
+		"budgetMs":        budgetMs,
+		"targetFPS":       targetFPS,
+		"targetFPSSource": targetSource,
+		"suggestions":     suggestions,
+		"analysis":        analysis,
+		"summary":         summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// smtContention finds pairs of heavy threads on sibling logical cores in over-budget frames
+func smtContention(data *FrameProData, threads map[int]*ThreadAffinity, times []float64, budgetMs float64, width int, layout string) []*SMTContention {
+	if width < 2 {
+		return []*SMTContention{}
+	}
+	logicalCores := 0
+	for _, thread := range threads {
+		for core := range thread.coreMs {
+			logicalCores = max(logicalCores, core+1)
+		}
+	}
+
+	overBudget := 0
+	pairs := make(map[[2]int]*SMTContention)
+	for i, frame := range data.Frames {
+		over := times[i] > budgetMs
+		if over {
+			overBudget++
+		}
+		// The core each heavy thread mostly ran on this frame
+		heavy := []int{}
+		core := make(map[int]int)
+		for id, coreMs := range frameCores(frame) {
+			var busy float64
+			for _, ms := range coreMs {
+				busy += ms
+			}
+			if busy >= budgetMs*heavyThreadBudgetShare {
+				heavy = append(heavy, id)
+				core[id] = dominantCore(coreMs)
+			}
+		}
+		sort.Ints(heavy)
+		for a := 0; a < len(heavy); a++ {
+			for b := a + 1; b < len(heavy); b++ {
+				first, second := core[heavy[a]], core[heavy[b]]
+				if first == second || physicalCore(first, width, layout, logicalCores) != physicalCore(second, width, layout, logicalCores) {
+					continue // The same logical core is reported as a shared core
+				}
+				key := [2]int{heavy[a], heavy[b]}
+				pair := pairs[key]
+				if pair == nil {
+					pair = &SMTContention{
+						Threads:      []string{threads[key[0]].ThreadName, threads[key[1]].ThreadName},
+						PhysicalCore: physicalCore(first, width, layout, logicalCores),
+						frames:       make(map[int]bool),
+						cores:        make(map[int]bool),
+					}
+					pairs[key] = pair
+				}
+				pair.cores[first], pair.cores[second] = true, true
+				pair.sharedMs += times[i]
+				pair.frames[frame.FrameNumber] = true
+				if over {
+					pair.OverBudgetFrames++
+				} else {
+					pair.InBudgetFrames++
+				}
+			}
+		}
+	}
+
+	var totalMs float64
+	for _, ms := range times {
+		totalMs += ms
+	}
+	contention := []*SMTContention{}
+	for _, pair := range pairs {
+		if overBudget == 0 || float64(pair.OverBudgetFrames) < float64(overBudget)*smtSharedFrameShare {
+			continue
+		}
+		// Sharing that is as common in frames within budget is not what makes frames slow
+		if inBudget := len(times) - overBudget; inBudget > 0 && float64(pair.InBudgetFrames)/float64(inBudget) > float64(pair.OverBudgetFrames)/float64(overBudget) {
+			continue
+		}
+		pair.overBudgetTotal = overBudget
+		pair.OverBudgetShare = float64(pair.OverBudgetFrames) / float64(overBudget) * 100
+		shared := len(pair.frames)
+		pair.AvgFrameMsShared = pair.sharedMs / float64(shared)
+		if separate := len(times) - shared; separate > 0 {
+			pair.AvgFrameMsSeparate = (totalMs - pair.sharedMs) / float64(separate)
+		}
+		for core := range pair.cores {
+			pair.LogicalCores = append(pair.LogicalCores, core)
+		}
+		sort.Ints(pair.LogicalCores)
+		frames := pair.frames
+		pair.Evidence = frameEvidence(data, func(frame FrameProFrame) float64 {
+			if frames[frame.FrameNumber] {
+				return 1
+			}
+			return 0
+		}, 0)
+		contention = append(contention, pair)
+	}
+	sort.Slice(contention, func(i, j int) bool {
+		if contention[i].OverBudgetFrames != contention[j].OverBudgetFrames {
+			return contention[i].OverBudgetFrames > contention[j].OverBudgetFrames
+		}
+		return strings.Join(contention[i].Threads, "/") < strings.Join(contention[j].Threads, "/")
+	})
+	return contention
+}
+
+// joinInts formats integers as a comma-separated list
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,46 @@
+package main
+
+import "slices"
+
+// With aggregate_by_function, entries of the same function on different threads, such as
+// a job run by every worker, are merged into one (see mergeFunctionsByName) that keeps the
+// first thread's ID and lists every thread it ran on.
+
+// aggregateByFunction merges functions by name in first-seen order and returns the threads
+// each ran on
+func aggregateByFunction(functions []FrameProFunction) ([]FrameProFunction, map[string][]string) {
+	merged := mergeFunctionsByName(functions)
+	threads := make(map[string][]string)
+	aggregated := []FrameProFunction{}
+	for _, fn := range functions {
+		if _, seen := threads[fn.FunctionName]; !seen {
+			aggregated = append(aggregated, merged[fn.FunctionName])
+		}
+		if !slices.Contains(threads[fn.FunctionName], fn.ThreadName) {
+			threads[fn.FunctionName] = append(threads[fn.FunctionName], fn.ThreadName)
+		}
+	}
+	return aggregated, threads
+}
+
+// aggregateShares sums the hotspotShares of every thread's entry into the merged function's key
+func aggregateShares(functions, aggregated []FrameProFunction, shares map[string]float64) map[string]float64 {
+	byName := make(map[string]float64)
+	for _, fn := range functions {
+		byName[fn.FunctionName] += shares[functionKey(fn)]
+	}
+	merged := make(map[string]float64)
+	for _, fn := range aggregated {
+		merged[functionKey(fn)] = byName[fn.FunctionName]
+	}
+	return merged
+}
+
+// attachFunctionThreads adds the threads of each merged function to entries built by hotspotAnalysis
+func attachFunctionThreads(entries []map[string]interface{}, threads map[string][]string) {
+	for _, entry := range entries {
+		names := threads[entry["functionName"].(string)]
+		entry["threads"] = names
+		entry["threadCount"] = len(names)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// SessionAnnotation is a conclusion recorded against a profiling session
+type SessionAnnotation struct {
+	Text      string   `json:"text"`
+	Author    string   `json:"author,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+var annotationsMu sync.Mutex
+
+// annotationsPath returns the file annotations are persisted in
+func annotationsPath() string {
+	if path := os.Getenv("FRAMEPRO_ANNOTATIONS_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(outputDir, "framepro_annotations.json")
+}
+
+// loadAnnotations reads all annotations keyed by session name. A missing file means no annotations.
+func loadAnnotations() (map[string][]SessionAnnotation, error) {
+	annotations := make(map[string][]SessionAnnotation)
+	data, err := os.ReadFile(annotationsPath())
+	if os.IsNotExist(err) {
+		return annotations, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotations: %w", err)
+	}
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("failed to parse annotations: %w", err)
+	}
+	return annotations, nil
+}
+
+// sessionAnnotations returns the annotations of one session, or nil when there are none or they can't be read
+func sessionAnnotations(sessionName string) []SessionAnnotation {
+	annotationsMu.Lock()
+	defer annotationsMu.Unlock()
+
+	annotations, err := loadAnnotations()
+	if err != nil {
+		return nil
+	}
+	return annotations[sessionName]
+}
+
+// addAnnotation appends an annotation to a session and returns all of that session's annotations
+func addAnnotation(sessionName string, annotation SessionAnnotation, dryRun bool) ([]SessionAnnotation, FileWrite, error) {
+	annotationsMu.Lock()
+	defer annotationsMu.Unlock()
+
+	annotations, err := loadAnnotations()
+	if err != nil {
+		return nil, FileWrite{}, err
+	}
+	annotations[sessionName] = append(annotations[sessionName], annotation)
+
+	data, _ := json.MarshalIndent(annotations, "", "  ")
+	write, err := writeFile(annotationsPath(), data, dryRun)
+	if err != nil {
+		return nil, write, fmt.Errorf("failed to write annotations: %w", err)
+	}
+	return annotations[sessionName], write, nil
+}
+
+// attachAnnotations adds a session's annotations to a tool result under the given key
+func attachAnnotations(output map[string]interface{}, key, sessionName string) {
+	if annotations := sessionAnnotations(sessionName); len(annotations) > 0 {
+		output[key] = annotations
+	}
+}
+
+func annotateSessionHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	text, _ := args["text"].(string)
+	if text == "" {
+		return mcp.NewToolResultError("text is required"), nil
+	}
+	author, _ := args["author"].(string)
+	tags := []string{}
+	if raw, ok := args["tags"].([]interface{}); ok {
+		for _, t := range raw {
+			if tag, ok := t.(string); ok && tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	// The session is named directly or taken from a capture file
+	sessionName, _ := args["session_name"].(string)
+	if sessionName == "" {
+		filePath, _ := args["file_path"].(string)
+		if filePath == "" {
+			return mcp.NewToolResultError("Either session_name or file_path is required"), nil
+		}
+		data, err := loadFrameProData(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+		}
+		sessionName = data.SessionName
+	}
+
+	dryRun := isDryRun(args)
+	annotations, write, err := addAnnotation(sessionName, SessionAnnotation{
+		Text:      text,
+		Author:    author,
+		Tags:      tags,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}, dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save annotation: %v", err)), nil
+	}
+
+	output := map[string]interface{}{
+		"sessionName": sessionName,
+		"annotations": annotations,
+		"storedIn":    annotationsPath(),
+	}
+	attachWrites(output, []FileWrite{write}, dryRun)
+	if dryRun {
+		output["summary"] = fmt.Sprintf("Dry run: would add annotation %d to session '%s' in %s; nothing written", len(annotations), sessionName, write.Path)
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
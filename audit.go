@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// AuditEntry is one line of the audit log (JSON Lines)
+type AuditEntry struct {
+	Time       string                 `json:"time"`
+	Client     string                 `json:"client"`
+	SessionID  string                 `json:"sessionId,omitempty"`
+	Tool       string                 `json:"tool"`
+	Inputs     []string               `json:"inputs,omitempty"`    // Captures named by the call, as resolved for reading
+	Selection  map[string]interface{} `json:"selection,omitempty"` // Arguments that pick captures by pattern
+	Outputs    []string               `json:"outputs,omitempty"`   // Files the call wrote
+	IsError    bool                   `json:"isError"`
+	DurationMs int64                  `json:"durationMs"`
+}
+
+// AuditLog appends tool invocations to a file. Entries are never rewritten,
+// and no tool exposes the log for modification.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{file: file}, nil
+}
+
+func (a *AuditLog) append(entry AuditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.file.Write(append(line, '\n'))
+}
+
+// middleware records every tool call after it completes, including failed ones
+func (a *AuditLog) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		entry := AuditEntry{
+			Time:       start.UTC().Format(time.RFC3339),
+			Client:     "unknown",
+			Tool:       request.Params.Name,
+			Inputs:     auditInputs(request),
+			Selection:  auditSelection(request),
+			IsError:    err != nil || (result != nil && result.IsError),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if result != nil {
+			entry.Outputs = resultWrites(result)
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			entry.SessionID = session.SessionID()
+			if withInfo, ok := session.(server.SessionWithClientInfo); ok {
+				info := withInfo.GetClientInfo()
+				if info.Name != "" {
+					entry.Client = strings.TrimSpace(info.Name + " " + info.Version)
+				}
+			}
+		}
+
+		a.append(entry)
+		return result, err
+	}
+}
+
+// captureArguments collects the captures named by a tool call's arguments
+func captureArguments(request mcp.CallToolRequest) []string {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	named := []interface{}{args["file_path"], args["baseline_path"], args["current_path"], args["files"]}
+	if groups, ok := args["groups"].([]interface{}); ok {
+		for _, group := range groups {
+			if spec, ok := group.(map[string]interface{}); ok {
+				named = append(named, spec["files"])
+			}
+		}
+	}
+	files := []string{}
+	add := func(value interface{}) {
+		if path, ok := value.(string); ok && path != "" {
+			files = append(files, path)
+		}
+	}
+	for _, value := range named {
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				add(item)
+			}
+			continue
+		}
+		add(value)
+	}
+	return files
+}
+
+// auditInputs resolves the captures named by a tool call the way they are read
+func auditInputs(request mcp.CallToolRequest) []string {
+	files := captureArguments(request)
+	for i, file := range files {
+		files[i] = resolveCapturePath(file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// auditSelection collects the arguments that select captures by directory or pattern
+func auditSelection(request mcp.CallToolRequest) map[string]interface{} {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	selection := make(map[string]interface{})
+	for _, key := range []string{"directory", "pattern", "session_pattern"} {
+		if value, _ := args[key].(string); value != "" {
+			selection[key] = value
+		}
+	}
+	if groups, ok := args["groups"].([]interface{}); ok {
+		patterns := []string{}
+		for _, group := range groups {
+			if spec, ok := group.(map[string]interface{}); ok {
+				if pattern, _ := spec["pattern"].(string); pattern != "" {
+					patterns = append(patterns, pattern)
+				}
+			}
+		}
+		if len(patterns) > 0 {
+			selection["groupPatterns"] = patterns
+		}
+	}
+	if len(selection) == 0 {
+		return nil
+	}
+	return selection
+}
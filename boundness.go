@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Frame classification from scope names:
+//
+//	vsync-limited - the frame time sits on a multiple of the refresh interval and the
+//	                render thread spends real time in present or vblank waits
+//	gpu-bound     - the render thread spends a large share of the frame waiting for the GPU
+//	                (present, fence or GPU sync scopes) and the frame isn't vsync-locked
+//	cpu-bound     - anything else: the CPU critical path (main, render or workers) sets the pace
+
+// Frame classes
+const (
+	boundCPU   = "cpu-bound"
+	boundGPU   = "gpu-bound"
+	boundVSync = "vsync-limited"
+)
+
+const (
+	gpuWaitShare   = 0.20 // Share of the frame waiting on the GPU to call a frame GPU-bound
+	vsyncWaitShare = 0.10 // Share of the frame in present/vblank waits for a vsync-locked frame
+	vsyncTolerance = 0.05 // Allowed deviation from a refresh interval multiple
+	minRangeFrames = 5    // Shorter runs are merged into the preceding range
+)
+
+// FrameRangeClass is a run of consecutive frames with the same classification
+type FrameRangeClass struct {
+	StartFrame int                `json:"startFrame"`
+	EndFrame   int                `json:"endFrame"`
+	Frames     int                `json:"frames"`
+	Class      string             `json:"class"`
+	Evidence   map[string]float64 `json:"evidence"`
+	Reason     string             `json:"reason"`
+}
+
+// isVSyncScope reports whether a scope waits for the display's vertical blank
+func isVSyncScope(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "vsync") || strings.Contains(lower, "vblank")
+}
+
+// isGPUWaitScope reports whether a scope is the CPU waiting for the GPU
+func isGPUWaitScope(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range []string{"present", "swapbuffers", "swapchain", "flip", "fence"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return strings.Contains(lower, "gpu") && (strings.Contains(lower, "wait") || strings.Contains(lower, "sync") || strings.Contains(lower, "flush"))
+}
+
+// classifyFrame labels one frame and returns the evidence used
+func classifyFrame(frame FrameProFrame, stages map[int]string, refreshHz float64) (string, map[string]float64) {
+	frameTime := frameTimeMs(frame, stages)
+	path := criticalPath(frame, stages)
+
+	var gpuWait, vsyncWait float64
+	for _, fn := range frame.Functions {
+		switch {
+		case isVSyncScope(fn.FunctionName):
+			vsyncWait += fn.TimeMs
+		case isGPUWaitScope(fn.FunctionName):
+			gpuWait += fn.TimeMs
+		}
+	}
+
+	evidence := map[string]float64{
+		"frameTimeMs":    frameTime,
+		"mainBusyMs":     path.Stages[stageMain],
+		"renderBusyMs":   path.Stages[stageRender],
+		"workersBusyMs":  path.Stages[stageWorkers],
+		"gpuWaitMs":      gpuWait,
+		"vsyncWaitMs":    vsyncWait,
+		"criticalPathMs": path.CriticalPathMs,
+	}
+	if frameTime <= 0 {
+		return boundCPU, evidence
+	}
+
+	interval := 1000.0 / refreshHz
+	multiple := math.Max(1, math.Round(frameTime/interval))
+	onInterval := math.Abs(frameTime-multiple*interval) <= interval*vsyncTolerance
+	if onInterval && (vsyncWait+gpuWait)/frameTime >= vsyncWaitShare {
+		return boundVSync, evidence
+	}
+	if gpuWait/frameTime >= gpuWaitShare {
+		return boundGPU, evidence
+	}
+	return boundCPU, evidence
+}
+
+// classifyFrameRanges classifies every frame and groups consecutive frames with the same
+// class into ranges, averaging their evidence. It also returns the number of frames per class.
+func classifyFrameRanges(data *FrameProData, frames []FrameProFrame, refreshHz float64) ([]FrameRangeClass, map[string]int) {
+	stages := threadStages(data)
+	ranges := []FrameRangeClass{}
+	counts := make(map[string]int)
+	for _, frame := range frames {
+		class, evidence := classifyFrame(frame, stages, refreshHz)
+		counts[class]++
+		last := len(ranges) - 1
+		if last < 0 || ranges[last].Class != class {
+			ranges = append(ranges, FrameRangeClass{StartFrame: frame.FrameNumber, Class: class, Evidence: make(map[string]float64)})
+			last++
+		}
+		ranges[last].EndFrame = frame.FrameNumber
+		ranges[last].Frames++
+		for key, value := range evidence {
+			ranges[last].Evidence[key] += value
+		}
+	}
+
+	// Merge short runs into the preceding range so one odd frame doesn't split a section
+	merged := []FrameRangeClass{}
+	for _, r := range ranges {
+		last := len(merged) - 1
+		if last >= 0 && (r.Frames < minRangeFrames || merged[last].Class == r.Class) {
+			merged[last].EndFrame = r.EndFrame
+			merged[last].Frames += r.Frames
+			for key, value := range r.Evidence {
+				merged[last].Evidence[key] += value
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	for i := range merged {
+		r := &merged[i]
+		for key := range r.Evidence {
+			r.Evidence[key] /= float64(r.Frames)
+		}
+		r.Reason = classReason(r.Class, r.Evidence, refreshHz)
+	}
+	return merged, counts
+}
+
+// classReason explains a classification from its averaged evidence
+func classReason(class string, evidence map[string]float64, refreshHz float64) string {
+	frameTime := evidence["frameTimeMs"]
+	switch class {
+	case boundVSync:
+		return fmt.Sprintf("Frame time %.2fms sits on the %.0fHz refresh interval with %.2fms in present/vblank waits",
+			frameTime, refreshHz, evidence["gpuWaitMs"]+evidence["vsyncWaitMs"])
+	case boundGPU:
+		return fmt.Sprintf("Render thread waits %.2fms (%.0f%% of the %.2fms frame) on the GPU in present/fence scopes",
+			evidence["gpuWaitMs"], evidence["gpuWaitMs"]/frameTime*100, frameTime)
+	}
+
+	limiter, busiest := stageMain, evidence["mainBusyMs"]
+	if evidence["renderBusyMs"] > busiest {
+		limiter, busiest = stageRender, evidence["renderBusyMs"]
+	}
+	if evidence["workersBusyMs"] > busiest {
+		limiter, busiest = stageWorkers, evidence["workersBusyMs"]
+	}
+	return fmt.Sprintf("CPU work sets the pace: %s stage busy %.2fms of the %.2fms frame, %.2fms waiting on the GPU",
+		limiter, busiest, frameTime, evidence["gpuWaitMs"])
+}
+
+// frameBoundness summarizes the classification of a capture for analyze_frame_times
+func frameBoundness(data *FrameProData, refreshHz float64) map[string]interface{} {
+	frames := data.Frames
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+	}
+	ranges, counts := classifyFrameRanges(data, frames, refreshHz)
+	verdict := boundCPU
+	for _, class := range []string{boundCPU, boundGPU, boundVSync} {
+		if counts[class] > counts[verdict] {
+			verdict = class
+		}
+	}
+
+	boundness := map[string]interface{}{
+		"verdict":     verdict,
+		"refreshHz":   refreshHz,
+		"frameCounts": counts,
+		"ranges":      ranges,
+	}
+	if len(data.Frames) == 0 {
+		boundness["note"] = "No per-frame data; classified from average time per frame"
+	}
+	return boundness
+}
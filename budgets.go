@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// SubsystemBudget is a per-frame time budget for the functions matching a name pattern.
+// Each function counts toward the first budget it matches. Scopes are not de-nested, so
+// a pattern matching both a scope and its children counts their time twice.
+type SubsystemBudget struct {
+	Name     string  `json:"name"`
+	Pattern  string  `json:"pattern"`
+	BudgetMs float64 `json:"budgetMs"`
+	re       *regexp.Regexp
+}
+
+// defaultBudgets split a 60 FPS frame between the usual engine subsystems. They are
+// scaled to the target frame rate; the rest of the frame is left for everything else.
+var defaultBudgets = []SubsystemBudget{
+	{Name: "Rendering", Pattern: "(render|draw|scene|present|shadow|occlusion|cull)", BudgetMs: 6},
+	{Name: "Physics", Pattern: "(physic|collision|chaos|physx|raycast)", BudgetMs: 3},
+	{Name: "AI", Pattern: "(^ai[:._ ]|navigation|navmesh|pathfind|behaviou?r ?tree|perception)", BudgetMs: 2},
+	{Name: "Animation", Pattern: "(anim|skeletal|skinn|ik[:._ ])", BudgetMs: 2},
+	{Name: "UI", Pattern: "(slate|canvas|widget|hud|umg|menu|[^a-z]ui[^a-z])", BudgetMs: 1},
+	{Name: "Garbage Collection", Pattern: "(^gc[:._ ]|garbage|gc\\.collect)", BudgetMs: 0.5},
+	{Name: "Streaming", Pattern: "(load|stream|decompress)", BudgetMs: 1},
+	{Name: "Audio", Pattern: "(audio|sound|wwise|fmod)", BudgetMs: 0.5},
+}
+
+// budgetsSchema describes the budgets parameter shared by the budget tools
+func budgetsSchema() mcp.ToolOption {
+	return mcp.WithArray("budgets",
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":      map[string]any{"type": "string"},
+				"pattern":   map[string]any{"type": "string"},
+				"budget_ms": map[string]any{"type": "number"},
+			},
+			"required": []string{"name", "pattern", "budget_ms"},
+		}),
+		mcp.Description("Per-frame budgets by function name pattern, e.g. [{\"name\": \"Physics\", \"pattern\": \"*Physics*\", \"budget_ms\": 3}]. Patterns use * and ? wildcards or a regular expression, case-insensitive (default: built-in engine subsystems scaled to target_fps)"))
+}
+
+// parseBudgets reads the budgets argument, or scales the defaults to the target frame rate
+func parseBudgets(args map[string]interface{}, targetFPS float64) ([]SubsystemBudget, error) {
+	budgets := []SubsystemBudget{}
+	raw, ok := args["budgets"].([]interface{})
+	if !ok || len(raw) == 0 {
+		for _, budget := range defaultBudgets {
+			if !ruleEnabled(budgetRuleID(budget)) {
+				continue
+			}
+			budget.BudgetMs *= 60 / targetFPS
+			budgets = append(budgets, budget)
+		}
+	} else {
+		for i, entry := range raw {
+			spec, _ := entry.(map[string]interface{})
+			name, _ := spec["name"].(string)
+			pattern, _ := spec["pattern"].(string)
+			budgetMs, _ := spec["budget_ms"].(float64)
+			if name == "" || pattern == "" || budgetMs <= 0 {
+				return nil, fmt.Errorf("budget %d needs a name, a pattern and a positive budget_ms", i+1)
+			}
+			budgets = append(budgets, SubsystemBudget{Name: name, Pattern: pattern, BudgetMs: budgetMs})
+		}
+	}
+
+	return compileBudgets(budgets)
+}
+
+// subsystemsSchema is the subsystems parameter: name rules without budgets
+func subsystemsSchema() mcp.ToolOption {
+	return mcp.WithArray("subsystems",
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":    map[string]any{"type": "string"},
+				"pattern": map[string]any{"type": "string"},
+			},
+			"required": []string{"name", "pattern"},
+		}),
+		mcp.Description("Subsystems by function name rule, first match wins, e.g. [{\"name\": \"Physics\", \"pattern\": \"Physics::*\"}]. A rule is a prefix (\"Anim*\"), a namespace (\"Physics::*\"), a substring or a regular expression, case-insensitive (default: built-in engine subsystems)"))
+}
+
+// parseSubsystems reads the subsystems argument, or takes the default budgets' rules
+func parseSubsystems(args map[string]interface{}) ([]SubsystemBudget, error) {
+	subsystems := []SubsystemBudget{}
+	raw, ok := args["subsystems"].([]interface{})
+	if !ok || len(raw) == 0 {
+		for _, budget := range defaultBudgets {
+			subsystems = append(subsystems, SubsystemBudget{Name: budget.Name, Pattern: budget.Pattern})
+		}
+	} else {
+		for i, entry := range raw {
+			spec, _ := entry.(map[string]interface{})
+			name, _ := spec["name"].(string)
+			pattern, _ := spec["pattern"].(string)
+			if name == "" || pattern == "" {
+				return nil, fmt.Errorf("subsystem %d needs a name and a pattern", i+1)
+			}
+			subsystems = append(subsystems, SubsystemBudget{Name: name, Pattern: pattern})
+		}
+	}
+	return compileBudgets(subsystems)
+}
+
+// compileBudgets compiles the name pattern of every budget
+func compileBudgets(budgets []SubsystemBudget) ([]SubsystemBudget, error) {
+	for i := range budgets {
+		re, err := compileFunctionPattern(budgets[i].Pattern, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", budgets[i].Name, err)
+		}
+		budgets[i].re = re
+	}
+	return budgets, nil
+}
+
+// matchBudget returns the index of the first budget matching a function name, or -1
+func matchBudget(budgets []SubsystemBudget, functionName string) int {
+	for i, budget := range budgets {
+		if budget.re.MatchString(functionName) {
+			return i
+		}
+	}
+	return -1
+}
+
+// subsystemFrameTimes returns each budget's time in every frame, indexed [budget][frame].
+// Without per-frame data the capture's average frame stands in as a single frame.
+func subsystemFrameTimes(data *FrameProData, budgets []SubsystemBudget) ([][]float64, []FrameProFrame) {
+	frames := data.Frames
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+	}
+	matches := make(map[string]int)
+	times := make([][]float64, len(budgets))
+	for i := range times {
+		times[i] = make([]float64, len(frames))
+	}
+	for f, frame := range frames {
+		for _, fn := range frame.Functions {
+			index, seen := matches[fn.FunctionName]
+			if !seen {
+				index = matchBudget(budgets, fn.FunctionName)
+				matches[fn.FunctionName] = index
+			}
+			if index >= 0 {
+				times[index][f] += fn.TimeMs
+			}
+		}
+	}
+	return times, frames
+}
+
+// budgetFunctions returns the most expensive functions counted toward budget index,
+// by average time per frame
+func budgetFunctions(frames []FrameProFrame, budgets []SubsystemBudget, index, n int) []FunctionCost {
+	threadNames := make(map[string]string)
+	for _, frame := range frames {
+		for _, fn := range frame.Functions {
+			threadNames[functionKey(fn)] = fn.ThreadName
+		}
+	}
+	functions := []FunctionCost{}
+	for key, avg := range avgFunctionTimes(frames) {
+		name := functionNameFromKey(key)
+		if matchBudget(budgets, name) == index {
+			functions = append(functions, FunctionCost{FunctionName: name, ThreadName: threadNames[key], AvgTimePerFrameMs: avg})
+		}
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].AvgTimePerFrameMs > functions[j].AvgTimePerFrameMs
+	})
+	if len(functions) > n {
+		functions = functions[:n]
+	}
+	return functions
+}
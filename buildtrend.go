@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Trends across builds follow frame time statistics and every function's time per frame
+// through captures of successive builds, oldest first. A cost that has only risen (steps
+// down within tolerance_percent count as noise) over at least three builds and grown by
+// min_growth_percent along that run is regressing; if no single step reached
+// compare_profiles' 10% threshold it is creeping, a regression build-to-build diffs never
+// flag. detectableAt is the first build where the run had grown that much. Functions are
+// matched by name across threads, after renames, and trended only when every build has them.
+
+// Build trend classes, besides the session trend classes
+const (
+	trendCreeping   = "creeping"
+	trendRegressing = "regressing"
+	trendImproving  = "improving"
+)
+
+const (
+	trendStepThresholdPercent = 10.0 // compare_profiles' regression threshold
+	minTrendBuilds            = 3
+)
+
+// TrendBuild is one capture in a build trend
+type TrendBuild struct {
+	Index       int    `json:"index"`
+	File        string `json:"file"`
+	SessionName string `json:"sessionName"`
+	Date        string `json:"date,omitempty"`
+}
+
+// BuildTrend is the evolution of a metric or function cost over the builds
+type BuildTrend struct {
+	Metric         string    `json:"metric,omitempty"`
+	FunctionName   string    `json:"functionName,omitempty"`
+	ThreadName     string    `json:"threadName,omitempty"`
+	Unit           string    `json:"unit"`
+	Values         []float64 `json:"values"` // One per build
+	First          float64   `json:"first"`
+	Last           float64   `json:"last"`
+	ChangePercent  float64   `json:"changePercent"`  // Last build against the first
+	MaxStepPercent float64   `json:"maxStepPercent"` // Largest build-to-build change in the worse direction
+	Correlation    float64   `json:"correlation"`    // Of a line through the values, by build index
+	Trend          string    `json:"trend"`
+	RunStart       string    `json:"runStart,omitempty"` // Build the steady run starts at
+	RunBuilds      int       `json:"runBuilds,omitempty"`
+	RunPercent     float64   `json:"runPercent,omitempty"`
+	DetectableAt   string    `json:"detectableAt,omitempty"`
+	higherIsBetter bool
+}
+
+// classifyBuildTrend finds the longest run of builds ending at the last one along which the
+// cost only got worse, and classifies the trend by that run
+func classifyBuildTrend(trend *BuildTrend, files []string, minGrowth, tolerance float64) {
+	values := trend.Values
+	worse := func(from, to float64) float64 { // Percent change in the worse direction
+		if from <= 0 {
+			return 0
+		}
+		change := (to - from) / from * 100
+		if trend.higherIsBetter {
+			return -change
+		}
+		return change
+	}
+	indexes := make([]float64, len(values))
+	for i := range indexes {
+		indexes[i] = float64(i)
+	}
+	_, _, trend.Correlation = linearFit(indexes, values)
+	trend.First, trend.Last = values[0], values[len(values)-1]
+	if trend.First > 0 {
+		trend.ChangePercent = (trend.Last - trend.First) / trend.First * 100
+	}
+	for i := 1; i < len(values); i++ {
+		trend.MaxStepPercent = math.Max(trend.MaxStepPercent, worse(values[i-1], values[i]))
+	}
+	trend.Trend = trendStable
+
+	// Runs of worsening and improving builds ending at the last build
+	classify := func(sign float64) bool {
+		start := len(values) - 1
+		for start > 0 && sign*worse(values[start-1], values[start]) >= -tolerance {
+			start--
+		}
+		if len(values)-start < minTrendBuilds || sign*worse(values[start], values[len(values)-1]) < minGrowth {
+			return false
+		}
+		trend.RunStart, trend.RunBuilds = files[start], len(values)-start
+		trend.RunPercent = sign * worse(values[start], values[len(values)-1])
+		for k := start + minTrendBuilds - 1; k < len(values); k++ {
+			if sign*worse(values[start], values[k]) >= minGrowth {
+				trend.DetectableAt = files[k]
+				break
+			}
+		}
+		return true
+	}
+	switch {
+	case classify(1):
+		trend.Trend = trendRegressing
+		if trend.MaxStepPercent < trendStepThresholdPercent {
+			trend.Trend = trendCreeping
+		}
+	case classify(-1):
+		trend.Trend = trendImproving
+	case math.Abs(worse(trend.First, trend.Last)) >= minGrowth:
+		trend.Trend = trendErratic
+	}
+}
+
+// naturalLess orders names with numbers by value, so build_9 comes before build_10
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		i := strings.IndexFunc(a, isNotDigit)
+		j := strings.IndexFunc(b, isNotDigit)
+		if i != 0 && j != 0 { // Both start with digits
+			if i < 0 {
+				i = len(a)
+			}
+			if j < 0 {
+				j = len(b)
+			}
+			x, _ := strconv.ParseUint(a[:i], 10, 64)
+			y, _ := strconv.ParseUint(b[:j], 10, 64)
+			if x != y {
+				return x < y
+			}
+			a, b = a[i:], b[j:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+// buildTrendFiles returns the captures to trend: the files argument in its order, or the
+// captures matching pattern in the data directory ordered by orderBy
+func buildTrendFiles(args map[string]interface{}) ([]CaptureFile, string, error) {
+	if raw, ok := args["files"].([]interface{}); ok && len(raw) > 0 {
+		files := []CaptureFile{}
+		for _, entry := range raw {
+			if path, ok := entry.(string); ok && path != "" {
+				files = append(files, CaptureFile{Path: path})
+			}
+		}
+		return files, "files", nil
+	}
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		return nil, "", fmt.Errorf("give files, in build order, or a pattern matching the captures of the builds")
+	}
+	files, err := listCaptureFiles(dataDir, pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	orderBy, _ := args["order_by"].(string)
+	switch orderBy {
+	case "", "modified":
+		orderBy = "modified" // listCaptureFiles already sorts by modification time
+	case "name":
+		sort.SliceStable(files, func(i, j int) bool {
+			return naturalLess(filepath.Base(files[i].Path), filepath.Base(files[j].Path))
+		})
+	default:
+		return nil, "", fmt.Errorf("invalid order_by '%s': expected 'modified' or 'name'", orderBy)
+	}
+	return files, orderBy, nil
+}
+
+func analyzeTrendHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	minGrowth := 10.0
+	if p, ok := args["min_growth_percent"].(float64); ok && p > 0 {
+		minGrowth = p
+	}
+	tolerance := 2.0
+	if p, ok := args["tolerance_percent"].(float64); ok && p >= 0 {
+		tolerance = p
+	}
+	minMs := 0.1
+	if ms, ok := args["min_ms"].(float64); ok && ms >= 0 {
+		minMs = ms
+	}
+	topN := 20
+	if n, ok := args["top_n"].(float64); ok && n >= 1 {
+		topN = int(n)
+	}
+	renames, err := parseRenames(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	files, order, err := buildTrendFiles(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	builds := []TrendBuild{}
+	names := []string{}
+	metrics := map[string][]float64{}
+	functions := []map[string]FrameProFunction{}
+	skipped := []string{}
+	read := len(files)
+	for i, file := range files {
+		if ctx.Err() != nil { // Out of time or canceled: trend the builds read so far
+			read = i
+			break
+		}
+		data, err := loadFrameProData(file.Path)
+		if err != nil {
+			if order == "files" {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data from %s: %v", file.Path, err)), nil
+			}
+			skipped = append(skipped, filepath.Base(file.Path))
+			continue
+		}
+		applyRenames(data, renames)
+		build := TrendBuild{Index: len(builds), File: filepath.Base(file.Path), SessionName: data.SessionName}
+		if !file.Modified.IsZero() {
+			build.Date = file.Modified.Format(time.RFC3339)
+		}
+		builds = append(builds, build)
+		names = append(names, build.File)
+
+		metrics["avgFrameTimeMs"] = append(metrics["avgFrameTimeMs"], averageFrameMs(data))
+		if len(data.Frames) > 0 {
+			stats := computeFrameTimePercentiles(frameTimes(data))
+			metrics["p95FrameTimeMs"] = append(metrics["p95FrameTimeMs"], stats.P95Ms)
+			metrics["p99FrameTimeMs"] = append(metrics["p99FrameTimeMs"], stats.P99Ms)
+			metrics["onePercentLowFPS"] = append(metrics["onePercentLowFPS"], stats.OnePercentLowFPS)
+		}
+		functions = append(functions, mergeFunctionsByName(data.Functions))
+	}
+	if len(builds) < minTrendBuilds {
+		if read < len(files) {
+			return mcp.NewToolResultError(fmt.Sprintf("Timed out after reading %d of %d captures; a trend needs at least %d builds. Pass fewer files or raise timeout_seconds", read, len(files), minTrendBuilds)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("A trend needs at least %d builds; found %d captures", minTrendBuilds, len(builds))), nil
+	}
+
+	// Frame statistics, where every build has them
+	metricTrends := []BuildTrend{}
+	for _, name := range []string{"avgFrameTimeMs", "p95FrameTimeMs", "p99FrameTimeMs", "onePercentLowFPS"} {
+		if len(metrics[name]) != len(builds) {
+			continue
+		}
+		trend := BuildTrend{Metric: name, Unit: "ms", Values: metrics[name]}
+		if name == "onePercentLowFPS" {
+			trend.Unit, trend.higherIsBetter = "fps", true
+		}
+		classifyBuildTrend(&trend, names, minGrowth, tolerance)
+		metricTrends = append(metricTrends, trend)
+	}
+
+	// Functions present in every build and above min_ms in the last one
+	functionTrends := []BuildTrend{}
+	missing := 0
+	counts := make(map[string]int)
+	for name, last := range functions[len(functions)-1] {
+		values := make([]float64, len(functions))
+		present := true
+		for b, byName := range functions {
+			fn, exists := byName[name]
+			present = present && exists
+			values[b] = fn.AvgTimePerFrameMs
+		}
+		if !present {
+			missing++
+			continue
+		}
+		if last.AvgTimePerFrameMs < minMs {
+			continue
+		}
+		trend := BuildTrend{FunctionName: name, ThreadName: last.ThreadName, Unit: "ms", Values: values}
+		classifyBuildTrend(&trend, names, minGrowth, tolerance)
+		counts[trend.Trend]++
+		if trend.Trend != trendStable {
+			functionTrends = append(functionTrends, trend)
+		}
+	}
+	// Creeping regressions first: they are what build-to-build diffs miss
+	rank := map[string]int{trendCreeping: 0, trendRegressing: 1, trendErratic: 2, trendImproving: 3}
+	sort.Slice(functionTrends, func(i, j int) bool {
+		a, b := functionTrends[i], functionTrends[j]
+		if rank[a.Trend] != rank[b.Trend] {
+			return rank[a.Trend] < rank[b.Trend]
+		}
+		return math.Abs(a.Last-a.First) > math.Abs(b.Last-b.First)
+	})
+	tracked := 0
+	for _, count := range counts {
+		tracked += count
+	}
+	listed := functionTrends
+	if len(listed) > topN {
+		listed = listed[:topN]
+	}
+
+	analysis := []string{}
+	describe := func(trend BuildTrend, name string) {
+		switch trend.Trend {
+		case trendCreeping:
+			verb := "rose"
+			if trend.higherIsBetter {
+				verb = "fell"
+			}
+			runFirst := trend.Values[len(trend.Values)-trend.RunBuilds]
+			analysis = append(analysis, fmt.Sprintf("%s steadily %s %.1f%% over %d builds since %s (%.2f to %.2f %s) without a step over %.0f%% (largest %.1f%%), so no build-to-build comparison flags it; detectable at %s",
+				name, verb, trend.RunPercent, trend.RunBuilds, trend.RunStart, runFirst, trend.Last, trend.Unit, trendStepThresholdPercent, trend.MaxStepPercent, trend.DetectableAt))
+		case trendRegressing:
+			analysis = append(analysis, fmt.Sprintf("%s regressed %.1f%% over %d builds since %s, with a step of %.1f%%", name, trend.RunPercent, trend.RunBuilds, trend.RunStart, trend.MaxStepPercent))
+		}
+	}
+	for _, trend := range metricTrends {
+		describe(trend, trend.Metric)
+	}
+	for _, trend := range listed {
+		describe(trend, trend.FunctionName)
+	}
+
+	summary := fmt.Sprintf("%d builds from %s to %s: %d of %d functions creeping, %d regressing, %d improving, %d erratic",
+		len(builds), builds[0].File, builds[len(builds)-1].File, counts[trendCreeping], tracked, counts[trendRegressing], counts[trendImproving], counts[trendErratic])
+	for _, trend := range metricTrends {
+		if trend.Metric == "avgFrameTimeMs" {
+			summary += fmt.Sprintf("; average frame time %s (%+.1f%%)", trend.Trend, trend.ChangePercent)
+		}
+	}
+	output := map[string]interface{}{
+		"order":            order,
+		"builds":           builds,
+		"minGrowthPercent": minGrowth,
+		"tolerancePercent": tolerance,
+		"metricTrends":     metricTrends,
+		"functionTrends":   listed,
+		"functionsTracked": tracked,
+		"trendCounts":      counts,
+		"analysis":         analysis,
+		"summary":          summary,
+	}
+	if missing > 0 {
+		output["functionsNotInEveryBuild"] = missing
+	}
+	if len(skipped) > 0 {
+		output["skippedFiles"] = skipped
+	}
+	if read < len(files) {
+		attachPartial(ctx, output, read, len(files), "capture files")
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Call-frequency anomalies point at per-entity loops: a function called far more often
+// per frame than its peers (functions in the same namespace, else on the same thread), or
+// one whose call count swings from frame to frame. When time per frame follows the call
+// count, the cost scales with the number of entities and batching the calls pays off.
+
+// minPeers is the smallest peer group a function's call count is compared against
+const minPeers = 3
+
+// CallAnomaly is a function with an abnormal number of calls per frame
+type CallAnomaly struct {
+	FunctionName      string         `json:"functionName"`
+	ThreadName        string         `json:"threadName"`
+	AvgCountPerFrame  float64        `json:"avgCountPerFrame"`
+	AvgTimePerFrameMs float64        `json:"avgTimePerFrameMs"`
+	AvgTimePerCallMs  float64        `json:"avgTimePerCallMs"`
+	PeerGroup         string         `json:"peerGroup"`
+	PeerMedianCount   float64        `json:"peerMedianCount"`
+	TimesPeerMedian   float64        `json:"timesPeerMedian"`
+	MinCount          int            `json:"minCountPerFrame,omitempty"`
+	MaxCount          int            `json:"maxCountPerFrame,omitempty"`
+	CountCV           float64        `json:"countCoefficientOfVariation,omitempty"`
+	CountTimeR        float64        `json:"countTimeCorrelation,omitempty"`
+	Reasons           []string       `json:"reasons"`
+	Suggestion        string         `json:"suggestion"`
+	Evidence          *IssueEvidence `json:"evidence,omitempty"`
+}
+
+// avgCallsPerFrame returns a function's calls per frame, derived from the totals when the
+// export has no average
+func avgCallsPerFrame(fn FrameProFunction, totalFrames int) float64 {
+	if fn.AvgCountPerFrame > 0 || totalFrames == 0 {
+		return fn.AvgCountPerFrame
+	}
+	return float64(fn.TotalCount) / float64(totalFrames)
+}
+
+// callPeers returns the name of the peer group of each function and the call counts in it
+func callPeers(functions []FrameProFunction, counts map[string]float64) (map[string]string, map[string][]float64) {
+	namespaceOf := func(fn FrameProFunction) string {
+		namespace, _, found := strings.Cut(fn.FunctionName, "::")
+		if !found {
+			return ""
+		}
+		return namespace
+	}
+	byNamespace := make(map[string][]float64)
+	byThread := make(map[string][]float64)
+	for _, fn := range functions {
+		if namespace := namespaceOf(fn); namespace != "" {
+			byNamespace[namespace] = append(byNamespace[namespace], counts[functionKey(fn)])
+		}
+		byThread[fn.ThreadName] = append(byThread[fn.ThreadName], counts[functionKey(fn)])
+	}
+
+	groups := make(map[string]string)
+	peers := make(map[string][]float64)
+	all := []float64{}
+	for _, fn := range functions {
+		all = append(all, counts[functionKey(fn)])
+	}
+	for _, fn := range functions {
+		key := functionKey(fn)
+		namespace := namespaceOf(fn)
+		switch {
+		case namespace != "" && len(byNamespace[namespace]) > minPeers:
+			groups[key], peers[key] = "namespace "+namespace, byNamespace[namespace]
+		case len(byThread[fn.ThreadName]) > minPeers:
+			groups[key], peers[key] = "thread "+fn.ThreadName, byThread[fn.ThreadName]
+		default:
+			groups[key], peers[key] = "all functions", all
+		}
+	}
+	return groups, peers
+}
+
+func detectCallAnomaliesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	peerFactor := 10.0
+	if f, ok := args["peer_factor"].(float64); ok && f > 1 {
+		peerFactor = f
+	}
+	maxCV := 0.5
+	if cv, ok := args["variation_threshold"].(float64); ok && cv > 0 {
+		maxCV = cv
+	}
+	minCount := 10.0
+	if n, ok := args["min_count_per_frame"].(float64); ok && n >= 0 {
+		minCount = n
+	}
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	totalFrames := data.TotalFrames
+	if len(data.Frames) > 0 {
+		totalFrames = len(data.Frames)
+	}
+	average := aggregateFrame(data)
+	counts := make(map[string]float64)
+	for _, fn := range average.Functions {
+		counts[functionKey(fn)] = avgCallsPerFrame(fn, totalFrames)
+	}
+	groups, peers := callPeers(average.Functions, counts)
+
+	// Calls and time of every function in every frame; absent means not called
+	countSeries := make(map[string][]float64)
+	timeSeries := make(map[string][]float64)
+	for f, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			key := functionKey(fn)
+			if countSeries[key] == nil {
+				countSeries[key] = make([]float64, len(data.Frames))
+				timeSeries[key] = make([]float64, len(data.Frames))
+			}
+			countSeries[key][f] += float64(fn.Count)
+			timeSeries[key][f] += fn.TimeMs
+		}
+	}
+
+	anomalies := []CallAnomaly{}
+	for _, fn := range average.Functions {
+		key := functionKey(fn)
+		count := counts[key]
+		if count < minCount {
+			continue
+		}
+		anomaly := CallAnomaly{
+			FunctionName:      fn.FunctionName,
+			ThreadName:        fn.ThreadName,
+			AvgCountPerFrame:  count,
+			AvgTimePerFrameMs: fn.TimeMs,
+			AvgTimePerCallMs:  fn.TimeMs / count,
+			PeerGroup:         groups[key],
+			PeerMedianCount:   medianOf(peers[key]),
+			Reasons:           []string{},
+		}
+		if anomaly.PeerMedianCount > 0 {
+			anomaly.TimesPeerMedian = count / anomaly.PeerMedianCount
+			if anomaly.TimesPeerMedian >= peerFactor {
+				anomaly.Reasons = append(anomaly.Reasons, fmt.Sprintf("%.0f calls per frame, %.0fx the median of %s (%.1f)",
+					count, anomaly.TimesPeerMedian, anomaly.PeerGroup, anomaly.PeerMedianCount))
+			}
+		}
+
+		if series := countSeries[key]; series != nil {
+			mean, stdDev := meanStdDev(series)
+			anomaly.MinCount, anomaly.MaxCount = int(series[0]), int(series[0])
+			for _, c := range series {
+				anomaly.MinCount, anomaly.MaxCount = min(anomaly.MinCount, int(c)), max(anomaly.MaxCount, int(c))
+			}
+			if mean > 0 {
+				anomaly.CountCV = stdDev / mean
+			}
+			_, _, anomaly.CountTimeR = linearFit(series, timeSeries[key])
+			if anomaly.CountCV >= maxCV {
+				anomaly.Reasons = append(anomaly.Reasons, fmt.Sprintf("Call count varies from %d to %d per frame (coefficient of variation %.2f)",
+					anomaly.MinCount, anomaly.MaxCount, anomaly.CountCV))
+				anomaly.Evidence = callCountEvidence(data, fn, mean+stdDev)
+			}
+		}
+		if len(anomaly.Reasons) == 0 {
+			continue
+		}
+
+		anomaly.Suggestion = "Likely a per-entity loop: batch the calls or process the entities in one pass"
+		if anomaly.CountTimeR >= 0.7 {
+			anomaly.Suggestion = fmt.Sprintf("Time per frame follows the call count (r=%.2f), so the cost scales with the number of entities: batch the calls or process the entities in one pass", anomaly.CountTimeR)
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].AvgTimePerFrameMs > anomalies[j].AvgTimePerFrameMs
+	})
+	found := len(anomalies)
+	if len(anomalies) > topN {
+		anomalies = anomalies[:topN]
+	}
+
+	analysis := []string{}
+	for i, anomaly := range anomalies {
+		if i == 3 {
+			break
+		}
+		analysis = append(analysis, fmt.Sprintf("%s on %s (%.2fms per frame): %s",
+			anomaly.FunctionName, anomaly.ThreadName, anomaly.AvgTimePerFrameMs, strings.Join(anomaly.Reasons, "; ")))
+	}
+	summary := fmt.Sprintf("No call-frequency anomalies among functions called at least %.0f times per frame", minCount)
+	if found > 0 {
+		summary = fmt.Sprintf("%d functions have abnormal call counts; %s costs the most (%.2fms per frame)",
+			found, anomalies[0].FunctionName, anomalies[0].AvgTimePerFrameMs)
+	}
+
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"anomalies":   anomalies,
+		"totalFound":  found,
+		"analysis":    analysis,
+		"summary":     summary,
+	}
+	if len(data.Frames) == 0 {
+		addWarning(output, warnNoFrameData, "No per-frame data; only counts relative to peer functions were checked, not their variation across frames")
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CallTreeNode is one scope of a call tree with inclusive and exclusive times
+type CallTreeNode struct {
+	FunctionName   string         `json:"function"`
+	ThreadName     string         `json:"threadName"`
+	InclusiveMs    float64        `json:"inclusiveMs"`
+	ExclusiveMs    float64        `json:"exclusiveMs"`
+	PercentOfRoot  float64        `json:"percentOfRoot"`
+	Count          int            `json:"count"`
+	Children       []CallTreeNode `json:"children,omitempty"`
+	TruncatedNodes int            `json:"truncatedChildren,omitempty"` // Children hidden by max_depth
+}
+
+// isHierarchical reports whether any function carries nested child scopes
+func isHierarchical(functions []FrameProFunction) bool {
+	for _, fn := range functions {
+		if len(fn.Children) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// inclusiveTimeMs returns the time of a scope including its children
+func inclusiveTimeMs(fn FrameProFunction) float64 {
+	return rankingTimeMs(fn, "inclusive")
+}
+
+// fillHierarchyTimes derives SelfTimeMs for hierarchical exports that don't provide it:
+// a scope's self time is its inclusive time minus the inclusive time of its children
+func fillHierarchyTimes(functions []FrameProFunction) {
+	for i := range functions {
+		fn := &functions[i]
+		fillHierarchyTimes(fn.Children)
+		if fn.SelfTimeMs > 0 {
+			continue
+		}
+		self := inclusiveTimeMs(*fn)
+		for _, child := range fn.Children {
+			self -= inclusiveTimeMs(child)
+		}
+		if self < 0 {
+			self = 0 // Overlapping child scopes on other threads can exceed the parent
+		}
+		fn.SelfTimeMs = self
+	}
+}
+
+// scopeTree returns the scope hierarchy of a capture: Tree for hierarchical captures,
+// whose Functions list every scope flat, else Functions
+func scopeTree(data *FrameProData) []FrameProFunction {
+	if data.Tree != nil {
+		return data.Tree
+	}
+	return data.Functions
+}
+
+// flattenScopes lists every scope of a hierarchy once per function and thread, as flat
+// exports do: times include the children, with self times alongside. Calls of a scope
+// nested in itself are part of the outer call's time and only add to its counts. Frame
+// marker scopes span their whole frame, so only their children are listed.
+func flattenScopes(functions []FrameProFunction) []FrameProFunction {
+	flat := []FrameProFunction{}
+	byKey := make(map[string]int)
+	open := make(map[string]bool)
+	var walk func(functions []FrameProFunction)
+	walk = func(functions []FrameProFunction) {
+		for _, fn := range functions {
+			if isTraceFrameMarker(fn.FunctionName) {
+				walk(fn.Children)
+				continue
+			}
+			key := functionKey(fn)
+			index, exists := byKey[key]
+			if !exists {
+				entry := fn
+				entry.Children = nil
+				entry.TotalTimeMs, entry.SelfTimeMs, entry.InclusiveTimeMs, entry.AvgTimePerFrameMs = 0, 0, 0, 0
+				entry.TotalCount, entry.AvgCountPerFrame = 0, 0
+				entry.MaxTimePerFrameMs, entry.MaxCountPerFrame = 0, 0
+				index = len(flat)
+				byKey[key] = index
+				flat = append(flat, entry)
+			}
+			entry := &flat[index]
+			entry.SelfTimeMs += fn.SelfTimeMs
+			entry.TotalCount += fn.TotalCount
+			entry.AvgCountPerFrame += fn.AvgCountPerFrame
+			entry.MaxCountPerFrame = max(entry.MaxCountPerFrame, fn.MaxCountPerFrame)
+			if !open[key] {
+				entry.TotalTimeMs += fn.TotalTimeMs
+				entry.InclusiveTimeMs += inclusiveTimeMs(fn)
+				entry.AvgTimePerFrameMs += fn.AvgTimePerFrameMs
+				entry.MaxTimePerFrameMs = max(entry.MaxTimePerFrameMs, fn.MaxTimePerFrameMs)
+			}
+			wasOpen := open[key]
+			open[key] = true
+			walk(fn.Children)
+			open[key] = wasOpen
+		}
+	}
+	walk(functions)
+	return flat
+}
+
+// findScopes returns every scope with the given name anywhere in the hierarchy.
+// Matches nested inside another match are not returned separately.
+func findScopes(functions []FrameProFunction, name string, threadID int) []FrameProFunction {
+	matches := []FrameProFunction{}
+	for _, fn := range functions {
+		if fn.FunctionName == name && (threadID == 0 || fn.ThreadID == threadID) {
+			matches = append(matches, fn)
+			continue
+		}
+		matches = append(matches, findScopes(fn.Children, name, threadID)...)
+	}
+	return matches
+}
+
+// buildCallTree converts a scope and its descendants into call tree nodes, down to maxDepth levels
+func buildCallTree(fn FrameProFunction, rootTime float64, depth, maxDepth int) CallTreeNode {
+	node := CallTreeNode{
+		FunctionName: fn.FunctionName,
+		ThreadName:   fn.ThreadName,
+		InclusiveMs:  inclusiveTimeMs(fn),
+		ExclusiveMs:  fn.SelfTimeMs,
+		Count:        fn.TotalCount,
+	}
+	if rootTime > 0 {
+		node.PercentOfRoot = node.InclusiveMs / rootTime * 100
+	}
+
+	if depth >= maxDepth {
+		node.TruncatedNodes = len(fn.Children)
+		return node
+	}
+
+	children := append([]FrameProFunction(nil), fn.Children...)
+	sort.Slice(children, func(i, j int) bool {
+		return inclusiveTimeMs(children[i]) > inclusiveTimeMs(children[j])
+	})
+	for _, child := range children {
+		node.Children = append(node.Children, buildCallTree(child, rootTime, depth+1, maxDepth))
+	}
+	return node
+}
+
+func callTreeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	functionName, _ := args["function"].(string)
+	if functionName == "" {
+		return mcp.NewToolResultError("function is required"), nil
+	}
+	threadID := 0
+	if id, ok := args["thread_id"].(float64); ok {
+		threadID = int(id)
+	}
+	maxDepth := 10
+	if d, ok := args["max_depth"].(float64); ok && d >= 0 {
+		maxDepth = int(d)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if !isHierarchical(scopeTree(data)) {
+		return mcp.NewToolResultError("This export has no call hierarchy (no function has Children). Export with nested scopes to use call trees"), nil
+	}
+
+	roots := findScopes(scopeTree(data), functionName, threadID)
+	if len(roots) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Function '%s' not found in the call hierarchy", functionName)), nil
+	}
+
+	trees := make([]CallTreeNode, len(roots))
+	for i, root := range roots {
+		trees[i] = buildCallTree(root, inclusiveTimeMs(root), 0, maxDepth)
+	}
+
+	first := trees[0]
+	summary := fmt.Sprintf("%s: %.2fms inclusive, %.2fms exclusive", first.FunctionName, first.InclusiveMs, first.ExclusiveMs)
+	if len(first.Children) > 0 {
+		summary += fmt.Sprintf("; heaviest child %s (%.1f%% of root)", first.Children[0].FunctionName, first.Children[0].PercentOfRoot)
+	}
+	if len(trees) > 1 {
+		summary += fmt.Sprintf(" (first of %d matches)", len(trees))
+	}
+
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"function":    functionName,
+		"matches":     len(trees),
+		"maxDepth":    maxDepth,
+		"trees":       trees,
+		"summary":     summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
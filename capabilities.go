@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// inputFormats are the capture formats loadFrameProData understands
+var inputFormats = []map[string]string{
+	{"name": "functions_analysis", "pattern": "*_functions_analysis.json",
+		"description": "FramePro aggregated function export: totals, averages and maxima per function and thread"},
+	{"name": "frame_analysis", "pattern": "*_frame_analysis.json",
+		"description": "FramePro per-frame export: every function's time per frame. Needed for percentiles, pacing, hitches, phases, trends, evidence frames and frame windows"},
+	{"name": "framepro-summary", "pattern": "*summary*.txt",
+		"description": "Legacy FramePro text summaries: Session and Frames header lines and a function table (Function, Thread, Total, Count, Avg/Frame, Max/Frame, in any order, any missing); loads like a functions_analysis export, without per-frame data"},
+	{"name": "hierarchical", "pattern": "*.json",
+		"description": "Exports with nested Children scopes; self times are derived from the children when missing"},
+	{"name": "chrome-trace", "pattern": "*.json",
+		"description": "Chrome Trace Event / Perfetto JSON: X and B/E events become nested scopes per thread; frame markers (Frame, BeginFrame, doFrame) cut per-frame data"},
+	{"name": "speedscope", "pattern": "*.speedscope.json",
+		"description": "speedscope JSON: evented and sampled profiles become nested scopes per profile (thread); frame markers cut per-frame data as in Chrome traces"},
+	{"name": "pprof", "pattern": "*.pprof, *.pb.gz",
+		"description": "pprof CPU profiles (gzipped protobuf, e.g. from Go's runtime/pprof): stacks become a hierarchical call tree, split into threads by a \"thread\" label; the whole profile is one frame"},
+	{"name": "perf-script", "pattern": "*.txt, *.perf",
+		"description": "Linux perf script output (perf record -g): samples become nested scopes per thread; frames are cut where the FRAMEPRO_FRAME_MARKER function enters the stack. The thread whose ID is its process ID is the main thread"},
+	{"name": "folded-stacks", "pattern": "*.folded",
+		"description": "Folded stacks (stackcollapse-perf.pl: \"root;...;leaf count\"): stacks become a call tree per comm-pid/tid prefix, timed at FRAMEPRO_PERF_FREQUENCY (default 4000 Hz); the whole capture is one frame"},
+	{"name": "unreal-csv", "pattern": "*.csv",
+		"description": "Unreal Engine CSV profiler captures (one row per frame; Exclusive/<Thread>/<Stat> and GPU/<Pass> columns become scopes) and Unreal Insights timing event exports (ThreadId, StartTime, EndTime, TimerName; frames cut at FEngineLoop::Tick)"},
+	{"name": "tracy-csv", "pattern": "*.csv",
+		"description": "tracy-csvexport output: unwrapped zones (-u) nest by time per thread with frames cut at Frame zones; zone statistics load as one frame. Binary .tracy captures are recognized but must be exported with tracy-csvexport"},
+	{"name": "unity-json", "pattern": "*.json",
+		"description": "Unity profiler JSON exports (frames with threads and samples from the frame data views): PlayerLoop and other markers become nested scopes, Main Thread is the main thread and job threads are workers. Binary .data captures are recognized but must be exported to JSON"},
+	{"name": "framepro-session", "pattern": "*.framepro",
+		"description": "Native FramePro sessions are recognized but not decoded; export them to JSON from FramePro"},
+}
+
+// analyzePerformanceThresholds are the limits analyze_performance applies
+var analyzePerformanceThresholds = map[string]interface{}{
+	"hotspotTotalMs":          map[string]float64{"high": 100, "critical": 500},
+	"callFrequency":           map[string]float64{"calls": 10000, "totalMs": 50},
+	"longFrameMs":             map[string]float64{"maxPerFrameMs": 16.67, "minCalls": 100},
+	"threadSaturationPercent": map[string]float64{"utilization": 95, "minTotalMs": 100},
+	"mainThreadSpikeMs":       map[string]float64{"high": 16.67, "critical": 33},
+	"maxToAvgRatio":           map[string]float64{"ratio": 5, "minAvgMs": 1},
+	"mainToRenderRatio":       2,
+	"evidenceFramesPerIssue":  maxEvidenceFrames,
+	"evidenceRangesPerIssue":  maxEvidenceRanges,
+}
+
+// optionalFile reports whether an optional data file exists yet
+func optionalFile(path string) map[string]interface{} {
+	_, err := os.Stat(path)
+	return map[string]interface{}{"path": path, "exists": err == nil}
+}
+
+// capabilitiesHandler reports what this deployment supports; it lists the tools registered on s
+func capabilitiesHandler(s *server.MCPServer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tools := []string{}
+		frameTools := []string{}
+		for name, tool := range s.ListTools() {
+			tools = append(tools, name)
+			if _, windowed := tool.Tool.InputSchema.Properties["frame_range"]; windowed {
+				frameTools = append(frameTools, name)
+			}
+		}
+		sort.Strings(tools)
+		sort.Strings(frameTools)
+
+		engines := make(map[string]int)
+		for _, issue := range knownIssues {
+			engines[issue.Engine]++
+		}
+		projectKnownIssues := os.Getenv("FRAMEPRO_KNOWN_ISSUES")
+
+		captures, _ := listCaptureFiles(dataDir, "*.json")
+		auditLogPath := os.Getenv("FRAMEPRO_AUDIT_LOG")
+
+		budgets := []map[string]interface{}{}
+		for _, budget := range defaultBudgets {
+			budgets = append(budgets, map[string]interface{}{"name": budget.Name, "pattern": budget.Pattern, "budgetMs": budget.BudgetMs})
+		}
+
+		output := map[string]interface{}{
+			"dataDirectory":    dataDir,
+			"outputDirectory":  outputDir,
+			"inputFormats":     inputFormats,
+			"tools":            tools,
+			"frameWindowTools": frameTools,
+			"knownIssues": map[string]interface{}{
+				"entries":     len(knownIssues),
+				"byEngine":    engines,
+				"projectFile": projectKnownIssues,
+			},
+			"thresholds": map[string]interface{}{
+				"analyze_performance": analyzePerformanceThresholds,
+				"defaultBudgets60FPS": budgets,
+			},
+			"subsystems": map[string]interface{}{
+				"captureHistory": map[string]interface{}{
+					"enabled":  true,
+					"captures": len(captures),
+					"usedBy":   []string{"search_history", "find_similar_regressions", "project_budget_breach"},
+				},
+				"derivedMetrics":     map[string]interface{}{"declared": len(projectMetrics), "projectFile": os.Getenv("FRAMEPRO_METRICS"), "usedBy": []string{"derived_metrics", "check_budgets", "analyze_trends_within_session", "compare_profiles"}},
+				"tickAnchors":        map[string]interface{}{"declared": len(projectTicks), "projectFile": os.Getenv("FRAMEPRO_TICKS")},
+				"pipelineStages":     map[string]interface{}{"declared": len(projectPipelineStages), "projectFile": os.Getenv("FRAMEPRO_STAGES")},
+				"disabledRules":      map[string]interface{}{"patterns": disabledRules, "projectFile": os.Getenv("FRAMEPRO_RULES")},
+				"annotations":        optionalFile(annotationsPath()),
+				"feedback":           optionalFile(feedbackPath()),
+				"auditLog":           map[string]interface{}{"enabled": auditLogPath != "", "path": auditLogPath},
+				"sessionResources":   map[string]interface{}{"enabled": true, "root": "framepro://sessions"},
+				"outputDetailLevels": []string{"L0", "L1", "L2", "L3"},
+				"warningCodes":       warningCodes,
+				"toolTimeout":        map[string]interface{}{"default": toolTimeout.String(), "perTool": timeoutStrings(toolTimeouts), "tools": timeoutToolNames()},
+				"liveCapture": map[string]interface{}{
+					"enabled": false,
+					"note":    "Only exported JSON captures are analyzed; there is no connection to a running FramePro session",
+				},
+				"transport": "stdio",
+			},
+		}
+
+		analysis := []string{
+			fmt.Sprintf("%d tools, %d of them accept frame windows and outlier filtering", len(tools), len(frameTools)),
+			fmt.Sprintf("%d captures in %s for history searches and budget projections", len(captures), dataDir),
+		}
+		if projectKnownIssues != "" {
+			analysis = append(analysis, fmt.Sprintf("Project known issues loaded from %s", projectKnownIssues))
+		}
+		if auditLogPath != "" {
+			analysis = append(analysis, fmt.Sprintf("Tool calls are audited to %s", auditLogPath))
+		}
+		engineNames := []string{}
+		for engine := range engines {
+			engineNames = append(engineNames, engine)
+		}
+		sort.Strings(engineNames)
+		output["analysis"] = analysis
+		output["summary"] = fmt.Sprintf("Reads FramePro JSON exports (aggregated, per-frame and hierarchical) over stdio; known-issue guidance covers %s; no live capture",
+			strings.Join(engineNames, ", "))
+
+		result, _ := json.MarshalIndent(output, "", "  ")
+
+		return mcp.NewToolResultText(string(result)), nil
+	}
+}
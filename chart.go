@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Charts are drawn into a PNG with the standard library alone and returned as MCP image
+// content, so clients show them inline next to the numbers:
+//
+//	frame_times - frame time per frame as a line, with the 60 and 30 FPS budgets
+//	hotspots    - the functions with the most time per frame as horizontal bars
+//	histogram   - frame times in fixed-width bins, colored by the budget they meet
+//
+// Text uses a built-in 5x7 pixel font (uppercase, digits and common punctuation). With
+// format "text" the same charts are drawn in characters instead (see textchart.go).
+
+const (
+	chartDefaultWidth  = 960
+	chartDefaultHeight = 480
+	chartTextScale     = 2 // Font pixels per glyph pixel
+	chartMargin        = 16
+	chartBudget60Ms    = 1000.0 / 60
+	chartBudget30Ms    = 1000.0 / 30
+)
+
+var (
+	chartBackground = color.RGBA{255, 255, 255, 255}
+	chartInk        = color.RGBA{40, 40, 40, 255}
+	chartGrid       = color.RGBA{225, 225, 225, 255}
+	chartLine       = color.RGBA{21, 101, 192, 255}
+	chartGood       = color.RGBA{67, 160, 71, 255}
+	chartWarn       = color.RGBA{249, 168, 37, 255}
+	chartBad        = color.RGBA{211, 47, 47, 255}
+)
+
+// chartFont holds 5x7 glyphs, one row per byte with the leftmost pixel in bit 4
+var chartFont = map[rune][7]uint8{
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E}, '1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F}, '3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02}, '5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E}, '7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E}, '9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x11, 0x1F, 0x11, 0x11}, 'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E}, 'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F}, 'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F}, 'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E}, 'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11}, 'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11}, 'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E}, 'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D}, 'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E}, 'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E}, 'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A}, 'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04}, 'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	' ': {}, '.': {0, 0, 0, 0, 0, 0x0C, 0x0C}, ':': {0, 0x0C, 0x0C, 0, 0x0C, 0x0C, 0},
+	',': {0, 0, 0, 0, 0x0C, 0x04, 0x08}, '-': {0, 0, 0, 0x1F, 0, 0, 0}, '_': {0, 0, 0, 0, 0, 0, 0x1F},
+	'+': {0, 0x04, 0x04, 0x1F, 0x04, 0x04, 0}, '=': {0, 0, 0x1F, 0, 0x1F, 0, 0},
+	'%': {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03}, '/': {0, 0x01, 0x02, 0x04, 0x08, 0x10, 0},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02}, ')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'[': {0x0E, 0x08, 0x08, 0x08, 0x08, 0x08, 0x0E}, ']': {0x0E, 0x02, 0x02, 0x02, 0x02, 0x02, 0x0E},
+	'<': {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02}, '>': {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
+	'#': {0x0A, 0x0A, 0x1F, 0x0A, 0x1F, 0x0A, 0x0A}, '*': {0, 0x04, 0x15, 0x0E, 0x15, 0x04, 0},
+	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0, 0x04},
+}
+
+// chartCanvas is an image with the drawing primitives the charts need
+type chartCanvas struct {
+	img *image.RGBA
+}
+
+func newChartCanvas(width, height int) *chartCanvas {
+	c := &chartCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+	c.fill(0, 0, width, height, chartBackground)
+	return c
+}
+
+// fill paints a rectangle given by its corner and size
+func (c *chartCanvas) fill(x, y, w, h int, col color.RGBA) {
+	r := image.Rect(x, y, x+w, y+h).Intersect(c.img.Bounds())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			c.img.SetRGBA(px, py, col)
+		}
+	}
+}
+
+// line draws a line two pixels wide; dash > 0 leaves gaps of that length
+func (c *chartCanvas) line(x0, y0, x1, y1 float64, col color.RGBA, dash int) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		if dash > 0 && i/dash%2 == 1 {
+			continue
+		}
+		t := float64(i) / float64(steps)
+		c.fill(int(x0+(x1-x0)*t), int(y0+(y1-y0)*t), 2, 2, col)
+	}
+}
+
+// textWidth is the width of a string in pixels
+func textWidth(s string) int {
+	return len([]rune(s)) * 6 * chartTextScale
+}
+
+// text writes a string with its top left corner at x, y; lowercase is drawn as uppercase
+// and characters without a glyph as '?'
+func (c *chartCanvas) text(x, y int, s string, col color.RGBA) {
+	for _, r := range s {
+		glyph, ok := chartFont[unicode.ToUpper(r)]
+		if !ok {
+			glyph = chartFont['?']
+		}
+		for row, bits := range glyph {
+			for bit := 0; bit < 5; bit++ {
+				if bits&(0x10>>bit) != 0 {
+					c.fill(x+bit*chartTextScale, y+row*chartTextScale, chartTextScale, chartTextScale, col)
+				}
+			}
+		}
+		x += 6 * chartTextScale
+	}
+}
+
+// truncateLabel shortens a label to fit a number of characters
+func truncateLabel(label string, chars int) string {
+	runes := []rune(label)
+	if len(runes) <= chars {
+		return label
+	}
+	return string(runes[:chars-2]) + ".."
+}
+
+// niceStep picks a round axis step (1, 2 or 5 times a power of ten) giving about n ticks
+func niceStep(maxValue float64, n int) float64 {
+	if maxValue <= 0 {
+		return 1
+	}
+	raw := maxValue / float64(n)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// chartLabel formats an axis value without needless decimals
+func chartLabel(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
+
+// budgetColor colors a frame time by the budget it meets
+func budgetColor(ms float64) color.RGBA {
+	switch {
+	case ms > chartBudget30Ms:
+		return chartBad
+	case ms > chartBudget60Ms:
+		return chartWarn
+	}
+	return chartGood
+}
+
+// chartPlot is the plotting area of a chart below its title, with a value axis on the left
+type chartPlot struct {
+	c               *chartCanvas
+	left, top, w, h int
+	maxValue        float64
+}
+
+// newChartPlot draws the title and the horizontal grid lines of a value axis up to maxValue
+func newChartPlot(c *chartCanvas, title, unit string, maxValue float64) *chartPlot {
+	width, height := c.img.Bounds().Dx(), c.img.Bounds().Dy()
+	c.text(chartMargin, chartMargin, truncateLabel(title, (width-2*chartMargin)/(6*chartTextScale)), chartInk)
+	step := niceStep(maxValue, 5)
+	maxValue = math.Ceil(maxValue/step) * step
+	labelWidth := textWidth(chartLabel(maxValue)+unit) + chartMargin
+	top := chartMargin*2 + 7*chartTextScale
+	p := &chartPlot{c: c, left: chartMargin + labelWidth, top: top, w: width - chartMargin*2 - labelWidth, h: height - top - chartMargin*2 - 7*chartTextScale, maxValue: maxValue}
+	for v := 0.0; v <= maxValue+step/2; v += step {
+		y := p.y(v)
+		c.fill(p.left, int(y), p.w, 1, chartGrid)
+		label := chartLabel(v) + unit
+		c.text(p.left-textWidth(label)-chartTextScale*3, int(y)-7*chartTextScale/2, label, chartInk)
+	}
+	return p
+}
+
+// y is the pixel row of a value
+func (p *chartPlot) y(v float64) float64 {
+	return float64(p.top+p.h) - math.Min(v, p.maxValue)/p.maxValue*float64(p.h)
+}
+
+// budgetLines marks the 60 and 30 FPS frame budgets when they are on the axis
+func (p *chartPlot) budgetLines() {
+	for _, budget := range []struct {
+		ms    float64
+		label string
+		col   color.RGBA
+	}{{chartBudget60Ms, "60 FPS", chartWarn}, {chartBudget30Ms, "30 FPS", chartBad}} {
+		if budget.ms < p.maxValue {
+			y := p.y(budget.ms)
+			p.c.line(float64(p.left), y, float64(p.left+p.w), y, budget.col, 8)
+			p.c.text(p.left+p.w-textWidth(budget.label), int(y)-9*chartTextScale, budget.label, budget.col)
+		}
+	}
+}
+
+// drawFrameTimeChart plots the frame times as a line
+func drawFrameTimeChart(c *chartCanvas, title string, numbers []int, times []float64) {
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+	top := math.Max(percentile(sorted, 99)*1.2, chartBudget30Ms*1.1) // Outliers are clipped at the top
+	p := newChartPlot(c, title, "MS", top)
+	p.budgetLines()
+	x := func(i int) float64 {
+		if len(times) == 1 {
+			return float64(p.left + p.w/2)
+		}
+		return float64(p.left) + float64(i)/float64(len(times)-1)*float64(p.w-2)
+	}
+	for i := range times {
+		if i > 0 {
+			c.line(x(i-1), p.y(times[i-1]), x(i), p.y(times[i]), chartLine, 0)
+		}
+	}
+	bottom := p.top + p.h + chartTextScale*3
+	c.text(p.left, bottom, fmt.Sprintf("FRAME %d", numbers[0]), chartInk)
+	last := fmt.Sprintf("FRAME %d", numbers[len(numbers)-1])
+	c.text(p.left+p.w-textWidth(last), bottom, last, chartInk)
+}
+
+// ChartBar is one bar of a bar chart
+type ChartBar struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+}
+
+// drawHotspotChart draws the bars horizontally, largest first, with their values
+func drawHotspotChart(c *chartCanvas, title string, bars []ChartBar) {
+	width, height := c.img.Bounds().Dx(), c.img.Bounds().Dy()
+	c.text(chartMargin, chartMargin, truncateLabel(title, (width-2*chartMargin)/(6*chartTextScale)), chartInk)
+	labelChars := 0
+	for _, bar := range bars {
+		labelChars = max(labelChars, len([]rune(bar.Label)))
+	}
+	labelChars = min(labelChars, (width/3)/(6*chartTextScale))
+	left := chartMargin*2 + labelChars*6*chartTextScale
+	top := chartMargin*2 + 7*chartTextScale
+	valueWidth := textWidth("0000.00MS") + chartMargin
+	rowHeight := max((height-top-chartMargin)/max(len(bars), 1), 10*chartTextScale)
+	maxValue := 0.0
+	for _, bar := range bars {
+		maxValue = math.Max(maxValue, bar.Value)
+	}
+	for i, bar := range bars {
+		y := top + i*rowHeight
+		barHeight := rowHeight * 3 / 4
+		c.text(chartMargin, y+(barHeight-7*chartTextScale)/2, truncateLabel(bar.Label, labelChars), chartInk)
+		barWidth := 0
+		if maxValue > 0 {
+			barWidth = int(bar.Value / maxValue * float64(width-left-valueWidth-chartMargin))
+		}
+		c.fill(left, y, max(barWidth, 1), barHeight, chartLine)
+		c.text(left+barWidth+chartTextScale*3, y+(barHeight-7*chartTextScale)/2, chartLabel(math.Round(bar.Value*100)/100)+"MS", chartInk)
+	}
+}
+
+// drawHistogramChart draws the bins as vertical bars colored by frame budget
+func drawHistogramChart(c *chartCanvas, title string, buckets []HistogramBucket) {
+	maxCount := 0
+	for _, b := range buckets {
+		maxCount = max(maxCount, b.Count)
+	}
+	p := newChartPlot(c, title, "", float64(max(maxCount, 1)))
+	slot := float64(p.w) / float64(len(buckets))
+	for i, b := range buckets {
+		y := p.y(float64(b.Count))
+		x := float64(p.left) + float64(i)*slot
+		c.fill(int(x)+1, int(y), max(int(slot)-2, 1), p.top+p.h-int(y), budgetColor((b.MinMs+b.MaxMs)/2))
+	}
+	bottom := p.top + p.h + chartTextScale*3
+	labelEvery := max(1, int(math.Ceil(float64(textWidth("000MS")+chartMargin)/slot)))
+	for i := 0; i < len(buckets); i += labelEvery {
+		label := chartLabel(buckets[i].MinMs) + "MS"
+		if x := p.left + int(float64(i)*slot); x+textWidth(label) <= p.left+p.w {
+			c.text(x, bottom, label, chartInk)
+		}
+	}
+}
+
+// encodeChart encodes the image as base64 PNG
+func encodeChart(c *chartCanvas) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func renderChartHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	chart, _ := args["chart"].(string)
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "text" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid format '%s': expected 'png' or 'text'", format)), nil
+	}
+	textMode := format == "text"
+	width, height := chartDefaultWidth, chartDefaultHeight
+	if textMode {
+		width = textChartDefaultWidth
+	}
+	if w, ok := args["width"].(float64); ok {
+		width = int(w)
+	}
+	if h, ok := args["height"].(float64); ok {
+		height = int(h)
+	}
+	if textMode && (width < textChartMinWidth || width > textChartMaxWidth) {
+		return mcp.NewToolResultError(fmt.Sprintf("width must be between %d and %d characters for text charts", textChartMinWidth, textChartMaxWidth)), nil
+	}
+	if !textMode && (width < 320 || height < 200 || width > 4096 || height > 4096) {
+		return mcp.NewToolResultError("width and height must be between 320x200 and 4096x4096 pixels"), nil
+	}
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+	binMs := 1.0
+	if b, ok := args["bin_ms"].(float64); ok {
+		if b <= 0 {
+			return mcp.NewToolResultError("bin_ms must be a positive bin width in milliseconds"), nil
+		}
+		binMs = b
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var canvas *chartCanvas
+	var text string
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"chart":       chart,
+		"format":      format,
+		"width":       width,
+	}
+	if textMode {
+		output["widthUnit"] = "characters"
+	} else {
+		canvas = newChartCanvas(width, height)
+		output["height"] = height
+	}
+	switch chart {
+	case "frame_times", "histogram":
+		if len(data.Frames) == 0 {
+			return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+		}
+		times := frameTimes(data)
+		stats := computeFrameTimePercentiles(times)
+		if chart == "frame_times" {
+			numbers := make([]int, len(data.Frames))
+			for i, frame := range data.Frames {
+				numbers[i] = frame.FrameNumber
+			}
+			title := fmt.Sprintf("Frame times - %s (%d frames)", data.SessionName, len(times))
+			if textMode {
+				text = textFrameTimeChart(title, numbers, times, width)
+			} else {
+				drawFrameTimeChart(canvas, title, numbers, times)
+			}
+			kind := "line chart"
+			if textMode {
+				kind = "sparkline"
+			}
+			output["summary"] = fmt.Sprintf("Frame-time %s of %d frames: avg %.2fms, p99 %.2fms", kind, len(times), stats.AvgFrameTimeMs, stats.P99Ms)
+		} else {
+			sorted := append([]float64(nil), times...)
+			sort.Float64s(sorted)
+			last := math.Max(math.Ceil(percentile(sorted, 99)*1.2/binMs)*binMs, math.Ceil(chartBudget30Ms*1.1/binMs)*binMs)
+			edges := []float64{}
+			for edge := binMs; edge <= last && len(edges) < 200; edge += binMs {
+				edges = append(edges, edge)
+			}
+			buckets := buildHistogram(times, edges)
+			buckets[len(buckets)-1].MaxMs = buckets[len(buckets)-1].MinMs + binMs // Overflow bin, drawn one bin wide
+			title := fmt.Sprintf("Frame-time histogram - %s (%sms bins)", data.SessionName, chartLabel(binMs))
+			if textMode {
+				text = textHistogramChart(title, buckets, width)
+			} else {
+				drawHistogramChart(canvas, title, buckets)
+			}
+			output["binMs"] = binMs
+			output["bins"] = len(buckets)
+			output["summary"] = fmt.Sprintf("Frame-time histogram of %d frames in %sms bins; the last bin holds everything from %.2fms", len(times), chartLabel(binMs), buckets[len(buckets)-1].MinMs)
+		}
+		output["frameTimes"] = stats
+	case "hotspots":
+		if err := checkCapture(data, false); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		functions := append([]FrameProFunction(nil), data.Functions...)
+		sort.Slice(functions, func(i, j int) bool { return functions[i].AvgTimePerFrameMs > functions[j].AvgTimePerFrameMs })
+		bars := []ChartBar{}
+		for _, fn := range functions[:min(topN, len(functions))] {
+			bars = append(bars, ChartBar{Label: fmt.Sprintf("%s (%s)", fn.FunctionName, fn.ThreadName), Value: fn.AvgTimePerFrameMs})
+		}
+		title := fmt.Sprintf("Top %d functions by ms per frame - %s", len(bars), data.SessionName)
+		if textMode {
+			text = textHotspotChart(title, bars, width)
+		} else {
+			drawHotspotChart(canvas, title, bars)
+		}
+		output["bars"] = bars
+		output["summary"] = fmt.Sprintf("Bar chart of the %d functions with the most time per frame", len(bars))
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid chart '%s': expected 'frame_times', 'hotspots' or 'histogram'", chart)), nil
+	}
+	attachFrameWindow(output, window)
+
+	if textMode {
+		result, _ := json.MarshalIndent(output, "", "  ")
+		return &mcp.CallToolResult{Content: []mcp.Content{
+			mcp.NewTextContent(string(result)),
+			mcp.NewTextContent(text),
+		}}, nil
+	}
+
+	encoded, err := encodeChart(canvas)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode the chart: %v", err)), nil
+	}
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.NewTextContent(string(result)),
+		mcp.NewImageContent(encoded, "image/png"),
+	}}, nil
+}
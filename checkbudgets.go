@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Budget checks compare every frame with the subsystem budgets (see budgets.go) and list,
+// per budget, the frames that blew it and the functions responsible. A function is blamed
+// for a breach frame when it is the budget's most expensive function in that frame.
+// Derived metrics with a budget (see metrics.go) are checked the same way, without culprits.
+
+// BudgetBreach is one frame over a subsystem budget
+type BudgetBreach struct {
+	Frame        int            `json:"frame"`
+	TimeMs       float64        `json:"timeMs"`
+	OverMs       float64        `json:"overMs"`
+	TopFunctions []SpikeCulprit `json:"topFunctions"`
+}
+
+// BudgetCulprit is a function that drives a subsystem over budget
+type BudgetCulprit struct {
+	FunctionName  string  `json:"functionName"`
+	ThreadName    string  `json:"threadName"`
+	AvgMs         float64 `json:"avgMs"`
+	AvgInBreachMs float64 `json:"avgInBreachFramesMs"`
+	TopInBreaches int     `json:"topInBreachFrames"` // Breach frames where it was the budget's largest function
+}
+
+// BudgetCheck is a subsystem's actual time per frame against its budget
+type BudgetCheck struct {
+	Name        string          `json:"name"`
+	Pattern     string          `json:"pattern"`
+	BudgetMs    float64         `json:"budgetMs"`
+	AvgMs       float64         `json:"avgMs"`
+	P95Ms       float64         `json:"p95Ms"`
+	MaxMs       float64         `json:"maxMs"`
+	FramesOver  int             `json:"framesOverBudget"`
+	PercentOver float64         `json:"percentFramesOverBudget"`
+	Status      string          `json:"status"`
+	WorstFrames []BudgetBreach  `json:"worstFrames"`
+	Culprits    []BudgetCulprit `json:"culprits"`
+	Evidence    *IssueEvidence  `json:"evidence,omitempty"`
+}
+
+// budgetStatus describes a subsystem's frames against its budget
+func budgetStatus(check BudgetCheck) string {
+	switch {
+	case check.AvgMs == 0:
+		return "not recorded"
+	case check.AvgMs > check.BudgetMs:
+		return "over budget on average"
+	case check.FramesOver > 0:
+		return "over budget in some frames"
+	default:
+		return "within budget"
+	}
+}
+
+func checkBudgetsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	topN := 5
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+	metrics, err := parseMetrics(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, data)
+	budgets, err := parseBudgets(args, targetFPS)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	times, frames := subsystemFrameTimes(data, budgets)
+	averages := avgFunctionTimes(frames)
+	checks := []BudgetCheck{}
+	for i, budget := range budgets {
+		check := BudgetCheck{Name: budget.Name, Pattern: budget.Pattern, BudgetMs: budget.BudgetMs, WorstFrames: []BudgetBreach{}, Culprits: []BudgetCulprit{}}
+		stats := computeFrameTimePercentiles(times[i])
+		check.AvgMs, check.P95Ms = stats.AvgFrameTimeMs, stats.P95Ms
+
+		// Frames over budget, with the budget's functions in each
+		culprits := make(map[string]*BudgetCulprit)
+		breaches := []BudgetBreach{}
+		for f, ms := range times[i] {
+			check.MaxMs = max(check.MaxMs, ms)
+			if ms <= budget.BudgetMs {
+				continue
+			}
+			breach := BudgetBreach{Frame: frames[f].FrameNumber, TimeMs: ms, OverMs: ms - budget.BudgetMs}
+			for _, fn := range topFrameFunctions(frames[f], 0) {
+				if matchBudget(budgets, fn.FunctionName) != i {
+					continue
+				}
+				key := functionKey(fn)
+				culprit, exists := culprits[key]
+				if !exists {
+					culprit = &BudgetCulprit{FunctionName: fn.FunctionName, ThreadName: fn.ThreadName}
+					culprits[key] = culprit
+				}
+				if len(breach.TopFunctions) == 0 {
+					culprit.TopInBreaches++
+				}
+				culprit.AvgInBreachMs += fn.TimeMs
+				if len(breach.TopFunctions) < 3 {
+					breach.TopFunctions = append(breach.TopFunctions, SpikeCulprit{FunctionName: fn.FunctionName, ThreadName: fn.ThreadName,
+						TimeMs: fn.TimeMs, AvgTimeMs: averages[key], ExcessMs: fn.TimeMs - averages[key]})
+				}
+			}
+			breaches = append(breaches, breach)
+		}
+		check.FramesOver = len(breaches)
+		check.PercentOver = float64(len(breaches)) / float64(len(frames)) * 100
+		check.Status = budgetStatus(check)
+
+		sort.Slice(breaches, func(a, b int) bool {
+			return breaches[a].OverMs > breaches[b].OverMs
+		})
+		if len(breaches) > topN {
+			breaches = breaches[:topN]
+		}
+		check.WorstFrames = breaches
+
+		for key, culprit := range culprits {
+			culprit.AvgInBreachMs /= float64(check.FramesOver)
+			culprit.AvgMs = averages[key]
+			check.Culprits = append(check.Culprits, *culprit)
+		}
+		sort.Slice(check.Culprits, func(a, b int) bool {
+			if check.Culprits[a].TopInBreaches != check.Culprits[b].TopInBreaches {
+				return check.Culprits[a].TopInBreaches > check.Culprits[b].TopInBreaches
+			}
+			return check.Culprits[a].AvgInBreachMs > check.Culprits[b].AvgInBreachMs
+		})
+		if len(check.Culprits) > topN {
+			check.Culprits = check.Culprits[:topN]
+		}
+
+		if len(data.Frames) > 0 {
+			byFrame := make(map[int]float64)
+			for f, ms := range times[i] {
+				byFrame[frames[f].FrameNumber] = ms
+			}
+			check.Evidence = frameEvidence(data, func(frame FrameProFrame) float64 { return byFrame[frame.FrameNumber] }, budget.BudgetMs)
+		}
+		checks = append(checks, check)
+	}
+	// Derived metrics with a budget are checked alongside the subsystems
+	checks = append(checks, metricBudgetChecks(data, metrics, topN)...)
+	sort.SliceStable(checks, func(a, b int) bool {
+		return checks[a].PercentOver > checks[b].PercentOver
+	})
+
+	analysis := []string{}
+	blown := 0
+	for _, check := range checks {
+		if check.FramesOver == 0 {
+			continue
+		}
+		blown++
+		line := fmt.Sprintf("%s blew its %.2fms budget in %d of %d frames (%.0f%%, worst %.2fms at frame %d)",
+			check.Name, check.BudgetMs, check.FramesOver, len(frames), check.PercentOver, check.WorstFrames[0].TimeMs, check.WorstFrames[0].Frame)
+		if len(check.Culprits) > 0 {
+			line += fmt.Sprintf("; %s is the largest %s function in %d of them", check.Culprits[0].FunctionName, check.Name, check.Culprits[0].TopInBreaches)
+		}
+		analysis = append(analysis, line)
+	}
+
+	summary := fmt.Sprintf("Every subsystem stayed within budget in all %d frames at %.0f FPS", len(frames), targetFPS)
+	if len(data.Frames) == 0 {
+		summary = fmt.Sprintf("Every subsystem is within budget on the average frame at %.0f FPS", targetFPS)
+	}
+	if blown > 0 {
+		summary = fmt.Sprintf("%d of %d subsystems went over budget at %.0f FPS; %s most often", blown, len(checks), targetFPS, checks[0].Name)
+	}
+
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"targetFPS":       targetFPS,
+		"targetFPSSource": targetSource,
+		"framesAnalyzed":  len(frames),
+		"budgets":         checks,
+		"analysis":        analysis,
+		"summary":         summary,
+	}
+	if len(data.Frames) == 0 {
+		addWarning(output, warnNoFrameData, "No per-frame data; budgets were checked against the average frame, so individual frames over budget are not visible")
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
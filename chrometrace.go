@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Chrome Trace Event files (chrome://tracing, Perfetto JSON) record scopes as complete
+// events (ph X, with a duration) or begin/end pairs (ph B/E) per thread, in microseconds.
+// Scopes nest by time on their thread and become hierarchical functions. Frames are cut at
+// frame markers: instant or scope events named like "Frame", "BeginFrame" or "doFrame" on
+// one thread, which also becomes the main thread. Without markers the trace is one frame.
+// Per-frame scopes keep their start within the frame (StartMs).
+
+// TraceEvent is one event of a Chrome trace
+type TraceEvent struct {
+	Name string                 `json:"name"`
+	Ph   string                 `json:"ph"`
+	Ts   float64                `json:"ts"`  // Microseconds
+	Dur  float64                `json:"dur"` // Microseconds, complete events only
+	Pid  json.RawMessage        `json:"pid"`
+	Tid  json.RawMessage        `json:"tid"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// traceSpan is a scope of a trace with its nested scopes
+type traceSpan struct {
+	name       string
+	thread     int
+	start, end float64
+	children   []*traceSpan
+}
+
+// traceFrameMarkers are the event names that start a frame, lowercased without spaces or numbers
+var traceFrameMarkers = map[string]bool{
+	"frame": true, "gameframe": true, "newframe": true, "beginframe": true, "framestart": true,
+	"framebegin": true, "framemarker": true, "frameboundary": true, "doframe": true, "choreographer#doframe": true,
+	"fengineloop::tick": true,
+}
+
+// frameMarker replaces traceFrameMarkers when FRAMEPRO_FRAME_MARKER names the project's own
+var frameMarker *regexp.Regexp
+
+// setFrameMarker makes scopes matching a function pattern the frame markers of every import
+func setFrameMarker(pattern string) error {
+	re, err := compileFunctionPattern(pattern, false)
+	if err != nil {
+		return fmt.Errorf("invalid FRAMEPRO_FRAME_MARKER: %w", err)
+	}
+	frameMarker = re
+	return nil
+}
+
+// isTraceFrameMarker reports whether an event name marks the start of a frame
+func isTraceFrameMarker(name string) bool {
+	if frameMarker != nil {
+		return frameMarker.MatchString(name)
+	}
+	key := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r >= '0' && r <= '9' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+	return traceFrameMarkers[strings.TrimSuffix(key, "#")]
+}
+
+// isChromeTrace reports whether a JSON document is a Chrome trace: an array of events or
+// an object with traceEvents
+func isChromeTrace(trimmed []byte) bool {
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return true
+	}
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(trimmed, &probe) != nil {
+		return false
+	}
+	_, found := probe["traceEvents"]
+	return found
+}
+
+// decodeChromeTrace converts a Chrome trace into hierarchical per-frame and aggregate functions
+func decodeChromeTrace(path string, content []byte) (*FrameProData, error) {
+	var events []TraceEvent
+	if len(content) > 0 && content[0] == '[' {
+		if err := json.Unmarshal(content, &events); err != nil {
+			return nil, fmt.Errorf("failed to parse trace events: %w", err)
+		}
+	} else {
+		var trace struct {
+			TraceEvents []TraceEvent `json:"traceEvents"`
+		}
+		if err := json.Unmarshal(content, &trace); err != nil {
+			return nil, fmt.Errorf("failed to parse trace events: %w", err)
+		}
+		events = trace.TraceEvents
+	}
+
+	// Threads are numbered in order of appearance; pid and tid may be numbers or strings
+	threadIDs := make(map[string]int)
+	threadNames := make(map[int]string)
+	threadOf := func(event TraceEvent) int {
+		key := string(event.Pid) + "/" + string(event.Tid)
+		id, exists := threadIDs[key]
+		if !exists {
+			id = len(threadIDs) + 1
+			threadIDs[key] = id
+			threadNames[id] = "Thread " + strings.Trim(string(event.Tid), `"`)
+		}
+		return id
+	}
+
+	spans := make(map[int][]*traceSpan)
+	open := make(map[int][]*traceSpan)
+	markers := make(map[int][]float64)
+	for _, event := range events {
+		switch event.Ph {
+		case "M":
+			if event.Name == "thread_name" {
+				if name, ok := event.Args["name"].(string); ok && name != "" {
+					threadNames[threadOf(event)] = name
+				}
+			}
+			continue
+		case "X", "B", "E", "i", "I", "R":
+		default:
+			continue
+		}
+		thread := threadOf(event)
+		if event.Ph != "E" && isTraceFrameMarker(event.Name) {
+			markers[thread] = append(markers[thread], event.Ts)
+		}
+		switch event.Ph {
+		case "X":
+			spans[thread] = append(spans[thread], &traceSpan{name: event.Name, thread: thread, start: event.Ts, end: event.Ts + event.Dur})
+		case "B":
+			open[thread] = append(open[thread], &traceSpan{name: event.Name, thread: thread, start: event.Ts})
+		case "E":
+			if stack := open[thread]; len(stack) > 0 {
+				span := stack[len(stack)-1]
+				open[thread] = stack[:len(stack)-1]
+				span.end = event.Ts
+				spans[thread] = append(spans[thread], span)
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("%s has no complete (X) or begin/end (B/E) trace events", filepath.Base(path))
+	}
+	return buildTraceCapture(path, spans, threadNames, markers), nil
+}
+
+// buildTraceCapture cuts timed spans (in microseconds) into frames at the frame markers
+// and builds hierarchical per-frame and aggregate functions
+func buildTraceCapture(path string, spans map[int][]*traceSpan, threadNames map[int]string, markers map[int][]float64) *FrameProData {
+	// The thread with the most frame markers cuts the frames
+	markerThread := 0
+	for thread, times := range markers {
+		if len(times) > len(markers[markerThread]) || len(times) == len(markers[markerThread]) && thread < markerThread {
+			markerThread = thread
+		}
+	}
+	boundaries := markers[markerThread]
+	sort.Float64s(boundaries)
+	// A marker scope on the last boundary also ends the last frame
+	for _, span := range spans[markerThread] {
+		if len(boundaries) > 0 && span.start == boundaries[len(boundaries)-1] && span.end > span.start && isTraceFrameMarker(span.name) {
+			boundaries = append(boundaries, span.end)
+			break
+		}
+	}
+
+	// A track holding nothing but frame markers only cuts frames; the main thread is found by name
+	markersOnly := len(spans[markerThread]) > 0
+	for _, span := range spans[markerThread] {
+		markersOnly = markersOnly && isTraceFrameMarker(span.name)
+	}
+	if markersOnly {
+		delete(spans, markerThread)
+		markerThread = 0
+	}
+
+	frameCount := max(len(boundaries)-1, 1)
+	roots := make([][]*traceSpan, frameCount)
+	for _, threadSpans := range spans {
+		for _, root := range nestTraceSpans(threadSpans) {
+			frame := 0
+			if len(boundaries) > 1 {
+				frame = sort.SearchFloat64s(boundaries, root.start+1e-9) - 1
+				if frame < 0 || frame >= frameCount {
+					continue // Before the first marker or in the unfinished last frame
+				}
+			}
+			roots[frame] = append(roots[frame], root)
+		}
+	}
+
+	flags := func(fn *FrameProFunction) {
+		name := strings.ToLower(fn.ThreadName)
+		fn.IsMainThread = fn.ThreadID == markerThread ||
+			markerThread == 0 && (strings.Contains(name, "main") || strings.Contains(name, "gamethread") || strings.Contains(name, "game thread"))
+		fn.IsRenderThread = !fn.IsMainThread && gpuQueueKind(*fn) == "" && (strings.Contains(name, "render") || strings.Contains(name, "rhi"))
+		fn.IsWorkerThread = !fn.IsMainThread && !fn.IsRenderThread && isWorkerThreadName(fn.ThreadName)
+	}
+	data := &FrameProData{
+		SessionName: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		TotalFrames: frameCount,
+	}
+	for i, frameRoots := range roots {
+		frame := FrameProFrame{FrameNumber: i}
+		sort.SliceStable(frameRoots, func(a, b int) bool {
+			return frameRoots[a].start < frameRoots[b].start
+		})
+		frameStart := 0.0
+		if len(boundaries) > 1 {
+			frameStart = boundaries[i]
+		} else if len(frameRoots) > 0 {
+			frameStart = frameRoots[0].start
+		}
+		for _, root := range frameRoots {
+			addTraceSpan(&frame.Functions, root, frameStart, threadNames, flags)
+		}
+		for _, fn := range frame.Functions {
+			addFrameScope(&data.Functions, fn)
+		}
+		data.Frames = append(data.Frames, frame)
+	}
+	if len(boundaries) < 2 {
+		data.Frames = nil // One frame for the whole trace is not per-frame data
+	}
+	averageScopes(data.Functions, float64(frameCount))
+	return data
+}
+
+// nestTraceSpans nests the spans of one thread by time and returns the outermost ones
+func nestTraceSpans(spans []*traceSpan) []*traceSpan {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+	roots := []*traceSpan{}
+	stack := []*traceSpan{}
+	for _, span := range spans {
+		for len(stack) > 0 && stack[len(stack)-1].end <= span.start {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, span)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, span)
+		}
+		stack = append(stack, span)
+	}
+	return roots
+}
+
+// addTraceSpan merges a span into a frame's scopes; same-named siblings on a thread add up
+// and keep the start of the first one
+func addTraceSpan(into *[]FrameProFunction, span *traceSpan, frameStart float64, threadNames map[int]string, flags func(*FrameProFunction)) {
+	index := -1
+	for i, fn := range *into {
+		if fn.FunctionName == span.name && fn.ThreadID == span.thread {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		startMs := (span.start - frameStart) / 1000
+		fn := FrameProFunction{FunctionName: span.name, ThreadID: span.thread, ThreadName: threadNames[span.thread], StartMs: &startMs}
+		flags(&fn)
+		*into = append(*into, fn)
+		index = len(*into) - 1
+	}
+	fn := &(*into)[index]
+	fn.TimeMs += (span.end - span.start) / 1000
+	fn.Count++
+	for _, child := range span.children {
+		addTraceSpan(&fn.Children, child, frameStart, threadNames, flags)
+	}
+}
+
+// addFrameScope adds one frame's scope and its children to the aggregate functions
+func addFrameScope(into *[]FrameProFunction, scope FrameProFunction) {
+	index := -1
+	for i, fn := range *into {
+		if fn.FunctionName == scope.FunctionName && fn.ThreadID == scope.ThreadID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		fn := scope
+		fn.TimeMs, fn.Count, fn.Children, fn.StartMs, fn.SelfTimeMs = 0, 0, nil, nil, 0
+		*into = append(*into, fn)
+		index = len(*into) - 1
+	}
+	fn := &(*into)[index]
+	fn.TotalTimeMs += scope.TimeMs
+	fn.TotalCount += scope.Count
+	fn.MaxTimePerFrameMs = max(fn.MaxTimePerFrameMs, scope.TimeMs)
+	fn.MaxCountPerFrame = max(fn.MaxCountPerFrame, scope.Count)
+	for _, child := range scope.Children {
+		addFrameScope(&fn.Children, child)
+	}
+}
+
+// averageScopes fills the per-frame averages of aggregate functions and their children
+func averageScopes(functions []FrameProFunction, frames float64) {
+	for i := range functions {
+		fn := &functions[i]
+		fn.AvgTimePerFrameMs = fn.TotalTimeMs / frames
+		fn.AvgCountPerFrame = float64(fn.TotalCount) / frames
+		fn.InclusiveTimeMs = fn.TotalTimeMs
+		averageScopes(fn.Children, frames)
+	}
+}
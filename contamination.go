@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// A capture machine that stalls (a background process, paging, a driver hiccup) freezes
+// every thread at once: each one's busy scopes grow by about the same time, whichever
+// scope it happened to be in. An in-game spike grows one thread's work while the others
+// wait for it, so their waits grow instead. Spike frames in which most active threads'
+// busy time grew by a large share of the spike are labeled contaminated; when the threads
+// grew by nearly the same milliseconds the frame is a uniform gap, typical of the whole
+// process being descheduled. Labeled frames can be excluded with exclude_contaminated.
+
+const (
+	contaminationThreadExcess = 0.5  // Share of the frame's excess a thread's busy time must grow by to count as stalled
+	contaminationThreadShare  = 0.6  // Share of active threads that must be stalled
+	contaminationMinThreadMs  = 0.5  // Median busy time for a thread to count as active
+	uniformGapSpread          = 0.25 // Coefficient of variation of the threads' growth below which the stall is a uniform gap
+)
+
+// Contamination kinds
+const (
+	contaminationStall = "system-stall"
+	contaminationGap   = "uniform-gap"
+)
+
+// ContaminatedFrame is a spike frame attributed to the capture machine
+type ContaminatedFrame struct {
+	FrameNumber    int      `json:"frame"`
+	FrameTimeMs    float64  `json:"frameTimeMs"`
+	ExcessMs       float64  `json:"excessMs"` // Above the median frame time
+	Kind           string   `json:"kind"`
+	StalledThreads []string `json:"stalledThreads"`
+	ActiveThreads  int      `json:"activeThreads"`
+	AvgGrowthMs    float64  `json:"avgThreadGrowthMs"`
+	Reason         string   `json:"reason"`
+}
+
+// frameBusy sums each thread's non-wait time in a frame
+func frameBusy(frame FrameProFrame) map[int]float64 {
+	busy := make(map[int]float64)
+	for _, fn := range frame.Functions {
+		if !isWaitScope(fn.FunctionName) {
+			busy[fn.ThreadID] += fn.TimeMs
+		}
+	}
+	return busy
+}
+
+// detectContamination labels the spike frames (over factor times the median) that look
+// like machine-wide stalls rather than in-game problems
+func detectContamination(data *FrameProData, factor float64) []ContaminatedFrame {
+	contaminated := []ContaminatedFrame{}
+	if len(data.Frames) == 0 {
+		return contaminated
+	}
+	times := frameTimes(data)
+	median := medianOf(times)
+
+	busy := make([]map[int]float64, len(data.Frames))
+	series := make(map[int][]float64)
+	names := make(map[int]string)
+	for i, frame := range data.Frames {
+		busy[i] = frameBusy(frame)
+		for _, fn := range frame.Functions {
+			names[fn.ThreadID] = fn.ThreadName
+		}
+	}
+	for i := range data.Frames {
+		for id := range names {
+			series[id] = append(series[id], busy[i][id])
+		}
+	}
+	active := []int{}
+	medianBusy := make(map[int]float64)
+	for id, values := range series {
+		medianBusy[id] = medianOf(values)
+		if medianBusy[id] >= contaminationMinThreadMs {
+			active = append(active, id)
+		}
+	}
+	sort.Ints(active)
+	if len(active) < 2 {
+		return contaminated // A single thread can't tell a stall from its own work
+	}
+
+	for i, frame := range data.Frames {
+		excess := times[i] - median
+		if times[i] <= median*factor || excess <= 0 {
+			continue
+		}
+		stalled := []string{}
+		growth := []float64{}
+		for _, id := range active {
+			grew := busy[i][id] - medianBusy[id]
+			if grew >= excess*contaminationThreadExcess {
+				stalled = append(stalled, names[id])
+				growth = append(growth, grew)
+			}
+		}
+		if len(stalled) < 2 || float64(len(stalled)) < float64(len(active))*contaminationThreadShare {
+			continue
+		}
+
+		mean, stdDev := meanStdDev(growth)
+		entry := ContaminatedFrame{
+			FrameNumber:    frame.FrameNumber,
+			FrameTimeMs:    times[i],
+			ExcessMs:       excess,
+			Kind:           contaminationStall,
+			StalledThreads: stalled,
+			ActiveThreads:  len(active),
+			AvgGrowthMs:    mean,
+		}
+		entry.Reason = fmt.Sprintf("%d of %d active threads did %.1fms more work on average at the same time", len(stalled), len(active), mean)
+		if mean > 0 && stdDev/mean <= uniformGapSpread {
+			entry.Kind = contaminationGap
+			entry.Reason = fmt.Sprintf("%d of %d active threads grew by nearly the same %.1fms (±%.1fms): the whole process was paused", len(stalled), len(active), mean, stdDev)
+		}
+		contaminated = append(contaminated, entry)
+	}
+	return contaminated
+}
+
+func detectContaminationHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	factor := 2.0
+	if f, ok := args["threshold_factor"].(float64); ok && f > 0 {
+		factor = f
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	median := medianOf(times)
+	spikes := 0
+	for _, t := range times {
+		if t > median*factor {
+			spikes++
+		}
+	}
+	contaminated := detectContamination(data, factor)
+	kinds := make(map[string]int)
+	var lostMs float64
+	for _, frame := range contaminated {
+		kinds[frame.Kind]++
+		lostMs += frame.ExcessMs
+	}
+
+	analysis := []string{}
+	suggestions := []string{}
+	summary := fmt.Sprintf("None of the %d spike frames (over %.2fms) looks like a capture-machine stall; treat them as in-game problems", spikes, median*factor)
+	if len(contaminated) > 0 {
+		summary = fmt.Sprintf("%d of %d spike frames look like capture-machine stalls rather than in-game problems (%.1fms lost)", len(contaminated), spikes, lostMs)
+		if kinds[contaminationGap] > 0 {
+			analysis = append(analysis, fmt.Sprintf("%d frames are uniform gaps: every stalled thread grew by about the same time, as when the process is descheduled", kinds[contaminationGap]))
+		}
+		if kinds[contaminationStall] > 0 {
+			analysis = append(analysis, fmt.Sprintf("%d frames are system-wide stalls: most threads slowed down together", kinds[contaminationStall]))
+		}
+		suggestions = append(suggestions,
+			"Pass exclude_contaminated to other tools (and to compare_profiles) so these frames don't count as hitches or regressions",
+			"Check the capture machine for background processes (updaters, indexers, antivirus scans) and re-capture if the stalls repeat")
+	}
+
+	output := map[string]interface{}{
+		"file":               filePath,
+		"sessionName":        data.SessionName,
+		"framesAnalyzed":     len(data.Frames),
+		"medianFrameTimeMs":  median,
+		"spikeThresholdMs":   median * factor,
+		"spikeFrames":        spikes,
+		"contaminatedFrames": contaminated,
+		"suggestions":        suggestions,
+		"analysis":           analysis,
+		"summary":            summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
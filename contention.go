@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// LockSite aggregates one synchronization scope (lock, mutex, wait...) across threads.
+// Time inside these scopes is counted as blocked time: the thread is acquiring or
+// waiting rather than doing its own work.
+type LockSite struct {
+	Name              string   `json:"site"`
+	Kind              string   `json:"kind"`
+	Threads           []string `json:"threads"`
+	BlockedMs         float64  `json:"blockedMs"`
+	BlockedMsPerFrame float64  `json:"blockedMsPerFrame"`
+	MaxFrameMs        float64  `json:"maxFrameMs"`
+	MaxFrame          int      `json:"maxFrame"`
+	MainThreadMs      float64  `json:"mainThreadBlockedMs"`
+	FramesPresent     int      `json:"framesPresent"`
+	ContendedFrames   int      `json:"contendedFrames"` // Frames where two or more threads hit the site
+	ContendedMs       float64  `json:"contendedMs"`     // Blocked time in those frames
+	threadSet         map[string]bool
+}
+
+// syncScopeKind classifies a scope name as a lock, a wait or neither ("")
+func syncScopeKind(name string) string {
+	lower := strings.ToLower(name)
+	for _, word := range []string{"mutex", "spinlock", "rwlock", "scopelock", "critical section", "criticalsection", "semaphore", "futex"} {
+		if strings.Contains(lower, word) {
+			return "lock"
+		}
+	}
+	if strings.Contains(lower, "lock") && !strings.Contains(lower, "block") && !strings.Contains(lower, "clock") && !strings.Contains(lower, "unlock") {
+		return "lock"
+	}
+	if isWaitScope(name) {
+		return "wait"
+	}
+	return ""
+}
+
+func contentionHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+	includeWaits := true
+	if w, ok := args["include_waits"].(bool); ok {
+		includeWaits = w
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	stages := threadStages(data)
+	sites := make(map[string]*LockSite)
+	site := func(fn FrameProFunction) *LockSite {
+		kind := syncScopeKind(fn.FunctionName)
+		if kind == "" || (kind == "wait" && !includeWaits) {
+			return nil
+		}
+		s, exists := sites[fn.FunctionName]
+		if !exists {
+			s = &LockSite{Name: fn.FunctionName, Kind: kind, threadSet: make(map[string]bool)}
+			sites[fn.FunctionName] = s
+		}
+		s.threadSet[fn.ThreadName] = true
+		return s
+	}
+
+	source := "frames"
+	frameCount := len(data.Frames)
+	if len(data.Frames) > 0 {
+		for _, frame := range data.Frames {
+			frameTotals := make(map[*LockSite]float64)
+			frameThreads := make(map[*LockSite]map[int]bool)
+			for _, fn := range frame.Functions {
+				s := site(fn)
+				if s == nil {
+					continue
+				}
+				s.BlockedMs += fn.TimeMs
+				if stages[fn.ThreadID] == stageMain {
+					s.MainThreadMs += fn.TimeMs
+				}
+				frameTotals[s] += fn.TimeMs
+				if frameThreads[s] == nil {
+					frameThreads[s] = make(map[int]bool)
+				}
+				frameThreads[s][fn.ThreadID] = true
+			}
+			for s, ms := range frameTotals {
+				s.FramesPresent++
+				if ms > s.MaxFrameMs {
+					s.MaxFrameMs, s.MaxFrame = ms, frame.FrameNumber
+				}
+				if len(frameThreads[s]) > 1 {
+					s.ContendedFrames++
+					s.ContendedMs += ms
+				}
+			}
+		}
+	} else {
+		// Aggregate exports can't correlate threads by frame; report totals only
+		source = "aggregate"
+		frameCount = data.TotalFrames
+		for _, fn := range data.Functions {
+			s := site(fn)
+			if s == nil {
+				continue
+			}
+			s.BlockedMs += fn.TotalTimeMs
+			if stages[fn.ThreadID] == stageMain {
+				s.MainThreadMs += fn.TotalTimeMs
+			}
+			if fn.MaxTimePerFrameMs > s.MaxFrameMs {
+				s.MaxFrameMs = fn.MaxTimePerFrameMs
+			}
+		}
+	}
+
+	ranked := make([]*LockSite, 0, len(sites))
+	var totalBlocked float64
+	for _, s := range sites {
+		for name := range s.threadSet {
+			s.Threads = append(s.Threads, name)
+		}
+		sort.Strings(s.Threads)
+		if frameCount > 0 {
+			s.BlockedMsPerFrame = s.BlockedMs / float64(frameCount)
+		}
+		totalBlocked += s.BlockedMs
+		ranked = append(ranked, s)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].BlockedMs > ranked[j].BlockedMs
+	})
+	total := len(ranked)
+	if len(ranked) > topN {
+		ranked = ranked[:topN]
+	}
+
+	analysis := []string{}
+	for _, s := range ranked {
+		line := fmt.Sprintf("%s blocks %.2fms per frame across %d thread(s)", s.Name, s.BlockedMsPerFrame, len(s.Threads))
+		if s.ContendedFrames > 0 {
+			line += fmt.Sprintf(", contended in %d frames", s.ContendedFrames)
+		}
+		if s.MainThreadMs > 0 {
+			line += fmt.Sprintf(", %.0f%% on the main thread", s.MainThreadMs/s.BlockedMs*100)
+		}
+		analysis = append(analysis, line)
+	}
+
+	summary := "No lock, mutex or wait scopes found"
+	if len(ranked) > 0 {
+		summary = fmt.Sprintf("%d synchronization sites block %.2fms per frame in total; worst is %s (%.2fms per frame)",
+			total, totalBlocked/float64(max(frameCount, 1)), ranked[0].Name, ranked[0].BlockedMsPerFrame)
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"source":         source,
+		"framesAnalyzed": frameCount,
+		"totalSites":     total,
+		"totalBlockedMs": totalBlocked,
+		"sites":          ranked,
+		"analysis":       analysis,
+		"summary":        summary,
+	}
+	if source == "aggregate" {
+		addWarning(output, warnNoFrameData, "No per-frame data; sites are not correlated across threads by frame")
+	}
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
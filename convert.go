@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Conversion is one derived value with the formula used to compute it
+type Conversion struct {
+	Name    string  `json:"name"`
+	Value   float64 `json:"value"`
+	Unit    string  `json:"unit"`
+	Formula string  `json:"formula"`
+}
+
+func convertMetricsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	fps, hasFPS := args["fps"].(float64)
+	frameTime, hasFrameTime := args["frame_time_ms"].(float64)
+	valueMs, hasValue := args["value_ms"].(float64)
+	totalMs, hasTotal := args["total_ms"].(float64)
+	frameCount, hasFrameCount := args["frame_count"].(float64)
+	duration, hasDuration := args["duration_seconds"].(float64)
+
+	conversions := []Conversion{}
+	add := func(name string, value float64, unit, formula string) {
+		conversions = append(conversions, Conversion{Name: name, Value: value, Unit: unit, Formula: formula})
+	}
+
+	// FPS and frame time are interchangeable; derive whichever is missing
+	if hasFPS && fps > 0 {
+		add("frameBudget", 1000.0/fps, "ms", fmt.Sprintf("1000 / %g fps", fps))
+		if !hasFrameTime {
+			frameTime, hasFrameTime = 1000.0/fps, true
+		}
+	}
+	if hasFrameTime && frameTime > 0 {
+		if !hasFPS {
+			add("fps", msToFPS(frameTime), "fps", fmt.Sprintf("1000 / %gms", frameTime))
+			fps, hasFPS = msToFPS(frameTime), true
+		}
+	}
+
+	if hasValue {
+		if hasFrameTime && frameTime > 0 {
+			add("percentOfFrameBudget", valueMs/frameTime*100, "%", fmt.Sprintf("%gms / %.3fms * 100", valueMs, frameTime))
+			add("fpsIfOnlyCost", msToFPS(valueMs), "fps", fmt.Sprintf("1000 / %gms", valueMs))
+			add("fpsWithCostRemoved", msToFPS(frameTime-valueMs), "fps", fmt.Sprintf("1000 / (%.3fms - %gms)", frameTime, valueMs))
+		}
+		if hasFPS {
+			add("perSecond", valueMs*fps, "ms/s", fmt.Sprintf("%gms per frame * %.3f fps", valueMs, fps))
+		}
+	}
+
+	if hasTotal {
+		if hasFrameCount && frameCount > 0 {
+			add("avgPerFrame", totalMs/frameCount, "ms", fmt.Sprintf("%gms / %g frames", totalMs, frameCount))
+		}
+		if hasDuration && duration > 0 {
+			add("perSecond", totalMs/duration, "ms/s", fmt.Sprintf("%gms / %gs", totalMs, duration))
+		}
+	}
+
+	if hasFrameCount && frameCount > 0 {
+		if hasDuration && duration > 0 {
+			add("avgFPS", frameCount/duration, "fps", fmt.Sprintf("%g frames / %gs", frameCount, duration))
+			add("avgFrameTime", duration*1000/frameCount, "ms", fmt.Sprintf("%gs * 1000 / %g frames", duration, frameCount))
+		} else if hasFPS {
+			add("duration", frameCount/fps, "s", fmt.Sprintf("%g frames / %.3f fps", frameCount, fps))
+		}
+	}
+
+	if len(conversions) == 0 {
+		return mcp.NewToolResultError("Provide at least one of: fps, frame_time_ms, value_ms with fps/frame_time_ms, total_ms with frame_count/duration_seconds, frame_count with duration_seconds/fps"), nil
+	}
+
+	result, _ := json.MarshalIndent(map[string]interface{}{
+		"conversions": conversions,
+	}, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
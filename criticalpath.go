@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Pipeline model of a frame: the game (main) thread, the render thread and the
+// worker pool run in parallel, so a frame takes as long as its slowest stage.
+// Only busy time counts; time in Wait/Sleep scopes is a stage waiting on another
+// stage and is not on the critical path. The worker stage is its busiest worker.
+
+// Pipeline stages
+const (
+	stageMain    = "main"
+	stageRender  = "render"
+	stageWorkers = "workers"
+	stageOther   = "other"
+)
+
+// CriticalPathFrame is the pipeline estimate of one frame
+type CriticalPathFrame struct {
+	FrameNumber    int                `json:"frame"`
+	CriticalPathMs float64            `json:"criticalPathMs"`
+	Limiter        string             `json:"limiter"`
+	LimiterThread  string             `json:"limiterThread"`
+	SlackMs        float64            `json:"slackMs"` // How much the limiter could shrink before another stage limits
+	Stages         map[string]float64 `json:"stagesMs"`
+}
+
+// threadStages assigns every thread to a pipeline stage, using thread flags
+// where present and thread names as a fallback
+func threadStages(data *FrameProData) map[int]string {
+	mainThreads := mainThreadIDs(data)
+	stages := make(map[int]string)
+	assign := func(fn FrameProFunction) {
+		if _, done := stages[fn.ThreadID]; done {
+			return
+		}
+		name := strings.ToLower(fn.ThreadName)
+		switch {
+		case fn.IsMainThread || mainThreads[fn.ThreadID]:
+			stages[fn.ThreadID] = stageMain
+		case fn.IsRenderThread || strings.Contains(name, "render") || strings.Contains(name, "rhi"):
+			stages[fn.ThreadID] = stageRender
+		case fn.IsWorkerThread || isWorkerThreadName(fn.ThreadName):
+			stages[fn.ThreadID] = stageWorkers
+		default:
+			stages[fn.ThreadID] = stageOther
+		}
+	}
+	for _, fn := range data.Functions {
+		assign(fn)
+	}
+	for _, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			assign(fn)
+		}
+	}
+	return stages
+}
+
+// criticalPath estimates a frame's duration as its slowest pipeline stage
+func criticalPath(frame FrameProFrame, stages map[int]string) CriticalPathFrame {
+	busy := make(map[int]float64)
+	names := make(map[int]string)
+	for _, fn := range frame.Functions {
+		if isWaitScope(fn.FunctionName) {
+			continue
+		}
+		busy[fn.ThreadID] += fn.TimeMs
+		names[fn.ThreadID] = fn.ThreadName
+	}
+
+	// Main and render stages sum their threads; parallel stages take their busiest thread
+	stageTimes := make(map[string]float64)
+	stageThread := make(map[string]string)
+	threadTime := make(map[string]float64)
+	for id, ms := range busy {
+		stage := stages[id]
+		if stage == "" {
+			stage = stageOther
+		}
+		if stage == stageMain || stage == stageRender {
+			stageTimes[stage] += ms
+			if ms > threadTime[stage] {
+				threadTime[stage], stageThread[stage] = ms, names[id]
+			}
+			continue
+		}
+		if ms > stageTimes[stage] {
+			stageTimes[stage], stageThread[stage] = ms, names[id]
+		}
+	}
+
+	result := CriticalPathFrame{FrameNumber: frame.FrameNumber, Stages: stageTimes}
+	var second float64
+	for _, stage := range []string{stageMain, stageRender, stageWorkers, stageOther} {
+		ms := stageTimes[stage]
+		if ms > result.CriticalPathMs {
+			second = result.CriticalPathMs
+			result.CriticalPathMs, result.Limiter = ms, stage
+		} else if ms > second {
+			second = ms
+		}
+	}
+	result.LimiterThread = stageThread[result.Limiter]
+	result.SlackMs = result.CriticalPathMs - second
+	return result
+}
+
+// criticalPathFrames applies the pipeline model to every frame
+func criticalPathFrames(data *FrameProData, frames []FrameProFrame) []CriticalPathFrame {
+	stages := threadStages(data)
+	result := make([]CriticalPathFrame, len(frames))
+	for i, frame := range frames {
+		result[i] = criticalPath(frame, stages)
+	}
+	return result
+}
+
+// limiterBreakdown counts how often each stage limited the frame and returns the overall
+// limiter: the stage with the highest average time
+func limiterBreakdown(frames []CriticalPathFrame) (map[string]int, map[string]float64, string) {
+	counts := make(map[string]int)
+	avg := make(map[string]float64)
+	for _, frame := range frames {
+		counts[frame.Limiter]++
+		for stage, ms := range frame.Stages {
+			avg[stage] += ms
+		}
+	}
+	overall := ""
+	for stage := range avg {
+		avg[stage] /= float64(len(frames))
+		if overall == "" || avg[stage] > avg[overall] || (avg[stage] == avg[overall] && stage < overall) {
+			overall = stage
+		}
+	}
+	return counts, avg, overall
+}
+
+func criticalPathHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	includeFrames, _ := args["include_frames"].(bool)
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	frames := data.Frames
+	source := "frames"
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+		source = "aggregate"
+	}
+
+	paths := criticalPathFrames(data, frames)
+	counts, avgStages, overall := limiterBreakdown(paths)
+
+	times := make([]float64, len(paths))
+	for i, path := range paths {
+		times[i] = path.CriticalPathMs
+	}
+	avgCriticalPath := 0.0
+	for _, t := range times {
+		avgCriticalPath += t
+	}
+	avgCriticalPath /= float64(len(times))
+
+	limiterPercent := make(map[string]float64)
+	for stage, n := range counts {
+		limiterPercent[stage] = float64(n) / float64(len(paths)) * 100
+	}
+
+	// Slowest frames by critical path
+	worst := append([]CriticalPathFrame(nil), paths...)
+	sort.Slice(worst, func(i, j int) bool {
+		return worst[i].CriticalPathMs > worst[j].CriticalPathMs
+	})
+	if len(worst) > 10 {
+		worst = worst[:10]
+	}
+
+	analysis := []string{}
+	stageNames := make([]string, 0, len(counts))
+	for stage := range counts {
+		stageNames = append(stageNames, stage)
+	}
+	sort.Slice(stageNames, func(i, j int) bool {
+		return counts[stageNames[i]] > counts[stageNames[j]]
+	})
+	for _, stage := range stageNames {
+		analysis = append(analysis, fmt.Sprintf("%s stage limited %.0f%% of frames (avg %.2fms)", stage, limiterPercent[stage], avgStages[stage]))
+	}
+
+	output := map[string]interface{}{
+		"file":               filePath,
+		"sessionName":        data.SessionName,
+		"source":             source,
+		"framesAnalyzed":     len(data.Frames),
+		"overallLimiter":     overall,
+		"avgCriticalPathMs":  avgCriticalPath,
+		"estimatedFPS":       msToFPS(avgCriticalPath),
+		"avgStageMs":         avgStages,
+		"limiterFrameCounts": counts,
+		"limiterPercent":     limiterPercent,
+		"slowestFrames":      worst,
+		"analysis":           analysis,
+		"summary": fmt.Sprintf("The %s stage limits the frame overall: %.2fms critical path (%.1f FPS)",
+			overall, avgCriticalPath, msToFPS(avgCriticalPath)),
+	}
+	if source == "aggregate" {
+		addWarning(output, warnNoFrameData, "No per-frame data; the model uses average time per frame")
+		delete(output, "slowestFrames")
+	}
+	if includeFrames && source == "frames" {
+		output["frames"] = paths
+	}
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// PipelineBubble is a frame where one stage sat idle waiting for the other
+type PipelineBubble struct {
+	FrameNumber int     `json:"frame"`
+	Waiting     string  `json:"waitingStage"`
+	WaitingOn   string  `json:"waitingOn"`
+	StallMs     float64 `json:"stallMs"`
+	FrameTimeMs float64 `json:"frameTimeMs"`
+}
+
+// stageScopes sums the busy scopes of one stage in a frame by function name
+func stageScopes(frame FrameProFrame, stages map[int]string, stage string, into map[string]float64) {
+	for _, fn := range frame.Functions {
+		if stages[fn.ThreadID] == stage && !isWaitScope(fn.FunctionName) {
+			into[fn.FunctionName] += fn.TimeMs
+		}
+	}
+}
+
+func pipelineBubblesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	thresholdMs := 2.0
+	if t, ok := args["threshold_ms"].(float64); ok && t >= 0 {
+		thresholdMs = t
+	}
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	// A stage stalls when the other stage limits the frame: the time it isn't busy
+	// is time spent waiting for the limiter to hand over work
+	stages := threadStages(data)
+	bubbles := []PipelineBubble{}
+	stallMs := map[string]float64{stageMain: 0, stageRender: 0}
+	stallFrames := map[string]int{stageMain: 0, stageRender: 0}
+	activeScopes := map[string]map[string]float64{stageMain: {}, stageRender: {}}
+	for _, frame := range data.Frames {
+		path := criticalPath(frame, stages)
+		frameTime := frameTimeMs(frame, stages)
+		for _, pair := range [][2]string{{stageRender, stageMain}, {stageMain, stageRender}} {
+			waiting, limiter := pair[0], pair[1]
+			if path.Limiter != limiter {
+				continue
+			}
+			stall := frameTime - path.Stages[waiting]
+			if stall <= thresholdMs {
+				continue
+			}
+			bubbles = append(bubbles, PipelineBubble{
+				FrameNumber: frame.FrameNumber,
+				Waiting:     waiting,
+				WaitingOn:   limiter,
+				StallMs:     stall,
+				FrameTimeMs: frameTime,
+			})
+			stallMs[waiting] += stall
+			stallFrames[waiting]++
+			stageScopes(frame, stages, limiter, activeScopes[waiting])
+		}
+	}
+
+	directions := []map[string]interface{}{}
+	analysis := []string{}
+	for _, pair := range [][2]string{{stageRender, stageMain}, {stageMain, stageRender}} {
+		waiting, limiter := pair[0], pair[1]
+		if stallFrames[waiting] == 0 {
+			continue
+		}
+		scopes := []map[string]interface{}{}
+		for name, ms := range activeScopes[waiting] {
+			scopes = append(scopes, map[string]interface{}{
+				"function":         name,
+				"avgMsDuringStall": ms / float64(stallFrames[waiting]),
+			})
+		}
+		sort.Slice(scopes, func(i, j int) bool {
+			return scopes[i]["avgMsDuringStall"].(float64) > scopes[j]["avgMsDuringStall"].(float64)
+		})
+		if len(scopes) > 5 {
+			scopes = scopes[:5]
+		}
+
+		directions = append(directions, map[string]interface{}{
+			"waitingStage":  waiting,
+			"waitingOn":     limiter,
+			"frames":        stallFrames[waiting],
+			"totalStallMs":  stallMs[waiting],
+			"avgStallMs":    stallMs[waiting] / float64(stallFrames[waiting]),
+			"limiterScopes": scopes,
+		})
+		line := fmt.Sprintf("%s thread stalled on %s in %d frames (%.2fms total, %.2fms avg)",
+			waiting, limiter, stallFrames[waiting], stallMs[waiting], stallMs[waiting]/float64(stallFrames[waiting]))
+		if len(scopes) > 0 {
+			line += fmt.Sprintf("; %s was busy in %s", limiter, scopes[0]["function"])
+		}
+		analysis = append(analysis, line)
+	}
+
+	sort.Slice(bubbles, func(i, j int) bool {
+		return bubbles[i].StallMs > bubbles[j].StallMs
+	})
+	totalBubbles := len(bubbles)
+	if len(bubbles) > topN {
+		bubbles = bubbles[:topN]
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"framesAnalyzed": len(data.Frames),
+		"thresholdMs":    thresholdMs,
+		"bubbleCount":    totalBubbles,
+		"totalStallMs":   stallMs[stageMain] + stallMs[stageRender],
+		"directions":     directions,
+		"worstBubbles":   bubbles,
+		"analysis":       analysis,
+		"summary": fmt.Sprintf("%d pipeline bubbles over %.2fms in %d frames, %.2fms of stalls in total",
+			totalBubbles, thresholdMs, len(data.Frames), stallMs[stageMain]+stallMs[stageRender]),
+	}
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Any tool result can also be written as CSV for spreadsheets: one of its tables (a list of
+// objects such as hotspots, issues or regressions) becomes the rows, and its fields the
+// columns. Nested objects become dotted columns (playerImpact.droppedFramesPerMinute),
+// lists of values are joined with "; " and anything deeper is kept as JSON.
+
+// csvLeadingColumns come first when present, so rows read as "what, where, how bad"
+var csvLeadingColumns = []string{"rank", "frame", "function", "functionName", "name", "thread", "threadName", "category", "severity", "ruleId"}
+
+// parseOutputFormat accepts "json" (default), "csv" or "markdown"
+func parseOutputFormat(value interface{}) (string, error) {
+	format, _ := value.(string)
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "json":
+		return "json", nil
+	case "csv":
+		return "csv", nil
+	case "markdown", "md":
+		return "markdown", nil
+	}
+	return "", fmt.Errorf("invalid output_format '%v': expected 'json', 'csv' or 'markdown'", value)
+}
+
+// resultTables lists the keys of a result's lists of objects, largest first
+func resultTables(full map[string]interface{}) []string {
+	tables := []string{}
+	for key, value := range full {
+		if rows, ok := value.([]interface{}); ok && len(rows) > 0 {
+			if _, isObject := rows[0].(map[string]interface{}); isObject {
+				tables = append(tables, key)
+			}
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		a, b := len(full[tables[i]].([]interface{})), len(full[tables[j]].([]interface{}))
+		if a != b {
+			return a > b
+		}
+		return tables[i] < tables[j]
+	})
+	return tables
+}
+
+// flattenCSVValue adds a value's cells under a column name
+func flattenCSVValue(cells map[string]string, column string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		cells[column] = ""
+	case string:
+		cells[column] = v
+	case float64:
+		cells[column] = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		cells[column] = strconv.FormatBool(v)
+	case map[string]interface{}:
+		for key, nested := range v {
+			flattenCSVValue(cells, column+"."+key, nested)
+		}
+	case []interface{}:
+		parts := []string{}
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				encoded, _ := json.Marshal(v)
+				cells[column] = string(encoded)
+				return
+			}
+			part := map[string]string{}
+			flattenCSVValue(part, "", item)
+			parts = append(parts, part[""])
+		}
+		cells[column] = strings.Join(parts, "; ")
+	}
+}
+
+// tableColumns flattens the rows of a table into cells and orders their columns
+func tableColumns(rows []interface{}) ([]string, []map[string]string) {
+	records := []map[string]string{}
+	seen := make(map[string]bool)
+	columns := []string{}
+	for _, row := range rows {
+		cells := make(map[string]string)
+		if object, isObject := row.(map[string]interface{}); isObject {
+			for key, value := range object {
+				flattenCSVValue(cells, key, value)
+			}
+		} else {
+			flattenCSVValue(cells, "value", row)
+		}
+		for column := range cells {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+		records = append(records, cells)
+	}
+	rank := make(map[string]int)
+	for i, column := range csvLeadingColumns {
+		rank[column] = i - len(csvLeadingColumns)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if rank[columns[i]] != rank[columns[j]] {
+			return rank[columns[i]] < rank[columns[j]]
+		}
+		return columns[i] < columns[j]
+	})
+	return columns, records
+}
+
+// writeResultCSV writes one table of a tool result to a CSV file, or only previews it in a
+// dry run, and describes the write
+func writeResultCSV(tool string, full map[string]interface{}, args map[string]interface{}) (map[string]interface{}, error) {
+	tables := resultTables(full)
+	table, _ := args["csv_table"].(string)
+	if table == "" {
+		if len(tables) == 0 {
+			return nil, fmt.Errorf("%s returned no table to write as CSV", tool)
+		}
+		table = tables[0]
+	}
+	rows, ok := full[table].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s has no table '%s'; tables in this result: %s", tool, table, strings.Join(tables, ", "))
+	}
+
+	outputPath, _ := args["csv_path"].(string)
+	dryRun := isDryRun(args)
+	outputPath, err := resolveOutputPath(outputPath, tool+"_"+table, ".csv", dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, records := tableColumns(rows)
+	var content bytes.Buffer
+	writer := csv.NewWriter(&content)
+	writer.Write(columns)
+	for _, cells := range records {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = cells[column]
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", outputPath, err)
+	}
+	write, err := writeFile(outputPath, content.Bytes(), dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	writes, _ := full["writes"].([]interface{})
+	written := map[string]interface{}{
+		"csvPath":   outputPath,
+		"csvTable":  table,
+		"csvRows":   len(records),
+		"csvTables": tables,
+		"writes":    append(writes, write),
+	}
+	if dryRun {
+		written["dryRun"] = true
+	}
+	return written, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+// An export without functions, or without any frame count, makes every statistic zero:
+// no hotspots, 0ms frames and a 1000 FPS estimate. analyze_performance reports these as
+// data quality issues, and tools that depend on the data refuse through checkCapture.
+
+// hasFrameCount reports whether per-frame values can be derived: from per-frame data,
+// TotalFrames, or per-frame averages already in the export
+func hasFrameCount(data *FrameProData) bool {
+	if len(data.Frames) > 0 || data.TotalFrames > 0 {
+		return true
+	}
+	for _, fn := range data.Functions {
+		if fn.AvgTimePerFrameMs > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Data quality problems, phrased to follow "capture has"
+const (
+	problemNoFunctions = "no functions recorded (Functions array is empty): the export failed or nothing was profiled"
+	problemNoFrames    = "no frames recorded (TotalFrames is 0 and there is no per-frame data), so per-frame times, FPS and utilization can't be computed"
+)
+
+// dataQualityProblems describes what makes a capture unusable, if anything
+func dataQualityProblems(data *FrameProData) []string {
+	switch {
+	case len(data.Functions) == 0 && len(data.Frames) == 0:
+		return []string{problemNoFunctions}
+	case !hasFrameCount(data):
+		return []string{problemNoFrames}
+	}
+	return nil
+}
+
+// checkCapture returns an error explaining why a capture can't be analyzed: it has no
+// functions, or needFrames is set and there is no frame count
+func checkCapture(data *FrameProData, needFrames bool) error {
+	for _, problem := range dataQualityProblems(data) {
+		if problem == problemNoFunctions || needFrames {
+			return fmt.Errorf("capture has %s. Re-export the capture from FramePro", problem)
+		}
+	}
+	return nil
+}
+
+// dataQualityIssues reports an unusable capture as critical issues, so an empty export
+// isn't mistaken for one without problems
+func dataQualityIssues(data *FrameProData) []PerformanceIssue {
+	issues := []PerformanceIssue{}
+	for _, problem := range dataQualityProblems(data) {
+		issues = append(issues, PerformanceIssue{
+			Severity:    "critical",
+			Category:    "Data Quality",
+			RuleID:      "data/capture-quality",
+			Description: "Capture has " + problem,
+			Impact:      "Findings that depend on this data are missing; an empty result does not mean the capture has no problems",
+			Suggestion:  "Re-export the capture from FramePro, making sure profiling was running and frames were recorded",
+		})
+	}
+	return issues
+}
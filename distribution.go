@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Two captures can have the same average frame time and still feel different: one may
+// trade steady frames for occasional long ones. The frame-time distributions are compared
+// with a Kolmogorov-Smirnov test (did the shape change?) and the earth mover's distance
+// (by how many ms, on average?). Frame-to-frame changes get the same test, since pacing
+// is what players perceive as smoothness.
+
+const (
+	distributionAlpha     = 0.05 // Significance level of the KS tests
+	minDistributionMoveMs = 0.25 // Smaller shifts are real but too small to feel
+)
+
+// PercentileShift is one frame-time percentile in both captures
+type PercentileShift struct {
+	Percentile float64 `json:"percentile"`
+	BaselineMs float64 `json:"baselineMs"`
+	CurrentMs  float64 `json:"currentMs"`
+	DiffMs     float64 `json:"diffMs"`
+}
+
+// FrameDistributionComparison compares the frame-time distributions of two captures
+type FrameDistributionComparison struct {
+	BaselineFrames      int               `json:"baselineFrames"`
+	CurrentFrames       int               `json:"currentFrames"`
+	MeanDiffMs          float64           `json:"meanDiffMs"`
+	MeanPercentChange   float64           `json:"meanPercentChange"`
+	KSStatistic         float64           `json:"ksStatistic"`
+	KSPValue            float64           `json:"ksPValue"`
+	EarthMoverMs        float64           `json:"earthMoverDistanceMs"`
+	Percentiles         []PercentileShift `json:"percentiles"`
+	BaselinePacingScore float64           `json:"baselinePacingScore"`
+	CurrentPacingScore  float64           `json:"currentPacingScore"`
+	PacingKSPValue      float64           `json:"pacingKSPValue"`
+	PacingEarthMoverMs  float64           `json:"pacingEarthMoverDistanceMs"`
+	DistributionChanged bool              `json:"distributionChanged"`
+	SmoothnessChanged   bool              `json:"smoothnessChanged"`
+	Verdict             string            `json:"verdict"`
+}
+
+// frameDeltas returns the absolute frame-to-frame changes of a frame-time series
+func frameDeltas(times []float64) []float64 {
+	deltas := []float64{}
+	for i := 1; i < len(times); i++ {
+		deltas = append(deltas, math.Abs(times[i]-times[i-1]))
+	}
+	return deltas
+}
+
+// compareFrameDistributions compares two frame-time series; both need at least 2 frames
+func compareFrameDistributions(baseline, current []float64) FrameDistributionComparison {
+	result := FrameDistributionComparison{BaselineFrames: len(baseline), CurrentFrames: len(current)}
+	baseStats := computeFrameTimePercentiles(baseline)
+	currentStats := computeFrameTimePercentiles(current)
+	result.MeanDiffMs = currentStats.AvgFrameTimeMs - baseStats.AvgFrameTimeMs
+	if baseStats.AvgFrameTimeMs > 0 {
+		result.MeanPercentChange = result.MeanDiffMs / baseStats.AvgFrameTimeMs * 100
+	}
+	result.KSStatistic, result.KSPValue = ksTest(baseline, current)
+	result.EarthMoverMs = earthMoverDistance(baseline, current)
+	for _, p := range [][3]float64{{50, baseStats.P50Ms, currentStats.P50Ms}, {90, baseStats.P90Ms, currentStats.P90Ms},
+		{95, baseStats.P95Ms, currentStats.P95Ms}, {99, baseStats.P99Ms, currentStats.P99Ms}} {
+		result.Percentiles = append(result.Percentiles, PercentileShift{Percentile: p[0], BaselineMs: p[1], CurrentMs: p[2], DiffMs: p[2] - p[1]})
+	}
+
+	baseDeltas, currentDeltas := frameDeltas(baseline), frameDeltas(current)
+	baseJitter, _ := meanStdDev(baseDeltas)
+	currentJitter, _ := meanStdDev(currentDeltas)
+	result.BaselinePacingScore = pacingScore(baseJitter, medianOf(baseline))
+	result.CurrentPacingScore = pacingScore(currentJitter, medianOf(current))
+	_, result.PacingKSPValue = ksTest(baseDeltas, currentDeltas)
+	result.PacingEarthMoverMs = earthMoverDistance(baseDeltas, currentDeltas)
+
+	result.DistributionChanged = result.KSPValue < distributionAlpha && result.EarthMoverMs >= minDistributionMoveMs
+	result.SmoothnessChanged = result.PacingKSPValue < distributionAlpha && result.PacingEarthMoverMs >= minDistributionMoveMs
+	result.Verdict = distributionVerdict(result)
+	return result
+}
+
+// distributionVerdict says in one sentence whether players would notice the change
+func distributionVerdict(c FrameDistributionComparison) string {
+	moved := c.Percentiles[0]
+	for _, shift := range c.Percentiles {
+		if math.Abs(shift.DiffMs) > math.Abs(moved.DiffMs) {
+			moved = shift
+		}
+	}
+	direction := "slower"
+	if moved.DiffMs < 0 {
+		direction = "faster"
+	}
+	averagesMatch := math.Abs(c.MeanPercentChange) < 2
+
+	smoothness := "pacing is unchanged"
+	if c.SmoothnessChanged {
+		smoothness = fmt.Sprintf("pacing got worse (score %.0f to %.0f)", c.BaselinePacingScore, c.CurrentPacingScore)
+		if c.CurrentPacingScore > c.BaselinePacingScore {
+			smoothness = fmt.Sprintf("pacing improved (score %.0f to %.0f)", c.BaselinePacingScore, c.CurrentPacingScore)
+		}
+	}
+
+	switch {
+	case !c.DistributionChanged && !c.SmoothnessChanged:
+		return fmt.Sprintf("Frame-time distributions match (KS p=%.2f, %.2fms earth mover's distance): players won't notice a difference", c.KSPValue, c.EarthMoverMs)
+	case averagesMatch && c.DistributionChanged:
+		return fmt.Sprintf("Averages match within %.1f%% but the distribution changed (KS p=%.3f): p%.0f is %.2fms %s, and %s",
+			math.Abs(c.MeanPercentChange), c.KSPValue, moved.Percentile, math.Abs(moved.DiffMs), direction, smoothness)
+	case averagesMatch:
+		return fmt.Sprintf("Averages and frame-time distribution match, but %s (pacing KS p=%.3f)", smoothness, c.PacingKSPValue)
+	case c.DistributionChanged:
+		return fmt.Sprintf("Frame times changed (KS p=%.3f, %.2fms earth mover's distance): p%.0f is %.2fms %s, and %s",
+			c.KSPValue, c.EarthMoverMs, moved.Percentile, math.Abs(moved.DiffMs), direction, smoothness)
+	}
+	return fmt.Sprintf("The average moved %.1f%% but the frame-time distribution change is within noise (KS p=%.2f); %s", c.MeanPercentChange, c.KSPValue, smoothness)
+}
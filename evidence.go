@@ -0,0 +1,117 @@
+package main
+
+import "sort"
+
+const (
+	maxEvidenceFrames = 5  // Worst frames listed per issue
+	maxEvidenceRanges = 10 // Frame ranges listed per issue
+)
+
+// IssueEvidence points an issue at the frames that triggered it, for get_frame and
+// find_frames_where. Only set when the capture has per-frame data.
+type IssueEvidence struct {
+	Frames     []int       `json:"frames"`     // Worst frames first
+	Ranges     []FrameSpan `json:"ranges"`     // Triggering frames as runs of consecutive frames, longest first
+	FrameCount int         `json:"frameCount"` // Number of triggering frames
+	MoreRanges int         `json:"moreRanges,omitempty"`
+}
+
+// FrameSpan is a run of consecutive frame numbers, inclusive
+type FrameSpan struct {
+	StartFrame int `json:"startFrame"`
+	EndFrame   int `json:"endFrame"`
+}
+
+// frameEvidence collects the frames whose value is over min. It returns nil without
+// per-frame data or when no frame qualifies.
+func frameEvidence(data *FrameProData, value func(FrameProFrame) float64, min float64) *IssueEvidence {
+	type scored struct {
+		frame int
+		value float64
+	}
+	matches := []scored{}
+	for _, frame := range data.Frames {
+		if v := value(frame); v > min {
+			matches = append(matches, scored{frame.FrameNumber, v})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	evidence := &IssueEvidence{FrameCount: len(matches)}
+	for _, m := range matches {
+		last := len(evidence.Ranges) - 1
+		if last >= 0 && evidence.Ranges[last].EndFrame == m.frame-1 {
+			evidence.Ranges[last].EndFrame = m.frame
+			continue
+		}
+		evidence.Ranges = append(evidence.Ranges, FrameSpan{StartFrame: m.frame, EndFrame: m.frame})
+	}
+
+	if len(evidence.Ranges) > maxEvidenceRanges {
+		sort.SliceStable(evidence.Ranges, func(i, j int) bool {
+			return evidence.Ranges[i].EndFrame-evidence.Ranges[i].StartFrame > evidence.Ranges[j].EndFrame-evidence.Ranges[j].StartFrame
+		})
+		evidence.MoreRanges = len(evidence.Ranges) - maxEvidenceRanges
+		evidence.Ranges = evidence.Ranges[:maxEvidenceRanges]
+		sort.Slice(evidence.Ranges, func(i, j int) bool {
+			return evidence.Ranges[i].StartFrame < evidence.Ranges[j].StartFrame
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].value > matches[j].value
+	})
+	for i := 0; i < len(matches) && i < maxEvidenceFrames; i++ {
+		evidence.Frames = append(evidence.Frames, matches[i].frame)
+	}
+	return evidence
+}
+
+// functionEvidence returns the frames where a function, on its own thread, took over minMs
+func functionEvidence(data *FrameProData, fn FrameProFunction, minMs float64) *IssueEvidence {
+	return frameEvidence(data, func(frame FrameProFrame) float64 {
+		ms, _ := functionFrameTimeMs(frame, fn.FunctionName, fn.ThreadID)
+		return ms
+	}, minMs)
+}
+
+// callCountEvidence returns the frames where a function was called more than minCount times
+func callCountEvidence(data *FrameProData, fn FrameProFunction, minCount float64) *IssueEvidence {
+	return frameEvidence(data, func(frame FrameProFrame) float64 {
+		count := 0
+		for _, entry := range frame.Functions {
+			if entry.FunctionName == fn.FunctionName && entry.ThreadID == fn.ThreadID {
+				count += entry.Count
+			}
+		}
+		return float64(count)
+	}, minCount)
+}
+
+// threadEvidence returns the frames where a thread recorded over minMs of work
+func threadEvidence(data *FrameProData, threadID int, minMs float64) *IssueEvidence {
+	return frameEvidence(data, func(frame FrameProFrame) float64 {
+		if total, ok := frameThreadTotals(frame)[threadID]; ok {
+			return total.TimeMs
+		}
+		return 0
+	}, minMs)
+}
+
+// balanceEvidence returns the frames where the main/render imbalance exceeds 2:1 in the
+// same direction as the capture as a whole
+func balanceEvidence(data *FrameProData, mainThreadID, renderThreadID int, ratio float64) *IssueEvidence {
+	return frameEvidence(data, func(frame FrameProFrame) float64 {
+		totals := frameThreadTotals(frame)
+		mainThread, renderThread := totals[mainThreadID], totals[renderThreadID]
+		if mainThread == nil || renderThread == nil || mainThread.TimeMs <= 0 || renderThread.TimeMs <= 0 {
+			return 0
+		}
+		if ratio > 1 {
+			return mainThread.TimeMs / renderThread.TimeMs
+		}
+		return renderThread.TimeMs / mainThread.TimeMs
+	}, 2.0)
+}
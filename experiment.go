@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// An experiment compares labeled groups of captures, each capture one sample. Every
+// variant is compared with the first group, the control: difference of means with a
+// Welch 95% confidence interval and Cohen's d. A difference is significant when its
+// interval excludes zero.
+
+// Experiment metrics, in report order; all but FPS are better when lower
+var experimentMetrics = []string{"avgFrameTimeMs", "p95FrameTimeMs", "p99FrameTimeMs", "avgFPS", "mainThreadMsPerFrame"}
+
+// MetricStats summarizes one metric over the captures of a group
+type MetricStats struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	CILow  float64 `json:"ci95Low"`
+	CIHigh float64 `json:"ci95High"`
+}
+
+// ExperimentGroup is one variant with its captures and statistics
+type ExperimentGroup struct {
+	Label    string                 `json:"label"`
+	Captures []string               `json:"captures"`
+	Metrics  map[string]MetricStats `json:"metrics"`
+	samples  map[string][]float64
+}
+
+// MetricEffect is the difference between a variant and the control on one metric
+type MetricEffect struct {
+	Metric        string  `json:"metric"`
+	Variant       string  `json:"variant"`
+	Control       string  `json:"control"`
+	DiffMean      float64 `json:"diffMean"`
+	PercentChange float64 `json:"percentChange"`
+	CohensD       float64 `json:"cohensD"`
+	CILow         float64 `json:"ci95Low"`
+	CIHigh        float64 `json:"ci95High"`
+	Significant   bool    `json:"significant"`
+	Verdict       string  `json:"verdict"`
+}
+
+// sampleStats returns the mean, sample standard deviation and 95% confidence interval of the mean
+func sampleStats(values []float64) MetricStats {
+	stats := MetricStats{N: len(values)}
+	if len(values) == 0 {
+		return stats
+	}
+	mean, populationStdDev := meanStdDev(values)
+	stats.Mean, stats.CILow, stats.CIHigh = mean, mean, mean
+	if len(values) > 1 {
+		n := float64(len(values))
+		stats.StdDev = populationStdDev * math.Sqrt(n/(n-1))
+		margin := tCritical95(n-1) * stats.StdDev / math.Sqrt(n)
+		stats.CILow, stats.CIHigh = mean-margin, mean+margin
+	}
+	return stats
+}
+
+// compareSamples computes the effect of variant against control with Welch's interval
+func compareSamples(control, variant MetricStats, lowerIsBetter bool) MetricEffect {
+	effect := MetricEffect{DiffMean: variant.Mean - control.Mean, Verdict: "inconclusive"}
+	if control.Mean != 0 {
+		effect.PercentChange = effect.DiffMean / control.Mean * 100
+	}
+	effect.CILow, effect.CIHigh = effect.DiffMean, effect.DiffMean
+	if control.N < 2 || variant.N < 2 {
+		effect.Verdict = "inconclusive (needs at least 2 captures per group)"
+		return effect
+	}
+
+	nc, nv := float64(control.N), float64(variant.N)
+	pooled := math.Sqrt(((nc-1)*control.StdDev*control.StdDev + (nv-1)*variant.StdDev*variant.StdDev) / (nc + nv - 2))
+	if pooled > 0 {
+		effect.CohensD = effect.DiffMean / pooled
+	}
+	vc, vv := control.StdDev*control.StdDev/nc, variant.StdDev*variant.StdDev/nv
+	se := math.Sqrt(vc + vv)
+	df := nc + nv - 2
+	if vc+vv > 0 {
+		df = (vc + vv) * (vc + vv) / (vc*vc/(nc-1) + vv*vv/(nv-1))
+	}
+	margin := tCritical95(df) * se
+	effect.CILow, effect.CIHigh = effect.DiffMean-margin, effect.DiffMean+margin
+
+	effect.Significant = effect.CILow > 0 || effect.CIHigh < 0
+	switch {
+	case !effect.Significant:
+		effect.Verdict = "no significant difference"
+	case (effect.DiffMean < 0) == lowerIsBetter:
+		effect.Verdict = "better"
+	default:
+		effect.Verdict = "worse"
+	}
+	return effect
+}
+
+// captureMetrics measures one capture; percentiles need per-frame data
+func captureMetrics(data *FrameProData, functions []string) map[string]float64 {
+	metrics := make(map[string]float64)
+	average := aggregateFrame(data)
+	metrics["avgFrameTimeMs"] = averageFrameMs(data)
+	metrics["avgFPS"] = msToFPS(metrics["avgFrameTimeMs"])
+	if len(data.Frames) > 0 {
+		stats := computeFrameTimePercentiles(frameTimes(data))
+		metrics["p95FrameTimeMs"], metrics["p99FrameTimeMs"] = stats.P95Ms, stats.P99Ms
+	}
+	stages := threadStages(data)
+	for _, fn := range mainThreadFunctions(average.Functions, stages) {
+		metrics["mainThreadMsPerFrame"] += fn.TimeMs
+	}
+	for _, name := range functions {
+		key := "function:" + name
+		metrics[key] = 0
+		for _, fn := range average.Functions {
+			if fn.FunctionName == name {
+				metrics[key] += fn.TimeMs
+			}
+		}
+	}
+	return metrics
+}
+
+// sessionExperimentGroups builds one group per value of the groupBy field from the captures
+// in the data directory matching pattern. values picks and orders the groups, control first;
+// by default every value is a group, in ascending order. Loaded captures go into cache.
+func sessionExperimentGroups(filter *SessionFilter, groupBy, pattern string, values []string, cache map[string]*FrameProData) ([]*ExperimentGroup, error) {
+	files, err := listCaptureFiles(dataDir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	byValue := make(map[string]*ExperimentGroup)
+	for _, file := range files {
+		data, err := loadFrameProData(file.Path)
+		if err != nil {
+			continue
+		}
+		fields, selected := filter.match(file.Path, data)
+		if !selected {
+			continue
+		}
+		cache[file.Path] = data
+		value := fields[groupBy]
+		if byValue[strings.ToLower(value)] == nil {
+			byValue[strings.ToLower(value)] = &ExperimentGroup{Label: value, Metrics: make(map[string]MetricStats), samples: make(map[string][]float64)}
+		}
+		group := byValue[strings.ToLower(value)]
+		group.Captures = append(group.Captures, file.Path)
+	}
+
+	groups := []*ExperimentGroup{}
+	if len(values) == 0 {
+		for _, group := range byValue {
+			groups = append(groups, group)
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			return lessFieldValue(groups[i].Label, groups[j].Label)
+		})
+	}
+	for _, value := range values {
+		group := byValue[strings.ToLower(value)]
+		if group == nil {
+			return nil, fmt.Errorf("no captures in %s have %s '%s'", dataDir, groupBy, value)
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) < 2 {
+		return nil, fmt.Errorf("captures matching '%s' have %d distinct %s values; an experiment needs at least two", filter.Pattern.Raw, len(groups), groupBy)
+	}
+	return groups, nil
+}
+
+// parseExperimentGroups reads the groups argument: each a label with files and/or a glob pattern
+func parseExperimentGroups(args map[string]interface{}) ([]*ExperimentGroup, error) {
+	raw, _ := args["groups"].([]interface{})
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("groups needs at least two groups: the control first, then one or more variants")
+	}
+	groups := []*ExperimentGroup{}
+	labels := make(map[string]bool)
+	for i, entry := range raw {
+		spec, _ := entry.(map[string]interface{})
+		label, _ := spec["label"].(string)
+		if label == "" {
+			label = fmt.Sprintf("group %d", i+1)
+		}
+		if labels[label] {
+			return nil, fmt.Errorf("duplicate group label '%s'", label)
+		}
+		labels[label] = true
+
+		group := &ExperimentGroup{Label: label, Captures: []string{}, Metrics: make(map[string]MetricStats), samples: make(map[string][]float64)}
+		files, _ := spec["files"].([]interface{})
+		for _, file := range files {
+			if path, ok := file.(string); ok && path != "" {
+				group.Captures = append(group.Captures, path)
+			}
+		}
+		if pattern, _ := spec["pattern"].(string); pattern != "" {
+			matches, err := listCaptureFiles(dataDir, pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", label, err)
+			}
+			for _, match := range matches {
+				group.Captures = append(group.Captures, match.Path)
+			}
+		}
+		if len(group.Captures) == 0 {
+			return nil, fmt.Errorf("%s has no captures: give files or a pattern matching files in %s", label, dataDir)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func analyzeExperimentHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	functions := []string{}
+	if raw, ok := args["functions"].([]interface{}); ok {
+		for _, entry := range raw {
+			if name, ok := entry.(string); ok && name != "" {
+				functions = append(functions, name)
+			}
+		}
+	}
+	filter, err := parseSessionFilter(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	groupBy, _ := args["group_by"].(string)
+	cache := make(map[string]*FrameProData)
+	var groups []*ExperimentGroup
+	if groupBy != "" {
+		if filter == nil || !filter.hasField(groupBy) {
+			return mcp.NewToolResultError(fmt.Sprintf("group_by '%s' must be a field of session_pattern", groupBy)), nil
+		}
+		pattern, _ := args["pattern"].(string)
+		if pattern == "" {
+			pattern = "*.json"
+		}
+		values := []string{}
+		if raw, ok := args["group_values"].([]interface{}); ok {
+			for _, entry := range raw {
+				values = append(values, fmt.Sprint(entry))
+			}
+		}
+		groups, err = sessionExperimentGroups(filter, groupBy, pattern, values, cache)
+	} else {
+		groups, err = parseExperimentGroups(args)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	metrics := append([]string{}, experimentMetrics...)
+	for _, name := range functions {
+		metrics = append(metrics, "function:"+name)
+	}
+	excluded := []string{}
+	for _, group := range groups {
+		selected := []string{}
+		for _, path := range group.Captures {
+			data, cached := cache[path]
+			if !cached {
+				if data, err = loadFrameProData(path); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("%s: failed to load %s: %v", group.Label, path, err)), nil
+				}
+				if _, match := filter.match(path, data); !match {
+					excluded = append(excluded, path)
+					continue
+				}
+			}
+			selected = append(selected, path)
+			if err := checkCapture(data, true); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%s: %s: %v", group.Label, path, err)), nil
+			}
+			for metric, value := range captureMetrics(data, functions) {
+				group.samples[metric] = append(group.samples[metric], value)
+			}
+		}
+		if len(selected) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("%s has no captures left after the session filter", group.Label)), nil
+		}
+		group.Captures = selected
+		for _, metric := range metrics {
+			if samples := group.samples[metric]; len(samples) > 0 {
+				group.Metrics[metric] = sampleStats(samples)
+			}
+		}
+	}
+
+	// Every variant against the control, metric by metric
+	control := groups[0]
+	effects := []MetricEffect{}
+	for _, variant := range groups[1:] {
+		for _, metric := range metrics {
+			c, cok := control.Metrics[metric]
+			v, vok := variant.Metrics[metric]
+			if !cok || !vok {
+				continue
+			}
+			effect := compareSamples(c, v, metric != "avgFPS")
+			effect.Metric, effect.Variant, effect.Control = metric, variant.Label, control.Label
+			effects = append(effects, effect)
+		}
+	}
+
+	analysis := []string{}
+	verdicts := []string{}
+	for _, variant := range groups[1:] {
+		better, worse := []string{}, []string{}
+		for _, effect := range effects {
+			if effect.Variant != variant.Label || !effect.Significant {
+				continue
+			}
+			analysis = append(analysis, fmt.Sprintf("%s vs %s, %s: %+.2f (%+.1f%%, 95%% CI %.2f to %.2f, d=%.2f), %s",
+				effect.Variant, effect.Control, effect.Metric, effect.DiffMean, effect.PercentChange, effect.CILow, effect.CIHigh, effect.CohensD, effect.Verdict))
+			if effect.Verdict == "better" {
+				better = append(better, effect.Metric)
+			} else {
+				worse = append(worse, effect.Metric)
+			}
+		}
+		switch {
+		case len(better) == 0 && len(worse) == 0:
+			verdicts = append(verdicts, fmt.Sprintf("%s shows no significant difference from %s", variant.Label, control.Label))
+		case len(worse) == 0:
+			verdicts = append(verdicts, fmt.Sprintf("%s is better than %s on %s", variant.Label, control.Label, strings.Join(better, ", ")))
+		case len(better) == 0:
+			verdicts = append(verdicts, fmt.Sprintf("%s is worse than %s on %s", variant.Label, control.Label, strings.Join(worse, ", ")))
+		default:
+			verdicts = append(verdicts, fmt.Sprintf("%s is better than %s on %s but worse on %s", variant.Label, control.Label, strings.Join(better, ", "), strings.Join(worse, ", ")))
+		}
+	}
+
+	output := map[string]interface{}{
+		"control":  control.Label,
+		"metrics":  metrics,
+		"groups":   groups,
+		"effects":  effects,
+		"analysis": analysis,
+		"summary":  strings.Join(verdicts, "; "),
+	}
+	if groupBy != "" {
+		output["groupBy"] = groupBy
+	}
+	if len(excluded) > 0 {
+		output["excludedCaptures"] = excluded
+	}
+	for _, group := range groups {
+		if len(group.Captures) < 2 {
+			addWarning(output, warnFewCaptures, "Groups with a single capture have no spread, so their differences can't be tested; add captures from repeated runs")
+			break
+		}
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
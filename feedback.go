@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// IssueFeedback records that an analyze_performance finding was marked as a false positive.
+// "suppress" hides the finding from future analyses; "downgrade" lowers its severity one level.
+type IssueFeedback struct {
+	Fingerprint string `json:"fingerprint"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+	Action      string `json:"action"`
+	Reason      string `json:"reason,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Marks       int    `json:"marks"` // Times this finding was marked
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// categoryThresholds names the built-in rule behind each issue category, for the feedback report
+var categoryThresholds = map[string]string{
+	"CPU Hotspot":               "function total time over 100ms (critical over 500ms or on the main thread)",
+	"Call Frequency":            "over 10,000 calls with over 50ms total",
+	"Frame Spike":               "max time per frame over 16.67ms with over 100 calls",
+	"Thread Saturation":         "thread utilization over 90-95%",
+	"Frame Spike - Main Thread": "main thread function over 33ms in a frame",
+	"Frame Performance":         "main thread function over 16.67ms in a frame",
+	"Inconsistent Performance":  "max/avg time ratio over 5x with over 1ms average",
+	"Thread Balance":            "main/render thread time ratio over 2:1",
+}
+
+var feedbackMu sync.Mutex
+
+// feedbackPath returns the file false-positive feedback is persisted in
+func feedbackPath() string {
+	if path := os.Getenv("FRAMEPRO_FEEDBACK_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(outputDir, "framepro_feedback.json")
+}
+
+// issueFingerprint identifies a finding across captures: the same rule firing for the
+// same function or thread gets the same fingerprint
+func issueFingerprint(issue PerformanceIssue) string {
+	sum := sha1.Sum([]byte(issue.Category + "|" + issue.Description))
+	return hex.EncodeToString(sum[:6])
+}
+
+// loadFeedback reads all feedback keyed by fingerprint. A missing file means no feedback.
+func loadFeedback() (map[string]IssueFeedback, error) {
+	feedback := make(map[string]IssueFeedback)
+	data, err := os.ReadFile(feedbackPath())
+	if os.IsNotExist(err) {
+		return feedback, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback: %w", err)
+	}
+	if err := json.Unmarshal(data, &feedback); err != nil {
+		return nil, fmt.Errorf("failed to parse feedback: %w", err)
+	}
+	return feedback, nil
+}
+
+// saveFeedback writes all feedback back to disk, or only describes the write in a dry run
+func saveFeedback(feedback map[string]IssueFeedback, dryRun bool) (FileWrite, error) {
+	data, _ := json.MarshalIndent(feedback, "", "  ")
+	write, err := writeFile(feedbackPath(), data, dryRun)
+	if err != nil {
+		return write, fmt.Errorf("failed to write feedback: %w", err)
+	}
+	return write, nil
+}
+
+// downgradeSeverity lowers a severity by one level
+func downgradeSeverity(severity string) string {
+	switch severity {
+	case "critical":
+		return "high"
+	case "high":
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// applyFeedback fingerprints issues and applies recorded feedback: suppressed issues are
+// dropped and downgraded ones lose a severity level. It returns the remaining issues and
+// the number suppressed.
+func applyFeedback(issues []PerformanceIssue) ([]PerformanceIssue, int) {
+	feedbackMu.Lock()
+	feedback, err := loadFeedback()
+	feedbackMu.Unlock()
+	if err != nil {
+		feedback = nil // Unreadable feedback must not break analysis
+	}
+
+	kept := []PerformanceIssue{}
+	suppressed := 0
+	for _, issue := range issues {
+		issue.Fingerprint = issueFingerprint(issue)
+		if entry, marked := feedback[issue.Fingerprint]; marked {
+			if entry.Action == "suppress" {
+				suppressed++
+				continue
+			}
+			issue.Feedback = fmt.Sprintf("Downgraded from %s (marked as false positive)", issue.Severity)
+			issue.Severity = downgradeSeverity(issue.Severity)
+		}
+		kept = append(kept, issue)
+	}
+	return kept, suppressed
+}
+
+func markFalsePositiveHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	fingerprint, _ := args["fingerprint"].(string)
+	if fingerprint == "" {
+		return mcp.NewToolResultError("fingerprint is required (see the fingerprint of each issue in analyze_performance)"), nil
+	}
+	action, _ := args["action"].(string)
+	if action == "" {
+		action = "downgrade"
+	}
+	if action != "downgrade" && action != "suppress" && action != "remove" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid action '%s': expected 'downgrade', 'suppress' or 'remove'", action)), nil
+	}
+	reason, _ := args["reason"].(string)
+	author, _ := args["author"].(string)
+
+	// The capture the issue came from, if given, fills in what the fingerprint stands for
+	var category, description string
+	if filePath, _ := args["file_path"].(string); filePath != "" {
+		data, err := loadFrameProData(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+		}
+		issues := append(analyzeCPUPerformance(data), analyzeFramePerformance(data)...)
+		issues = append(issues, analyzeThreadPerformance(data)...)
+		for _, issue := range issues {
+			if issueFingerprint(issue) == fingerprint {
+				category, description = issue.Category, issue.Description
+				break
+			}
+		}
+		if category == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("No issue with fingerprint '%s' in %s", fingerprint, filePath)), nil
+		}
+	}
+
+	feedbackMu.Lock()
+	defer feedbackMu.Unlock()
+
+	feedback, err := loadFeedback()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	entry, exists := feedback[fingerprint]
+	if action == "remove" {
+		if !exists {
+			return mcp.NewToolResultError(fmt.Sprintf("No feedback recorded for fingerprint '%s'", fingerprint)), nil
+		}
+		delete(feedback, fingerprint)
+	} else {
+		if !exists {
+			entry = IssueFeedback{Fingerprint: fingerprint, CreatedAt: now}
+		}
+		if category != "" {
+			entry.Category, entry.Description = category, description
+		}
+		entry.Action = action
+		if reason != "" {
+			entry.Reason = reason
+		}
+		if author != "" {
+			entry.Author = author
+		}
+		entry.Marks++
+		entry.UpdatedAt = now
+		feedback[fingerprint] = entry
+	}
+	dryRun := isDryRun(args)
+	write, err := saveFeedback(feedback, dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	summary := fmt.Sprintf("Future analyses will %s issue %s", action, fingerprint)
+	if action == "remove" {
+		summary = fmt.Sprintf("Feedback for issue %s removed", fingerprint)
+	}
+	if dryRun {
+		summary = fmt.Sprintf("Dry run: marking would make future analyses %s issue %s; nothing written to %s", action, fingerprint, write.Path)
+		if action == "remove" {
+			summary = fmt.Sprintf("Dry run: would remove the feedback for issue %s from %s; nothing written", fingerprint, write.Path)
+		}
+	}
+
+	output := map[string]interface{}{
+		"fingerprint": fingerprint,
+		"action":      action,
+		"storedIn":    feedbackPath(),
+		"summary":     summary,
+	}
+	if action != "remove" {
+		output["feedback"] = entry
+	} else if dryRun {
+		output["removes"] = entry
+	}
+	attachWrites(output, []FileWrite{write}, dryRun)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func feedbackReportHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	feedbackMu.Lock()
+	feedback, err := loadFeedback()
+	feedbackMu.Unlock()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	type categoryStats struct {
+		Category   string          `json:"category"`
+		Rule       string          `json:"rule,omitempty"`
+		Findings   int             `json:"findings"`
+		Marks      int             `json:"marks"`
+		Suppressed int             `json:"suppressed"`
+		Downgraded int             `json:"downgraded"`
+		Entries    []IssueFeedback `json:"entries"`
+	}
+	byCategory := make(map[string]*categoryStats)
+	for _, entry := range feedback {
+		category := entry.Category
+		if category == "" {
+			category = "Unknown (marked without file_path)"
+		}
+		stats, exists := byCategory[category]
+		if !exists {
+			stats = &categoryStats{Category: category, Rule: categoryThresholds[category]}
+			byCategory[category] = stats
+		}
+		stats.Findings++
+		stats.Marks += entry.Marks
+		if entry.Action == "suppress" {
+			stats.Suppressed++
+		} else {
+			stats.Downgraded++
+		}
+		stats.Entries = append(stats.Entries, entry)
+	}
+
+	categories := make([]*categoryStats, 0, len(byCategory))
+	for _, stats := range byCategory {
+		sort.Slice(stats.Entries, func(i, j int) bool {
+			return stats.Entries[i].Marks > stats.Entries[j].Marks
+		})
+		categories = append(categories, stats)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Marks > categories[j].Marks
+	})
+
+	// Rules that keep producing false positives are candidates for a higher threshold
+	tuning := []string{}
+	for _, stats := range categories {
+		if stats.Rule != "" && stats.Findings >= 3 {
+			tuning = append(tuning, fmt.Sprintf("'%s' produced %d false positives; consider raising its threshold (%s) for this project",
+				stats.Category, stats.Findings, stats.Rule))
+		}
+	}
+
+	summary := "No false-positive feedback recorded"
+	if len(feedback) > 0 {
+		summary = fmt.Sprintf("%d findings marked as false positives in %d categories", len(feedback), len(categories))
+	}
+
+	result, _ := json.MarshalIndent(map[string]interface{}{
+		"storedIn":   feedbackPath(),
+		"findings":   len(feedback),
+		"categories": categories,
+		"analysis":   tuning,
+		"summary":    summary,
+	}, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Flame graphs are exported as Brendan Gregg's folded stacks: one "thread;root;...;leaf value"
+// line per call path with the path's self time, in integer microseconds so flamegraph.pl,
+// inferno and speedscope read them as sample counts. Per-frame data is summed over the
+// frames and aggregate data uses the capture totals; flat exports without Children give
+// one-level stacks. The SVG is drawn the way flamegraph.pl draws it: the root at the
+// bottom, widths by inclusive time, colors hashed from the name.
+
+const (
+	flameWidth      = 1200.0 // SVG width in pixels
+	flameRowHeight  = 16.0
+	flameFontSize   = 12.0
+	flameMinWidthPx = 0.1 // Narrower scopes are left out of the SVG
+)
+
+// flameNode is one call path of the flame graph
+type flameNode struct {
+	name     string
+	self     float64 // Milliseconds
+	total    float64
+	children []*flameNode
+	index    map[string]*flameNode
+}
+
+// child returns the named child of a node, adding it if new
+func (n *flameNode) child(name string) *flameNode {
+	if n.index == nil {
+		n.index = make(map[string]*flameNode)
+	}
+	c, exists := n.index[name]
+	if !exists {
+		c = &flameNode{name: name}
+		n.index[name] = c
+		n.children = append(n.children, c)
+	}
+	return c
+}
+
+// addScope adds a scope and its children below a node, timed by timeMs
+func (n *flameNode) addScope(fn FrameProFunction, timeMs func(FrameProFunction) float64) {
+	node := n.child(fn.FunctionName)
+	childMs := 0.0
+	for _, c := range fn.Children {
+		if ms := timeMs(c); ms > 0 {
+			node.addScope(c, timeMs)
+			childMs += ms
+		}
+	}
+	node.self += max(timeMs(fn)-childMs, 0)
+}
+
+// sum sets the inclusive times of a node and its children, and returns its own
+func (n *flameNode) sum() float64 {
+	n.total = n.self
+	for _, c := range n.children {
+		n.total += c.sum()
+	}
+	return n.total
+}
+
+// buildFlameTree arranges the scopes below one node per thread: per-frame scopes summed over
+// the frames, or the capture totals without per-frame data
+func buildFlameTree(data *FrameProData) *flameNode {
+	root := &flameNode{name: "all"}
+	add := func(functions []FrameProFunction, timeMs func(FrameProFunction) float64) {
+		for _, fn := range functions {
+			if timeMs(fn) > 0 {
+				root.child(fn.ThreadName).addScope(fn, timeMs)
+			}
+		}
+	}
+	for _, frame := range data.Frames {
+		add(frame.Functions, func(fn FrameProFunction) float64 { return fn.TimeMs })
+	}
+	if len(data.Frames) == 0 {
+		add(scopeTree(data), func(fn FrameProFunction) float64 {
+			if fn.TotalTimeMs > 0 {
+				return fn.TotalTimeMs
+			}
+			return fn.AvgTimePerFrameMs * float64(max(data.TotalFrames, 1))
+		})
+	}
+	root.sum()
+	return root
+}
+
+// foldedStacks writes every path with self time as a folded line, sorted
+func foldedStacks(root *flameNode) []string {
+	lines := []string{}
+	var walk func(node *flameNode, path []string)
+	walk = func(node *flameNode, path []string) {
+		path = append(path, strings.ReplaceAll(node.name, ";", ":"))
+		if us := math.Round(node.self * 1000); us > 0 {
+			lines = append(lines, fmt.Sprintf("%s %.0f", strings.Join(path, ";"), us))
+		}
+		for _, c := range node.children {
+			walk(c, path)
+		}
+	}
+	for _, thread := range root.children {
+		walk(thread, nil)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// flameColor is a warm color hashed from a function name, as flamegraph.pl's "hot" palette
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+int(v%50), int(v>>8%230), int(v>>16%55))
+}
+
+// flameSVG draws the flame graph with a tooltip per scope
+func flameSVG(root *flameNode, title string) string {
+	depth := 0
+	var measure func(node *flameNode, level int)
+	measure = func(node *flameNode, level int) {
+		depth = max(depth, level)
+		for _, c := range node.children {
+			measure(c, level+1)
+		}
+	}
+	measure(root, 0)
+	top := 2 * flameRowHeight
+	height := top + float64(depth+1)*flameRowHeight + flameRowHeight
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" standalone="no"?>`+"\n")
+	fmt.Fprintf(&b, `<svg version="1.1" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" xmlns="http://www.w3.org/2000/svg" font-family="Verdana" font-size="%.0f">`+"\n", flameWidth, height, flameWidth, height, flameFontSize)
+	fmt.Fprintf(&b, `<rect x="0" y="0" width="100%%" height="100%%" fill="#f8f8f8"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="%.0f">%s</text>`+"\n", flameWidth/2, flameRowHeight*1.2, flameFontSize+4, html.EscapeString(title))
+
+	scale := 0.0
+	if root.total > 0 {
+		scale = flameWidth / root.total
+	}
+	var draw func(node *flameNode, x float64, level int)
+	draw = func(node *flameNode, x float64, level int) {
+		width := node.total * scale
+		if width < flameMinWidthPx {
+			return
+		}
+		y := height - flameRowHeight - float64(level+1)*flameRowHeight
+		label := fmt.Sprintf("%s (%.2fms, %.2f%%)", node.name, node.total, node.total/root.total*100)
+		fmt.Fprintf(&b, `<g><title>%s</title><rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" rx="2" ry="2"/>`,
+			html.EscapeString(label), x, y, width, flameRowHeight-1, flameColor(node.name))
+		if chars := int(width / (flameFontSize * 0.59)); chars >= 3 {
+			text := node.name
+			if len(text) > chars {
+				text = text[:chars-2] + ".."
+			}
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f">%s</text>`, x+3, y+flameRowHeight-4, html.EscapeString(text))
+		}
+		b.WriteString("</g>\n")
+		for _, c := range node.children {
+			draw(c, x, level+1)
+			x += c.total * scale
+		}
+	}
+	draw(root, 0, 0)
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+func exportFlamegraphHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	outputPath, _ := args["output_path"].(string)
+	writeSVG, _ := args["svg"].(bool)
+	dryRun := isDryRun(args)
+	outputPath, err := resolveOutputPath(outputPath, outputStem(filePath), ".folded", dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, false); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	note := fmt.Sprintf("Summed %d frames; values are self times in microseconds", len(data.Frames))
+	if len(data.Frames) == 0 {
+		note = "No per-frame data: exported the capture's total times; values are self times in microseconds"
+	}
+	root := buildFlameTree(data)
+	lines := foldedStacks(root)
+	if len(lines) == 0 {
+		return mcp.NewToolResultError("No scopes with time to export"), nil
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	write, err := writeFile(outputPath, []byte(content), dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	writes := []FileWrite{write}
+
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"outputPath":  outputPath,
+		"stacks":      len(lines),
+		"threads":     len(root.children),
+		"totalMs":     root.total,
+		"bytes":       len(content),
+		"analysis":    []string{note},
+		"summary":     fmt.Sprintf("%s %d folded stacks to %s; render them with flamegraph.pl or inferno-flamegraph, or load them in speedscope", wroteVerb(dryRun), len(lines), outputPath),
+	}
+	if writeSVG {
+		svgPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".svg"
+		svgWrite, err := writeFile(svgPath, []byte(flameSVG(root, data.SessionName)), dryRun)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		writes = append(writes, svgWrite)
+		output["svgPath"] = svgPath
+		output["summary"] = fmt.Sprintf("%s %d folded stacks to %s and the flame graph to %s", wroteVerb(dryRun), len(lines), outputPath, svgPath)
+	}
+	attachWrites(output, writes, dryRun)
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
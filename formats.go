@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Capture formats recognized by loadFrameProData
+const (
+	formatFrameProJSON   = "framepro-json"
+	formatFrameProBinary = "framepro-binary"
+	formatChromeTrace    = "chrome-trace"
+	formatSpeedscope     = "speedscope"
+	formatPprof          = "pprof"
+	formatUnrealCSV      = "unreal-csv"
+	formatUnityJSON      = "unity-json"
+	formatTracyCSV       = "tracy-csv"
+	formatTracyCapture   = "tracy"
+	formatUnityData      = "unity-data"
+	formatPerfScript     = "perf-script"
+	formatFoldedStacks   = "folded-stacks"
+	formatLegacySummary  = "framepro-summary"
+	formatUnknown        = "unknown"
+)
+
+// detectCaptureFormat identifies a capture from its extension and first bytes
+func detectCaptureFormat(path string, content []byte) string {
+	if isPprof(path, content) {
+		return formatPprof
+	}
+	if isTracyCapture(path, content) {
+		return formatTracyCapture
+	}
+	if isTracyCSV(path, content) {
+		return formatTracyCSV
+	}
+	if isUnrealCSV(path, content) {
+		return formatUnrealCSV
+	}
+	if isLegacySummary(path, content) {
+		return formatLegacySummary
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '[' || bytes.Contains(trimmed, []byte(`"traceEvents"`))) && isChromeTrace(trimmed) {
+		return formatChromeTrace
+	}
+	if isSpeedscope(trimmed) {
+		return formatSpeedscope
+	}
+	if isUnityProfile(trimmed) {
+		return formatUnityJSON
+	}
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return formatFrameProJSON
+	}
+	if isPerfScript(content) {
+		return formatPerfScript
+	}
+	if isFoldedStacks(path, content) {
+		return formatFoldedStacks
+	}
+	if isUnityData(path) {
+		return formatUnityData
+	}
+	head := content[:min(len(content), 512)]
+	if strings.EqualFold(filepath.Ext(path), ".framepro") || bytes.IndexByte(head, 0) >= 0 {
+		return formatFrameProBinary
+	}
+	return formatUnknown
+}
+
+// decodeCapture parses a capture in any recognized format. Binary captures of FramePro
+// (.framepro), Tracy (.tracy) and Unity (.data) are recognized but not decoded: their
+// layouts are undocumented and change with the version of the tool that wrote them, so
+// loading one fails with how to export it to a format read here.
+func decodeCapture(path string, content []byte) (*FrameProData, error) {
+	if isGzip(content) && !isPprof(path, content) {
+		// Other gzipped captures (e.g. trace.json.gz) are read as the capture inside
+		decompressed, err := gunzipCapture(content)
+		if err != nil {
+			return nil, err
+		}
+		return decodeCapture(strings.TrimSuffix(path, filepath.Ext(path)), decompressed)
+	}
+	switch detectCaptureFormat(path, content) {
+	case formatChromeTrace:
+		return decodeChromeTrace(path, bytes.TrimLeft(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), " \t\r\n"))
+	case formatUnrealCSV:
+		return decodeUnrealCSV(path, content)
+	case formatTracyCSV:
+		return decodeTracyCSV(path, content)
+	case formatTracyCapture:
+		return nil, fmt.Errorf("%s is a binary Tracy capture, which can't be read directly. Export its zones with tracy-csvexport, preferably unwrapped (tracy-csvexport -u %s > zones.csv) to keep threads and frames", filepath.Base(path), filepath.Base(path))
+	case formatPprof:
+		return decodePprof(path, content)
+	case formatPerfScript:
+		return decodePerfScript(path, content)
+	case formatFoldedStacks:
+		return decodeFoldedStacks(path, content)
+	case formatLegacySummary:
+		return decodeLegacySummary(path, content)
+	case formatSpeedscope:
+		return decodeSpeedscope(path, bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	case formatUnityJSON:
+		return decodeUnityProfile(path, bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	case formatUnityData:
+		return nil, fmt.Errorf("%s is a binary Unity profiler capture, which can't be read directly. Load it in the Unity Profiler and export its frames as JSON (frames with threads and samples: name, startTimeNs, timeMs, childrenCount)", filepath.Base(path))
+	case formatFrameProJSON:
+		var data FrameProData
+		if err := json.Unmarshal(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), &data); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		return &data, nil
+	case formatFrameProBinary:
+		return nil, fmt.Errorf("%s is a native FramePro session, which can't be read directly. Open it in FramePro and export the frame and function analysis as JSON (*_frame_analysis.json, *_functions_analysis.json)", filepath.Base(path))
+	}
+	return nil, fmt.Errorf("unrecognized capture format in %s: expected a FramePro JSON export or text summary, a Chrome trace, a speedscope file, a pprof profile, perf script output or folded stacks, an Unreal or Tracy CSV export or a Unity profiler JSON export", filepath.Base(path))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The FPS model estimates a frame as long as its busiest thread: threads run in parallel,
+// so summing their scopes overstates the frame. A thread's time is the sum of its top-level
+// scopes, since nested scopes are already part of their parent's time. Without per-frame
+// data the busiest thread of the average frame is used, which understates spiky frames.
+
+// FPSEstimate is the frame rate predicted by the model, with what it rests on
+type FPSEstimate struct {
+	FrameTimeMs    float64  `json:"frameTimeMs"`
+	FPS            float64  `json:"fps"`
+	FramesModeled  int      `json:"framesModeled"` // 0 when only the average frame was available
+	LimitingThread string   `json:"limitingThread"`
+	LimitingFrames int      `json:"limitingFrames,omitempty"` // Frames in which that thread was the busiest
+	Confidence     string   `json:"confidence"`
+	Assumptions    []string `json:"assumptions"`
+}
+
+// busiestThread returns the thread with the largest sum of top-level scopes in a frame.
+// Hierarchical exports list only top-level scopes per frame; flat exports list every
+// scope, which are assumed not to nest.
+func busiestThread(frame FrameProFrame) (string, float64) {
+	totals := frameThreadTotals(frame)
+	ids := make([]int, 0, len(totals))
+	for id := range totals {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	name, busiest := "", 0.0
+	for _, id := range ids {
+		if totals[id].TimeMs > busiest {
+			name, busiest = totals[id].ThreadName, totals[id].TimeMs
+		}
+	}
+	return name, busiest
+}
+
+// estimateFPS applies the model to every frame, or to the average frame
+func estimateFPS(data *FrameProData) FPSEstimate {
+	estimate := FPSEstimate{Confidence: "high", Assumptions: []string{
+		"Threads run fully in parallel: a frame takes as long as its busiest thread. Work one thread waits for on another is not serialized, so the estimate is an upper bound on FPS",
+		"Time outside instrumented scopes and GPU time are not seen",
+	}}
+	lower := func() {
+		switch estimate.Confidence {
+		case "high":
+			estimate.Confidence = "medium"
+		case "medium":
+			estimate.Confidence = "low"
+		}
+	}
+
+	hierarchical := isHierarchical(scopeTree(data))
+	if len(data.Frames) > 0 {
+		limiting := make(map[string]int)
+		var total float64
+		for _, frame := range data.Frames {
+			if isHierarchical(frame.Functions) {
+				hierarchical = true
+			}
+			name, ms := busiestThread(frame)
+			total += ms
+			limiting[name]++
+		}
+		estimate.FrameTimeMs = total / float64(len(data.Frames))
+		estimate.FramesModeled = len(data.Frames)
+		for name, frames := range limiting {
+			if frames > estimate.LimitingFrames || frames == estimate.LimitingFrames && name < estimate.LimitingThread {
+				estimate.LimitingThread, estimate.LimitingFrames = name, frames
+			}
+		}
+	} else {
+		estimate.LimitingThread, estimate.FrameTimeMs = busiestThread(aggregateFrame(data))
+		estimate.Assumptions = append(estimate.Assumptions, "No per-frame data: the busiest thread of the average frame is used, which understates frame time when different threads spike in different frames")
+		lower()
+	}
+	if hierarchical {
+		estimate.Assumptions = append(estimate.Assumptions, "Only top-level scopes are summed per thread; nested scopes are part of their parent's time")
+	} else {
+		estimate.Assumptions = append(estimate.Assumptions, "Flat export: scopes on a thread are assumed not to nest. Nested scopes would be counted twice and lower the estimate")
+		lower()
+	}
+
+	estimate.FPS = msToFPS(estimate.FrameTimeMs)
+	if estimate.FPS > 1000.0 || estimate.FPS == 0 {
+		estimate.FPS = 1000.0 // Cap at reasonable value
+	}
+	return estimate
+}
+
+// describeFPSEstimate renders an estimate as one analysis line
+func describeFPSEstimate(estimate FPSEstimate) string {
+	line := fmt.Sprintf("Model estimate %.1f FPS (%.2fms per frame, %s confidence), limited by %s", estimate.FPS, estimate.FrameTimeMs, estimate.Confidence, estimate.LimitingThread)
+	if estimate.FramesModeled > 0 {
+		line += fmt.Sprintf(" in %d of %d frames", estimate.LimitingFrames, estimate.FramesModeled)
+	}
+	return line
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Helpers shared by the tools that walk per-frame data (data.Frames)
+
+// ThreadFrameTotal is the summed time of one thread within a single frame
+type ThreadFrameTotal struct {
+	ThreadID   int
+	ThreadName string
+	TimeMs     float64
+}
+
+// mainThreadIDs returns the IDs of threads flagged as main thread.
+// Per-frame entries don't always carry the thread flags, so the aggregate
+// Functions list is consulted first and thread names are used as a fallback.
+func mainThreadIDs(data *FrameProData) map[int]bool {
+	ids := make(map[int]bool)
+	for _, fn := range data.Functions {
+		if fn.IsMainThread {
+			ids[fn.ThreadID] = true
+		}
+	}
+	for _, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			if fn.IsMainThread {
+				ids[fn.ThreadID] = true
+			}
+		}
+	}
+	if len(ids) > 0 {
+		return ids
+	}
+
+	for _, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			name := strings.ToLower(fn.ThreadName)
+			if strings.Contains(name, "main") || strings.Contains(name, "game thread") || name == "gamethread" {
+				ids[fn.ThreadID] = true
+			}
+		}
+	}
+	return ids
+}
+
+// frameThreadTotals sums TimeMs per thread for a single frame
+func frameThreadTotals(frame FrameProFrame) map[int]*ThreadFrameTotal {
+	totals := make(map[int]*ThreadFrameTotal)
+	for _, fn := range frame.Functions {
+		total, exists := totals[fn.ThreadID]
+		if !exists {
+			total = &ThreadFrameTotal{ThreadID: fn.ThreadID, ThreadName: fn.ThreadName}
+			totals[fn.ThreadID] = total
+		}
+		total.TimeMs += fn.TimeMs
+	}
+	return totals
+}
+
+// frameTimeMs estimates the duration of a frame with the pipeline model (see criticalPath):
+// the main thread's total work, which includes its waits on other stages, or the critical
+// path through the render and worker stages, whichever is longer.
+// When no main thread is known (or it recorded nothing), the busiest thread is used.
+func frameTimeMs(frame FrameProFrame, stages map[int]string) float64 {
+	totals := frameThreadTotals(frame)
+
+	var mainTime, busiest float64
+	for id, total := range totals {
+		if stages[id] == stageMain {
+			mainTime += total.TimeMs
+		}
+		if total.TimeMs > busiest {
+			busiest = total.TimeMs
+		}
+	}
+	frameTime := mainTime
+	if frameTime == 0 {
+		frameTime = busiest
+	}
+	if path := criticalPath(frame, stages).CriticalPathMs; path > frameTime {
+		frameTime = path
+	}
+	return frameTime
+}
+
+// frameTimes returns the estimated duration of every frame, in frame order
+func frameTimes(data *FrameProData) []float64 {
+	stages := threadStages(data)
+	times := make([]float64, len(data.Frames))
+	for i, frame := range data.Frames {
+		times[i] = frameTimeMs(frame, stages)
+	}
+	return times
+}
+
+// medianOf returns the median of values without modifying the input
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// avgFunctionTimes returns the average TimeMs per frame of every function, keyed by functionKey
+func avgFunctionTimes(frames []FrameProFrame) map[string]float64 {
+	avgTimes := make(map[string]float64)
+	for _, frame := range frames {
+		for _, fn := range frame.Functions {
+			avgTimes[functionKey(fn)] += fn.TimeMs
+		}
+	}
+	for key := range avgTimes {
+		avgTimes[key] /= float64(len(frames))
+	}
+	return avgTimes
+}
+
+// topFrameFunctions returns the n most expensive functions recorded in a frame
+func topFrameFunctions(frame FrameProFrame, n int) []FrameProFunction {
+	functions := append([]FrameProFunction(nil), frame.Functions...)
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].TimeMs > functions[j].TimeMs
+	})
+	if n > 0 && len(functions) > n {
+		functions = functions[:n]
+	}
+	return functions
+}
+
+// functionKey identifies a function on a specific thread
+func functionKey(fn FrameProFunction) string {
+	return fmt.Sprintf("%s:%d", fn.FunctionName, fn.ThreadID)
+}
+
+// functionNameFromKey returns the function name part of a functionKey
+func functionNameFromKey(key string) string {
+	return key[:strings.LastIndex(key, ":")]
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// functionFrameTimeMs sums the time of every entry of a function within a frame, nested
+// scopes of hierarchical frames included. threadID 0 matches all threads.
+func functionFrameTimeMs(frame FrameProFrame, functionName string, threadID int) (float64, bool) {
+	var total float64
+	scopes := findScopes(frame.Functions, functionName, threadID)
+	for _, fn := range scopes {
+		total += fn.TimeMs
+	}
+	return total, len(scopes) > 0
+}
+
+func findFramesWhereHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	functionName, _ := args["function"].(string)
+	thresholdMs, ok := args["threshold_ms"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("threshold_ms is required"), nil
+	}
+	threadID := 0
+	if id, ok := args["thread_id"].(float64); ok {
+		threadID = int(id)
+	}
+	offset := 0
+	if o, ok := args["offset"].(float64); ok && o > 0 {
+		offset = int(o)
+	}
+	limit := 100
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	// Without a function the condition applies to the frame time itself
+	condition := fmt.Sprintf("frame time exceeds %.2fms", thresholdMs)
+	if functionName != "" {
+		condition = fmt.Sprintf("%s exceeds %.2fms", functionName, thresholdMs)
+	}
+
+	times := frameTimes(data)
+	matches := []map[string]interface{}{}
+	read := len(data.Frames)
+	for i, frame := range data.Frames {
+		if ctx.Err() != nil { // Out of time or canceled: report the matches among the frames searched so far
+			read = i
+			break
+		}
+		value := times[i]
+		if functionName != "" {
+			var found bool
+			value, found = functionFrameTimeMs(frame, functionName, threadID)
+			if !found {
+				continue
+			}
+		}
+		if value > thresholdMs {
+			matches = append(matches, map[string]interface{}{
+				"frame":       frame.FrameNumber,
+				"valueMs":     value,
+				"frameTimeMs": times[i],
+			})
+		}
+	}
+
+	total := len(matches)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"condition":      condition,
+		"framesSearched": read,
+		"totalMatches":   total,
+		"offset":         offset,
+		"limit":          limit,
+		"frames":         matches[offset:end],
+		"summary":        fmt.Sprintf("%d of %d frames match '%s'", total, read, condition),
+	}
+	if end < total {
+		output["nextOffset"] = end
+	}
+	if read < len(data.Frames) {
+		attachPartial(ctx, output, read, len(data.Frames), "frames")
+	}
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// findFrame returns the index of the frame with the given frame number
+func findFrame(data *FrameProData, frameNumber int) (int, bool) {
+	for i, frame := range data.Frames {
+		if frame.FrameNumber == frameNumber {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func getFrameHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	number, ok := args["frame"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("frame is required"), nil
+	}
+	frameNumber := int(number)
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	index, found := findFrame(data, frameNumber)
+	if !found {
+		return mcp.NewToolResultError(fmt.Sprintf("Frame %d not found (capture has frames %d to %d)",
+			frameNumber, data.Frames[0].FrameNumber, data.Frames[len(data.Frames)-1].FrameNumber)), nil
+	}
+	frame := data.Frames[index]
+	mainThreads := mainThreadIDs(data)
+
+	// Group the frame's functions by thread, most expensive first
+	threads := []map[string]interface{}{}
+	for id, total := range frameThreadTotals(frame) {
+		functions := []map[string]interface{}{}
+		for _, fn := range topFrameFunctions(frame, 0) {
+			if fn.ThreadID != id {
+				continue
+			}
+			functions = append(functions, map[string]interface{}{
+				"function": fn.FunctionName,
+				"timeMs":   fn.TimeMs,
+				"count":    fn.Count,
+			})
+		}
+		threads = append(threads, map[string]interface{}{
+			"threadId":     id,
+			"threadName":   total.ThreadName,
+			"isMainThread": mainThreads[id],
+			"totalMs":      total.TimeMs,
+			"functions":    functions,
+		})
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i]["totalMs"].(float64) > threads[j]["totalMs"].(float64)
+	})
+
+	frameTime := frameTimeMs(frame, threadStages(data))
+	median := medianOf(frameTimes(data))
+
+	summary := fmt.Sprintf("Frame %d took %.2fms (%.1fx the median of %.2fms)", frame.FrameNumber, frameTime, frameTime/(median+0.001), median)
+	if top := topFrameFunctions(frame, 1); len(top) > 0 {
+		summary += fmt.Sprintf("; most expensive function: %s on %s (%.2fms)", top[0].FunctionName, top[0].ThreadName, top[0].TimeMs)
+	}
+
+	result, _ := json.MarshalIndent(map[string]interface{}{
+		"file":              filePath,
+		"sessionName":       data.SessionName,
+		"frame":             frame.FrameNumber,
+		"frameTimeMs":       frameTime,
+		"medianFrameTimeMs": median,
+		"ratioToMedian":     frameTime / (median + 0.001),
+		"functionCount":     len(frame.Functions),
+		"threads":           threads,
+		"summary":           summary,
+	}, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// GPU work shows up as scopes on one track per GPU queue: scopes tagged with GpuQueue, or
+// on threads named like "GPU Graphics Queue", "Async Compute" or "Copy Queue" (as Chrome
+// traces from GPU tools export them). Async compute only pays off when it runs while the
+// graphics queue of the same GPU is busy. With start times (StartMs, which Chrome trace
+// imports carry) the overlap is measured per frame; with durations only, just the
+// per-queue load is reported, and a frame lasts as long as its busiest queue.
+
+// GPU queue kinds
+const (
+	gpuQueueGraphics = "graphics"
+	gpuQueueCompute  = "compute"
+	gpuQueueCopy     = "copy"
+)
+
+const (
+	minHiddenShare    = 0.25 // Share of async work overlapping graphics work below which a frame counts as serialized
+	minAsyncQueueMs   = 0.5  // Async work per frame worth checking for overlap
+	serializedFrameOK = 0.25 // Share of frames that may be serialized before the queue is flagged
+)
+
+// gpuQueueKind returns the kind of GPU queue a scope ran on, or "" for CPU scopes
+func gpuQueueKind(fn FrameProFunction) string {
+	if fn.GPUQueue != "" {
+		return strings.ToLower(fn.GPUQueue)
+	}
+	name := strings.ToLower(fn.ThreadName)
+	if !strings.Contains(name, "gpu") && !strings.Contains(name, "queue") && !strings.Contains(name, "async compute") {
+		return ""
+	}
+	switch {
+	case strings.Contains(name, "compute"):
+		return gpuQueueCompute
+	case strings.Contains(name, "copy") || strings.Contains(name, "dma") || strings.Contains(name, "transfer"):
+		return gpuQueueCopy
+	}
+	return gpuQueueGraphics
+}
+
+// gpuIndex returns the GPU a queue track belongs to, from a number after "GPU" in its
+// name ("GPU1 Compute", "GPU 2 Graphics"); 0 when there is none
+func gpuIndex(queue string) int {
+	lower := strings.ToLower(queue)
+	at := strings.Index(lower, "gpu")
+	if at < 0 {
+		return 0
+	}
+	index := 0
+	for _, r := range strings.TrimLeft(lower[at+3:], " #") {
+		if r < '0' || r > '9' {
+			break
+		}
+		index = index*10 + int(r-'0')
+	}
+	return index
+}
+
+// GPUQueueStats is the load of one GPU queue
+type GPUQueueStats struct {
+	Queue              string                   `json:"queue"`
+	Kind               string                   `json:"kind"`
+	AvgBusyMs          float64                  `json:"avgBusyMs"`
+	P95BusyMs          float64                  `json:"p95BusyMs"`
+	UtilizationPercent float64                  `json:"utilizationPercent"` // Of the GPU frame time
+	AvgHiddenPercent   float64                  `json:"avgHiddenPercent,omitempty"`
+	SerializedFrames   int                      `json:"serializedFrames,omitempty"`
+	TopPasses          []map[string]interface{} `json:"topPasses"`
+	Evidence           *IssueEvidence           `json:"evidence,omitempty"`
+	passes             map[string]float64       // Pass -> total ms
+	busy               []float64
+}
+
+// GPUQueueFrame is the queue overlap in one frame
+type GPUQueueFrame struct {
+	FrameNumber int                `json:"frame"`
+	GPUFrameMs  float64            `json:"gpuFrameMs"`
+	BusyMs      map[string]float64 `json:"busyMs"`
+	OverlapMs   map[string]float64 `json:"overlapWithGraphicsMs,omitempty"`
+}
+
+// gpuInterval is a scope's span within its frame
+type gpuInterval struct{ start, end float64 }
+
+// mergeIntervals sorts intervals and merges the overlapping ones
+func mergeIntervals(intervals []gpuInterval) []gpuInterval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start < intervals[j].start
+	})
+	merged := []gpuInterval{}
+	for _, interval := range intervals {
+		if n := len(merged); n > 0 && interval.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, interval.end)
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// intervalOverlap returns the time two merged interval lists have in common
+func intervalOverlap(a, b []gpuInterval) float64 {
+	var overlap float64
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		overlap += max(0, min(a[i].end, b[j].end)-max(a[i].start, b[j].start))
+		if a[i].end < b[j].end {
+			i++
+		} else {
+			j++
+		}
+	}
+	return overlap
+}
+
+func gpuQueuesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	topN := 5
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	// Overlap is measured only when every GPU scope has a start time
+	timed := true
+	for _, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			if gpuQueueKind(fn) != "" && fn.StartMs == nil {
+				timed = false
+			}
+		}
+	}
+
+	queues := make(map[string]*GPUQueueStats)
+	frames := []GPUQueueFrame{}
+	serializedBy := make(map[string]map[int]float64) // Queue -> frame -> unhidden ms
+	for _, frame := range data.Frames {
+		busy := make(map[string]float64)
+		intervals := make(map[string][]gpuInterval)
+		kinds := make(map[string]string)
+		start, end := -1.0, 0.0
+		for _, fn := range frame.Functions {
+			kind := gpuQueueKind(fn)
+			if kind == "" {
+				continue
+			}
+			queue := fn.ThreadName
+			kinds[queue] = kind
+			busy[queue] += fn.TimeMs
+			stats := queues[queue]
+			if stats == nil {
+				stats = &GPUQueueStats{Queue: queue, Kind: kind, passes: make(map[string]float64)}
+				queues[queue] = stats
+			}
+			stats.passes[fn.FunctionName] += fn.TimeMs
+			if !timed {
+				continue
+			}
+			interval := gpuInterval{*fn.StartMs, *fn.StartMs + fn.TimeMs}
+			intervals[queue] = append(intervals[queue], interval)
+			if start < 0 || interval.start < start {
+				start = interval.start
+			}
+			end = max(end, interval.end)
+		}
+		if len(busy) == 0 {
+			continue
+		}
+
+		entry := GPUQueueFrame{FrameNumber: frame.FrameNumber, BusyMs: busy}
+		graphics := make(map[int][]gpuInterval) // GPU -> graphics work
+		for queue, kind := range kinds {
+			entry.GPUFrameMs = max(entry.GPUFrameMs, busy[queue])
+			if kind == gpuQueueGraphics {
+				graphics[gpuIndex(queue)] = append(graphics[gpuIndex(queue)], intervals[queue]...)
+			}
+		}
+		if timed && start >= 0 {
+			entry.GPUFrameMs = end - start
+		}
+		for queue, kind := range kinds {
+			if kind == gpuQueueGraphics || !timed {
+				continue
+			}
+			overlap := intervalOverlap(mergeIntervals(intervals[queue]), mergeIntervals(graphics[gpuIndex(queue)]))
+			if entry.OverlapMs == nil {
+				entry.OverlapMs = make(map[string]float64)
+			}
+			entry.OverlapMs[queue] = overlap
+			if busy[queue] >= minAsyncQueueMs && overlap < busy[queue]*minHiddenShare {
+				if serializedBy[queue] == nil {
+					serializedBy[queue] = make(map[int]float64)
+				}
+				serializedBy[queue][frame.FrameNumber] = busy[queue] - overlap
+			}
+		}
+		for queue := range queues {
+			queues[queue].busy = append(queues[queue].busy, busy[queue])
+		}
+		frames = append(frames, entry)
+	}
+	if len(queues) == 0 {
+		return mcp.NewToolResultError("This capture has no GPU queue data: no scopes with GpuQueue and no threads named like GPU queues (graphics, compute, copy). FramePro's standard exports don't include GPU timings"), nil
+	}
+
+	// Per-queue averages; frames without work on a queue count as idle
+	var gpuFrameTotal float64
+	for _, frame := range frames {
+		gpuFrameTotal += frame.GPUFrameMs
+	}
+	stats := []*GPUQueueStats{}
+	for _, queue := range queues {
+		for len(queue.busy) < len(frames) {
+			queue.busy = append(queue.busy, 0)
+		}
+		var total float64
+		for _, ms := range queue.busy {
+			total += ms
+		}
+		queue.AvgBusyMs = total / float64(len(frames))
+		sorted := append([]float64(nil), queue.busy...)
+		sort.Float64s(sorted)
+		queue.P95BusyMs = percentile(sorted, 95)
+		if gpuFrameTotal > 0 {
+			queue.UtilizationPercent = total / gpuFrameTotal * 100
+		}
+		names := make([]string, 0, len(queue.passes))
+		for name := range queue.passes {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return queue.passes[names[i]] > queue.passes[names[j]]
+		})
+		for _, name := range names[:min(len(names), topN)] {
+			queue.TopPasses = append(queue.TopPasses, map[string]interface{}{"pass": name, "avgMs": queue.passes[name] / float64(len(frames))})
+		}
+		stats = append(stats, queue)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Kind != stats[j].Kind {
+			return stats[i].Kind > stats[j].Kind // graphics, copy, compute
+		}
+		return stats[i].Queue < stats[j].Queue
+	})
+
+	analysis := []string{}
+	suggestions := []string{}
+	load := []string{}
+	for _, queue := range stats {
+		load = append(load, fmt.Sprintf("%s (%s): %.2fms busy per frame (p95 %.2fms), %.0f%% of GPU frame time",
+			queue.Queue, queue.Kind, queue.AvgBusyMs, queue.P95BusyMs, queue.UtilizationPercent))
+	}
+	if timed {
+		for _, queue := range stats {
+			if queue.Kind == gpuQueueGraphics {
+				continue
+			}
+			var hidden, work float64
+			for _, frame := range frames {
+				hidden += frame.OverlapMs[queue.Queue]
+				work += frame.BusyMs[queue.Queue]
+			}
+			if work > 0 {
+				queue.AvgHiddenPercent = hidden / work * 100
+			}
+			queue.SerializedFrames = len(serializedBy[queue.Queue])
+			if queue.SerializedFrames == 0 || float64(queue.SerializedFrames) < float64(len(frames))*serializedFrameOK {
+				continue
+			}
+			serialized := serializedBy[queue.Queue]
+			queue.Evidence = frameEvidence(data, func(frame FrameProFrame) float64 {
+				return serialized[frame.FrameNumber]
+			}, 0)
+			analysis = append(analysis, fmt.Sprintf("%s work is serialized with graphics in %d of %d frames: only %.0f%% of it overlaps graphics work",
+				queue.Queue, queue.SerializedFrames, len(frames), queue.AvgHiddenPercent))
+			if queue.Kind == gpuQueueCompute {
+				suggestions = append(suggestions, fmt.Sprintf("Async compute on %s isn't hidden: check for fences or barriers that make graphics wait on it, and schedule it alongside graphics passes that leave the shader units idle (shadow maps, depth prepass)", queue.Queue))
+			} else {
+				suggestions = append(suggestions, fmt.Sprintf("Uploads on %s run while graphics is idle: issue them earlier or double-buffer the resources so graphics doesn't wait for them", queue.Queue))
+			}
+		}
+	} else {
+		analysis = append(analysis, "GPU scopes have no start times (StartMs), so queue overlap and serialization can't be measured; GPU frame time is the busiest queue's time")
+	}
+
+	analysis = append(analysis, load...) // Serialization findings first
+
+	summary := fmt.Sprintf("%d GPU queues over %d frames; average GPU frame %.2fms", len(stats), len(frames), gpuFrameTotal/float64(len(frames)))
+	if len(suggestions) > 0 {
+		summary = fmt.Sprintf("%d of %d GPU queues are serialized with graphics work they should overlap", len(suggestions), len(stats))
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"framesAnalyzed": len(frames),
+		"timed":          timed,
+		"queues":         stats,
+		"frames":         frames,
+		"suggestions":    suggestions,
+		"analysis":       analysis,
+		"summary":        summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
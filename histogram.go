@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Default bin edges: 120fps, 60fps and 30fps frame budgets
+var defaultHistogramBinsMs = []float64{8.33, 16.67, 33.33}
+
+// HistogramBucket is one frame-time range of the histogram
+type HistogramBucket struct {
+	Label   string  `json:"label"`
+	MinMs   float64 `json:"minMs"`
+	MaxMs   float64 `json:"maxMs,omitempty"` // Omitted for the open-ended last bucket
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+func frameTimeHistogramHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	bins := defaultHistogramBinsMs
+	if raw, ok := args["bins_ms"].([]interface{}); ok && len(raw) > 0 {
+		bins = []float64{}
+		for _, v := range raw {
+			if edge, ok := v.(float64); ok && edge > 0 {
+				bins = append(bins, edge)
+			}
+		}
+		if len(bins) == 0 {
+			return mcp.NewToolResultError("bins_ms must contain positive frame-time edges in milliseconds"), nil
+		}
+		sort.Float64s(bins)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	buckets := buildHistogram(times, bins)
+
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"totalFrames": len(times),
+		"binsMs":      bins,
+		"buckets":     buckets,
+		"summary":     describeHistogram(buckets),
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// buildHistogram counts frame times into buckets delimited by the ascending edges
+func buildHistogram(times []float64, edges []float64) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(edges)+1)
+	for i := range buckets {
+		switch {
+		case i == 0:
+			buckets[i] = HistogramBucket{Label: fmt.Sprintf("under %.2fms", edges[0]), MaxMs: edges[0]}
+		case i == len(edges):
+			buckets[i] = HistogramBucket{Label: fmt.Sprintf("%.2fms+", edges[i-1]), MinMs: edges[i-1]}
+		default:
+			buckets[i] = HistogramBucket{Label: fmt.Sprintf("%.2f-%.2fms", edges[i-1], edges[i]), MinMs: edges[i-1], MaxMs: edges[i]}
+		}
+	}
+
+	for _, t := range times {
+		i := sort.SearchFloat64s(edges, t)
+		// SearchFloat64s returns the first edge >= t; a time equal to an edge belongs to the next bucket
+		if i < len(edges) && edges[i] == t {
+			i++
+		}
+		buckets[i].Count++
+	}
+
+	if len(times) > 0 {
+		for i := range buckets {
+			buckets[i].Percent = float64(buckets[i].Count) / float64(len(times)) * 100
+		}
+	}
+	return buckets
+}
+
+// describeHistogram renders the distribution as one readable sentence
+func describeHistogram(buckets []HistogramBucket) string {
+	summary := "Frame-time distribution:"
+	for i, b := range buckets {
+		if i > 0 {
+			summary += ","
+		}
+		summary += fmt.Sprintf(" %.1f%% %s", b.Percent, b.Label)
+	}
+	return summary
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CaptureFile is a FramePro export found on disk
+type CaptureFile struct {
+	Path     string
+	Modified time.Time
+}
+
+// listCaptureFiles returns the files matching a glob pattern in dir, oldest first.
+// The server's own bookkeeping files are skipped.
+func listCaptureFiles(dir, pattern string) ([]CaptureFile, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+
+	files := []CaptureFile{}
+	for _, path := range matches {
+		if strings.HasPrefix(filepath.Base(path), "framepro_") {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, CaptureFile{Path: path, Modified: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Modified.Before(files[j].Modified)
+	})
+	return files, nil
+}
+
+// mergeFunctionsByName combines entries of the same function on different threads.
+// Thread IDs are assigned by the OS per run, so cross-session matching goes by name.
+// Times and counts are summed, maxima take the largest value.
+func mergeFunctionsByName(functions []FrameProFunction) map[string]FrameProFunction {
+	merged := make(map[string]FrameProFunction)
+	for _, fn := range functions {
+		existing, exists := merged[fn.FunctionName]
+		if !exists {
+			merged[fn.FunctionName] = fn
+			continue
+		}
+		existing.TotalTimeMs += fn.TotalTimeMs
+		existing.TotalCount += fn.TotalCount
+		existing.SelfTimeMs += fn.SelfTimeMs
+		existing.InclusiveTimeMs += fn.InclusiveTimeMs
+		existing.AvgTimePerFrameMs += fn.AvgTimePerFrameMs
+		existing.AvgCountPerFrame += fn.AvgCountPerFrame
+		if fn.MaxTimePerFrameMs > existing.MaxTimePerFrameMs {
+			existing.MaxTimePerFrameMs = fn.MaxTimePerFrameMs
+		}
+		if fn.MaxCountPerFrame > existing.MaxCountPerFrame {
+			existing.MaxCountPerFrame = fn.MaxCountPerFrame
+		}
+		if fn.ThreadName != existing.ThreadName && !strings.Contains(existing.ThreadName, fn.ThreadName) {
+			existing.ThreadName += ", " + fn.ThreadName
+		}
+		existing.IsMainThread = existing.IsMainThread || fn.IsMainThread
+		existing.IsRenderThread = existing.IsRenderThread || fn.IsRenderThread
+		existing.IsWorkerThread = existing.IsWorkerThread || fn.IsWorkerThread
+		merged[fn.FunctionName] = existing
+	}
+	return merged
+}
+
+// functionMetricMs returns the named per-function metric used for history thresholds
+func functionMetricMs(fn FrameProFunction, metric string) float64 {
+	switch metric {
+	case "total":
+		return fn.TotalTimeMs
+	case "max_per_frame":
+		return fn.MaxTimePerFrameMs
+	default:
+		return fn.AvgTimePerFrameMs
+	}
+}
+
+// matchesFunctionName compares a function name exactly or, when contains is set, case-insensitively by substring
+func matchesFunctionName(name, query string, contains bool) bool {
+	if contains {
+		return strings.Contains(strings.ToLower(name), strings.ToLower(query))
+	}
+	return name == query
+}
+
+func searchHistoryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	functionName, _ := args["function"].(string)
+	if functionName == "" {
+		return mcp.NewToolResultError("function is required"), nil
+	}
+	contains, _ := args["contains"].(bool)
+	thresholdMs, _ := args["threshold_ms"].(float64)
+	metric, _ := args["metric"].(string)
+	if metric == "" {
+		metric = "avg_per_frame"
+	}
+	if metric != "avg_per_frame" && metric != "total" && metric != "max_per_frame" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid metric '%s': expected 'avg_per_frame', 'total' or 'max_per_frame'", metric)), nil
+	}
+	directory, _ := args["directory"].(string)
+	if directory == "" {
+		directory = dataDir
+	}
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		pattern = "*.json"
+	}
+
+	filter, err := parseSessionFilter(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	files, err := listCaptureFiles(directory, pattern)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	occurrences := []map[string]interface{}{}
+	skipped := []string{}
+	sessionsWithHit := make(map[string]bool)
+	scanned := 0
+	read := len(files)
+	for i, file := range files {
+		if ctx.Err() != nil { // Out of time or canceled: report the captures read so far
+			read = i
+			break
+		}
+		data, err := loadFrameProData(file.Path)
+		if err != nil {
+			skipped = append(skipped, filepath.Base(file.Path))
+			continue
+		}
+		fields, selected := filter.match(file.Path, data)
+		if !selected {
+			continue
+		}
+		scanned++
+
+		for _, fn := range data.Functions {
+			if !matchesFunctionName(fn.FunctionName, functionName, contains) {
+				continue
+			}
+			value := functionMetricMs(fn, metric)
+			if value <= thresholdMs {
+				continue
+			}
+
+			occurrence := map[string]interface{}{
+				"file":              filepath.Base(file.Path),
+				"sessionName":       data.SessionName,
+				"date":              file.Modified.Format(time.RFC3339),
+				"function":          fn.FunctionName,
+				"threadName":        fn.ThreadName,
+				"valueMs":           value,
+				"totalTimeMs":       fn.TotalTimeMs,
+				"avgTimePerFrameMs": fn.AvgTimePerFrameMs,
+				"maxTimePerFrameMs": fn.MaxTimePerFrameMs,
+			}
+			if fields != nil {
+				occurrence["fields"] = fields
+			}
+			attachAnnotations(occurrence, "annotations", data.SessionName)
+			occurrences = append(occurrences, occurrence)
+			sessionsWithHit[data.SessionName] = true
+		}
+	}
+
+	output := map[string]interface{}{
+		"function":     functionName,
+		"metric":       metric,
+		"thresholdMs":  thresholdMs,
+		"directory":    directory,
+		"filesScanned": scanned,
+		"occurrences":  occurrences,
+		"summary": fmt.Sprintf("'%s' exceeded %.2fms (%s) in %d of %d sessions",
+			functionName, thresholdMs, metric, len(sessionsWithHit), scanned),
+	}
+	if len(skipped) > 0 {
+		output["skippedFiles"] = skipped
+	}
+	if read < len(files) {
+		attachPartial(ctx, output, read, len(files), "capture files")
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// HitchRun is a sequence of consecutive hitch frames
+type HitchRun struct {
+	StartFrame     int     `json:"startFrame"`
+	EndFrame       int     `json:"endFrame"`
+	Length         int     `json:"length"`
+	PeakFrameMs    float64 `json:"peakFrameTimeMs"`
+	Classification string  `json:"classification"` // "spike" or "sustained"
+}
+
+func detectHitchesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	factor := 2.0
+	if f, ok := args["threshold_factor"].(float64); ok && f > 0 {
+		factor = f
+	}
+	sustainedFrames := 3
+	if n, ok := args["sustained_frames"].(float64); ok && n >= 2 {
+		sustainedFrames = int(n)
+	}
+	topN := 5
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	median := medianOf(times)
+	threshold := median * factor
+
+	// Group consecutive hitch frames into runs first, so each hitch can be classified
+	runs := []HitchRun{}
+	runOf := make(map[int]int) // frame index -> run index
+	for i, t := range times {
+		if t <= threshold {
+			continue
+		}
+		if i > 0 && times[i-1] > threshold && len(runs) > 0 {
+			run := &runs[len(runs)-1]
+			run.EndFrame = data.Frames[i].FrameNumber
+			run.Length++
+			if t > run.PeakFrameMs {
+				run.PeakFrameMs = t
+			}
+		} else {
+			runs = append(runs, HitchRun{
+				StartFrame:  data.Frames[i].FrameNumber,
+				EndFrame:    data.Frames[i].FrameNumber,
+				Length:      1,
+				PeakFrameMs: t,
+			})
+		}
+		runOf[i] = len(runs) - 1
+	}
+
+	// Hitches that look like capture-machine stalls are labeled, not dropped
+	contamination := make(map[int]string)
+	for _, frame := range detectContamination(data, factor) {
+		contamination[frame.FrameNumber] = frame.Kind
+	}
+
+	spikeCount, sustainedCount := 0, 0
+	for i := range runs {
+		if runs[i].Length >= sustainedFrames {
+			runs[i].Classification = "sustained"
+			sustainedCount++
+		} else {
+			runs[i].Classification = "spike"
+			spikeCount++
+		}
+	}
+
+	hitches := []map[string]interface{}{}
+	for i, t := range times {
+		runIndex, isHitch := runOf[i]
+		if !isHitch {
+			continue
+		}
+
+		topFunctions := []map[string]interface{}{}
+		for _, fn := range topFrameFunctions(data.Frames[i], topN) {
+			topFunctions = append(topFunctions, map[string]interface{}{
+				"function":   fn.FunctionName,
+				"threadName": fn.ThreadName,
+				"timeMs":     fn.TimeMs,
+				"count":      fn.Count,
+			})
+		}
+
+		hitch := map[string]interface{}{
+			"frame":          data.Frames[i].FrameNumber,
+			"frameTimeMs":    t,
+			"ratioToMedian":  t / (median + 0.001),
+			"classification": runs[runIndex].Classification,
+			"topFunctions":   topFunctions,
+		}
+		if kind, found := contamination[data.Frames[i].FrameNumber]; found {
+			hitch["contamination"] = kind
+		}
+		hitches = append(hitches, hitch)
+	}
+
+	summary := fmt.Sprintf("%d hitch frames (>%.1fx median of %.2fms): %d isolated spikes, %d sustained slowdowns of %d+ frames",
+		len(hitches), factor, median, spikeCount, sustainedCount, sustainedFrames)
+	if len(contamination) > 0 {
+		summary += fmt.Sprintf("; %d look like capture-machine stalls (see detect_contamination)", len(contamination))
+	}
+
+	output := map[string]interface{}{
+		"file":              filePath,
+		"sessionName":       data.SessionName,
+		"framesAnalyzed":    len(times),
+		"medianFrameTimeMs": median,
+		"thresholdMs":       threshold,
+		"hitchFrames":       len(hitches),
+		"hitches":           hitches,
+		"runs":              runs,
+		"summary":           summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The capture viewer is one HTML file with the parsed session embedded as JSON and a small
+// script drawing it, so a capture can be reviewed in any browser with nothing installed and
+// nothing fetched: the frame timeline with the 60 and 30 FPS budgets, one lane per thread
+// shaded by its busy time in each frame, the top scopes of the frame clicked, and a sortable,
+// filterable table of every function.
+
+const viewerFrameFunctions = 15 // Scopes listed per frame
+
+// ViewerData is the session as the viewer script reads it
+type ViewerData struct {
+	Session   string           `json:"session"`
+	File      string           `json:"file"`
+	TargetMs  float64          `json:"targetMs"`
+	Frames    []ViewerFrame    `json:"frames"`
+	Threads   []ViewerThread   `json:"threads"`
+	Functions []ViewerFunction `json:"functions"`
+}
+
+// ViewerFrame is one frame of the timeline with its most expensive scopes
+type ViewerFrame struct {
+	Number int             `json:"n"`
+	Ms     float64         `json:"ms"`
+	Top    [][]interface{} `json:"top"` // [function, thread index, ms]
+}
+
+// ViewerThread is one thread lane: its busy time in every frame
+type ViewerThread struct {
+	Name  string    `json:"name"`
+	Stage string    `json:"stage"`
+	Ms    []float64 `json:"ms"`
+}
+
+// ViewerFunction is one row of the function table
+type ViewerFunction struct {
+	Name    string  `json:"name"`
+	Thread  string  `json:"thread"`
+	TotalMs float64 `json:"totalMs"`
+	AvgMs   float64 `json:"avgMs"`
+	MaxMs   float64 `json:"maxMs"`
+	Calls   int     `json:"calls"`
+}
+
+// roundMs keeps viewer times to microseconds, which keeps the file small
+func roundMs(ms float64) float64 {
+	return math.Round(ms*1000) / 1000
+}
+
+// buildViewerData arranges a capture for the viewer
+func buildViewerData(data *FrameProData, filePath string) ViewerData {
+	view := ViewerData{Session: data.SessionName, File: filePath, TargetMs: 1000.0 / 60, Frames: []ViewerFrame{}, Threads: []ViewerThread{}, Functions: []ViewerFunction{}}
+
+	stages := threadStages(data)
+	threadIndex := make(map[int]int)
+	for _, fn := range data.Functions {
+		if _, exists := threadIndex[fn.ThreadID]; !exists {
+			threadIndex[fn.ThreadID] = len(view.Threads)
+			view.Threads = append(view.Threads, ViewerThread{Name: fn.ThreadName, Stage: stages[fn.ThreadID], Ms: make([]float64, len(data.Frames))})
+		}
+		view.Functions = append(view.Functions, ViewerFunction{
+			Name:    fn.FunctionName,
+			Thread:  fn.ThreadName,
+			TotalMs: roundMs(fn.TotalTimeMs),
+			AvgMs:   roundMs(fn.AvgTimePerFrameMs),
+			MaxMs:   roundMs(fn.MaxTimePerFrameMs),
+			Calls:   fn.TotalCount,
+		})
+	}
+	sort.Slice(view.Functions, func(i, j int) bool { return view.Functions[i].TotalMs > view.Functions[j].TotalMs })
+
+	for f, frame := range data.Frames {
+		for id, total := range frameThreadTotals(frame) {
+			index, exists := threadIndex[id]
+			if !exists { // Only recorded per frame
+				index = len(view.Threads)
+				threadIndex[id] = index
+				view.Threads = append(view.Threads, ViewerThread{Name: total.ThreadName, Stage: stages[id], Ms: make([]float64, len(data.Frames))})
+			}
+			view.Threads[index].Ms[f] = roundMs(total.TimeMs)
+		}
+		top := [][]interface{}{}
+		for _, fn := range topFrameFunctions(frame, viewerFrameFunctions) {
+			top = append(top, []interface{}{fn.FunctionName, threadIndex[fn.ThreadID], roundMs(fn.TimeMs)})
+		}
+		view.Frames = append(view.Frames, ViewerFrame{Number: frame.FrameNumber, Ms: roundMs(frameTimeMs(frame, stages)), Top: top})
+	}
+	return view
+}
+
+// viewerHTML renders the viewer page around the session data
+func viewerHTML(view ViewerData) ([]byte, error) {
+	encoded, err := json.Marshal(view) // Escapes <, > and &, so it can't close the script element
+	if err != nil {
+		return nil, err
+	}
+	page := strings.Replace(viewerTemplate, "{{DATA}}", string(encoded), 1)
+	page = strings.Replace(page, "{{TITLE}}", html.EscapeString(view.Session), 2) // Both come before the data
+	return []byte(page), nil
+}
+
+func exportHTMLViewerHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	outputPath, _ := args["output_path"].(string)
+	dryRun := isDryRun(args)
+	outputPath, err := resolveOutputPath(outputPath, outputStem(filePath), ".html", dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, false); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	view := buildViewerData(data, filePath)
+	content, err := viewerHTML(view)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode the viewer: %v", err)), nil
+	}
+	write, err := writeFile(outputPath, content, dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	note := fmt.Sprintf("Timeline of %d frames with %d thread lanes; click a frame for its top %d scopes", len(view.Frames), len(view.Threads), viewerFrameFunctions)
+	if len(view.Frames) == 0 {
+		note = "No per-frame data: the viewer has the function table only"
+	}
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"outputPath":  outputPath,
+		"frames":      len(view.Frames),
+		"threads":     len(view.Threads),
+		"functions":   len(view.Functions),
+		"bytes":       len(content),
+		"analysis":    []string{note},
+		"summary":     fmt.Sprintf("%s the capture viewer to %s; it opens in any browser without network access", wroteVerb(dryRun), outputPath),
+	}
+	attachWrites(output, []FileWrite{write}, dryRun)
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+const viewerTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{TITLE}} - capture viewer</title>
+<style>
+body { font: 13px/1.4 system-ui, sans-serif; margin: 16px; color: #222; background: #fafafa; }
+h1 { font-size: 18px; margin: 0 0 4px; }
+h2 { font-size: 14px; margin: 20px 0 6px; }
+#stats { color: #555; }
+canvas { display: block; background: #fff; border: 1px solid #ddd; cursor: crosshair; }
+table { border-collapse: collapse; background: #fff; }
+th, td { border: 1px solid #ddd; padding: 3px 8px; text-align: right; white-space: nowrap; }
+th { background: #eee; cursor: pointer; user-select: none; }
+td.name, th.name { text-align: left; }
+#filter { margin-bottom: 6px; width: 320px; padding: 3px; }
+#tip { position: fixed; pointer-events: none; background: #333; color: #fff; padding: 3px 6px; border-radius: 3px; display: none; }
+</style>
+</head>
+<body>
+<h1>{{TITLE}}</h1>
+<div id="stats"></div>
+<div id="timeline"><h2>Frames and thread lanes</h2><canvas id="chart"></canvas></div>
+<div id="frame"></div>
+<h2>Functions</h2>
+<input id="filter" placeholder="Filter by function or thread">
+<table id="functions"></table>
+<div id="tip"></div>
+<script>
+var data = {{DATA}};
+var fmt = function (ms) { return ms.toFixed(2); };
+
+function percentile(values, p) {
+  if (!values.length) return 0;
+  var sorted = values.slice().sort(function (a, b) { return a - b; });
+  return sorted[Math.min(sorted.length - 1, Math.floor(p / 100 * sorted.length))];
+}
+
+var times = data.frames.map(function (f) { return f.ms; });
+var stats = data.frames.length + " frames, " + data.threads.length + " threads, " + data.functions.length + " functions";
+if (times.length) {
+  var avg = times.reduce(function (a, b) { return a + b; }, 0) / times.length;
+  var over = times.filter(function (t) { return t > data.targetMs; }).length;
+  stats += " - frame time avg " + fmt(avg) + "ms, p95 " + fmt(percentile(times, 95)) + "ms, max " + fmt(Math.max.apply(null, times)) +
+    "ms; " + over + " frames over the 60 FPS budget";
+}
+document.getElementById("stats").textContent = data.file + ": " + stats;
+
+var chart = document.getElementById("chart"), tip = document.getElementById("tip");
+var barHeight = 160, laneHeight = 18, labelWidth = 140, selected = -1;
+
+function drawChart() {
+  var ctx = chart.getContext("2d");
+  var width = Math.max(600, Math.min(window.innerWidth - 40, labelWidth + data.frames.length * 8));
+  chart.width = width;
+  chart.height = barHeight + data.threads.length * laneHeight + 10;
+  var step = (width - labelWidth) / data.frames.length;
+  var top = Math.max(data.targetMs * 2.2, percentile(times, 99) * 1.1);
+  var y = function (ms) { return barHeight - Math.min(ms, top) / top * (barHeight - 10); };
+
+  ctx.font = "11px sans-serif";
+  data.frames.forEach(function (f, i) {
+    ctx.fillStyle = i === selected ? "#1565c0" : f.ms > data.targetMs * 2 ? "#d32f2f" : f.ms > data.targetMs ? "#f9a825" : "#43a047";
+    ctx.fillRect(labelWidth + i * step, y(f.ms), Math.max(step - 1, 1), barHeight - y(f.ms));
+  });
+  [[data.targetMs, "60 FPS"], [data.targetMs * 2, "30 FPS"]].forEach(function (line) {
+    ctx.strokeStyle = "#999"; ctx.setLineDash([4, 3]);
+    ctx.beginPath(); ctx.moveTo(labelWidth, y(line[0])); ctx.lineTo(width, y(line[0])); ctx.stroke();
+    ctx.setLineDash([]); ctx.fillStyle = "#555"; ctx.fillText(line[1] + " (" + fmt(line[0]) + "ms)", 4, y(line[0]) + 4);
+  });
+
+  data.threads.forEach(function (t, lane) {
+    var top = barHeight + 6 + lane * laneHeight;
+    ctx.fillStyle = "#333";
+    ctx.fillText(t.name.length > 20 ? t.name.slice(0, 19) + "…" : t.name, 4, top + 12);
+    t.ms.forEach(function (ms, i) {
+      if (ms <= 0) return;
+      var load = Math.min(ms / data.targetMs, 1);
+      ctx.fillStyle = "rgba(" + Math.round(60 + 195 * load) + "," + Math.round(120 - 80 * load) + "," + Math.round(200 - 160 * load) + "," + (0.25 + 0.75 * load) + ")";
+      ctx.fillRect(labelWidth + i * step, top, Math.max(step - 1, 1), laneHeight - 3);
+    });
+  });
+}
+
+function frameAt(event) {
+  var rect = chart.getBoundingClientRect();
+  var i = Math.floor((event.clientX - rect.left - labelWidth) / ((chart.width - labelWidth) / data.frames.length));
+  return i >= 0 && i < data.frames.length ? i : -1;
+}
+
+function showFrame(i) {
+  selected = i;
+  drawChart();
+  var f = data.frames[i], rows = "";
+  f.top.forEach(function (scope) {
+    rows += "<tr><td class=name>" + escapeHTML(scope[0]) + "</td><td class=name>" + escapeHTML(data.threads[scope[1]].name) + "</td><td>" + fmt(scope[2]) + "</td></tr>";
+  });
+  document.getElementById("frame").innerHTML = "<h2>Frame " + f.n + " - " + fmt(f.ms) + "ms</h2><table><tr><th class=name>Function</th><th class=name>Thread</th><th>ms</th></tr>" + rows + "</table>";
+}
+
+function escapeHTML(text) {
+  return String(text).replace(/[&<>"]/g, function (c) { return { "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;" }[c]; });
+}
+
+if (data.frames.length) {
+  drawChart();
+  window.addEventListener("resize", drawChart);
+  chart.addEventListener("click", function (event) { var i = frameAt(event); if (i >= 0) showFrame(i); });
+  chart.addEventListener("mousemove", function (event) {
+    var i = frameAt(event);
+    if (i < 0) { tip.style.display = "none"; return; }
+    tip.textContent = "Frame " + data.frames[i].n + ": " + fmt(data.frames[i].ms) + "ms";
+    tip.style.left = event.clientX + 12 + "px"; tip.style.top = event.clientY + 12 + "px"; tip.style.display = "block";
+  });
+  chart.addEventListener("mouseleave", function () { tip.style.display = "none"; });
+} else {
+  document.getElementById("timeline").innerHTML = "<h2>Frames</h2><p>No per-frame data in this capture.</p>";
+}
+
+var columns = [["name", "Function"], ["thread", "Thread"], ["totalMs", "Total ms"], ["avgMs", "Avg ms/frame"], ["maxMs", "Max ms/frame"], ["calls", "Calls"]];
+var sortKey = "totalMs", descending = true;
+
+function drawTable() {
+  var filter = document.getElementById("filter").value.toLowerCase();
+  var rows = data.functions.filter(function (fn) {
+    return !filter || fn.name.toLowerCase().indexOf(filter) >= 0 || fn.thread.toLowerCase().indexOf(filter) >= 0;
+  });
+  rows.sort(function (a, b) {
+    var x = a[sortKey], y = b[sortKey], order = x < y ? -1 : x > y ? 1 : 0;
+    return descending ? -order : order;
+  });
+  var head = "<tr>" + columns.map(function (c) {
+    var mark = c[0] === sortKey ? (descending ? " ▼" : " ▲") : "";
+    return "<th data-key=" + c[0] + (c[0] === "name" || c[0] === "thread" ? " class=name" : "") + ">" + c[1] + mark + "</th>";
+  }).join("") + "</tr>";
+  var body = rows.map(function (fn) {
+    return "<tr><td class=name>" + escapeHTML(fn.name) + "</td><td class=name>" + escapeHTML(fn.thread) + "</td><td>" + fmt(fn.totalMs) +
+      "</td><td>" + fmt(fn.avgMs) + "</td><td>" + fmt(fn.maxMs) + "</td><td>" + fn.calls + "</td></tr>";
+  }).join("");
+  document.getElementById("functions").innerHTML = head + body;
+}
+
+document.getElementById("functions").addEventListener("click", function (event) {
+  var key = event.target.getAttribute("data-key");
+  if (!key) return;
+  descending = key === sortKey ? !descending : key !== "name" && key !== "thread";
+  sortKey = key;
+  drawTable();
+});
+document.getElementById("filter").addEventListener("input", drawTable);
+drawTable();
+</script>
+</body>
+</html>
+`
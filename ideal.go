@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The ideal profile is the capture as it would look on target: every subsystem at its
+// budget in every frame, no spikes, and frames at the target frame time. Diffing against
+// it gives the gap to close per subsystem rather than the drift from an earlier build.
+
+// SubsystemGap compares one subsystem with its budget
+type SubsystemGap struct {
+	Name             string         `json:"name"`
+	Pattern          string         `json:"pattern"`
+	BudgetMs         float64        `json:"budgetMs"`
+	AvgMs            float64        `json:"avgMs"`
+	P95Ms            float64        `json:"p95Ms"`
+	MaxMs            float64        `json:"maxMs"`
+	GapMs            float64        `json:"gapMs"`      // Average over budget; negative is headroom
+	SpikeGapMs       float64        `json:"spikeGapMs"` // p95 over budget
+	FramesOverBudget int            `json:"framesOverBudget"`
+	PercentOver      float64        `json:"percentFramesOverBudget"`
+	Status           string         `json:"status"`
+	TopFunctions     []FunctionCost `json:"topFunctions"`
+}
+
+// gapStatus describes a subsystem against its budget
+func gapStatus(gap SubsystemGap) string {
+	switch {
+	case gap.AvgMs == 0:
+		return "not recorded"
+	case gap.GapMs > 0:
+		return "over budget"
+	case gap.SpikeGapMs > 0:
+		return "spikes over budget"
+	default:
+		return "within budget"
+	}
+}
+
+func compareToIdealHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, data)
+	budgets, err := parseBudgets(args, targetFPS)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	times, frames := subsystemFrameTimes(data, budgets)
+	gaps := []SubsystemGap{}
+	var budgeted float64
+	for i, budget := range budgets {
+		budgeted += budget.BudgetMs
+		gap := SubsystemGap{Name: budget.Name, Pattern: budget.Pattern, BudgetMs: budget.BudgetMs}
+		stats := computeFrameTimePercentiles(times[i])
+		gap.AvgMs, gap.P95Ms = stats.AvgFrameTimeMs, stats.P95Ms
+		for _, ms := range times[i] {
+			gap.MaxMs = max(gap.MaxMs, ms)
+			if ms > budget.BudgetMs {
+				gap.FramesOverBudget++
+			}
+		}
+		gap.PercentOver = float64(gap.FramesOverBudget) / float64(len(frames)) * 100
+		gap.GapMs = gap.AvgMs - budget.BudgetMs
+		gap.SpikeGapMs = gap.P95Ms - budget.BudgetMs
+		gap.Status = gapStatus(gap)
+		gap.TopFunctions = budgetFunctions(frames, budgets, i, 3)
+		gaps = append(gaps, gap)
+	}
+	sort.SliceStable(gaps, func(i, j int) bool {
+		return gaps[i].GapMs > gaps[j].GapMs
+	})
+
+	// Frame time against the ideal, where every frame fits the target
+	targetMs := 1000 / targetFPS
+	frameStats := computeFrameTimePercentiles([]float64{frameTimeMs(aggregateFrame(data), threadStages(data))})
+	if len(data.Frames) > 0 {
+		frameStats = computeFrameTimePercentiles(frameTimes(data))
+	}
+
+	analysis := []string{}
+	over := 0
+	var toCut float64
+	for _, gap := range gaps {
+		switch gap.Status {
+		case "over budget":
+			over++
+			toCut += gap.GapMs
+			analysis = append(analysis, fmt.Sprintf("%s needs -%.2fms (%.2fms per frame against a %.2fms budget)", gap.Name, gap.GapMs, gap.AvgMs, gap.BudgetMs))
+		case "spikes over budget":
+			analysis = append(analysis, fmt.Sprintf("%s fits on average (%.2fms of %.2fms) but spikes to %.2fms at p95; %.0f%% of frames are over budget",
+				gap.Name, gap.AvgMs, gap.BudgetMs, gap.P95Ms, gap.PercentOver))
+		case "within budget":
+			analysis = append(analysis, fmt.Sprintf("%s is fine (%.2fms of %.2fms)", gap.Name, gap.AvgMs, gap.BudgetMs))
+		}
+	}
+
+	summary := fmt.Sprintf("Every subsystem is within budget; frame time averages %.2fms against a %.2fms target (%.0f FPS)",
+		frameStats.AvgFrameTimeMs, targetMs, targetFPS)
+	if over > 0 {
+		summary = fmt.Sprintf("%d of %d subsystems are over budget; %.2fms per frame must be cut to reach the ideal profile. Frame time averages %.2fms against a %.2fms target (%.0f FPS)",
+			over, len(budgets), toCut, frameStats.AvgFrameTimeMs, targetMs, targetFPS)
+	}
+
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"targetFPS":       targetFPS,
+		"targetFPSSource": targetSource,
+		"ideal": map[string]interface{}{
+			"frameTimeMs":   targetMs,
+			"budgetedMs":    budgeted,
+			"unbudgetedMs":  targetMs - budgeted,
+			"spikesAllowed": false,
+		},
+		"frameTime": map[string]interface{}{
+			"avgMs":    frameStats.AvgFrameTimeMs,
+			"p95Ms":    frameStats.P95Ms,
+			"gapMs":    frameStats.AvgFrameTimeMs - targetMs,
+			"p95GapMs": frameStats.P95Ms - targetMs,
+		},
+		"subsystems": gaps,
+		"analysis":   analysis,
+		"summary":    summary,
+	}
+	if len(data.Frames) == 0 {
+		addWarning(output, warnNoFrameData, "No per-frame data; compared using average time per frame, so spikes are not visible")
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
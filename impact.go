@@ -0,0 +1,54 @@
+package main
+
+import "fmt"
+
+// Player-facing translation of frame-time numbers. A frame over the target
+// frame time misses its present and is treated as a dropped frame.
+
+// budgetImpact describes how a capture's frames fare against a frame budget
+func budgetImpact(times []float64, targetFPS float64) map[string]interface{} {
+	budgetMs := 1000.0 / targetFPS
+	over := 0
+	for _, t := range times {
+		if t > budgetMs {
+			over++
+		}
+	}
+	percentOver := float64(over) / float64(len(times)) * 100
+
+	return map[string]interface{}{
+		"framesOverBudget":       over,
+		"percentOverBudget":      percentOver,
+		"droppedFramesPerMinute": percentOver / 100 * targetFPS * 60,
+	}
+}
+
+// regressionPlayerImpact estimates what a per-frame cost increase means for players.
+// With per-frame data of the current capture it counts the frames the increase pushed over
+// budget: frames over budget now that would have fit without the added time.
+func regressionPlayerImpact(avgTimeDiffMs float64, currentTimes []float64, targetFPS float64) map[string]interface{} {
+	budgetMs := 1000.0 / targetFPS
+	impact := map[string]interface{}{
+		"percentOfFrameBudget": avgTimeDiffMs / budgetMs * 100,
+	}
+
+	if len(currentTimes) > 0 && avgTimeDiffMs > 0 {
+		pushed := 0
+		for _, t := range currentTimes {
+			if t > budgetMs && t-avgTimeDiffMs <= budgetMs {
+				pushed++
+			}
+		}
+		percentPushed := float64(pushed) / float64(len(currentTimes)) * 100
+		droppedPerMinute := percentPushed / 100 * targetFPS * 60
+		impact["framesPushedOverBudget"] = pushed
+		impact["percentFramesPushedOverBudget"] = percentPushed
+		impact["droppedFramesPerMinute"] = droppedPerMinute
+		impact["description"] = fmt.Sprintf("Costs %.1f%% of the %.0ffps frame budget and pushes %.1f%% of frames over it (~%.0f dropped frames per minute)",
+			avgTimeDiffMs/budgetMs*100, targetFPS, percentPushed, droppedPerMinute)
+	} else {
+		impact["description"] = fmt.Sprintf("Costs %.1f%% of the %.0ffps frame budget every frame",
+			avgTimeDiffMs/budgetMs*100, targetFPS)
+	}
+	return impact
+}
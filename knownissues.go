@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// KnownIssue is curated guidance for a well-known engine or middleware function.
+// Patterns use the same syntax as search_functions: * and ? wildcards, or a regular expression.
+type KnownIssue struct {
+	Pattern     string   `json:"pattern"`
+	Engine      string   `json:"engine"`
+	Explanation string   `json:"explanation"`
+	Remediation []string `json:"remediation"`
+	re          *regexp.Regexp
+}
+
+// builtinKnownIssues ships with the server; project entries loaded from
+// FRAMEPRO_KNOWN_ISSUES are checked first and can override these
+var builtinKnownIssues = []KnownIssue{
+	// Unreal Engine
+	{Pattern: "*SceneRenderer::Render*", Engine: "Unreal Engine",
+		Explanation: "Render thread scene rendering: visibility, draw call submission and pass setup for the whole frame",
+		Remediation: []string{"Break it down with 'stat scenerendering' and 'stat initviews'", "Reduce draw calls with instancing, HLODs and merged actors", "Tighten cull distances and occlusion settings"}},
+	{Pattern: "*FScene::Render*", Engine: "Unreal Engine",
+		Explanation: "Render thread scene rendering entry point",
+		Remediation: []string{"Break it down with 'stat scenerendering'", "Reduce draw calls and dynamic shadow casters"}},
+	{Pattern: "*UWorld::Tick*", Engine: "Unreal Engine",
+		Explanation: "Game thread world tick: actor and component ticking, timers and latent actions",
+		Remediation: []string{"Find the expensive tick groups with 'stat game' and 'stat tickables'", "Disable ticking on actors that don't need it or raise their tick interval", "Move per-frame logic to events or timers"}},
+	{Pattern: "*CollectGarbage*", Engine: "Unreal Engine",
+		Explanation: "Unreal garbage collection: reachability analysis and purge of UObjects, blocks the game thread",
+		Remediation: []string{"Enable incremental purge (gc.IncrementalBeginDestroyEnabled)", "Pool frequently spawned actors instead of destroying them", "Use GC clustering for large static object sets"}},
+	{Pattern: "*FlushAsyncLoading*", Engine: "Unreal Engine",
+		Explanation: "The game thread waits for outstanding async loads to finish: a synchronous load hitch",
+		Remediation: []string{"Find the caller forcing the flush (often LoadObject or a hard reference)", "Preload assets asynchronously ahead of use with the streamable manager"}},
+	{Pattern: "*LoadPackage*", Engine: "Unreal Engine",
+		Explanation: "Synchronous package load on the calling thread",
+		Remediation: []string{"Replace with async loading (LoadPackageAsync / FStreamableManager)", "Convert hard references to soft references"}},
+	{Pattern: "*WaitUntilTaskCompletes*", Engine: "Unreal Engine",
+		Explanation: "A thread blocks until task graph work completes; the cost is the tasks it waits on, not this scope",
+		Remediation: []string{"Look at the worker threads during these frames to find the late task", "Kick the tasks earlier in the frame so they finish before they're needed"}},
+	{Pattern: "*UpdateComponentToWorld*", Engine: "Unreal Engine",
+		Explanation: "Transform propagation through component attachment hierarchies",
+		Remediation: []string{"Reduce deep attachment hierarchies", "Disable overlap events and physics state on components that don't need them"}},
+	{Pattern: "*UAnimInstance*", Engine: "Unreal Engine",
+		Explanation: "Animation blueprint update on the game thread",
+		Remediation: []string{"Enable multi-threaded animation update", "Use Update Rate Optimizations (URO) or the animation budget allocator for distant meshes"}},
+	{Pattern: "*Slate*", Engine: "Unreal Engine",
+		Explanation: "Slate/UMG UI layout, paint and tick",
+		Remediation: []string{"Use invalidation boxes and retainer boxes for static UI", "Avoid property bindings evaluated every frame"}},
+	{Pattern: "*Chaos*", Engine: "Unreal Engine",
+		Explanation: "Chaos physics simulation",
+		Remediation: []string{"Simplify collision geometry", "Reduce simulated bodies and enable sleeping", "Check substepping settings"}},
+
+	// Unity
+	{Pattern: "Gfx.WaitForPresent*", Engine: "Unity",
+		Explanation: "The CPU waits for the GPU to finish the previous frame: the frame is GPU-bound (or vsync-limited), not CPU-bound",
+		Remediation: []string{"Profile the GPU (Frame Debugger, RenderDoc, platform GPU profiler)", "Reduce resolution, overdraw, post-processing or shader cost", "Check whether vsync is on before treating this as a problem"}},
+	{Pattern: "WaitForTargetFPS", Engine: "Unity",
+		Explanation: "Idle time spent waiting for vsync or Application.targetFrameRate: headroom, not a cost",
+		Remediation: []string{"Exclude it when judging CPU cost", "Disable vsync and targetFrameRate to measure the real frame time"}},
+	{Pattern: "Gfx.WaitForGfxCommandsFromMainThread", Engine: "Unity",
+		Explanation: "The render thread waits for the main thread to submit commands: the frame is main-thread bound",
+		Remediation: []string{"Optimize the main thread; this scope is a symptom"}},
+	{Pattern: "GC.Collect", Engine: "Unity",
+		Explanation: "Managed garbage collection, usually a spike caused by per-frame allocations",
+		Remediation: []string{"Find allocations with the GC.Alloc column and remove them from hot paths", "Enable incremental garbage collection", "Pool objects and reuse collections"}},
+	{Pattern: "Physics.Simulate", Engine: "Unity",
+		Explanation: "PhysX simulation step, run once per FixedUpdate",
+		Remediation: []string{"Raise Fixed Timestep or lower Maximum Allowed Timestep to avoid catch-up steps", "Prune the layer collision matrix", "Use simpler colliders"}},
+	{Pattern: "Camera.Render", Engine: "Unity",
+		Explanation: "CPU-side rendering of one camera: culling, sorting and draw call submission",
+		Remediation: []string{"Reduce draw calls with the SRP Batcher, static/dynamic batching or GPU instancing", "Remove unneeded cameras", "Use occlusion culling"}},
+	{Pattern: "Canvas.*", Engine: "Unity",
+		Explanation: "uGUI canvas rebuild and batching",
+		Remediation: []string{"Split dynamic UI into its own canvases", "Avoid layout groups on frequently changing elements"}},
+	{Pattern: "BehaviourUpdate", Engine: "Unity",
+		Explanation: "All MonoBehaviour.Update calls",
+		Remediation: []string{"Enable deep profiling briefly to find the expensive scripts", "Replace many small Update methods with a manager that updates in batches"}},
+	{Pattern: "Shader.CreateGPUProgram", Engine: "Unity",
+		Explanation: "A shader variant compiled on first use, causing a hitch",
+		Remediation: []string{"Warm up shaders with ShaderVariantCollection.WarmUp during loading"}},
+	{Pattern: "Loading.*", Engine: "Unity",
+		Explanation: "Asset loading on the main thread",
+		Remediation: []string{"Load asynchronously (Addressables, Resources.LoadAsync)", "Preload during loading screens"}},
+}
+
+// knownIssues holds the project entries (first) followed by the built-in ones
+var knownIssues = compileKnownIssues(builtinKnownIssues)
+
+// compileKnownIssues compiles each entry's pattern, dropping entries with invalid patterns
+func compileKnownIssues(entries []KnownIssue) []KnownIssue {
+	compiled := []KnownIssue{}
+	for _, entry := range entries {
+		re, err := compileFunctionPattern(entry.Pattern, false)
+		if err != nil {
+			continue
+		}
+		entry.re = re
+		compiled = append(compiled, entry)
+	}
+	return compiled
+}
+
+// loadKnownIssues adds project entries from a JSON array of KnownIssue, ahead of the built-ins
+func loadKnownIssues(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read known issues: %w", err)
+	}
+	var entries []KnownIssue
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return fmt.Errorf("failed to parse known issues %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		if _, err := compileFunctionPattern(entry.Pattern, false); err != nil {
+			return fmt.Errorf("known issue %q: %w", entry.Pattern, err)
+		}
+	}
+	knownIssues = append(compileKnownIssues(entries), knownIssues...)
+	return nil
+}
+
+// lookupKnownIssue returns the first known issue matching a function name, or nil
+func lookupKnownIssue(functionName string) *KnownIssue {
+	for i := range knownIssues {
+		if knownIssues[i].re.MatchString(functionName) && ruleEnabled(knownIssueRuleID(knownIssues[i])) {
+			return &knownIssues[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Older FramePro versions only wrote text summaries (*summary*.txt): "Key: value" header
+// lines such as Session and Frames, then a table of functions with a header row, columns
+// separated by tabs, pipes or runs of spaces:
+//
+//	FramePro Summary
+//	Session: 02_10_2019+10_11_12
+//	Frames: 3000
+//
+//	Function        Thread        Total (ms)   Count   Avg/Frame (ms)   Max/Frame (ms)
+//	World::Tick     Main Thread   18770.9      6030    6.26             26.86
+//
+// Columns are matched by their header words, in any order, and any can be missing: times
+// in (us) or (s) are converted, averages are derived from totals and the frame count (and
+// back), and without a Thread column rows go to the thread named by the last "Thread: ..."
+// line, or Main Thread. The summary has no per-frame data, so it loads like a
+// *_functions_analysis.json export and joins history searches and trends with its pattern.
+
+var (
+	legacyColumnSplit = regexp.MustCompile(`\t+|\s*\|\s*|\s{2,}`)
+	legacyHeaderLine  = regexp.MustCompile(`^([A-Za-z][A-Za-z /]*?)\s*[:=]\s*(.+)$`)
+	legacyNumber      = regexp.MustCompile(`^[-+]?[\d,]*\.?\d+`)
+)
+
+// Legacy summary columns
+const (
+	legacyName       = "name"
+	legacyThread     = "thread"
+	legacyTotal      = "total"
+	legacySelf       = "self"
+	legacyCount      = "count"
+	legacyAvg        = "avg"
+	legacyMax        = "max"
+	legacyAvgCount   = "avgCount"
+	legacyMaxCount   = "maxCount"
+	legacyPercentage = "percent"
+)
+
+// isLegacySummary reports whether content is a legacy FramePro text summary
+func isLegacySummary(path string, content []byte) bool {
+	base := strings.ToLower(filepath.Base(path))
+	if strings.HasSuffix(base, ".txt") && strings.Contains(base, "summary") {
+		return true
+	}
+	first, _, _ := bytes.Cut(bytes.TrimLeft(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), " \t\r\n"), []byte("\n"))
+	first = bytes.ToLower(first)
+	return bytes.HasPrefix(first, []byte("framepro")) && bytes.Contains(first, []byte("summary"))
+}
+
+// legacyColumn names what a table header cell holds, and the factor converting its time unit to ms
+func legacyColumn(header string) (string, float64) {
+	h := strings.ToLower(header)
+	scale := 1.0
+	switch {
+	case strings.Contains(h, "(us)") || strings.Contains(h, "(µs)") || strings.Contains(h, "(μs)"):
+		scale = 0.001
+	case strings.Contains(h, "(s)") || strings.Contains(h, "(sec"):
+		scale = 1000
+	}
+	counted := strings.Contains(h, "count") || strings.Contains(h, "calls")
+	perFrame := strings.Contains(h, "frame") || strings.Contains(h, "avg") || strings.Contains(h, "mean") || strings.Contains(h, "max")
+	switch {
+	case strings.Contains(h, "%") || strings.Contains(h, "percent"):
+		return legacyPercentage, 1
+	case strings.Contains(h, "thread"):
+		return legacyThread, 1
+	case strings.Contains(h, "function") || strings.Contains(h, "name") || strings.Contains(h, "scope"):
+		return legacyName, 1
+	case counted && strings.Contains(h, "max"):
+		return legacyMaxCount, 1
+	case counted && perFrame:
+		return legacyAvgCount, 1
+	case counted:
+		return legacyCount, 1
+	case strings.Contains(h, "max"):
+		return legacyMax, scale
+	case strings.Contains(h, "avg") || strings.Contains(h, "mean") || strings.Contains(h, "frame"):
+		return legacyAvg, scale
+	case strings.Contains(h, "self") || strings.Contains(h, "exclusive"):
+		return legacySelf, scale
+	case strings.Contains(h, "total") || strings.Contains(h, "time"):
+		return legacyTotal, scale
+	}
+	return "", 1
+}
+
+// legacyValue parses a number cell, ignoring thousands separators and trailing units
+func legacyValue(cell string) (float64, bool) {
+	match := legacyNumber.FindString(strings.TrimSpace(cell))
+	if match == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", ""), 64)
+	return value, err == nil
+}
+
+// splitLegacyRow splits a table line into cells
+func splitLegacyRow(line string) []string {
+	line = strings.Trim(strings.TrimSpace(line), "|")
+	cells := legacyColumnSplit.Split(strings.TrimSpace(line), -1)
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// decodeLegacySummary converts a legacy FramePro text summary into the data model
+func decodeLegacySummary(path string, content []byte) (*FrameProData, error) {
+	data := &FrameProData{SessionName: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	thread := "Main Thread"
+	threadIDs := make(map[string]int)
+	var columns []string
+	var scales []float64
+	type legacyRow struct {
+		fn                  FrameProFunction
+		hasTotal, hasAvg    bool
+		hasCount, hasAvgCnt bool
+	}
+	rows := []legacyRow{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.Trim(trimmed, "-=|+ ") == "" {
+			continue // Blank lines and table rules
+		}
+
+		cells := splitLegacyRow(line)
+		if len(cells) >= 2 {
+			kinds, units := make([]string, len(cells)), make([]float64, len(cells))
+			hasName, hasTime := false, false
+			for i, cell := range cells {
+				kinds[i], units[i] = legacyColumn(cell)
+				hasName = hasName || kinds[i] == legacyName
+				hasTime = hasTime || kinds[i] == legacyTotal || kinds[i] == legacyAvg || kinds[i] == legacySelf
+			}
+			if hasName && hasTime && !legacyNumber.MatchString(cells[len(cells)-1]) {
+				columns, scales = kinds, units
+				continue
+			}
+		}
+
+		if columns == nil || len(cells) < 2 {
+			if match := legacyHeaderLine.FindStringSubmatch(trimmed); match != nil {
+				key, value := strings.ToLower(strings.TrimSpace(match[1])), strings.TrimSpace(match[2])
+				switch {
+				case strings.Contains(key, "session") || key == "name" || key == "capture":
+					data.SessionName = value
+				case strings.Contains(key, "frame") && !strings.Contains(key, "time") && !strings.Contains(key, "rate"):
+					if frames, ok := legacyValue(value); ok {
+						data.TotalFrames = int(frames)
+					}
+				case key == "thread":
+					thread = value
+				}
+				continue
+			}
+		}
+		if columns == nil {
+			continue
+		}
+
+		row := legacyRow{fn: FrameProFunction{ThreadName: thread}}
+		for i, cell := range cells {
+			if i >= len(columns) {
+				break
+			}
+			value, isNumber := legacyValue(cell)
+			switch columns[i] {
+			case legacyName:
+				row.fn.FunctionName = cell
+			case legacyThread:
+				row.fn.ThreadName = cell
+			case legacyTotal:
+				row.fn.TotalTimeMs, row.hasTotal = value*scales[i], isNumber
+			case legacySelf:
+				row.fn.SelfTimeMs = value * scales[i]
+			case legacyAvg:
+				row.fn.AvgTimePerFrameMs, row.hasAvg = value*scales[i], isNumber
+			case legacyMax:
+				row.fn.MaxTimePerFrameMs = value * scales[i]
+			case legacyCount:
+				row.fn.TotalCount, row.hasCount = int(value), isNumber
+			case legacyAvgCount:
+				row.fn.AvgCountPerFrame, row.hasAvgCnt = value, isNumber
+			case legacyMaxCount:
+				row.fn.MaxCountPerFrame = int(value)
+			}
+		}
+		if row.fn.FunctionName == "" || !row.hasTotal && !row.hasAvg {
+			continue // A note or footer line, not a function
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s has no function table: expected a header row naming Function and a Total or Avg time column", filepath.Base(path))
+	}
+
+	if data.TotalFrames <= 0 {
+		data.TotalFrames = 1
+	}
+	frames := float64(data.TotalFrames)
+	for _, row := range rows {
+		fn := row.fn
+		switch {
+		case row.hasTotal && !row.hasAvg:
+			fn.AvgTimePerFrameMs = fn.TotalTimeMs / frames
+		case row.hasAvg && !row.hasTotal:
+			fn.TotalTimeMs = fn.AvgTimePerFrameMs * frames
+		}
+		switch {
+		case row.hasCount && !row.hasAvgCnt:
+			fn.AvgCountPerFrame = float64(fn.TotalCount) / frames
+		case row.hasAvgCnt && !row.hasCount:
+			fn.TotalCount = int(fn.AvgCountPerFrame*frames + 0.5)
+		}
+		if fn.MaxTimePerFrameMs == 0 {
+			fn.MaxTimePerFrameMs = fn.AvgTimePerFrameMs // Unknown: no spike rather than an infinite one
+		}
+
+		id, exists := threadIDs[fn.ThreadName]
+		if !exists {
+			id = len(threadIDs) + 1
+			threadIDs[fn.ThreadName] = id
+		}
+		fn.ThreadID = id
+		name := strings.ToLower(fn.ThreadName)
+		fn.IsMainThread = strings.Contains(name, "main") || strings.Contains(name, "gamethread") || strings.Contains(name, "game thread")
+		fn.IsRenderThread = !fn.IsMainThread && (strings.Contains(name, "render") || strings.Contains(name, "rhi"))
+		fn.IsWorkerThread = !fn.IsMainThread && !fn.IsRenderThread && isWorkerThreadName(fn.ThreadName)
+		data.Functions = append(data.Functions, fn)
+	}
+	data.TotalFunctions = len(data.Functions)
+	return data, nil
+}
@@ -22,11 +22,13 @@ type FrameProData struct {
 	TotalFunctions  int                   `json:"TotalFunctions,omitempty"`
 	Frames          []FrameProFrame       `json:"Frames,omitempty"`
 	Functions       []FrameProFunction    `json:"Functions,omitempty"`
+	Tree            []FrameProFunction    `json:"-"` // Scope hierarchy of hierarchical captures, whose Functions then list every scope
 }
 
 type FrameProFrame struct {
 	FrameNumber int                  `json:"FrameNumber"`
 	Functions   []FrameProFunction   `json:"Functions,omitempty"`
+	Present     *FramePresent        `json:"Present,omitempty"` // PresentMon timing, when merged in
 }
 
 type FrameProFunction struct {
@@ -37,6 +39,8 @@ type FrameProFunction struct {
 	Count                     int     `json:"Count,omitempty"`           // Count in current frame
 	TotalTimeMs               float64 `json:"TotalTimeMs"`               // Total time across all frames
 	TotalCount                int     `json:"TotalCount"`                // Total count across all frames
+	SelfTimeMs                float64 `json:"SelfTimeMs,omitempty"`      // Total time excluding child scopes
+	InclusiveTimeMs           float64 `json:"InclusiveTimeMs,omitempty"` // Total time including child scopes
 	MaxTimeMs                 float64 `json:"MaxTimeMs,omitempty"`
 	MaxTimePerFrameMs         float64 `json:"MaxTimePerFrameMs"`
 	MaxCountPerFrame          int     `json:"MaxCountPerFrame"`
@@ -47,16 +51,29 @@ type FrameProFunction struct {
 	IsRenderThread            bool    `json:"IsRenderThread"`
 	IsWorkerThread            bool    `json:"IsWorkerThread"`
 	ThreadPriority            int     `json:"ThreadPriority"`
+	Children                  []FrameProFunction `json:"Children,omitempty"` // Nested scopes in hierarchical exports
+	CoreID                    *int    `json:"CoreId,omitempty"`          // CPU core the scope ran on, in per-frame data when recorded
+	ContextSwitches           *int     `json:"ContextSwitches,omitempty"` // Times the thread was switched out during the scope, when recorded
+	PreemptedMs               *float64 `json:"PreemptedMs,omitempty"`     // Time the thread was runnable but not running during the scope
+	PreemptedBy               []string `json:"PreemptedBy,omitempty"`     // Processes or threads that ran in its place
+	GPUQueue                  string   `json:"GpuQueue,omitempty"`        // GPU queue (graphics, compute, copy) for GPU scopes
+	StartMs                   *float64 `json:"StartMs,omitempty"`         // Start within the frame, in per-frame data when recorded
 }
 
 // PerformanceIssue represents a detected performance problem
 type PerformanceIssue struct {
-	Severity    string  `json:"severity"`
-	Category    string  `json:"category"`
-	Description string  `json:"description"`
-	Impact      string  `json:"impact"`
-	Suggestion  string  `json:"suggestion"`
-	Value       float64 `json:"value,omitempty"`
+	Severity    string         `json:"severity"`
+	Category    string         `json:"category"`
+	Description string         `json:"description"`
+	Impact      string         `json:"impact"`
+	Suggestion  string         `json:"suggestion"`
+	Value       float64        `json:"value,omitempty"`
+	RuleID      string         `json:"ruleId,omitempty"`      // Rule that raised it, see export_rule_catalog
+	Thread      string         `json:"thread,omitempty"`      // Thread it was raised on
+	Remapped    string         `json:"remappedFrom,omitempty"` // Severity before the project's severity rules
+	Fingerprint string         `json:"fingerprint,omitempty"` // Stable ID for mark_false_positive
+	Feedback    string         `json:"feedback,omitempty"`
+	Evidence    *IssueEvidence `json:"evidence,omitempty"` // Frames that triggered the issue
 }
 
 var dataDir string
@@ -72,17 +89,112 @@ func main() {
 			dataDir = "."
 		}
 	}
+	setOutputDir(os.Getenv("FRAMEPRO_OUTPUT_DIR"))
+
+	serverOptions := []server.ServerOption{
+		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, true),
+	}
+
+	// Optional append-only audit trail of tool invocations
+	if auditPath := os.Getenv("FRAMEPRO_AUDIT_LOG"); auditPath != "" {
+		auditLog, err := openAuditLog(auditPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(auditLog.middleware))
+	}
+
+	// Optional project additions to the known-issue database
+	if knownIssuesPath := os.Getenv("FRAMEPRO_KNOWN_ISSUES"); knownIssuesPath != "" {
+		if err := loadKnownIssues(knownIssuesPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional project derived metrics
+	if metricsPath := os.Getenv("FRAMEPRO_METRICS"); metricsPath != "" {
+		if err := loadProjectMetrics(metricsPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional project map of functions renamed between captures
+	if renamesPath := os.Getenv("FRAMEPRO_RENAMES"); renamesPath != "" {
+		if err := loadProjectRenames(renamesPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional project declaration of the engine's top-level ticks
+	if ticksPath := os.Getenv("FRAMEPRO_TICKS"); ticksPath != "" {
+		if err := loadProjectTicks(ticksPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional project declaration of the renderer's pipeline stages
+	if stagesPath := os.Getenv("FRAMEPRO_STAGES"); stagesPath != "" {
+		if err := loadProjectPipelineStages(stagesPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional project frame marker, replacing the default marker names when frames are cut
+	if marker := os.Getenv("FRAMEPRO_FRAME_MARKER"); marker != "" {
+		if err := setFrameMarker(marker); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Sampling frequency of folded perf stacks, which don't record it
+	if frequency := os.Getenv("FRAMEPRO_PERF_FREQUENCY"); frequency != "" {
+		if err := setPerfFrequency(frequency); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional project list of disabled rules, checked against the catalog once it is complete
+	if rulesPath := os.Getenv("FRAMEPRO_RULES"); rulesPath != "" {
+		if err := loadRuleConfig(rulesPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Per-tool timeouts, replacing the default
+	if timeouts := os.Getenv("FRAMEPRO_TOOL_TIMEOUT"); timeouts != "" {
+		if err := setToolTimeouts(timeouts); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Captures opened by tool calls are listed as session resources
+	sessionResources := newSessionResources()
+	serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(sessionResources.middleware))
+
+	// Shared output options (detail levels) applied to every tool result
+	serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(outputMiddleware))
+
+	// Results list their data-quality caveats as warnings, at every detail level
+	serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(warningsMiddleware))
+
+	// Results note the rules disabled for the project
+	serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(rulesMiddleware))
+
+	// Scanning tools run under a timeout, innermost so partial results are formatted like any other
+	serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(timeoutMiddleware))
 
 	// Create MCP server
 	s := server.NewMCPServer(
 		"FramePro Performance Analyzer",
 		"1.0.0",
-		server.WithToolCapabilities(true),
+		serverOptions...,
 	)
+	sessionResources.server = s
 
 	// Register tools
 	analyzePerformanceTool := mcp.NewTool("analyze_performance",
-		mcp.WithDescription("Analyzes FramePro JSON data and identifies performance bottlenecks, hotspots, and optimization opportunities"),
+		mcp.WithDescription("Analyzes FramePro JSON data and identifies performance bottlenecks, hotspots, and optimization opportunities. With per-frame data each issue lists the frames that triggered it (evidence), ready for get_frame"),
 		mcp.WithString("file_path",
 			mcp.Required(),
 			mcp.Description("Path to the FramePro JSON file to analyze")),
@@ -97,6 +209,16 @@ func main() {
 			mcp.Description("Path to the FramePro JSON file")),
 		mcp.WithNumber("top_n",
 			mcp.Description("Number of top hotspots to return (default: 10)")),
+		mcp.WithString("rank_by",
+			mcp.Description("Time metric used for ranking: 'total', 'self' (excluding children) or 'inclusive' (default: 'total')")),
+		mcp.WithBoolean("group_by_thread",
+			mcp.Description("Return the top N functions for each thread separately instead of one mixed list (default: false)")),
+		mcp.WithString("thread_filter",
+			mcp.Description("Only include threads whose name matches this wildcard or regular expression, e.g. '*Worker*'")),
+		mcp.WithString("normalize",
+			mcp.Description("Also express each function's cost as a percentage: 'main_thread' of the average frame time, 'own_thread' of its thread's time per frame, or 'none' (default: 'none'). Comparable across captures of different lengths")),
+		mcp.WithBoolean("aggregate_by_function",
+			mcp.Description("Merge entries of the same function on different threads into one, summing times and counts and listing the threads (default: false)")),
 	)
 
 	frameAnalysisTool := mcp.NewTool("analyze_frame_times",
@@ -105,26 +227,811 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path to the FramePro JSON file")),
 		mcp.WithNumber("target_fps",
-			mcp.Description("Target FPS for comparison (default: 60)")),
+			mcp.Description("Target FPS for comparison (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		mcp.WithNumber("refresh_hz",
+			mcp.Description("Display refresh rate used to detect vsync-limited frames (default: 60)")),
 	)
 
 	compareProfilesTool := mcp.NewTool("compare_profiles",
-		mcp.WithDescription("Compares two FramePro profiles to identify performance regressions or improvements"),
+		mcp.WithDescription("Compares two FramePro profiles to identify performance regressions or improvements. With per-frame data it also tests whether the frame-time distribution and pacing changed (Kolmogorov-Smirnov, earth mover's distance), even when averages match"),
 		mcp.WithString("baseline_path",
 			mcp.Required(),
 			mcp.Description("Path to the baseline FramePro JSON file")),
 		mcp.WithString("current_path",
 			mcp.Required(),
 			mcp.Description("Path to the current FramePro JSON file")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target FPS used to express regressions as dropped frames and budget share (default: detected from the baseline's frame times, else 60)")),
+		mcp.WithBoolean("aggregate_by_function",
+			mcp.Description("Compare functions by name, summed over all threads, instead of per thread (default: false)")),
+		metricsSchema(),
+		renamesSchema(),
+	)
+
+	frameTimelineTool := mcp.NewTool("analyze_frame_timeline",
+		mcp.WithDescription("Walks per-frame data to compute per-thread totals for every frame and reports the exact frames where spikes occurred and which functions caused them"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("spike_factor",
+			mcp.Description("A frame is a spike when it exceeds this multiple of the median frame time (default: 2.0)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of culprit functions to report per spike (default: 3)")),
+		mcp.WithBoolean("include_frames",
+			mcp.Description("Include the full per-frame, per-thread timeline in the output (default: false)")),
+	)
+
+	histogramTool := mcp.NewTool("frame_time_histogram",
+		mcp.WithDescription("Buckets frames by frame time into configurable bins and returns counts and percentages per bin"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithArray("bins_ms",
+			mcp.WithNumberItems(),
+			mcp.Description("Bin edges in milliseconds (default: [8.33, 16.67, 33.33] for 120/60/30 fps)")),
+	)
+
+	detectHitchesTool := mcp.NewTool("detect_hitches",
+		mcp.WithDescription("Finds frames exceeding N times the median frame time, lists the top functions in each hitch frame and classifies hitches as isolated spikes or sustained slowdowns"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("threshold_factor",
+			mcp.Description("A frame is a hitch when it exceeds this multiple of the median frame time (default: 2.0)")),
+		mcp.WithNumber("sustained_frames",
+			mcp.Description("Consecutive hitch frames needed to classify a run as a sustained slowdown (default: 3)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of top functions to list per hitch frame (default: 5)")),
+	)
+
+	callTreeTool := mcp.NewTool("get_call_tree",
+		mcp.WithDescription("Returns the call tree below a given function from a hierarchical export, with inclusive and exclusive times per node"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with nested scopes (Children)")),
+		mcp.WithString("function",
+			mcp.Required(),
+			mcp.Description("Name of the root function of the tree")),
+		mcp.WithNumber("thread_id",
+			mcp.Description("Only use roots on this thread ID (default: all threads)")),
+		mcp.WithNumber("max_depth",
+			mcp.Description("Maximum tree depth below the root (default: 10)")),
+	)
+
+	annotateSessionTool := mcp.NewTool("annotate_session",
+		mcp.WithDescription("Records a conclusion against a profiling session (e.g. 'root cause: shader compile; fixed in CL 12345'). Annotations persist and appear in future reports for that session"),
+		mcp.WithString("text",
+			mcp.Required(),
+			mcp.Description("The conclusion to record")),
+		mcp.WithString("session_name",
+			mcp.Description("Session to annotate (as in SessionName); alternatively pass file_path")),
+		mcp.WithString("file_path",
+			mcp.Description("FramePro JSON file whose session should be annotated")),
+		mcp.WithString("author",
+			mcp.Description("Optional author of the annotation")),
+		mcp.WithArray("tags",
+			mcp.WithStringItems(),
+			mcp.Description("Optional tags, e.g. ['root-cause', 'fixed']")),
+	)
+
+	searchHistoryTool := mcp.NewTool("search_history",
+		mcp.WithDescription("Scans every recorded capture in the data directory and returns each session where a function exceeded a cost threshold, with dates, values and session annotations"),
+		mcp.WithString("function",
+			mcp.Required(),
+			mcp.Description("Function name to search for")),
+		mcp.WithBoolean("contains",
+			mcp.Description("Match function names by case-insensitive substring instead of exactly (default: false)")),
+		mcp.WithNumber("threshold_ms",
+			mcp.Description("Only report sessions where the metric exceeds this value in ms (default: 0)")),
+		mcp.WithString("metric",
+			mcp.Description("Metric compared to the threshold: 'avg_per_frame', 'total' or 'max_per_frame' (default: 'avg_per_frame')")),
+		mcp.WithString("directory",
+			mcp.Description("Directory to scan (default: FRAMEPRO_DATA_DIR)")),
+		mcp.WithString("pattern",
+			mcp.Description("Glob pattern of capture files (default: '*.json')")),
+	)
+
+	findFramesWhereTool := mcp.NewTool("find_frames_where",
+		mcp.WithDescription("Returns the frame numbers where a named function exceeded a time threshold, or where the frame time exceeded a budget when no function is given. Results are paginated"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("threshold_ms",
+			mcp.Required(),
+			mcp.Description("Threshold in milliseconds the function time (or frame time) must exceed")),
+		mcp.WithString("function",
+			mcp.Description("Function name, e.g. 'Physics::Step' (default: compare the frame time)")),
+		mcp.WithNumber("thread_id",
+			mcp.Description("Only count the function on this thread ID (default: all threads)")),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of matching frames to skip (default: 0)")),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of frames to return (default: 100)")),
+	)
+
+	getFrameTool := mcp.NewTool("get_frame",
+		mcp.WithDescription("Returns every function recorded in a single frame, grouped by thread and sorted by time, to explain why that frame was slow"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("frame",
+			mcp.Required(),
+			mcp.Description("Frame number to inspect")),
+	)
+
+	similarRegressionsTool := mcp.NewTool("find_similar_regressions",
+		mcp.WithDescription("Looks up past regressions of a function with a similar magnitude by replaying consecutive captures in the data directory, returning the annotations/resolutions recorded for those sessions"),
+		mcp.WithString("function",
+			mcp.Required(),
+			mcp.Description("Function that regressed")),
+		mcp.WithNumber("percent_change",
+			mcp.Description("Regression magnitude as percent change of total time (as reported by compare_profiles)")),
+		mcp.WithNumber("avg_time_diff_ms",
+			mcp.Description("Regression magnitude as change of average time per frame in ms")),
+		mcp.WithNumber("tolerance",
+			mcp.Description("Accepted relative difference in magnitude, 0.5 = within 50% (default: 0.5)")),
+		mcp.WithBoolean("contains",
+			mcp.Description("Match function names by case-insensitive substring (default: false)")),
+		mcp.WithString("directory",
+			mcp.Description("Directory of historical captures (default: FRAMEPRO_DATA_DIR)")),
+		mcp.WithString("pattern",
+			mcp.Description("Glob pattern of capture files (default: '*.json')")),
+	)
+
+	convertMetricsTool := mcp.NewTool("convert_metrics",
+		mcp.WithDescription("Converts between frame-rate metrics (ms <-> FPS, per-frame <-> per-second totals, share of a frame budget) so results can be discussed without mental arithmetic. Every conversion possible from the given inputs is returned"),
+		mcp.WithNumber("fps",
+			mcp.Description("Frame rate, e.g. 60")),
+		mcp.WithNumber("frame_time_ms",
+			mcp.Description("Frame time in milliseconds, e.g. 16.67")),
+		mcp.WithNumber("value_ms",
+			mcp.Description("A per-frame cost in milliseconds to express against fps / frame_time_ms")),
+		mcp.WithNumber("total_ms",
+			mcp.Description("A total time in milliseconds to spread over frame_count or duration_seconds")),
+		mcp.WithNumber("frame_count",
+			mcp.Description("Number of frames")),
+		mcp.WithNumber("duration_seconds",
+			mcp.Description("Capture duration in seconds")),
+	)
+
+	searchFunctionsTool := mcp.NewTool("search_functions",
+		mcp.WithDescription("Searches a profile for functions matching a wildcard ('*Physics*') or regular expression ('^UWorld::') and returns aggregates per match, sorted by total time"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the FramePro JSON file")),
+		mcp.WithString("pattern",
+			mcp.Required(),
+			mcp.Description("Wildcard pattern using * and ?, or a regular expression")),
+		mcp.WithBoolean("case_sensitive",
+			mcp.Description("Match case-sensitively (default: false)")),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of matches to return (default: 50)")),
+	)
+
+	threadUtilizationTool := mcp.NewTool("analyze_thread_utilization",
+		mcp.WithDescription("Computes busy, waiting and idle time per thread per frame, flags threads that mostly wait (Wait/Sleep scopes) and recommends work that could move to underutilized workers"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to FramePro JSON file; per-frame data (*_frame_analysis.json) gives the most accurate results")),
+		mcp.WithNumber("saturated_percent",
+			mcp.Description("Busy percentage above which a main or render thread is considered saturated and its work a migration candidate (default: 80)")),
 	)
 
-	s.AddTool(analyzePerformanceTool, analyzePerformanceHandler)
-	s.AddTool(findHotspotsTool, findHotspotsHandler)
-	s.AddTool(frameAnalysisTool, frameAnalysisHandler)
-	s.AddTool(compareProfilesTool, compareProfilesHandler)
+	utilizationTimelineTool := mcp.NewTool("thread_utilization_timeline",
+		mcp.WithDescription("Reconstructs each thread's busy percentage over time from per-frame data, in buckets of frames, and reports the largest shifts (e.g. the render thread going from 60% to 100% at a given frame)"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("bucket_frames",
+			mcp.Description("Number of frames per bucket (default: about 20 buckets over the capture)")),
+		mcp.WithString("thread_filter",
+			mcp.Description("Only include threads whose name matches this wildcard or regular expression, e.g. 'Render*'")),
+		mcp.WithNumber("min_shift_percent",
+			mcp.Description("Smallest change in busy percentage between buckets reported as a shift (default: 10)")),
+	)
 
-	// Note: Resources disabled to avoid null array error
-	// Tools provide all necessary functionality
+	criticalPathTool := mcp.NewTool("analyze_critical_path",
+		mcp.WithDescription("Models each frame as a pipeline of main, render and worker threads running in parallel and reports which stage (and thread) limited each frame and the capture overall. Time in Wait/Sleep scopes is not on the critical path. To follow the scopes inside the main thread instead, use main_thread_path"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to FramePro JSON file; per-frame data (*_frame_analysis.json) gives a per-frame breakdown")),
+		mcp.WithBoolean("include_frames",
+			mcp.Description("Include the critical path of every frame in the output (default: false)")),
+	)
+
+	contentionTool := mcp.NewTool("analyze_contention",
+		mcp.WithDescription("Aggregates lock, mutex and wait scopes, correlates them across threads by frame and reports the estimated blocked time per lock site, including frames where several threads hit the same site"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to FramePro JSON file; per-frame data (*_frame_analysis.json) is needed for cross-thread correlation")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of sites to return (default: 10)")),
+		mcp.WithBoolean("include_waits",
+			mcp.Description("Include Wait/Sleep scopes as well as locks (default: true)")),
+	)
+
+	pipelineBubblesTool := mcp.NewTool("detect_pipeline_bubbles",
+		mcp.WithDescription("Uses the pipeline model to find frames where the render thread sat idle waiting for the main thread (or vice versa) longer than a threshold, totals the stall time and lists the scopes the other thread was busy in during those stalls"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("threshold_ms",
+			mcp.Description("Minimum idle time of a stage in a frame to count as a bubble (default: 2.0)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of worst bubbles to list (default: 10)")),
+	)
+
+	framePacingTool := mcp.NewTool("analyze_frame_pacing",
+		mcp.WithDescription("Analyzes how evenly frames are delivered: frame-to-frame delta variance and standard deviation, runs of consecutive jittery frames and a 0-100 pacing score, so a steady 40fps can be told apart from a jittery 55fps"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("jitter_threshold_ms",
+			mcp.Description("Frame-to-frame change in milliseconds that counts as a jittery frame (default: 4.0)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of worst jittery runs to list (default: 5)")),
+	)
+
+	markFalsePositiveTool := mcp.NewTool("mark_false_positive",
+		mcp.WithDescription("Records that an analyze_performance issue is a false positive for this project. Future analyses downgrade or suppress the issue, matched by its fingerprint"),
+		mcp.WithString("fingerprint",
+			mcp.Required(),
+			mcp.Description("Fingerprint of the issue as shown in analyze_performance output")),
+		mcp.WithString("action",
+			mcp.Enum("downgrade", "suppress", "remove"),
+			mcp.Description("'downgrade' lowers the severity one level, 'suppress' hides the issue, 'remove' deletes the feedback (default: 'downgrade')")),
+		mcp.WithString("file_path",
+			mcp.Description("Capture the issue was found in; records the issue's category and description for the feedback report")),
+		mcp.WithString("reason",
+			mcp.Description("Why the issue is a false positive")),
+		mcp.WithString("author",
+			mcp.Description("Who gave the feedback")),
+	)
+
+	feedbackReportTool := mcp.NewTool("false_positive_report",
+		mcp.WithDescription("Reports accumulated false-positive feedback by issue category, with the built-in rule behind each category and which thresholds may need raising for this project"),
+	)
+
+	sessionPhasesTool := mcp.NewTool("detect_session_phases",
+		mcp.WithDescription("Splits a capture spanning loading, menus and gameplay into phases by change-point detection on frame times and active functions, and reports frame-time statistics and top functions per phase"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("min_phase_frames",
+			mcp.Description("Shortest phase in frames, also the window compared either side of a boundary (default: 30)")),
+		mcp.WithNumber("time_shift_percent",
+			mcp.Description("Median frame time change that starts a new phase (default: 30)")),
+		mcp.WithNumber("function_change_percent",
+			mcp.Description("Change in the set of active functions that starts a new phase (default: 40)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of top functions listed per phase (default: 5)")),
+	)
+
+	compareToIdealTool := mcp.NewTool("compare_to_ideal",
+		mcp.WithDescription("Diffs a capture against an ideal profile built from budgets (every subsystem at its budget, no spikes, frames at the target frame time) and reports the gap per subsystem, e.g. 'Rendering needs -2.10ms, Physics is fine'"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the FramePro JSON file to analyze")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target FPS the ideal frame time and default budgets are derived from (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		budgetsSchema(),
+	)
+
+	sessionTrendsTool := mcp.NewTool("analyze_trends_within_session",
+		mcp.WithDescription("Computes moving averages of frame time and per-function time over windows of N frames and reports functions whose cost grows steadily over the session (memory growth, entity-count creep) versus stable or erratic ones"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("window_frames",
+			mcp.Description("Frames per moving-average window; windows slide by half a window (default: 60)")),
+		mcp.WithNumber("min_growth_percent",
+			mcp.Description("Change from the first to the last window, as percent of the average, that counts as a trend (default: 20)")),
+		mcp.WithNumber("min_avg_ms",
+			mcp.Description("Ignore functions averaging less than this per frame (default: 0.05)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of functions listed per trend class (default: 10)")),
+		metricsSchema(),
+	)
+
+	correlateSpikesTool := mcp.NewTool("correlate_spikes",
+		mcp.WithDescription("Correlates every function's per-frame cost with frame-time spikes and groups functions that spike in the same hitches, e.g. 'GC::Collect and Streaming::Update spike together and are responsible for 87% of hitch time'"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("threshold_factor",
+			mcp.Description("A frame is a hitch when it exceeds this multiple of the median frame time (default: 2.0)")),
+		mcp.WithNumber("min_correlation",
+			mcp.Description("Also list functions whose correlation with the spike signal reaches this, even if they explain no hitch (default: 0.5)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of functions to list (default: 10)")),
+	)
+
+	projectBudgetsTool := mcp.NewTool("project_budget_breach",
+		mcp.WithDescription("Projects when a steadily growing subsystem or function will exceed its budget at the current rate, from the captures in a directory dated by modification time (e.g. 'AI will blow its 2ms budget in ~3 weeks')"),
+		mcp.WithString("directory",
+			mcp.Description("Directory to scan (default: the server's data directory)")),
+		mcp.WithString("pattern",
+			mcp.Description("Glob pattern for capture files (default: '*.json')")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target FPS the default budgets are scaled to (default: 60)")),
+		budgetsSchema(),
+		mcp.WithString("function",
+			mcp.Description("Project a single function (exact name, all threads) instead of the budgets")),
+		mcp.WithNumber("budget_ms",
+			mcp.Description("Average time per frame budget for function")),
+	)
+
+	scoreProfileTool := mcp.NewTool("score_profile",
+		mcp.WithDescription("Rolls frame rate (average and 1% low FPS against the target), frame pacing, hotspot concentration and thread balance into a single weighted 0-100 score with sub-scores and a letter grade, for tracking one number build over build"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the FramePro JSON file to score")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target FPS the frame rate sub-score is measured against (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		mcp.WithObject("weights",
+			mcp.Description("Relative weights of the sub-scores fps, pacing, hotspots and balance (default: 0.3, 0.3, 0.2, 0.2)")),
+	)
+
+	capabilitiesTool := mcp.NewTool("get_capabilities",
+		mcp.WithDescription("Reports what this deployment supports: input formats, registered tools and which accept frame windows, the known-issue database by engine, analysis thresholds and default budgets, and optional subsystems (capture history, annotations, audit log, session resources, live capture). Call it first to adapt the workflow"),
+	)
+
+	checkBudgetsTool := mcp.NewTool("check_budgets",
+		mcp.WithDescription("Checks every frame against per-subsystem budgets (e.g. Physics 3ms, AI 2ms, Rendering 6ms, by function name pattern) and reports actual vs. budget per subsystem, the frames that blew each budget and the functions responsible"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file, ideally with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target FPS the default budgets are scaled to (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		budgetsSchema(),
+		mcp.WithNumber("top_n",
+			mcp.Description("Worst frames and culprit functions listed per budget (default: 5)")),
+		metricsSchema(),
+	)
+
+	subsystemBreakdownTool := mcp.NewTool("subsystem_breakdown",
+		mcp.WithDescription("Rolls functions up into subsystems (Physics, Animation, Rendering, ...) by configurable name rules and reports time per frame per subsystem and per thread, with each subsystem's most expensive functions"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file")),
+		subsystemsSchema(),
+		mcp.WithBoolean("group_unmatched_by_namespace",
+			mcp.Description("Group functions matching no rule by their namespace (the part before '::') instead of 'Other' (default: true)")),
+		mcp.WithString("rank_by",
+			mcp.Description("Time metric rolled up: 'total' (default), 'self' (exclusive, avoids counting nested scopes twice) or 'inclusive'")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Functions listed per subsystem (default: 3)")),
+	)
+
+	frameTriptychTool := mcp.NewTool("frame_triptych",
+		mcp.WithDescription("Puts the best, median and worst frames of a capture side by side: per-thread totals and each function's time in all three, ranked by how much more it costs in the worst frame than in the best"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of functions to compare across the three frames (default: 10)")),
+	)
+
+	budgetWaterfallTool := mcp.NewTool("budget_waterfall",
+		mcp.WithDescription("Lays one frame's work against the frame budget as a waterfall: functions or subsystems of the main thread (or a chosen thread), most expensive first, with the cumulative cost after each and the item that pushed the frame over budget"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("frame",
+			mcp.Description("Frame number to break down (default: the worst frame)")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target FPS that sets the frame budget (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		mcp.WithNumber("budget_ms",
+			mcp.Description("Frame budget in milliseconds; overrides target_fps")),
+		mcp.WithString("thread",
+			mcp.Description("Wildcard or regular expression for the thread laid against the budget, e.g. '*Render*' (default: the main thread)")),
+		mcp.WithString("group_by",
+			mcp.Description("Waterfall items: 'function' (default) or 'subsystem'")),
+		subsystemsSchema(),
+	)
+
+	detectCallAnomaliesTool := mcp.NewTool("detect_call_anomalies",
+		mcp.WithDescription("Flags functions called abnormally often per frame compared with similar functions (same namespace or thread), or whose call count varies wildly across frames: per-entity loops that should be batched"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file; per-frame data (*_frame_analysis.json) adds the variation across frames")),
+		mcp.WithNumber("peer_factor",
+			mcp.Description("Flag a function called at least this many times the median of its peers (default: 10)")),
+		mcp.WithNumber("variation_threshold",
+			mcp.Description("Flag a function whose calls per frame have at least this coefficient of variation, standard deviation over mean (default: 0.5)")),
+		mcp.WithNumber("min_count_per_frame",
+			mcp.Description("Ignore functions called fewer times per frame on average (default: 10)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of anomalies to return, most expensive first (default: 10)")),
+	)
+
+	mainThreadPathTool := mcp.NewTool("main_thread_path",
+		mcp.WithDescription("Reconstructs the longest chain of main-thread work in the worst frames as an ordered path with times: the most expensive scope at every level of hierarchical exports, or the main thread's scopes by cost with its waits attributed to the limiting stage in flat exports. Unlike analyze_critical_path, which names the pipeline stage that limited each frame, this follows the scopes inside the main thread"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame (*_frame_analysis.json) or hierarchical data")),
+		mcp.WithNumber("worst_frames",
+			mcp.Description("Number of worst frames to reconstruct the path for (default: 3)")),
+	)
+
+	analyzeExperimentTool := mcp.NewTool("analyze_experiment",
+		mcp.WithDescription("Evaluates an optimization experiment run across many sessions: labeled groups of captures (control first, then variants), or groups formed from a session-name field (group_by), per-metric group statistics, and each variant's effect against the control with a 95% confidence interval and Cohen's d"),
+		mcp.WithArray("groups",
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"label":   map[string]any{"type": "string"},
+					"files":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"pattern": map[string]any{"type": "string"},
+				},
+			}),
+			mcp.Description("Groups of captures, the control first, e.g. [{\"label\": \"A\", \"pattern\": \"forest_a_*.json\"}, {\"label\": \"B\", \"files\": [\"b1.json\", \"b2.json\"]}]. Patterns are globs in the data directory")),
+		mcp.WithString("group_by",
+			mcp.Description("Instead of groups: a session_pattern field whose values form the groups, e.g. 'build' with session_pattern '{level}_{build}_{run}'")),
+		mcp.WithArray("group_values",
+			mcp.WithStringItems(),
+			mcp.Description("With group_by: the values to compare, control first, e.g. [\"1042\", \"1043\"] (default: every value, in ascending order)")),
+		mcp.WithString("pattern",
+			mcp.Description("With group_by: glob pattern of capture files in the data directory (default: '*.json')")),
+		mcp.WithArray("functions",
+			mcp.WithStringItems(),
+			mcp.Description("Function names whose time per frame is compared as additional metrics, summed over threads")),
+	)
+
+	listCapturesTool := mcp.NewTool("list_captures",
+		mcp.WithDescription("Lists the captures in the data directory with their session-name fields (session_pattern, e.g. '{level}_{build}_{run}'), optionally filtered (where) and grouped by one field with the average frame time per group"),
+		mcp.WithString("directory",
+			mcp.Description("Directory to scan (default: FRAMEPRO_DATA_DIR)")),
+		mcp.WithString("pattern",
+			mcp.Description("Glob pattern of capture files (default: '*.json')")),
+		mcp.WithString("group_by",
+			mcp.Description("session_pattern field to group the captures by, e.g. 'build'")),
+	)
+
+	waitChainsTool := mcp.NewTool("analyze_wait_chains",
+		mcp.WithDescription("Finds frames where main-thread Wait/Join scopes coincide with a saturated render or worker thread and reports the likely blocking thread and the function it was busy in, confirmed by how wait time follows that thread's busy time across frames"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("saturation_percent",
+			mcp.Description("Busy share of the frame time at which a thread counts as saturated (default: 90)")),
+		mcp.WithNumber("min_wait_ms",
+			mcp.Description("Minimum main-thread wait time in a frame to analyze it (default: 0.5)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of worst waits to list (default: 10)")),
+	)
+
+	renderThreadTool := mcp.NewTool("analyze_render_thread",
+		mcp.WithDescription("Focuses on the render thread: breaks its time down into draw, submit, state-change, present and wait scopes, lists state-change-heavy functions and checks each frame whether render work fits the budget and finishes before the main thread's work"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target frame rate defining the budget (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		mcp.WithNumber("budget_ms",
+			mcp.Description("Render-thread budget per frame in ms; overrides target_fps")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of worst frames and state-change functions to list (default: 10)")),
+	)
+
+	workerBalanceTool := mcp.NewTool("analyze_worker_balance",
+		mcp.WithDescription("Groups the worker threads and reports how evenly the pool shares the work: busy time and share per worker, how many times more the busiest worker does than the least busy, per-frame imbalance, jobs pinned to one worker and job scheduling suggestions"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file (per-frame data also gives the imbalance within frames)")),
+		mcp.WithNumber("imbalance_ratio",
+			mcp.Description("Busiest over least busy worker ratio at which the pool counts as imbalanced (default: 2.0)")),
+	)
+
+	coreAffinityTool := mcp.NewTool("analyze_core_affinity",
+		mcp.WithDescription("Uses the CPU core recorded on per-frame scopes (CoreId) to find core migrations and affinity problems: the main thread bouncing across cores, with frame times when it migrates, threads such as workers pinned to the same core, and heavy threads sharing SMT siblings of one physical core in over-budget frames"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data whose scopes carry CoreId")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target frame rate for over-budget frames (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		mcp.WithNumber("budget_ms",
+			mcp.Description("Frame budget in milliseconds; overrides target_fps")),
+		mcp.WithNumber("smt_width",
+			mcp.Description("Logical cores per physical core (default: 2; 1 disables the SMT check)")),
+		mcp.WithString("smt_layout",
+			mcp.Description("How SMT siblings are numbered: adjacent (0-1, 2-3; consoles, Windows) or split (0 and N/2; Linux) (default: adjacent)")),
+	)
+
+	preemptionTool := mcp.NewTool("analyze_preemption",
+		mcp.WithDescription("Uses context-switch data recorded on per-frame scopes (ContextSwitches, PreemptedMs, PreemptedBy) to find threads that lose much more time to preemption in spike frames than in normal frames, and what preempted them, such as background processes on the capture machine"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data whose scopes carry ContextSwitches or PreemptedMs")),
+		mcp.WithNumber("threshold_factor",
+			mcp.Description("A frame is a spike if its time exceeds the median frame time times this factor (default: 2.0)")),
+		mcp.WithNumber("min_preempted_ms",
+			mcp.Description("Preempted time per frame at which a thread counts as heavily preempted (default: 1)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of worst preempted spike frames to list (default: 10)")),
+	)
+
+	exportSpeedscopeTool := mcp.NewTool("export_speedscope",
+		mcp.WithDescription("Converts a profile into a speedscope JSON file (one track per thread plus a Frames track) to browse interactively at https://www.speedscope.app. FramePro exports have no start times, so each thread's scopes are laid out back to back from the start of their frame"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the profile to convert")),
+		mcp.WithString("output_path",
+			mcp.Description("Where to write the speedscope file, relative to the output directory (default: <input>-<timestamp>.speedscope.json there)")),
+	)
+
+	detectContaminationTool := mcp.NewTool("detect_contamination",
+		mcp.WithDescription("Separates capture-machine contamination from in-game problems: labels spike frames in which most active threads did more work at the same time (a system-wide stall) or grew by the same time (a uniform gap from the process being paused), so they can be excluded with exclude_contaminated instead of counting as regressions"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("threshold_factor",
+			mcp.Description("A frame is a spike if its time exceeds the median frame time times this factor (default: 2.0)")),
+	)
+
+	gpuQueuesTool := mcp.NewTool("analyze_gpu_queues",
+		mcp.WithDescription("Breaks GPU work down per queue (graphics, compute, copy) from scopes tagged with GpuQueue or on GPU queue tracks: busy time and utilization per queue, top passes, and, when scopes carry start times, how much async compute and copy work overlaps graphics work, flagging queues serialized with graphics instead of hidden behind it"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a capture with per-frame GPU queue scopes, e.g. a Chrome trace with GPU queue tracks")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of top passes to list per queue (default: 5)")),
+	)
+
+	presentationTool := mcp.NewTool("analyze_presentation",
+		mcp.WithDescription("Analyzes present/flip timing (PresentMon columns merged into frames under Present): display change intervals, render completion to display latency, dropped presents, late flips, display hitches the CPU frame time doesn't explain, and tearing risk from presents without vsync. Separates display problems from CPU problems, which frame times alone cannot"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file whose frames carry Present timing")),
+		mcp.WithNumber("refresh_hz",
+			mcp.Description("Display refresh rate (default: estimated from the shortest common display change interval)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of worst frames to list (default: 10)")),
+	)
+
+	tickDecompositionTool := mcp.NewTool("tick_decomposition",
+		mcp.WithDescription("Decomposes every frame by the engine's declared top-level ticks (e.g. World::Tick, RenderFrame, AudioUpdate) instead of ad-hoc hotspots, so the same rows come back for every capture: per tick average, p95, max and share of the frame, the main thread time outside all ticks, the tick that grows in the slowest frames, and a per-frame table"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		ticksSchema(),
+	)
+
+	derivedMetricsTool := mcp.NewTool("derived_metrics",
+		mcp.WithDescription("Evaluates derived per-frame metrics defined as expressions over existing values (e.g. gameplay_ms = sum(category:Gameplay), sim_to_render = main_ms / render_ms) and reports their average, median, p95, max and budget breaches. The same metrics flow into check_budgets, analyze_trends_within_session and compare_profiles"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the FramePro JSON file to analyze")),
+		metricsSchema(),
+	)
+
+	ruleCatalogTool := mcp.NewTool("export_rule_catalog",
+		mcp.WithDescription("Lists every detection rule with its stable ID, source, default thresholds, severity and description: analyze_performance rules (their findings carry the ID as ruleId), built-in and FRAMEPRO_KNOWN_ISSUES known issues, default subsystem budgets, FRAMEPRO_METRICS metrics with budgets and detector thresholds. Use the IDs in suppression, severity and budget files"),
+		mcp.WithString("source",
+			mcp.Description("Only rules from this source: analyze_performance, known-issue, budget, derived-metric or detector (default: all)")),
+		mcp.WithString("output_path",
+			mcp.Description("Also write the catalog as a JSON array to this path, relative to the output directory")),
+	)
+
+	exportFlamegraphTool := mcp.NewTool("export_flamegraph",
+		mcp.WithDescription("Writes the profile as Brendan Gregg folded stacks (thread;root;...;leaf self-microseconds per line) for flamegraph.pl, inferno or speedscope, and optionally a flame graph SVG. Per-frame data is summed over the frames; aggregate exports use the capture totals"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the profile to export")),
+		mcp.WithString("output_path",
+			mcp.Description("Where to write the folded stacks, relative to the output directory (default: <input>-<timestamp>.folded there)")),
+		mcp.WithBoolean("svg",
+			mcp.Description("Also draw the flame graph as an SVG next to the folded stacks (default: false)")),
+	)
+
+	exportHTMLViewerTool := mcp.NewTool("export_html_viewer",
+		mcp.WithDescription("Writes the capture as one self-contained HTML file with an embedded viewer: frame timeline with the 60/30 FPS budgets, thread lanes shaded by busy time, the top scopes of a clicked frame, and a sortable, filterable function table. Opens in any browser with no tools installed and no network access"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the profile to export")),
+		mcp.WithString("output_path",
+			mcp.Description("Where to write the HTML file, relative to the output directory (default: <input>-<timestamp>.html there)")),
+	)
+
+	renderChartTool := mcp.NewTool("render_chart",
+		mcp.WithDescription("Renders a chart of a capture to PNG and returns it as image content, so it shows inline: 'frame_times' (frame time per frame with the 60/30 FPS budgets), 'hotspots' (functions with the most time per frame as bars) or 'histogram' (frame times in fixed-width bins colored by budget). The numbers behind the chart come back as text. format 'text' draws the chart in Unicode block characters instead (a sparkline for frame_times, bars for hotspots and histogram) for clients that can't show images"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the profile to chart")),
+		mcp.WithString("chart",
+			mcp.Required(),
+			mcp.Enum("frame_times", "hotspots", "histogram"),
+			mcp.Description("Chart to render")),
+		mcp.WithString("format",
+			mcp.Enum("png", "text"),
+			mcp.Description("'png' image (default) or 'text' chart returned as a second text block")),
+		mcp.WithNumber("top_n",
+			mcp.Description("hotspots: number of functions to show (default: 10)")),
+		mcp.WithNumber("bin_ms",
+			mcp.Description("histogram: bin width in milliseconds (default: 1)")),
+		mcp.WithNumber("width",
+			mcp.Description("Image width in pixels (default: 960), or in characters for text charts (20-400, default: 60)")),
+		mcp.WithNumber("height",
+			mcp.Description("Image height in pixels (default: 480)")),
+	)
+
+	renderMermaidTool := mcp.NewTool("render_mermaid",
+		mcp.WithDescription("Returns a Mermaid diagram, which many clients render natively: 'gantt' draws the thread timelines of a few frames (the slowest frame and its neighbours, or the start of frame_range) with each thread's most expensive scopes; 'flowchart' draws the call hierarchy below a function from a hierarchical export"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the profile to draw")),
+		mcp.WithString("diagram",
+			mcp.Required(),
+			mcp.Enum("gantt", "flowchart"),
+			mcp.Description("Diagram to draw")),
+		mcp.WithNumber("max_frames",
+			mcp.Description("gantt: number of frames to draw, up to 20 (default: 3)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("gantt: scopes drawn per thread and frame (default: 5)")),
+		mcp.WithString("function",
+			mcp.Description("flowchart: root function (default: the top-level scope with the most inclusive time)")),
+		mcp.WithNumber("thread_id",
+			mcp.Description("flowchart: only use roots on this thread ID (default: all threads)")),
+		mcp.WithNumber("max_depth",
+			mcp.Description("flowchart: levels below the root (default: 4)")),
+		mcp.WithNumber("min_percent",
+			mcp.Description("flowchart: fold calls under this percentage of the root into a 'more' node (default: 1)")),
+	)
+
+	pipelineStagesTool := mcp.NewTool("analyze_pipeline_stages",
+		mcp.WithDescription("Splits every frame by pipeline stage (simulate, prepare, submit, present, or stages declared by the project) instead of by main/render thread, and measures how much the stages overlap: exactly from scope start times when recorded, otherwise as a lower bound. Lists the threads where two stages serialize"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithArray("stages",
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":    map[string]any{"type": "string"},
+					"pattern": map[string]any{"type": "string"},
+				},
+				"required": []string{"name", "pattern"},
+			}),
+			mcp.Description("Pipeline stages in report order, e.g. [{\"name\": \"simulate\", \"pattern\": \"World::Tick\"}, {\"name\": \"submit\", \"pattern\": \"RHI*\"}]. A scope counts for the first stage it matches. Patterns use * and ? wildcards or a regular expression, case-insensitive (default: the FRAMEPRO_STAGES project file, else built-in simulate/prepare/submit/present patterns)")),
+	)
+
+	statsPerSecondTool := mcp.NewTool("stats_per_second",
+		mcp.WithDescription("Aggregates a capture per wall-clock second (or interval_seconds), measured by frame times: average, min and max frame time, FPS, spike count and the category with the most time in each interval, so long captures can be discussed and charted at a readable resolution"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to a FramePro JSON file with per-frame data (*_frame_analysis.json)")),
+		mcp.WithNumber("interval_seconds",
+			mcp.Description("Length of each interval in seconds (default: 1)")),
+		mcp.WithNumber("spike_factor",
+			mcp.Description("A frame is a spike when it exceeds this multiple of the capture's median frame time (default: 2.0)")),
+		subsystemsSchema(),
+	)
+
+	exportOTelTool := mcp.NewTool("export_otel",
+		mcp.WithDescription("Converts a capture into OpenTelemetry traces (OTLP/JSON) so observability stacks such as Jaeger, Tempo or Honeycomb can show it: one trace per frame with a span per thread and its scopes as child spans. Writes them to a file and/or POSTs them to an OTLP/HTTP endpoint"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the profile to export")),
+		mcp.WithString("output_path",
+			mcp.Description("File to write the OTLP/JSON traces to, relative to the output directory (default: <session>-<timestamp>.otlp.json in the output directory when no endpoint is given)")),
+		mcp.WithString("endpoint",
+			mcp.Description("OTLP/HTTP endpoint to send the traces to, e.g. http://localhost:4318 (/v1/traces is added when the URL has no path)")),
+		mcp.WithObject("headers",
+			mcp.Description("HTTP headers for the endpoint, e.g. {\"x-honeycomb-team\": \"<key>\"}")),
+		mcp.WithString("service_name",
+			mcp.Description("service.name of the exported resource (default: framepro)")),
+		mcp.WithString("start_time",
+			mcp.Description("RFC 3339 time the capture's first frame starts at (default: so the last frame ends at the time of the export)")),
+	)
+
+	exportMetricsTool := mcp.NewTool("export_metrics",
+		mcp.WithDescription("Summarizes a capture into dashboard metrics (frame time percentiles, FPS, the score_profile score and sub-scores, time per frame of each subsystem) in Prometheus or InfluxDB format, so perf dashboards can track profiles over time. Writes them to a file and/or pushes them to a Prometheus Pushgateway or InfluxDB"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the profile to summarize")),
+		mcp.WithString("format",
+			mcp.Description("'prometheus' (text exposition format for a Pushgateway, default) or 'influx' (line protocol)"),
+			mcp.Enum("prometheus", "influx")),
+		mcp.WithString("output_path",
+			mcp.Description("File to write the metrics to, relative to the output directory (default: <session>-<timestamp>.prom or .influx in the output directory when no endpoint is given)")),
+		mcp.WithString("endpoint",
+			mcp.Description("Where to send the metrics: the Pushgateway URL, e.g. http://localhost:9091 (the job and labels are added as the grouping key), or the InfluxDB write URL, e.g. http://localhost:8086/api/v2/write?org=studio&bucket=perf")),
+		mcp.WithObject("headers",
+			mcp.Description("HTTP headers for the endpoint, e.g. {\"Authorization\": \"Token <token>\"} for InfluxDB 2")),
+		mcp.WithString("job",
+			mcp.Description("Pushgateway job name, and the InfluxDB measurement name (default: framepro)")),
+		mcp.WithObject("labels",
+			mcp.Description("Labels for the series, e.g. {\"build\": \"1234\", \"platform\": \"ps5\"}: the Pushgateway grouping key, or InfluxDB tags next to the session name")),
+		mcp.WithString("timestamp",
+			mcp.Description("RFC 3339 time of the InfluxDB points (default: now); the Pushgateway always uses the push time")),
+		mcp.WithNumber("target_fps",
+			mcp.Description("Target FPS of the score (default: the rate the capture's frame times cluster at, 120, 60 or 30, else 60)")),
+		subsystemsSchema(),
+	)
+
+	analyzeTrendTool := mcp.NewTool("analyze_trend",
+		mcp.WithDescription("Follows frame time statistics and every function's time per frame through captures of successive builds and flags monotonic regressions: costs that rose build after build, including creeping ones where no single build-to-build diff reached compare_profiles' 10% threshold, with the build where the creep became detectable"),
+		mcp.WithArray("files",
+			mcp.WithStringItems(),
+			mcp.Description("Captures of the builds, oldest first")),
+		mcp.WithString("pattern",
+			mcp.Description("Glob matching the builds' captures in the data directory, e.g. 'nightly_*.json', instead of files")),
+		mcp.WithString("order_by",
+			mcp.Description("Build order of pattern matches: 'modified' (file modification time, default) or 'name' (numbers in names by value, so build_9 precedes build_10)"),
+			mcp.Enum("modified", "name")),
+		mcp.WithNumber("min_growth_percent",
+			mcp.Description("Growth along a run of worsening builds that counts as a regression (default: 10)")),
+		mcp.WithNumber("tolerance_percent",
+			mcp.Description("Step in the better direction still counted as noise within a run (default: 2)")),
+		mcp.WithNumber("min_ms",
+			mcp.Description("Functions under this time per frame in the last build are not trended (default: 0.1)")),
+		mcp.WithNumber("top_n",
+			mcp.Description("Number of non-stable function trends listed, creeping first (default: 20)")),
+		renamesSchema(),
+	)
+
+	s.AddTool(withOutputOptions(withFrameWindow(analyzePerformanceTool)), analyzePerformanceHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(findHotspotsTool)), findHotspotsHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(frameAnalysisTool)), frameAnalysisHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(compareProfilesTool)), compareProfilesHandler)
+	s.AddTool(withOutputOptions(withTimeout(withFrameWindow(frameTimelineTool))), frameTimelineHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(histogramTool)), frameTimeHistogramHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(detectHitchesTool)), detectHitchesHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(callTreeTool)), callTreeHandler)
+	s.AddTool(withOutputOptions(annotateSessionTool), annotateSessionHandler)
+	s.AddTool(withOutputOptions(withTimeout(withSessionFilter(searchHistoryTool))), searchHistoryHandler)
+	s.AddTool(withOutputOptions(withTimeout(withFrameWindow(findFramesWhereTool))), findFramesWhereHandler)
+	s.AddTool(withOutputOptions(getFrameTool), getFrameHandler)
+	s.AddTool(withOutputOptions(withTimeout(withSessionFilter(similarRegressionsTool))), similarRegressionsHandler)
+	s.AddTool(withOutputOptions(convertMetricsTool), convertMetricsHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(searchFunctionsTool)), searchFunctionsHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(threadUtilizationTool)), threadUtilizationHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(utilizationTimelineTool)), utilizationTimelineHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(criticalPathTool)), criticalPathHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(contentionTool)), contentionHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(pipelineBubblesTool)), pipelineBubblesHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(framePacingTool)), framePacingHandler)
+	s.AddTool(withOutputOptions(markFalsePositiveTool), markFalsePositiveHandler)
+	s.AddTool(withOutputOptions(feedbackReportTool), feedbackReportHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(sessionPhasesTool)), sessionPhasesHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(compareToIdealTool)), compareToIdealHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(sessionTrendsTool)), sessionTrendsHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(correlateSpikesTool)), correlateSpikesHandler)
+	s.AddTool(withOutputOptions(withTimeout(withSessionFilter(projectBudgetsTool))), projectBudgetsHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(scoreProfileTool)), scoreProfileHandler)
+	s.AddTool(withOutputOptions(capabilitiesTool), capabilitiesHandler(s))
+	s.AddTool(withOutputOptions(withFrameWindow(checkBudgetsTool)), checkBudgetsHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(subsystemBreakdownTool)), subsystemBreakdownHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(frameTriptychTool)), frameTriptychHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(budgetWaterfallTool)), budgetWaterfallHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(detectCallAnomaliesTool)), detectCallAnomaliesHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(mainThreadPathTool)), mainThreadPathHandler)
+	s.AddTool(withOutputOptions(withSessionFilter(analyzeExperimentTool)), analyzeExperimentHandler)
+	s.AddTool(withOutputOptions(withTimeout(withSessionFilter(listCapturesTool))), listCapturesHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(waitChainsTool)), waitChainsHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(renderThreadTool)), renderThreadHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(workerBalanceTool)), workerBalanceHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(coreAffinityTool)), coreAffinityHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(preemptionTool)), preemptionHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(exportSpeedscopeTool)), exportSpeedscopeHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(detectContaminationTool)), detectContaminationHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(gpuQueuesTool)), gpuQueuesHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(presentationTool)), presentationHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(tickDecompositionTool)), tickDecompositionHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(derivedMetricsTool)), derivedMetricsHandler)
+	s.AddTool(withOutputOptions(ruleCatalogTool), ruleCatalogHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(exportFlamegraphTool)), exportFlamegraphHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(exportHTMLViewerTool)), exportHTMLViewerHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(renderChartTool)), renderChartHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(renderMermaidTool)), renderMermaidHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(pipelineStagesTool)), pipelineStagesHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(statsPerSecondTool)), statsPerSecondHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(exportOTelTool)), exportOTelHandler)
+	s.AddTool(withOutputOptions(withFrameWindow(exportMetricsTool)), exportMetricsHandler)
+	s.AddTool(withOutputOptions(withTimeout(analyzeTrendTool)), analyzeTrendHandler)
+
+	// Register resources for browsing captures (see resources.go)
+	registerResources(s)
 
 	// Start server using stdio
 	if err := server.ServeStdio(s); err != nil {
@@ -150,8 +1057,13 @@ func analyzePerformanceHandler(ctx context.Context, request mcp.CallToolRequest)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
 	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	issues := []PerformanceIssue{}
+	// Empty captures are reported rather than passing as problem-free
+	issues := dataQualityIssues(data)
 
 	// Analyze based on focus area
 	if focus == "all" || focus == "cpu" {
@@ -164,19 +1076,34 @@ func analyzePerformanceHandler(ctx context.Context, request mcp.CallToolRequest)
 		issues = append(issues, analyzeThreadPerformance(data)...)
 	}
 
+	// Drop the rules the project turned off and apply its severities, then apply false-positive
+	// feedback recorded with mark_false_positive
+	issues = remapSeverities(enabledIssues(issues))
+	issues, suppressed := applyFeedback(issues)
+
 	// Sort by severity
 	sort.Slice(issues, func(i, j int) bool {
 		severityOrder := map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3}
 		return severityOrder[issues[i].Severity] < severityOrder[issues[j].Severity]
 	})
 
-	result, _ := json.MarshalIndent(map[string]interface{}{
+	output := map[string]interface{}{
 		"file":          filePath,
 		"focus":         focus,
 		"issuesFound":   len(issues),
 		"issues":        issues,
 		"summary":       generateSummary(issues),
-	}, "", "  ")
+	}
+	if problems := dataQualityProblems(data); len(problems) > 0 {
+		output["summary"] = "Capture is unusable: it has " + problems[0]
+	}
+	if suppressed > 0 {
+		output["suppressedIssues"] = suppressed
+	}
+	attachAnnotations(output, "annotations", data.SessionName)
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
 
 	return mcp.NewToolResultText(string(result)), nil
 }
@@ -192,25 +1119,189 @@ func findHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp
 	if n, ok := args["top_n"].(float64); ok {
 		topN = int(n)
 	}
+	rankBy, _ := args["rank_by"].(string)
+	if rankBy == "" {
+		rankBy = "total"
+	}
+	if rankBy != "total" && rankBy != "self" && rankBy != "inclusive" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid rank_by '%s': expected 'total', 'self' or 'inclusive'", rankBy)), nil
+	}
+	groupByThread, _ := args["group_by_thread"].(bool)
+	threadFilter, _ := args["thread_filter"].(string)
+	normalize, _ := args["normalize"].(string)
+	if normalize == "" {
+		normalize = normalizeNone
+	}
+	if normalize != normalizeNone && normalize != normalizeMainThread && normalize != normalizeOwnThread {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid normalize '%s': expected 'none', 'main_thread' or 'own_thread'", normalize)), nil
+	}
+	aggregate, _ := args["aggregate_by_function"].(bool)
+	if aggregate && groupByThread {
+		return mcp.NewToolResultError("aggregate_by_function merges threads and can't be combined with group_by_thread"), nil
+	}
+	if aggregate && normalize == normalizeOwnThread {
+		return mcp.NewToolResultError("aggregate_by_function merges threads and can't be combined with normalize 'own_thread'"), nil
+	}
 
 	data, err := loadFrameProData(filePath)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
 	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	// Sort functions by total time
+	unfiltered := *data // Percentages are of the whole frame, not just the filtered threads
+	if threadFilter != "" {
+		re, err := compileFunctionPattern(threadFilter, false)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		filtered := []FrameProFunction{}
+		for _, fn := range data.Functions {
+			if re.MatchString(fn.ThreadName) {
+				filtered = append(filtered, fn)
+			}
+		}
+		if len(filtered) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("No threads match thread_filter '%s'", threadFilter)), nil
+		}
+		data.Functions = filtered
+	}
+
+	// Self time is only available when the export provides it
+	note := ""
+	if rankBy == "self" && !hasSelfTimes(data.Functions) {
+		note = "This export has no SelfTimeMs data; ranked by total time instead"
+		rankBy = "total"
+	}
+
+	var shares map[string]float64
+	if normalize != normalizeNone {
+		shares = hotspotShares(&unfiltered, rankBy, normalize)
+	}
+
+	var functionThreads map[string][]string
+	if aggregate {
+		var aggregated []FrameProFunction
+		aggregated, functionThreads = aggregateByFunction(data.Functions)
+		if shares != nil {
+			shares = aggregateShares(data.Functions, aggregated, shares)
+		}
+		data.Functions = aggregated
+	}
+
+	// Sort functions by the selected time metric
 	functions := data.Functions
 	sort.Slice(functions, func(i, j int) bool {
-		return functions[i].TotalTimeMs > functions[j].TotalTimeMs
+		return rankingTimeMs(functions[i], rankBy) > rankingTimeMs(functions[j], rankBy)
 	})
 
+	if groupByThread {
+		threads := hotspotsByThread(functions, topN, rankBy)
+		if shares != nil {
+			for _, thread := range threads {
+				attachHotspotShares(thread["hotspots"].([]map[string]interface{}), shares, normalize)
+			}
+		}
+		output := map[string]interface{}{
+			"file":    filePath,
+			"topN":    topN,
+			"rankBy":  rankBy,
+			"threads": threads,
+			"summary": fmt.Sprintf("Top %d hotspots for each of %d threads", topN, len(threads)),
+		}
+		if note != "" {
+			addWarning(output, warnNoSelfTimes, note)
+		}
+		if threadFilter != "" {
+			output["threadFilter"] = threadFilter
+		}
+		attachAnnotations(output, "annotations", data.SessionName)
+
+		attachFrameWindow(output, window)
+		result, _ := json.MarshalIndent(output, "", "  ")
+		return mcp.NewToolResultText(string(result)), nil
+	}
+
 	if topN > len(functions) {
 		topN = len(functions)
 	}
 
 	hotspots := functions[:topN]
 
-	// Generate optimization suggestions for each hotspot
+	output := map[string]interface{}{
+		"file":     filePath,
+		"topN":     topN,
+		"rankBy":   rankBy,
+		"hotspots": hotspotAnalysis(hotspots),
+		"summary":  summarizeHotspots(hotspots, rankBy),
+	}
+	if shares != nil {
+		attachHotspotShares(output["hotspots"].([]map[string]interface{}), shares, normalize)
+		output["normalize"] = normalize
+		output["summary"] = summarizeHotspotShares(hotspots, shares, normalize, rankBy)
+	}
+	if aggregate {
+		attachFunctionThreads(output["hotspots"].([]map[string]interface{}), functionThreads)
+		output["aggregateByFunction"] = true
+	}
+	if note != "" {
+		addWarning(output, warnNoSelfTimes, note)
+	}
+	if threadFilter != "" {
+		output["threadFilter"] = threadFilter
+	}
+	attachAnnotations(output, "annotations", data.SessionName)
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// hotspotsByThread returns the top N of the ranked functions for each thread separately,
+// busiest thread first
+func hotspotsByThread(ranked []FrameProFunction, topN int, rankBy string) []map[string]interface{} {
+	byThread := make(map[int][]FrameProFunction)
+	threadTime := make(map[int]float64)
+	order := []int{}
+	for _, fn := range ranked {
+		if _, exists := byThread[fn.ThreadID]; !exists {
+			order = append(order, fn.ThreadID)
+		}
+		byThread[fn.ThreadID] = append(byThread[fn.ThreadID], fn)
+		threadTime[fn.ThreadID] += rankingTimeMs(fn, rankBy)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return threadTime[order[i]] > threadTime[order[j]]
+	})
+
+	threads := []map[string]interface{}{}
+	for _, id := range order {
+		functions := byThread[id]
+		if len(functions) > topN {
+			functions = functions[:topN]
+		}
+		threads = append(threads, map[string]interface{}{
+			"threadId":       id,
+			"threadName":     functions[0].ThreadName,
+			"isMainThread":   functions[0].IsMainThread,
+			"isRenderThread": functions[0].IsRenderThread,
+			"isWorkerThread": functions[0].IsWorkerThread,
+			"threadTimeMs":   threadTime[id],
+			"hotspots":       hotspotAnalysis(functions),
+		})
+	}
+	return threads
+}
+
+// hotspotAnalysis describes ranked functions with their metrics and optimization suggestions
+func hotspotAnalysis(hotspots []FrameProFunction) []map[string]interface{} {
 	analysis := make([]map[string]interface{}, len(hotspots))
 	for i, fn := range hotspots {
 		avgTimePerCall := fn.TotalTimeMs / float64(fn.TotalCount+1)
@@ -223,6 +1314,8 @@ func findHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp
 			"isMainThread":          fn.IsMainThread,
 			"isRenderThread":        fn.IsRenderThread,
 			"totalTimeMs":           fn.TotalTimeMs,
+			"selfTimeMs":            fn.SelfTimeMs,
+			"inclusiveTimeMs":       rankingTimeMs(fn, "inclusive"),
 			"avgTimePerFrameMs":     fn.AvgTimePerFrameMs,
 			"maxTimePerFrameMs":     fn.MaxTimePerFrameMs,
 			"totalCount":            fn.TotalCount,
@@ -231,15 +1324,11 @@ func findHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp
 			"threadUtilization":     fn.ThreadUtilizationPercent,
 			"suggestions":           generateFunctionSuggestions(fn),
 		}
+		if known := lookupKnownIssue(fn.FunctionName); known != nil {
+			analysis[i]["knownIssue"] = known
+		}
 	}
-
-	result, _ := json.MarshalIndent(map[string]interface{}{
-		"file":     filePath,
-		"topN":     topN,
-		"hotspots": analysis,
-	}, "", "  ")
-
-	return mcp.NewToolResultText(string(result)), nil
+	return analysis
 }
 
 func frameAnalysisHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -249,16 +1338,20 @@ func frameAnalysisHandler(ctx context.Context, request mcp.CallToolRequest) (*mc
 	}
 
 	filePath, _ := args["file_path"].(string)
-	targetFPS := 60.0
-	if fps, ok := args["target_fps"].(float64); ok {
-		targetFPS = fps
-	}
 
 	data, err := loadFrameProData(filePath)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
 	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
+	targetFPS, targetSource := targetFPSFor(args, data)
 	targetFrameTime := 1000.0 / targetFPS // in milliseconds
 
 	// Analyze main thread functions for frame issues
@@ -284,28 +1377,73 @@ func frameAnalysisHandler(ctx context.Context, request mcp.CallToolRequest) (*mc
 		}
 	}
 
-	// Calculate approximate FPS based on main thread work
 	var mainThreadTotalAvgTime float64
 	for _, fn := range mainThreadFunctions {
 		mainThreadTotalAvgTime += fn.AvgTimePerFrameMs
 	}
-	estimatedFPS := 1000.0 / mainThreadTotalAvgTime
-	if estimatedFPS > 1000.0 {
-		estimatedFPS = 1000.0 // Cap at reasonable value
-	}
 
-	result, _ := json.MarshalIndent(map[string]interface{}{
+	// Estimate FPS from the busiest thread of each frame; the pipeline names the limiting stage
+	stages := threadStages(data)
+	pipeline := criticalPath(aggregateFrame(data), stages)
+	model := estimateFPS(data)
+	estimatedFPS := model.FPS
+
+	output := map[string]interface{}{
 		"file":                    filePath,
 		"sessionName":             data.SessionName,
 		"totalFrames":             data.TotalFrames,
 		"targetFPS":               targetFPS,
+		"targetFPSSource":         targetSource,
 		"estimatedFPS":            estimatedFPS,
+		"fpsModel":                model,
 		"mainThreadAvgWorkMs":     mainThreadTotalAvgTime,
+		"criticalPathMs":          pipeline.CriticalPathMs,
+		"frameLimiter":            pipeline.Limiter,
 		"targetFrameTimeMs":       targetFrameTime,
 		"problemFunctions":        problemFunctions,
 		"mainThreadFunctionCount": len(mainThreadFunctions),
-		"analysis":                analyzeFrameIssues(len(problemFunctions), 0, estimatedFPS, targetFPS),
-	}, "", "  ")
+	}
+
+	// Real percentiles need per-frame data; aggregate-only exports keep the estimate
+	slowFrames := len(problemFunctions)
+	if len(data.Frames) > 0 {
+		times := frameTimes(data)
+		percentiles := computeFrameTimePercentiles(times)
+		slowFrames = 0
+		for _, t := range times {
+			if t > targetFrameTime {
+				slowFrames++
+			}
+		}
+		counts, _, overall := limiterBreakdown(criticalPathFrames(data, data.Frames))
+		output["frameLimiter"] = overall
+		output["limiterFrameCounts"] = counts
+		output["frameTimePercentiles"] = percentiles
+		output["framesOverBudget"] = slowFrames
+	}
+	output["analysis"] = append(analyzeFrameIssues(slowFrames, 0, estimatedFPS, targetFPS), describeFPSEstimate(model))
+
+	refreshHz := 60.0
+	if hz, ok := args["refresh_hz"].(float64); ok && hz > 0 {
+		refreshHz = hz
+	}
+	boundness := frameBoundness(data, refreshHz)
+	output["boundness"] = boundness
+	if ranges := boundness["ranges"].([]FrameRangeClass); len(ranges) > 1 {
+		output["analysis"] = append(output["analysis"].([]string),
+			fmt.Sprintf("Mostly %s; classification changes %d times across the capture (see boundness.ranges)", boundness["verdict"], len(ranges)-1))
+	} else {
+		output["analysis"] = append(output["analysis"].([]string), fmt.Sprintf("Session is %s. %s", boundness["verdict"], ranges[0].Reason))
+	}
+	output["summary"] = fmt.Sprintf("Estimated %.1f FPS against a target of %.0f FPS (%.2fms budget)", estimatedFPS, targetFPS, targetFrameTime)
+	if percentiles, ok := output["frameTimePercentiles"].(FrameTimePercentiles); ok {
+		output["summary"] = fmt.Sprintf("Average %.1f FPS against a target of %.0f FPS, p99 frame time %.2fms, 1%% low %.1f FPS",
+			percentiles.AvgFPS, targetFPS, percentiles.P99Ms, percentiles.OnePercentLowFPS)
+	}
+	attachAnnotations(output, "annotations", data.SessionName)
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
 
 	return mcp.NewToolResultText(string(result)), nil
 }
@@ -318,6 +1456,15 @@ func compareProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*
 
 	baselinePath, _ := args["baseline_path"].(string)
 	currentPath, _ := args["current_path"].(string)
+	aggregate, _ := args["aggregate_by_function"].(bool)
+	metrics, err := parseMetrics(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	renames, err := parseRenames(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	baseline, err := loadFrameProData(baselinePath)
 	if err != nil {
@@ -329,20 +1476,53 @@ func compareProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to load current data: %v", err)), nil
 	}
 
-	// Compare functions
+	// The same frame window applies to both captures
+	window, err := applyFrameWindow(baseline, args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Baseline: %v", err)), nil
+	}
+	if _, err := applyFrameWindow(current, args); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Current: %v", err)), nil
+	}
+	if err := checkCapture(baseline, false); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Baseline: %v", err)), nil
+	}
+	if err := checkCapture(current, false); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Current: %v", err)), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, baseline) // Budgets judge both captures against the baseline's target
+
+	// Functions renamed since the baseline take their new names so both sides pair up
+	renamed := applyRenames(baseline, renames)
+
+	// Derived metrics are evaluated before functions are merged over threads
+	metricChanges := compareMetrics(baseline, current, metrics)
+
+	// Compare functions, by name alone when merged over threads
+	key := functionKey
+	var baselineThreads, currentThreads map[string][]string
+	if aggregate {
+		baseline.Functions, baselineThreads = aggregateByFunction(baseline.Functions)
+		current.Functions, currentThreads = aggregateByFunction(current.Functions)
+		key = func(fn FrameProFunction) string { return fn.FunctionName }
+	}
 	baselineFuncs := make(map[string]FrameProFunction)
 	for _, fn := range baseline.Functions {
-		key := fmt.Sprintf("%s:%d", fn.FunctionName, fn.ThreadID)
-		baselineFuncs[key] = fn
+		baselineFuncs[key(fn)] = fn
 	}
 
 	regressions := []map[string]interface{}{}
 	improvements := []map[string]interface{}{}
 	newFunctions := []map[string]interface{}{}
 
+	// Per-frame data allows translating regressions into dropped frames
+	var currentTimes []float64
+	if len(current.Frames) > 0 {
+		currentTimes = frameTimes(current)
+	}
+
 	for _, currentFn := range current.Functions {
-		key := fmt.Sprintf("%s:%d", currentFn.FunctionName, currentFn.ThreadID)
-		if baselineFn, exists := baselineFuncs[key]; exists {
+		if baselineFn, exists := baselineFuncs[key(currentFn)]; exists {
 			timeDiff := currentFn.TotalTimeMs - baselineFn.TotalTimeMs
 			percentChange := (timeDiff / (baselineFn.TotalTimeMs + 0.001)) * 100
 
@@ -373,6 +1553,7 @@ func compareProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*
 					"avgPercentChange":     avgPercentChange,
 					"baselineUtilization":  baselineFn.ThreadUtilizationPercent,
 					"currentUtilization":   currentFn.ThreadUtilizationPercent,
+					"playerImpact":         regressionPlayerImpact(avgTimeDiff, currentTimes, targetFPS),
 				})
 			} else if percentChange < -10.0 { // Improvement threshold
 				improvements = append(improvements, map[string]interface{}{
@@ -385,7 +1566,7 @@ func compareProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*
 					"avgPercentChange":   avgPercentChange,
 				})
 			}
-			delete(baselineFuncs, key)
+			delete(baselineFuncs, key(currentFn))
 		} else {
 			// New function not in baseline
 			if currentFn.TotalTimeMs > 10.0 { // Only report significant new functions
@@ -422,7 +1603,7 @@ func compareProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*
 		return regressions[i]["totalPercentChange"].(float64) > regressions[j]["totalPercentChange"].(float64)
 	})
 
-	result, _ := json.MarshalIndent(map[string]interface{}{
+	output := map[string]interface{}{
 		"baseline":         baselinePath,
 		"baselineSession":  baseline.SessionName,
 		"current":          currentPath,
@@ -433,7 +1614,54 @@ func compareProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*
 		"removedFunctions": removedFunctions,
 		"summary": fmt.Sprintf("Found %d regressions (%d critical), %d improvements, %d new functions, %d removed functions",
 			len(regressions), countBySeverity(regressions, "critical"), len(improvements), len(newFunctions), len(removedFunctions)),
-	}, "", "  ")
+	}
+	if len(baseline.Frames) > 0 && len(current.Frames) > 0 {
+		output["playerImpact"] = map[string]interface{}{
+			"targetFPS":       targetFPS,
+			"targetFPSSource": targetSource,
+			"baseline":        budgetImpact(frameTimes(baseline), targetFPS),
+			"current":         budgetImpact(currentTimes, targetFPS),
+		}
+	}
+	if len(baseline.Frames) > 1 && len(current.Frames) > 1 {
+		distribution := compareFrameDistributions(frameTimes(baseline), currentTimes)
+		output["frameTimeDistribution"] = distribution
+		output["analysis"] = []string{distribution.Verdict}
+	}
+	if len(metrics) > 0 {
+		output["derivedMetrics"] = metricChanges
+	}
+	if len(renamed) > 0 {
+		renamedFrom := make(map[string]string)
+		for _, use := range renamed {
+			renamedFrom[use.To] = use.From
+		}
+		for _, entries := range [][]map[string]interface{}{regressions, improvements, removedFunctions} {
+			for _, entry := range entries {
+				if from, found := renamedFrom[entry["function"].(string)]; found {
+					entry["renamedFrom"] = from
+				}
+			}
+		}
+		output["renames"] = renamed
+		output["summary"] = output["summary"].(string) + fmt.Sprintf("; %d renamed functions paired by name mapping", len(renamed))
+	}
+	if aggregate {
+		for _, entries := range [][]map[string]interface{}{regressions, improvements, newFunctions} {
+			for _, entry := range entries {
+				entry["threads"] = currentThreads[entry["function"].(string)]
+			}
+		}
+		for _, entry := range removedFunctions {
+			entry["threads"] = baselineThreads[entry["function"].(string)]
+		}
+		output["aggregateByFunction"] = true
+	}
+	attachAnnotations(output, "baselineAnnotations", baseline.SessionName)
+	attachAnnotations(output, "currentAnnotations", current.SessionName)
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
 
 	return mcp.NewToolResultText(string(result)), nil
 }
@@ -463,7 +1691,8 @@ func resourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mc
 
 // Helper functions
 
-func loadFrameProData(filePath string) (*FrameProData, error) {
+// resolveCapturePath returns the path a capture argument is read from
+func resolveCapturePath(filePath string) string {
 	// Try absolute path first
 	fullPath := filePath
 
@@ -476,18 +1705,32 @@ func loadFrameProData(filePath string) (*FrameProData, error) {
 			fullPath = filePath
 		}
 	}
+	return fullPath
+}
+
+func loadFrameProData(filePath string) (*FrameProData, error) {
+	fullPath := resolveCapturePath(filePath)
 
 	data, err := os.ReadFile(fullPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file (tried: %s, %s): %w", filePath, fullPath, err)
 	}
 
-	var frameProData FrameProData
-	if err := json.Unmarshal(data, &frameProData); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	frameProData, err := decodeCapture(fullPath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hierarchical exports may omit self times; derive them from the children.
+	// Flat analyses then see every scope, while call tree tools walk the Tree.
+	if isHierarchical(frameProData.Functions) {
+		fillHierarchyTimes(frameProData.Functions)
+		frameProData.Tree = frameProData.Functions
+		frameProData.Functions = flattenScopes(frameProData.Tree)
+		frameProData.TotalFunctions = len(frameProData.Functions)
 	}
 
-	return &frameProData, nil
+	return frameProData, nil
 }
 
 func analyzeCPUPerformance(data *FrameProData) []PerformanceIssue {
@@ -512,12 +1755,15 @@ func analyzeCPUPerformance(data *FrameProData) []PerformanceIssue {
 
 			issues = append(issues, PerformanceIssue{
 				Severity:    severity,
+				RuleID:      "perf/cpu-hotspot",
+				Thread:      fn.ThreadName,
 				Category:    "CPU Hotspot",
 				Description: fmt.Sprintf("Function '%s' on %s consumes excessive CPU time", fn.FunctionName, threadInfo),
 				Impact:      fmt.Sprintf("%.2fms total (%.2fms avg/frame), %d total calls, %.1f%% thread utilization",
 					fn.TotalTimeMs, fn.AvgTimePerFrameMs, fn.TotalCount, fn.ThreadUtilizationPercent),
 				Suggestion:  generateOptimizationSuggestion(fn),
 				Value:       fn.TotalTimeMs,
+				Evidence:    functionEvidence(data, fn, fn.AvgTimePerFrameMs*2),
 			})
 		}
 
@@ -525,12 +1771,15 @@ func analyzeCPUPerformance(data *FrameProData) []PerformanceIssue {
 		if fn.TotalCount > 10000 && fn.TotalTimeMs > 50.0 {
 			issues = append(issues, PerformanceIssue{
 				Severity:    "medium",
+				RuleID:      "perf/call-frequency",
+				Thread:      fn.ThreadName,
 				Category:    "Call Frequency",
 				Description: fmt.Sprintf("Function '%s' called very frequently on %s", fn.FunctionName, fn.ThreadName),
 				Impact:      fmt.Sprintf("%d total calls (%.1f avg/frame), %.2fms total time",
 					fn.TotalCount, fn.AvgCountPerFrame, fn.TotalTimeMs),
 				Suggestion:  "Consider caching results, batching calls, or reducing call frequency",
 				Value:       float64(fn.TotalCount),
+				Evidence:    callCountEvidence(data, fn, fn.AvgCountPerFrame*2),
 			})
 		}
 
@@ -538,12 +1787,15 @@ func analyzeCPUPerformance(data *FrameProData) []PerformanceIssue {
 		if fn.MaxTimePerFrameMs > 16.67 && fn.TotalCount > 100 { // Longer than 1 frame at 60fps
 			issues = append(issues, PerformanceIssue{
 				Severity:    "high",
+				RuleID:      "perf/frame-spike",
+				Thread:      fn.ThreadName,
 				Category:    "Frame Spike",
 				Description: fmt.Sprintf("Function '%s' causes frame spikes", fn.FunctionName),
 				Impact:      fmt.Sprintf("Max %.2fms in single frame (avg: %.2fms) on %s",
 					fn.MaxTimePerFrameMs, fn.AvgTimePerFrameMs, fn.ThreadName),
 				Suggestion:  "Investigate why this function occasionally takes much longer. Consider spreading work across frames",
 				Value:       fn.MaxTimePerFrameMs,
+				Evidence:    functionEvidence(data, fn, 16.67),
 			})
 		}
 
@@ -551,12 +1803,15 @@ func analyzeCPUPerformance(data *FrameProData) []PerformanceIssue {
 		if fn.ThreadUtilizationPercent > 95.0 && fn.TotalTimeMs > 100.0 {
 			issues = append(issues, PerformanceIssue{
 				Severity:    "critical",
+				RuleID:      "perf/function-saturation",
+				Thread:      fn.ThreadName,
 				Category:    "Thread Saturation",
 				Description: fmt.Sprintf("Function '%s' saturates %s", fn.FunctionName, fn.ThreadName),
 				Impact:      fmt.Sprintf("%.1f%% thread utilization, %.2fms total time",
 					fn.ThreadUtilizationPercent, fn.TotalTimeMs),
 				Suggestion:  "Thread is completely saturated. Critical optimization needed or work redistribution to other threads",
 				Value:       fn.ThreadUtilizationPercent,
+				Evidence:    functionEvidence(data, fn, fn.AvgTimePerFrameMs*2),
 			})
 		}
 	}
@@ -575,22 +1830,28 @@ func analyzeFramePerformance(data *FrameProData) []PerformanceIssue {
 			if fn.MaxTimePerFrameMs > 33.0 && fn.IsMainThread { // Slower than 30 FPS
 				issues = append(issues, PerformanceIssue{
 					Severity:    "critical",
+					RuleID:      "perf/main-thread-spike",
+					Thread:      fn.ThreadName,
 					Category:    "Frame Spike - Main Thread",
 					Description: fmt.Sprintf("Function '%s' causes critical frame spikes on main thread", fn.FunctionName),
 					Impact:      fmt.Sprintf("Max %.2fms per frame (target: 16.67ms for 60fps), avg %.2fms",
 						fn.MaxTimePerFrameMs, fn.AvgTimePerFrameMs),
 					Suggestion:  "This blocks the main thread and causes stuttering. Move to worker thread or optimize urgently",
 					Value:       fn.MaxTimePerFrameMs,
+					Evidence:    functionEvidence(data, fn, 33.0),
 				})
 			} else if fn.MaxTimePerFrameMs > 16.67 && fn.IsMainThread {
 				issues = append(issues, PerformanceIssue{
 					Severity:    "high",
+					RuleID:      "perf/main-thread-over-budget",
+					Thread:      fn.ThreadName,
 					Category:    "Frame Performance",
 					Description: fmt.Sprintf("Function '%s' on main thread exceeds 60fps budget", fn.FunctionName),
 					Impact:      fmt.Sprintf("Max %.2fms per frame (target: 16.67ms), avg %.2fms",
 						fn.MaxTimePerFrameMs, fn.AvgTimePerFrameMs),
 					Suggestion:  "Optimize or move to worker thread to maintain 60fps",
 					Value:       fn.MaxTimePerFrameMs,
+					Evidence:    functionEvidence(data, fn, 16.67),
 				})
 			}
 
@@ -599,12 +1860,15 @@ func analyzeFramePerformance(data *FrameProData) []PerformanceIssue {
 			if variance > 5.0 && fn.AvgTimePerFrameMs > 1.0 {
 				issues = append(issues, PerformanceIssue{
 					Severity:    "medium",
+					RuleID:      "perf/inconsistent-performance",
+					Thread:      fn.ThreadName,
 					Category:    "Inconsistent Performance",
 					Description: fmt.Sprintf("Function '%s' has highly variable frame times", fn.FunctionName),
 					Impact:      fmt.Sprintf("Max/Avg ratio: %.1fx (max: %.2fms, avg: %.2fms)",
 						variance, fn.MaxTimePerFrameMs, fn.AvgTimePerFrameMs),
 					Suggestion:  "Inconsistent performance causes stuttering. Investigate what causes occasional slowdowns",
 					Value:       variance,
+					Evidence:    functionEvidence(data, fn, fn.AvgTimePerFrameMs*5),
 				})
 			}
 		}
@@ -613,6 +1877,7 @@ func analyzeFramePerformance(data *FrameProData) []PerformanceIssue {
 		if data.TotalFrames > 0 {
 			issues = append(issues, PerformanceIssue{
 				Severity:    "info",
+				RuleID:      "perf/session-info",
 				Category:    "Session Info",
 				Description: fmt.Sprintf("Profiling session: %s", data.SessionName),
 				Impact:      fmt.Sprintf("Captured %d frames with %d unique functions",
@@ -652,12 +1917,13 @@ func analyzeThreadPerformance(data *FrameProData) []PerformanceIssue {
 
 	// Analyze each thread
 	var mainThreadTime, renderThreadTime float64
+	var mainThreadID, renderThreadID int
 	for _, stats := range threadStats {
 		if stats.IsMainThread {
-			mainThreadTime = stats.TotalTime
+			mainThreadTime, mainThreadID = stats.TotalTime, stats.ThreadID
 		}
 		if stats.IsRenderThread {
-			renderThreadTime = stats.TotalTime
+			renderThreadTime, renderThreadID = stats.TotalTime, stats.ThreadID
 		}
 
 		// Check for saturated threads
@@ -669,12 +1935,15 @@ func analyzeThreadPerformance(data *FrameProData) []PerformanceIssue {
 
 			issues = append(issues, PerformanceIssue{
 				Severity:    severity,
+				RuleID:      "perf/thread-saturation",
+				Thread:      stats.ThreadName,
 				Category:    "Thread Saturation",
 				Description: fmt.Sprintf("Thread '%s' is heavily saturated", stats.ThreadName),
 				Impact:      fmt.Sprintf("%.1f%% utilization with %.2fms total work across %d functions",
 					stats.MaxUtilization, stats.TotalTime, len(stats.Functions)),
 				Suggestion:  "Thread is running at capacity. Consider redistributing work or optimizing top functions",
 				Value:       stats.MaxUtilization,
+				Evidence:    threadEvidence(data, stats.ThreadID, stats.TotalTime/float64(max(data.TotalFrames, 1))*1.5),
 			})
 		}
 	}
@@ -685,12 +1954,14 @@ func analyzeThreadPerformance(data *FrameProData) []PerformanceIssue {
 		if ratio > 2.0 || ratio < 0.5 {
 			issues = append(issues, PerformanceIssue{
 				Severity:    "medium",
+				RuleID:      "perf/thread-balance",
 				Category:    "Thread Balance",
 				Description: "Imbalance between main thread and render thread",
 				Impact:      fmt.Sprintf("Main thread: %.2fms, Render thread: %.2fms (ratio: %.2f:1)",
 					mainThreadTime, renderThreadTime, ratio),
 				Suggestion:  "Consider redistributing work between main and render threads for better parallelization",
 				Value:       ratio,
+				Evidence:    balanceEvidence(data, mainThreadID, renderThreadID, ratio),
 			})
 		}
 	}
@@ -708,6 +1979,45 @@ type ThreadStats struct {
 	Functions      []FrameProFunction
 }
 
+// summarizeHotspots names the top three hotspots with their ranked and per-frame time
+func summarizeHotspots(hotspots []FrameProFunction, rankBy string) string {
+	if len(hotspots) == 0 {
+		return "No functions recorded"
+	}
+	parts := []string{}
+	for i, fn := range hotspots {
+		if i == 3 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s on %s (%.2fms %s, %.2fms avg/frame)",
+			fn.FunctionName, fn.ThreadName, rankingTimeMs(fn, rankBy), rankBy, fn.AvgTimePerFrameMs))
+	}
+	return "Top hotspots: " + strings.Join(parts, "; ")
+}
+
+// rankingTimeMs returns the time metric used to rank a function.
+// FramePro's TotalTimeMs is inclusive, so it stands in when InclusiveTimeMs is absent.
+func rankingTimeMs(fn FrameProFunction, rankBy string) float64 {
+	switch rankBy {
+	case "self":
+		return fn.SelfTimeMs
+	case "inclusive":
+		if fn.InclusiveTimeMs > 0 {
+			return fn.InclusiveTimeMs
+		}
+	}
+	return fn.TotalTimeMs
+}
+
+func hasSelfTimes(functions []FrameProFunction) bool {
+	for _, fn := range functions {
+		if fn.SelfTimeMs > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func generateSummary(issues []PerformanceIssue) string {
 	counts := map[string]int{"critical": 0, "high": 0, "medium": 0, "low": 0, "info": 0}
 	for _, issue := range issues {
@@ -765,13 +2075,20 @@ func generateOptimizationSuggestion(fn FrameProFunction) string {
 		suggestions = append(suggestions, fmt.Sprintf("High variance (%.1fx) - investigate occasional slowdowns", variance))
 	}
 
+	// Known engine functions get curated guidance; otherwise fall back to name heuristics
+	if known := lookupKnownIssue(fn.FunctionName); known != nil {
+		suggestions = append(suggestions, fmt.Sprintf("%s (%s): %s", fn.FunctionName, known.Engine, known.Explanation))
+		suggestions = append(suggestions, known.Remediation...)
+		return strings.Join(suggestions, "; ")
+	}
+
 	// Function name analysis
 	funcLower := strings.ToLower(fn.FunctionName)
 	if strings.Contains(funcLower, "wait") || strings.Contains(funcLower, "sleep") {
-		suggestions = append(suggestions, "WAIT/SLEEP detected - may indicate synchronization issues or idle time")
+		suggestions = append(suggestions, "WAIT/SLEEP detected - may indicate synchronization issues or idle time; use analyze_wait_chains to find the thread it waits on")
 	}
 	if strings.Contains(funcLower, "lock") || strings.Contains(funcLower, "mutex") {
-		suggestions = append(suggestions, "Lock contention possible - review synchronization strategy")
+		suggestions = append(suggestions, "Lock contention possible - use analyze_contention for blocked time per lock site")
 	}
 	if strings.Contains(funcLower, "physics") {
 		suggestions = append(suggestions, "Physics calculation - review collision detection and simulation complexity")
@@ -824,6 +2141,12 @@ func generateFunctionSuggestions(fn FrameProFunction) []string {
 		suggestions = append(suggestions, fmt.Sprintf("High avg time per call (%.3fms) - review algorithm complexity", avgTimePerCall))
 	}
 
+	// Known engine functions get curated guidance; otherwise fall back to name heuristics
+	if known := lookupKnownIssue(fn.FunctionName); known != nil {
+		suggestions = append(suggestions, fmt.Sprintf("%s: %s", known.Engine, known.Explanation))
+		return append(suggestions, known.Remediation...)
+	}
+
 	// Function name-based suggestions
 	funcLower := strings.ToLower(fn.FunctionName)
 	if strings.Contains(funcLower, "event") && strings.Contains(funcLower, "wait") {
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The main-thread critical path is the longest chain of main-thread work. In hierarchical
+// exports it follows the most expensive child scope from the most expensive root down to
+// a leaf. Flat exports don't record nesting, so the chain is the main thread's scopes in
+// order of cost, with its waits attributed to the pipeline stage that limited the frame.
+
+// PathStep is one scope on the main-thread critical path
+type PathStep struct {
+	Depth          int     `json:"depth"`
+	FunctionName   string  `json:"functionName"`
+	TimeMs         float64 `json:"timeMs"`
+	SelfMs         float64 `json:"selfMs,omitempty"`
+	CumulativeMs   float64 `json:"cumulativeMs,omitempty"` // Flat paths only
+	PercentOfPath  float64 `json:"percentOfPath"`
+	OtherChildMs   float64 `json:"siblingsMs,omitempty"` // Time of the scopes not followed at this level
+	WaitingOn      string  `json:"waitingOn,omitempty"`
+	WaitingOnStage string  `json:"waitingOnStage,omitempty"`
+}
+
+// MainThreadPath is the critical path of one frame, or of the average frame
+type MainThreadPath struct {
+	Frame        *int       `json:"frame,omitempty"`
+	FrameTimeMs  float64    `json:"frameTimeMs"`
+	MainThreadMs float64    `json:"mainThreadMs"`
+	Hierarchical bool       `json:"hierarchical"`
+	Steps        []PathStep `json:"steps"`
+}
+
+// hotChain follows the most expensive scope at every level, starting from roots.
+// Times are scaled by scale to convert totals to time per frame.
+func hotChain(roots []FrameProFunction, scale float64) []PathStep {
+	steps := []PathStep{}
+	var rootMs float64
+	level := roots
+	for depth := 0; len(level) > 0; depth++ {
+		hottest, levelMs := 0, 0.0
+		for i, fn := range level {
+			levelMs += inclusiveTimeMs(fn)
+			if inclusiveTimeMs(fn) > inclusiveTimeMs(level[hottest]) {
+				hottest = i
+			}
+		}
+		fn := level[hottest]
+		step := PathStep{
+			Depth:        depth,
+			FunctionName: fn.FunctionName,
+			TimeMs:       inclusiveTimeMs(fn) * scale,
+			SelfMs:       fn.SelfTimeMs * scale,
+			OtherChildMs: (levelMs - inclusiveTimeMs(fn)) * scale,
+		}
+		if depth == 0 {
+			rootMs = step.TimeMs
+		}
+		if rootMs > 0 {
+			step.PercentOfPath = step.TimeMs / rootMs * 100
+		}
+		steps = append(steps, step)
+		level = fn.Children
+	}
+	return steps
+}
+
+// flatChain orders the main-thread scopes of a frame by cost. Waits are attributed to the
+// stage that limited the frame, when that isn't the main thread.
+func flatChain(functions []FrameProFunction, limiter CriticalPathFrame) []PathStep {
+	scopes := append([]FrameProFunction(nil), functions...)
+	sort.Slice(scopes, func(i, j int) bool {
+		return scopes[i].TimeMs > scopes[j].TimeMs
+	})
+	var totalMs float64
+	for _, fn := range scopes {
+		totalMs += fn.TimeMs
+	}
+	steps := []PathStep{}
+	var cumulative float64
+	for _, fn := range scopes {
+		cumulative += fn.TimeMs
+		step := PathStep{FunctionName: fn.FunctionName, TimeMs: fn.TimeMs, CumulativeMs: cumulative}
+		if totalMs > 0 {
+			step.PercentOfPath = fn.TimeMs / totalMs * 100
+		}
+		if isWaitScope(fn.FunctionName) && limiter.Limiter != stageMain {
+			step.WaitingOn, step.WaitingOnStage = limiter.LimiterThread, limiter.Limiter
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// frameScopeTimes sets TotalTimeMs from TimeMs on the scopes of one frame, so the
+// hierarchy helpers see the frame's times
+func frameScopeTimes(functions []FrameProFunction) {
+	for i := range functions {
+		if functions[i].TotalTimeMs == 0 {
+			functions[i].TotalTimeMs = functions[i].TimeMs
+		}
+		frameScopeTimes(functions[i].Children)
+	}
+}
+
+// mainThreadFunctions returns the functions recorded on the main thread
+func mainThreadFunctions(functions []FrameProFunction, stages map[int]string) []FrameProFunction {
+	main := []FrameProFunction{}
+	for _, fn := range functions {
+		if stages[fn.ThreadID] == stageMain {
+			main = append(main, fn)
+		}
+	}
+	return main
+}
+
+// describePath renders a path as "A 12.00ms > B 8.00ms" (nested) or "A 12.00ms, B 8.00ms"
+func describePath(path MainThreadPath, maxSteps int) string {
+	parts := []string{}
+	for i, step := range path.Steps {
+		if i == maxSteps {
+			parts = append(parts, "...")
+			break
+		}
+		part := fmt.Sprintf("%s %.2fms", step.FunctionName, step.TimeMs)
+		if step.WaitingOn != "" {
+			part += " waiting on " + step.WaitingOn
+		}
+		parts = append(parts, part)
+	}
+	if path.Hierarchical {
+		return strings.Join(parts, " > ")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func mainThreadPathHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	worstN := 3
+	if n, ok := args["worst_frames"].(float64); ok && n > 0 {
+		worstN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	stages := threadStages(data)
+	paths := []MainThreadPath{}
+	warnings := []Warning{}
+	if len(data.Frames) > 0 {
+		times := frameTimes(data)
+		order := make([]int, len(times))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return times[order[i]] > times[order[j]]
+		})
+		if len(order) > worstN {
+			order = order[:worstN]
+		}
+		for _, index := range order {
+			frame := data.Frames[index]
+			main := mainThreadFunctions(frame.Functions, stages)
+			path := MainThreadPath{Frame: &frame.FrameNumber, FrameTimeMs: times[index], Hierarchical: isHierarchical(main)}
+			if path.Hierarchical {
+				frameScopeTimes(main)
+				fillHierarchyTimes(main)
+				path.Steps = hotChain(main, 1)
+			} else {
+				path.Steps = flatChain(main, criticalPath(frame, stages))
+			}
+			for _, fn := range main {
+				path.MainThreadMs += fn.TimeMs
+			}
+			paths = append(paths, path)
+		}
+	} else {
+		// Only the average frame: totals are divided by the frame count
+		main := mainThreadFunctions(scopeTree(data), stages)
+		scale := 1.0
+		if data.TotalFrames > 0 {
+			scale = 1 / float64(data.TotalFrames)
+		}
+		average := aggregateFrame(data)
+		path := MainThreadPath{FrameTimeMs: averageFrameMs(data), Hierarchical: isHierarchical(main)}
+		if path.Hierarchical {
+			path.Steps = hotChain(main, scale)
+		} else {
+			path.Steps = flatChain(mainThreadFunctions(average.Functions, stages), criticalPath(average, stages))
+		}
+		for _, fn := range mainThreadFunctions(average.Functions, stages) {
+			path.MainThreadMs += fn.TimeMs
+		}
+		paths = append(paths, path)
+		warnings = append(warnings, Warning{warnNoFrameData, "No per-frame data; the path is for the average frame"})
+	}
+	if len(paths[0].Steps) == 0 {
+		return mcp.NewToolResultError("No main-thread scopes recorded. Check that the capture includes the main (game) thread"), nil
+	}
+	if !paths[0].Hierarchical {
+		warnings = append(warnings, Warning{warnNoHierarchy, "This export has no nested scopes, so the path lists main-thread scopes by cost instead of a call chain"})
+	}
+
+	analysis := []string{}
+	for _, path := range paths {
+		label := "The average frame"
+		if path.Frame != nil {
+			label = fmt.Sprintf("Frame %d", *path.Frame)
+		}
+		analysis = append(analysis, fmt.Sprintf("%s (%.2fms, main thread %.2fms): %s", label, path.FrameTimeMs, path.MainThreadMs, describePath(path, 6)))
+	}
+
+	// How often the worst path's innermost (or largest) scope leads the other paths too
+	worst := paths[0]
+	last := worst.Steps[len(worst.Steps)-1]
+	if !worst.Hierarchical {
+		last = worst.Steps[0]
+	}
+	leads := 0
+	for _, path := range paths {
+		if path.Hierarchical && path.Steps[len(path.Steps)-1].FunctionName == last.FunctionName ||
+			!path.Hierarchical && path.Steps[0].FunctionName == last.FunctionName {
+			leads++
+		}
+	}
+	label := "the average frame"
+	if worst.Frame != nil {
+		label = fmt.Sprintf("worst frame %d", *worst.Frame)
+	}
+	summary := fmt.Sprintf("The main-thread critical path ends in %s (%.2fms in %s)", last.FunctionName, last.TimeMs, label)
+	if !worst.Hierarchical {
+		summary = fmt.Sprintf("%s is the largest main-thread scope (%.2fms of %.2fms in %s)", last.FunctionName, last.TimeMs, worst.MainThreadMs, label)
+	}
+	if len(paths) > 1 {
+		summary += fmt.Sprintf("; the same in %d of the %d worst frames", leads, len(paths))
+	}
+
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"paths":       paths,
+		"analysis":    analysis,
+		"summary":     summary,
+		"warnings":    warnings,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
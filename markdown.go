@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// output_format markdown renders a tool result for chat: the summary (or headline) as a
+// lead paragraph, plain values as a field table, lines of text such as analysis and
+// suggestions as bullet lists, and every list of objects as a table with the same columns
+// as its CSV. Nested objects become sections. Numbers are rounded to three decimals.
+
+// markdownTitle turns a result key into a heading: "frameTimes" -> "Frame times"
+func markdownTitle(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case i == 0:
+			b.WriteRune(unicode.ToUpper(r))
+		case unicode.IsUpper(r):
+			b.WriteByte(' ')
+			b.WriteRune(unicode.ToLower(r))
+		case r == '_':
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// markdownCell escapes a value for a table cell
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+// roundFloats rounds every number in a JSON value to three decimals
+func roundFloats(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		return math.Round(v*1000) / 1000
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = roundFloats(nested)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = roundFloats(item)
+		}
+	}
+	return value
+}
+
+// markdownKind decides how a value is rendered: a "field" of the field table, "bullets",
+// a "table" or a "section"
+func markdownKind(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "section"
+	case []interface{}:
+		if len(v) == 0 {
+			return "field"
+		}
+		if _, isObject := v[0].(map[string]interface{}); isObject {
+			return "table"
+		}
+		for _, item := range v {
+			if _, isText := item.(string); !isText {
+				return "field"
+			}
+		}
+		return "bullets"
+	}
+	return "field"
+}
+
+// writeMarkdownTable writes rows of cells as a table
+func writeMarkdownTable(b *strings.Builder, columns []string, records []map[string]string) {
+	header := make([]string, len(columns))
+	rule := make([]string, len(columns))
+	for i, column := range columns {
+		header[i], rule[i] = markdownCell(column), "---"
+	}
+	fmt.Fprintf(b, "| %s |\n| %s |\n", strings.Join(header, " | "), strings.Join(rule, " | "))
+	for _, cells := range records {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = markdownCell(cells[column])
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(row, " | "))
+	}
+	b.WriteString("\n")
+}
+
+// writeMarkdownSection writes an object's plain values as a field table, then its text
+// lists, tables and nested objects as sections one level down
+func writeMarkdownSection(b *strings.Builder, object map[string]interface{}, level int, skip map[string]bool) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		if !skip[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	fields := []map[string]string{}
+	for _, key := range keys {
+		if markdownKind(object[key]) == "field" {
+			cells := map[string]string{}
+			flattenCSVValue(cells, "", object[key])
+			fields = append(fields, map[string]string{"Field": key, "Value": cells[""]})
+		}
+	}
+	if len(fields) > 0 {
+		writeMarkdownTable(b, []string{"Field", "Value"}, fields)
+	}
+
+	heading := strings.Repeat("#", level)
+	for _, key := range keys {
+		value := object[key]
+		switch markdownKind(value) {
+		case "bullets":
+			fmt.Fprintf(b, "%s %s\n\n", heading, markdownTitle(key))
+			for _, line := range value.([]interface{}) {
+				fmt.Fprintf(b, "- %s\n", line)
+			}
+			b.WriteString("\n")
+		case "table":
+			fmt.Fprintf(b, "%s %s\n\n", heading, markdownTitle(key))
+			columns, records := tableColumns(value.([]interface{}))
+			writeMarkdownTable(b, columns, records)
+		case "section":
+			fmt.Fprintf(b, "%s %s\n\n", heading, markdownTitle(key))
+			writeMarkdownSection(b, value.(map[string]interface{}), min(level+1, 6), nil)
+		}
+	}
+}
+
+// renderMarkdown renders a tool result as a Markdown report
+func renderMarkdown(tool string, result map[string]interface{}) string {
+	var normalized map[string]interface{}
+	encoded, _ := json.Marshal(result) // Values added by the middlewares may still be structs
+	json.Unmarshal(encoded, &normalized)
+	roundFloats(normalized)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", tool)
+	for _, key := range []string{"summary", "headline"} {
+		if lead, ok := normalized[key].(string); ok && lead != "" {
+			fmt.Fprintf(&b, "%s\n\n", lead)
+			break
+		}
+	}
+	skip := map[string]bool{"summary": true, "headline": true}
+	if warnings, _ := normalized["warnings"].([]interface{}); len(warnings) == 0 {
+		skip["warnings"] = true // Present on every result; only worth a section when there are some
+	}
+	writeMarkdownSection(&b, normalized, 3, skip)
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
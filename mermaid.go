@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Mermaid diagrams are returned as text in a ```mermaid block, which many clients render:
+//
+//	gantt     - thread timelines of a few frames: one section per thread with its most
+//	            expensive scopes, and a Frames section marking where each frame starts
+//	flowchart - the call hierarchy below a function, from a hierarchical export
+//
+// Gantt charts count in dates, so times are written in microseconds as if they were
+// milliseconds (dateFormat x) and the axis (%s.%L) reads in milliseconds. Scopes without a
+// recorded start (StartMs) begin with their frame, so their bars show durations, not order.
+
+const (
+	mermaidMaxThreads   = 12 // Busiest threads drawn in a Gantt chart
+	mermaidDefaultDepth = 4
+)
+
+var (
+	mermaidTaskEscaper = strings.NewReplacer("::", ".", ":", " ", "#", "", ";", ",", "\n", " ")
+	mermaidNodeEscaper = strings.NewReplacer("\"", "#quot;", "\n", " ")
+)
+
+// mermaidLabel makes a name safe for a Gantt title, section or task, which end at a colon
+func mermaidLabel(name string) string {
+	return strings.TrimSpace(mermaidTaskEscaper.Replace(name))
+}
+
+// mermaidMicros converts milliseconds to the chart's microsecond units
+func mermaidMicros(ms float64) int64 {
+	return int64(math.Round(ms * 1000))
+}
+
+// ganttFrames picks the frames to draw: the start of the frame window when a frame range
+// was requested, otherwise the slowest frame with its neighbours
+func ganttFrames(data *FrameProData, args map[string]interface{}, maxFrames int, stages map[int]string) []FrameProFrame {
+	frames := data.Frames
+	if len(frames) <= maxFrames {
+		return frames
+	}
+	if _, ranged := args["frame_range"]; ranged {
+		return frames[:maxFrames]
+	}
+	slowest := 0
+	for i, frame := range frames {
+		if frameTimeMs(frame, stages) > frameTimeMs(frames[slowest], stages) {
+			slowest = i
+		}
+	}
+	first := min(max(slowest-maxFrames/2, 0), len(frames)-maxFrames)
+	return frames[first : first+maxFrames]
+}
+
+// mermaidGantt draws the thread timelines of frames, topN scopes per thread and frame
+func mermaidGantt(data *FrameProData, frames []FrameProFrame, topN int, stages map[int]string) (string, map[string]interface{}) {
+	type lane struct {
+		id     int
+		name   string
+		busyMs float64
+		lines  []string
+	}
+	lanes := make(map[int]*lane)
+	frameLines := []string{}
+	placed, scopes := 0, 0
+	var start float64
+	for _, frame := range frames {
+		frameMs := frameTimeMs(frame, stages)
+		frameLines = append(frameLines, fmt.Sprintf("    Frame %d (%.2fms) :f%d, %d, %d", frame.FrameNumber, frameMs, frame.FrameNumber, mermaidMicros(start), mermaidMicros(start+frameMs)))
+
+		byThread := make(map[int][]FrameProFunction)
+		for _, fn := range topFrameFunctions(frame, 0) {
+			byThread[fn.ThreadID] = append(byThread[fn.ThreadID], fn)
+		}
+		for id, functions := range byThread {
+			l, exists := lanes[id]
+			if !exists {
+				l = &lane{id: id, name: functions[0].ThreadName}
+				lanes[id] = l
+			}
+			for i, fn := range functions {
+				l.busyMs += fn.TimeMs
+				if i >= topN || fn.TimeMs <= 0 {
+					continue
+				}
+				scopes++
+				begin := start
+				if fn.StartMs != nil {
+					begin += *fn.StartMs
+					placed++
+				}
+				l.lines = append(l.lines, fmt.Sprintf("    %s %.2fms :%d, %d", mermaidLabel(fn.FunctionName), fn.TimeMs, mermaidMicros(begin), mermaidMicros(begin+math.Max(fn.TimeMs, 0.001))))
+			}
+		}
+		start += frameMs
+	}
+
+	ordered := make([]*lane, 0, len(lanes))
+	for _, l := range lanes {
+		ordered = append(ordered, l)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].busyMs != ordered[j].busyMs {
+			return ordered[i].busyMs > ordered[j].busyMs
+		}
+		return ordered[i].id < ordered[j].id
+	})
+	omitted := max(len(ordered)-mermaidMaxThreads, 0)
+	ordered = ordered[:min(len(ordered), mermaidMaxThreads)]
+	sort.SliceStable(ordered, func(i, j int) bool { return stageOrder(stages[ordered[i].id]) < stageOrder(stages[ordered[j].id]) })
+
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title %s - frames %d to %d\n", mermaidLabel(data.SessionName), frames[0].FrameNumber, frames[len(frames)-1].FrameNumber)
+	b.WriteString("    dateFormat x\n    axisFormat %s.%L ms\n    todayMarker off\n")
+	b.WriteString("    section Frames\n")
+	b.WriteString(strings.Join(frameLines, "\n") + "\n")
+	threadNames := []string{}
+	for _, l := range ordered {
+		fmt.Fprintf(&b, "    section %s\n", mermaidLabel(l.name))
+		b.WriteString(strings.Join(l.lines, "\n") + "\n")
+		threadNames = append(threadNames, l.name)
+	}
+
+	details := map[string]interface{}{
+		"frames":  len(frames),
+		"threads": threadNames,
+		"scopes":  scopes,
+		"spanMs":  start,
+	}
+	if omitted > 0 {
+		details["threadsOmitted"] = omitted
+	}
+	details["timing"] = "recorded"
+	if placed < scopes {
+		details["timing"] = "approximate" // Some scopes had no start time
+	}
+	return b.String(), details
+}
+
+// stageOrder sorts Gantt sections in pipeline order: main, render, workers, then the rest
+func stageOrder(stage string) int {
+	switch stage {
+	case stageMain:
+		return 0
+	case stageRender:
+		return 1
+	case stageWorkers:
+		return 2
+	}
+	return 3
+}
+
+// mermaidFlowchart draws call trees top-down, leaving out nodes under minPercent of the root
+func mermaidFlowchart(trees []CallTreeNode, minPercent float64) (string, int) {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	next, hidden := 0, 0
+	var walk func(node CallTreeNode) string
+	walk = func(node CallTreeNode) string {
+		id := fmt.Sprintf("n%d", next)
+		next++
+		fmt.Fprintf(&b, "    %s[\"%s<br/>%.2fms, %.1f%%\"]\n", id, mermaidNodeEscaper.Replace(node.FunctionName), node.InclusiveMs, node.PercentOfRoot)
+		more := node.TruncatedNodes
+		for _, child := range node.Children {
+			if child.PercentOfRoot < minPercent {
+				more++
+				continue
+			}
+			fmt.Fprintf(&b, "    %s --> %s\n", id, walk(child))
+		}
+		if more > 0 {
+			hidden += more
+			fmt.Fprintf(&b, "    %s -.-> %s_more[\"%d more\"]\n", id, id, more)
+		}
+		return id
+	}
+	for _, tree := range trees {
+		walk(tree)
+	}
+	return b.String(), hidden
+}
+
+func renderMermaidHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	diagram, _ := args["diagram"].(string)
+	maxFrames := 3
+	if n, ok := args["max_frames"].(float64); ok && n >= 1 {
+		maxFrames = min(int(n), 20)
+	}
+	topN := 5
+	if n, ok := args["top_n"].(float64); ok && n >= 1 {
+		topN = int(n)
+	}
+	functionName, _ := args["function"].(string)
+	threadID := 0
+	if id, ok := args["thread_id"].(float64); ok {
+		threadID = int(id)
+	}
+	maxDepth := mermaidDefaultDepth
+	if d, ok := args["max_depth"].(float64); ok && d >= 0 {
+		maxDepth = int(d)
+	}
+	minPercent := 1.0
+	if p, ok := args["min_percent"].(float64); ok && p >= 0 {
+		minPercent = p
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"diagram":     diagram,
+	}
+	var text string
+	switch diagram {
+	case "gantt":
+		if len(data.Frames) == 0 {
+			return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+		}
+		stages := threadStages(data)
+		frames := ganttFrames(data, args, maxFrames, stages)
+		var details map[string]interface{}
+		text, details = mermaidGantt(data, frames, topN, stages)
+		for key, value := range details {
+			output[key] = value
+		}
+		output["frameNumbers"] = []int{frames[0].FrameNumber, frames[len(frames)-1].FrameNumber}
+		output["summary"] = fmt.Sprintf("Gantt chart of frames %d to %d (%.2fms) across %d threads, up to %d scopes per thread and frame",
+			frames[0].FrameNumber, frames[len(frames)-1].FrameNumber, details["spanMs"], len(details["threads"].([]string)), topN)
+	case "flowchart":
+		if !isHierarchical(scopeTree(data)) {
+			return mcp.NewToolResultError("This export has no call hierarchy (no function has Children). Export with nested scopes to use flowcharts"), nil
+		}
+		var roots []FrameProFunction
+		if functionName == "" {
+			for _, fn := range scopeTree(data) { // The heaviest top-level scope
+				if (threadID == 0 || fn.ThreadID == threadID) && (len(roots) == 0 || inclusiveTimeMs(fn) > inclusiveTimeMs(roots[0])) {
+					roots = []FrameProFunction{fn}
+				}
+			}
+		} else {
+			roots = findScopes(scopeTree(data), functionName, threadID)
+		}
+		if len(roots) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Function '%s' not found in the call hierarchy", functionName)), nil
+		}
+		trees := make([]CallTreeNode, len(roots))
+		for i, root := range roots {
+			trees[i] = buildCallTree(root, inclusiveTimeMs(root), 0, maxDepth)
+		}
+		var hidden int
+		text, hidden = mermaidFlowchart(trees, minPercent)
+		output["function"] = trees[0].FunctionName
+		output["matches"] = len(trees)
+		output["maxDepth"] = maxDepth
+		output["minPercent"] = minPercent
+		output["hiddenNodes"] = hidden
+		output["summary"] = fmt.Sprintf("Call flowchart below %s (%.2fms inclusive), %d levels deep; %d nodes under %.1f%% of the root or past max_depth are folded into 'more' nodes",
+			trees[0].FunctionName, trees[0].InclusiveMs, maxDepth, hidden, minPercent)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid diagram '%s': expected 'gantt' or 'flowchart'", diagram)), nil
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.NewTextContent(string(result)),
+		mcp.NewTextContent("```mermaid\n" + text + "```\n"),
+	}}, nil
+}
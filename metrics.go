@@ -0,0 +1,612 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Derived metrics are per-frame values defined as expressions, e.g.
+//
+//	gameplay_ms = sum(category:Gameplay)
+//	sim_to_render_ratio = main_ms / render_ms
+//
+// Expressions combine numbers, + - * / and parentheses with:
+//
+//	frame_ms, main_ms, render_ms, worker_ms, gpu_ms  - frame time and per-stage thread totals
+//	sum(selector), count(selector)                   - time or calls of the matching scopes
+//	earlier metrics                                  - by name
+//
+// A selector is function:<pattern>, thread:<pattern> or category:<name>; categories are the
+// subsystem rules (see budgets.go) plus those declared with the metrics. As with tick
+// decomposition, a matching scope counts whole and its children are not searched further.
+// Division by zero gives 0. Metrics come from a project file named by FRAMEPRO_METRICS and
+// from the metrics argument, and appear in derived_metrics, check_budgets (when they have a
+// budget), analyze_trends_within_session and compare_profiles next to the built-in values.
+
+// DerivedMetric is a named per-frame expression
+type DerivedMetric struct {
+	Name       string   `json:"name"`
+	Expression string   `json:"expression"`
+	Budget     *float64 `json:"budget,omitempty"` // Upper limit per frame, in the metric's own unit
+	expr       metricExpr
+}
+
+// MetricsConfig is the FRAMEPRO_METRICS file: categories usable in selectors and metrics
+type MetricsConfig struct {
+	Categories []SubsystemBudget `json:"categories"`
+	Metrics    []DerivedMetric   `json:"metrics"`
+}
+
+// metricFrame is what expressions see of one frame
+type metricFrame struct {
+	frame   FrameProFrame
+	builtin map[string]float64
+	values  map[string]float64 // Metrics evaluated so far
+}
+
+type metricExpr func(*metricFrame) float64
+
+// metricBuiltins are the per-frame values every expression can use
+var metricBuiltins = []string{"frame_ms", "main_ms", "render_ms", "worker_ms", "gpu_ms"}
+
+// projectMetrics and projectCategories come from the FRAMEPRO_METRICS file
+var (
+	projectMetrics    []DerivedMetric
+	projectCategories []SubsystemBudget
+)
+
+// loadProjectMetrics reads the FRAMEPRO_METRICS file: a MetricsConfig or a plain list of metrics
+func loadProjectMetrics(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read metrics: %w", err)
+	}
+	var config MetricsConfig
+	if trimmed := strings.TrimSpace(string(content)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(content, &config.Metrics)
+	} else {
+		err = json.Unmarshal(content, &config)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse metrics %s: %w", path, err)
+	}
+	for i, category := range config.Categories {
+		if category.Name == "" || category.Pattern == "" {
+			return fmt.Errorf("category %d in %s needs a name and a pattern", i+1, path)
+		}
+	}
+	if projectCategories, err = compileBudgets(config.Categories); err != nil {
+		return err
+	}
+	if projectMetrics, err = compileMetrics(config.Metrics); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// metricsSchema describes the metrics parameter shared by the tools that report derived metrics
+func metricsSchema() mcp.ToolOption {
+	return mcp.WithArray("metrics",
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":       map[string]any{"type": "string"},
+				"expression": map[string]any{"type": "string"},
+				"budget":     map[string]any{"type": "number"},
+			},
+			"required": []string{"name", "expression"},
+		}),
+		mcp.Description("Derived per-frame metrics, added to those of the FRAMEPRO_METRICS file, e.g. [{\"name\": \"gameplay_ms\", \"expression\": \"sum(category:Gameplay)\", \"budget\": 4}, {\"name\": \"sim_to_render\", \"expression\": \"main_ms / render_ms\"}]. Expressions use numbers, + - * / and parentheses, frame_ms, main_ms, render_ms, worker_ms, gpu_ms, earlier metrics, and sum(...) or count(...) of function:<pattern>, thread:<pattern> or category:<name>"))
+}
+
+// parseMetrics returns the project's metrics followed by those of the metrics argument;
+// an argument metric replaces a project metric of the same name
+func parseMetrics(args map[string]interface{}) ([]DerivedMetric, error) {
+	metrics := append([]DerivedMetric(nil), projectMetrics...)
+	raw, _ := args["metrics"].([]interface{})
+	if len(raw) == 0 {
+		return metrics, nil
+	}
+	requested := []DerivedMetric{}
+	for i, entry := range raw {
+		spec, _ := entry.(map[string]interface{})
+		metric := DerivedMetric{}
+		metric.Name, _ = spec["name"].(string)
+		metric.Expression, _ = spec["expression"].(string)
+		if budget, ok := spec["budget"].(float64); ok {
+			metric.Budget = &budget
+		}
+		if metric.Name == "" || metric.Expression == "" {
+			return nil, fmt.Errorf("metric %d needs a name and an expression", i+1)
+		}
+		requested = append(requested, metric)
+	}
+	for _, metric := range requested {
+		metrics = slices.DeleteFunc(metrics, func(m DerivedMetric) bool { return m.Name == metric.Name })
+	}
+	return compileMetrics(append(metrics, requested...))
+}
+
+// compileMetrics parses every metric's expression; a metric may use the ones before it
+func compileMetrics(metrics []DerivedMetric) ([]DerivedMetric, error) {
+	known := make(map[string]bool)
+	for _, name := range metricBuiltins {
+		known[name] = true
+	}
+	for i := range metrics {
+		metric := &metrics[i]
+		if !isMetricName(metric.Name) {
+			return nil, fmt.Errorf("metric name %q must be letters, digits and underscores", metric.Name)
+		}
+		if known[metric.Name] {
+			return nil, fmt.Errorf("metric %q is defined twice or shadows a built-in value", metric.Name)
+		}
+		parser := &metricParser{input: metric.Expression, known: known}
+		expr, err := parser.parse()
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: %w", metric.Name, err)
+		}
+		metric.expr = expr
+		known[metric.Name] = true
+	}
+	return metrics, nil
+}
+
+// isMetricName reports whether name can be used as an identifier in expressions
+func isMetricName(name string) bool {
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// metricParser is a recursive descent parser for metric expressions
+type metricParser struct {
+	input string
+	pos   int
+	known map[string]bool
+}
+
+func (p *metricParser) parse() (metricExpr, error) {
+	expr, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.pos:], p.pos+1)
+	}
+	return expr, nil
+}
+
+func (p *metricParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space character, or 0 at the end
+func (p *metricParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *metricParser) sum() (metricExpr, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		a, b := left, right
+		if op == '+' {
+			left = func(f *metricFrame) float64 { return a(f) + b(f) }
+		} else {
+			left = func(f *metricFrame) float64 { return a(f) - b(f) }
+		}
+	}
+	return left, nil
+}
+
+func (p *metricParser) product() (metricExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		a, b := left, right
+		if op == '*' {
+			left = func(f *metricFrame) float64 { return a(f) * b(f) }
+		} else {
+			left = func(f *metricFrame) float64 {
+				if divisor := b(f); divisor != 0 {
+					return a(f) / divisor
+				}
+				return 0
+			}
+		}
+	}
+	return left, nil
+}
+
+func (p *metricParser) unary() (metricExpr, error) {
+	if p.peek() == '-' {
+		p.pos++
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(f *metricFrame) float64 { return -inner(f) }, nil
+	}
+	return p.primary()
+}
+
+func (p *metricParser) primary() (metricExpr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("expression ends early")
+	case c == '(':
+		p.pos++
+		inner, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at position %d", p.pos+1)
+		}
+		p.pos++
+		return inner, nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return func(*metricFrame) float64 { return value }, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] == '_' || unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+	if name == "" {
+		return nil, fmt.Errorf("unexpected %q at position %d", string(c), p.pos+1)
+	}
+	if p.peek() == '(' {
+		return p.call(name)
+	}
+	if !p.known[name] {
+		return nil, fmt.Errorf("unknown value %q: use %s or a metric defined before this one", name, strings.Join(metricBuiltins, ", "))
+	}
+	for _, builtin := range metricBuiltins {
+		if name == builtin {
+			return func(f *metricFrame) float64 { return f.builtin[name] }, nil
+		}
+	}
+	return func(f *metricFrame) float64 { return f.values[name] }, nil
+}
+
+// call parses sum(selector) or count(selector)
+func (p *metricParser) call(name string) (metricExpr, error) {
+	if name != "sum" && name != "count" {
+		return nil, fmt.Errorf("unknown function %q: expected sum or count", name)
+	}
+	p.pos++ // (
+	start, depth := p.pos, 1
+	for ; p.pos < len(p.input); p.pos++ {
+		if p.input[p.pos] == '(' {
+			depth++
+		} else if p.input[p.pos] == ')' {
+			if depth--; depth == 0 {
+				break
+			}
+		}
+	}
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("missing ) after %s(", name)
+	}
+	selector := strings.TrimSpace(p.input[start:p.pos])
+	p.pos++
+	match, err := metricSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	useCount := name == "count"
+	return func(f *metricFrame) float64 {
+		return selectScopes(f.frame.Functions, match, useCount)
+	}, nil
+}
+
+// metricSelector compiles function:<pattern>, thread:<pattern> or category:<name>
+func metricSelector(selector string) (func(FrameProFunction) bool, error) {
+	kind, value, found := strings.Cut(selector, ":")
+	value = strings.TrimSpace(value)
+	if !found || value == "" {
+		return nil, fmt.Errorf("invalid selector %q: expected function:<pattern>, thread:<pattern> or category:<name>", selector)
+	}
+	switch strings.TrimSpace(strings.ToLower(kind)) {
+	case "function":
+		re, err := compileFunctionPattern(value, false)
+		if err != nil {
+			return nil, err
+		}
+		return func(fn FrameProFunction) bool { return re.MatchString(fn.FunctionName) }, nil
+	case "thread":
+		re, err := compileFunctionPattern(value, false)
+		if err != nil {
+			return nil, err
+		}
+		return func(fn FrameProFunction) bool { return re.MatchString(fn.ThreadName) }, nil
+	case "category", "subsystem":
+		categories, err := metricCategories()
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for i, category := range categories {
+			if strings.EqualFold(category.Name, value) {
+				return func(fn FrameProFunction) bool { return matchBudget(categories, fn.FunctionName) == i }, nil
+			}
+			names = append(names, category.Name)
+		}
+		return nil, fmt.Errorf("unknown category %q: expected one of %s, or declare it under categories in the FRAMEPRO_METRICS file", value, strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("invalid selector %q: expected function:, thread: or category:", selector)
+}
+
+// metricCategories are the project's categories followed by the built-in subsystem rules
+func metricCategories() ([]SubsystemBudget, error) {
+	categories := append([]SubsystemBudget(nil), projectCategories...)
+	for _, budget := range defaultBudgets {
+		categories = append(categories, SubsystemBudget{Name: budget.Name, Pattern: budget.Pattern})
+	}
+	return compileBudgets(categories)
+}
+
+// selectScopes sums the time (or calls) of the outermost scopes matching a selector
+func selectScopes(functions []FrameProFunction, match func(FrameProFunction) bool, useCount bool) float64 {
+	var total float64
+	for _, fn := range functions {
+		if match(fn) {
+			if useCount {
+				total += float64(fn.Count)
+			} else {
+				total += fn.TimeMs
+			}
+			continue
+		}
+		total += selectScopes(fn.Children, match, useCount)
+	}
+	return total
+}
+
+// evaluateMetrics returns each metric's value in every frame, indexed [metric][frame].
+// Without per-frame data the capture's average frame stands in as a single frame.
+func evaluateMetrics(data *FrameProData, metrics []DerivedMetric) ([][]float64, []FrameProFrame) {
+	frames := data.Frames
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+	}
+	stages := threadStages(data)
+	values := make([][]float64, len(metrics))
+	for i := range values {
+		values[i] = make([]float64, len(frames))
+	}
+	for f, frame := range frames {
+		current := &metricFrame{frame: frame, builtin: map[string]float64{"frame_ms": frameTimeMs(frame, stages)}, values: make(map[string]float64)}
+		for id, total := range frameThreadTotals(frame) {
+			switch stages[id] {
+			case stageMain:
+				current.builtin["main_ms"] += total.TimeMs
+			case stageRender:
+				current.builtin["render_ms"] += total.TimeMs
+			case stageWorkers:
+				current.builtin["worker_ms"] += total.TimeMs
+			}
+		}
+		for _, fn := range frame.Functions {
+			if gpuQueueKind(fn) != "" {
+				current.builtin["gpu_ms"] += fn.TimeMs
+			}
+		}
+		for i, metric := range metrics {
+			values[i][f] = metric.expr(current)
+			current.values[metric.Name] = values[i][f]
+		}
+	}
+	return values, frames
+}
+
+// metricBudgetChecks checks the metrics that have a budget like subsystem budgets
+func metricBudgetChecks(data *FrameProData, metrics []DerivedMetric, topN int) []BudgetCheck {
+	values, frames := evaluateMetrics(data, metrics)
+	checks := []BudgetCheck{}
+	for i, metric := range metrics {
+		if metric.Budget == nil || !ruleEnabled("metric/"+metric.Name) {
+			continue
+		}
+		check := BudgetCheck{Name: metric.Name, Pattern: metric.Expression, BudgetMs: *metric.Budget, WorstFrames: []BudgetBreach{}, Culprits: []BudgetCulprit{}}
+		stats := computeFrameTimePercentiles(values[i])
+		check.AvgMs, check.P95Ms = stats.AvgFrameTimeMs, stats.P95Ms
+		breaches := []BudgetBreach{}
+		for f, value := range values[i] {
+			check.MaxMs = max(check.MaxMs, value)
+			if value > check.BudgetMs {
+				breaches = append(breaches, BudgetBreach{Frame: frames[f].FrameNumber, TimeMs: value, OverMs: value - check.BudgetMs, TopFunctions: []SpikeCulprit{}})
+			}
+		}
+		check.FramesOver = len(breaches)
+		check.PercentOver = float64(len(breaches)) / float64(len(frames)) * 100
+		check.Status = budgetStatus(check)
+		sort.Slice(breaches, func(a, b int) bool { return breaches[a].OverMs > breaches[b].OverMs })
+		check.WorstFrames = breaches[:min(len(breaches), topN)]
+		if len(data.Frames) > 0 {
+			byFrame := make(map[int]float64)
+			for f, value := range values[i] {
+				byFrame[frames[f].FrameNumber] = value
+			}
+			check.Evidence = frameEvidence(data, func(frame FrameProFrame) float64 { return byFrame[frame.FrameNumber] }, check.BudgetMs)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// MetricChange is a derived metric's average in two captures
+type MetricChange struct {
+	Name          string  `json:"name"`
+	Expression    string  `json:"expression"`
+	Baseline      float64 `json:"baseline"`
+	Current       float64 `json:"current"`
+	Diff          float64 `json:"diff"`
+	PercentChange float64 `json:"percentChange"`
+}
+
+// compareMetrics averages every metric over the frames of both captures
+func compareMetrics(baseline, current *FrameProData, metrics []DerivedMetric) []MetricChange {
+	baselineValues, _ := evaluateMetrics(baseline, metrics)
+	currentValues, _ := evaluateMetrics(current, metrics)
+	changes := []MetricChange{}
+	for i, metric := range metrics {
+		change := MetricChange{Name: metric.Name, Expression: metric.Expression}
+		change.Baseline, _ = meanStdDev(baselineValues[i])
+		change.Current, _ = meanStdDev(currentValues[i])
+		change.Diff = change.Current - change.Baseline
+		if change.Baseline != 0 {
+			change.PercentChange = change.Diff / math.Abs(change.Baseline) * 100
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// MetricSummary is a derived metric's distribution over the frames
+type MetricSummary struct {
+	Name        string   `json:"name"`
+	Expression  string   `json:"expression"`
+	Avg         float64  `json:"avg"`
+	Min         float64  `json:"min"`
+	Median      float64  `json:"median"`
+	P95         float64  `json:"p95"`
+	Max         float64  `json:"max"`
+	Budget      *float64 `json:"budget,omitempty"`
+	FramesOver  int      `json:"framesOverBudget,omitempty"`
+	PercentOver float64  `json:"percentFramesOverBudget,omitempty"`
+}
+
+// summarizeMetrics describes every metric's values over the frames
+func summarizeMetrics(metrics []DerivedMetric, values [][]float64) []MetricSummary {
+	summaries := []MetricSummary{}
+	for i, metric := range metrics {
+		sorted := append([]float64(nil), values[i]...)
+		sort.Float64s(sorted)
+		summary := MetricSummary{Name: metric.Name, Expression: metric.Expression, Budget: metric.Budget,
+			Min: sorted[0], Median: percentile(sorted, 50), P95: percentile(sorted, 95), Max: sorted[len(sorted)-1]}
+		summary.Avg, _ = meanStdDev(values[i])
+		if metric.Budget != nil {
+			for _, value := range values[i] {
+				if value > *metric.Budget {
+					summary.FramesOver++
+				}
+			}
+			summary.PercentOver = float64(summary.FramesOver) / float64(len(values[i])) * 100
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func derivedMetricsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	metrics, err := parseMetrics(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(metrics) == 0 {
+		return mcp.NewToolResultError("No derived metrics declared: pass metrics ([{\"name\": \"gameplay_ms\", \"expression\": \"sum(category:Gameplay)\"}, ...]) or point FRAMEPRO_METRICS at a JSON file with them"), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	values, frames := evaluateMetrics(data, metrics)
+	summaries := summarizeMetrics(metrics, values)
+
+	analysis := []string{}
+	parts := []string{}
+	over := 0
+	for _, summary := range summaries {
+		parts = append(parts, fmt.Sprintf("%s %.3g", summary.Name, summary.Avg))
+		if summary.FramesOver > 0 {
+			over++
+			analysis = append(analysis, fmt.Sprintf("%s exceeded its budget of %g in %d of %d frames (%.0f%%, worst %.3g)",
+				summary.Name, *summary.Budget, summary.FramesOver, len(frames), summary.PercentOver, summary.Max))
+		}
+	}
+	summary := fmt.Sprintf("Average per frame: %s", strings.Join(parts, ", "))
+	if over > 0 {
+		summary += fmt.Sprintf("; %d metrics over budget", over)
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"framesAnalyzed": len(frames),
+		"metrics":        summaries,
+		"analysis":       analysis,
+		"summary":        summary,
+	}
+	if len(data.Frames) == 0 {
+		addWarning(output, warnNoFrameData, "No per-frame data; metrics were evaluated on the average frame")
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Metrics exports summarize a capture into a handful of numbers for dashboards that track
+// profiles over time: frame time percentiles, FPS, the profile score of score_profile with
+// its sub-scores, and the time per frame of every subsystem (see subsystem_breakdown, using
+// self times when the export has them so nested scopes don't count twice).
+//
+//	prometheus  text exposition format, PUT to a Pushgateway under
+//	            /metrics/job/<job>/<label>/<value>..., so each push replaces the previous
+//	            one with the same labels; the session name is not a grouping label
+//	influx      line protocol: a <job> line with the summary fields and a <job>_subsystem
+//	            line per subsystem, tagged with the session and labels, POSTed to the
+//	            write URL as given (/write?db=... or /api/v2/write?org=...&bucket=...)
+
+const (
+	metricsFormatPrometheus = "prometheus"
+	metricsFormatInflux     = "influx"
+	metricsDefaultJob       = "framepro"
+)
+
+var (
+	metricLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// Labels the exported series use themselves
+	metricReservedLabels = map[string]bool{"job": true, "session": true, "stat": true, "component": true, "subsystem": true}
+
+	promLabelEscaper   = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	influxTagEscaper   = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", " ")
+	influxTableEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", " ")
+)
+
+// MetricValue is one labeled number of an exported metric
+type MetricValue struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// SessionMetrics is the summary of a capture that metrics exports send
+type SessionMetrics struct {
+	Frames     int           `json:"frames"`
+	FrameTime  []MetricValue `json:"frameTimeMs"` // avg, p50, p95, p99, max
+	FPS        []MetricValue `json:"fps"`         // avg, 1pct_low
+	Score      float64       `json:"score"`
+	SubScores  []MetricValue `json:"subScores"`
+	Subsystems []MetricValue `json:"subsystemMsPerFrame"`
+}
+
+// sessionMetrics computes the exported summary of a capture
+func sessionMetrics(data *FrameProData, targetFPS float64, rules []SubsystemBudget) (SessionMetrics, error) {
+	m := SessionMetrics{Frames: len(data.Frames)}
+	if len(data.Frames) > 0 {
+		times := frameTimes(data)
+		stats := computeFrameTimePercentiles(times)
+		maxMs := 0.0
+		for _, ms := range times {
+			maxMs = math.Max(maxMs, ms)
+		}
+		m.FrameTime = []MetricValue{{"avg", stats.AvgFrameTimeMs}, {"p50", stats.P50Ms}, {"p95", stats.P95Ms}, {"p99", stats.P99Ms}, {"max", maxMs}}
+		m.FPS = []MetricValue{{"avg", stats.AvgFPS}, {"1pct_low", stats.OnePercentLowFPS}}
+	} else {
+		frameMs := averageFrameMs(data)
+		m.FrameTime = []MetricValue{{"avg", frameMs}}
+		m.FPS = []MetricValue{{"avg", msToFPS(frameMs)}}
+	}
+
+	scores, score, err := profileScore(data, targetFPS, defaultScoreWeights)
+	if err != nil {
+		return m, err
+	}
+	m.Score = score
+	for _, sub := range scores {
+		m.SubScores = append(m.SubScores, MetricValue{sub.Name, sub.Score})
+	}
+
+	rankBy := "total"
+	if hasSelfTimes(data.Functions) {
+		rankBy = "self"
+	}
+	totals := make(map[string]float64)
+	for _, fn := range aggregateFrame(data).Functions {
+		name, _ := subsystemOf(rules, fn.FunctionName, true)
+		totals[name] += perFrameRankingMs(fn, rankBy)
+	}
+	for name, ms := range totals {
+		m.Subsystems = append(m.Subsystems, MetricValue{name, ms})
+	}
+	sort.Slice(m.Subsystems, func(i, j int) bool {
+		if m.Subsystems[i].Value != m.Subsystems[j].Value {
+			return m.Subsystems[i].Value > m.Subsystems[j].Value
+		}
+		return m.Subsystems[i].Name < m.Subsystems[j].Name
+	})
+
+	// Four decimals are plenty for a dashboard and keep float noise out of the payload
+	m.Score = roundMetric(m.Score)
+	for _, values := range [][]MetricValue{m.FrameTime, m.FPS, m.SubScores, m.Subsystems} {
+		for i := range values {
+			values[i].Value = roundMetric(values[i].Value)
+		}
+	}
+	return m, nil
+}
+
+// roundMetric rounds an exported value to four decimals
+func roundMetric(value float64) float64 {
+	return math.Round(value*1e4) / 1e4
+}
+
+// metricNumber formats a value for either format, without exponents for ordinary sizes
+func metricNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// prometheusPayload renders the metrics in the text exposition format
+func prometheusPayload(m SessionMetrics) string {
+	var b strings.Builder
+	gauge := func(name, help, label string, values []MetricValue) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "# HELP framepro_%s %s\n# TYPE framepro_%s gauge\n", name, help, name)
+		for _, v := range values {
+			if label == "" {
+				fmt.Fprintf(&b, "framepro_%s %s\n", name, metricNumber(v.Value))
+			} else {
+				fmt.Fprintf(&b, "framepro_%s{%s=\"%s\"} %s\n", name, label, promLabelEscaper.Replace(v.Name), metricNumber(v.Value))
+			}
+		}
+	}
+	gauge("frames", "Frames in the capture.", "", []MetricValue{{"", float64(m.Frames)}})
+	gauge("frame_time_ms", "Frame time statistics in milliseconds.", "stat", m.FrameTime)
+	gauge("fps", "Frames per second.", "stat", m.FPS)
+	gauge("score", "Profile score from 0 to 100.", "", []MetricValue{{"", m.Score}})
+	gauge("subscore", "Profile score components from 0 to 100.", "component", m.SubScores)
+	gauge("subsystem_ms_per_frame", "Time per frame of each subsystem in milliseconds.", "subsystem", m.Subsystems)
+	return b.String()
+}
+
+// influxPayload renders the metrics as line protocol at one timestamp
+func influxPayload(m SessionMetrics, job, session string, labels map[string]string, at time.Time) string {
+	tags := ",session=" + influxTagEscaper.Replace(session)
+	for _, key := range sortedKeys(labels) {
+		if labels[key] != "" { // Line protocol has no empty tag values
+			tags += "," + key + "=" + influxTagEscaper.Replace(labels[key])
+		}
+	}
+	stamp := strconv.FormatInt(at.UnixNano(), 10)
+
+	fields := []string{fmt.Sprintf("frames=%di", m.Frames)}
+	for _, v := range m.FrameTime {
+		fields = append(fields, fmt.Sprintf("frame_time_%s_ms=%s", v.Name, metricNumber(v.Value)))
+	}
+	for _, v := range m.FPS {
+		fields = append(fields, fmt.Sprintf("fps_%s=%s", v.Name, metricNumber(v.Value)))
+	}
+	fields = append(fields, "score="+metricNumber(m.Score))
+	for _, v := range m.SubScores {
+		fields = append(fields, fmt.Sprintf("subscore_%s=%s", v.Name, metricNumber(v.Value)))
+	}
+	table := influxTableEscaper.Replace(job)
+	lines := []string{fmt.Sprintf("%s%s %s %s", table, tags, strings.Join(fields, ","), stamp)}
+	for _, v := range m.Subsystems {
+		lines = append(lines, fmt.Sprintf("%s_subsystem%s,subsystem=%s ms_per_frame=%s %s", table, tags, influxTagEscaper.Replace(v.Name), metricNumber(v.Value), stamp))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// sortedKeys returns the keys of a string map in order, for stable payloads and URLs
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pushgatewayURL appends the grouping key to a Pushgateway URL. Values that are empty or
+// contain a slash use the base64 form the Pushgateway accepts for them.
+func pushgatewayURL(endpoint, job string, labels map[string]string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q: expected an http(s) URL such as http://localhost:9091", endpoint)
+	}
+	segment := func(name, value string) string {
+		if value == "" || strings.Contains(value, "/") {
+			encoded := base64.URLEncoding.EncodeToString([]byte(value))
+			if encoded == "" {
+				encoded = "="
+			}
+			return "/" + name + "@base64/" + encoded
+		}
+		return "/" + name + "/" + url.PathEscape(value)
+	}
+	path := strings.TrimSuffix(parsed.EscapedPath(), "/") + "/metrics" + segment("job", job)
+	for _, key := range sortedKeys(labels) {
+		path += segment(key, labels[key])
+	}
+	parsed.RawQuery = ""
+	return parsed.Scheme + "://" + parsed.Host + path, nil
+}
+
+// influxWriteURL checks an InfluxDB write URL, which names the database or bucket itself
+func influxWriteURL(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.Path == "" || parsed.Path == "/" {
+		return "", fmt.Errorf("invalid endpoint %q: expected the write URL, such as http://localhost:8086/write?db=perf or http://localhost:8086/api/v2/write?org=studio&bucket=perf", endpoint)
+	}
+	return parsed.String(), nil
+}
+
+func exportMetricsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	outputPath, _ := args["output_path"].(string)
+	endpoint, _ := args["endpoint"].(string)
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = metricsFormatPrometheus
+	}
+	if format != metricsFormatPrometheus && format != metricsFormatInflux {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid format '%s': expected 'prometheus' or 'influx'", format)), nil
+	}
+	job, _ := args["job"].(string)
+	if job == "" {
+		job = metricsDefaultJob
+	}
+	headers, err := parseHeaders(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	labels := make(map[string]string)
+	if raw, ok := args["labels"].(map[string]interface{}); ok {
+		for key, value := range raw {
+			text, isText := value.(string)
+			if !isText || !metricLabelPattern.MatchString(key) || metricReservedLabels[key] || strings.HasPrefix(key, "__") {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid label '%s': labels are string values under names like build or platform (letters, digits and underscores; not job, session, stat, component or subsystem)", key)), nil
+			}
+			labels[key] = text
+		}
+	}
+	timestamp := time.Now()
+	if text, _ := args["timestamp"].(string); text != "" {
+		parsed, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid timestamp %q: expected RFC 3339, e.g. 2025-10-09T03:33:23Z", text)), nil
+		}
+		timestamp = parsed
+	}
+	rules, err := parseSubsystems(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if endpoint != "" {
+		if format == metricsFormatPrometheus {
+			endpoint, err = pushgatewayURL(endpoint, job, labels)
+		} else {
+			endpoint, err = influxWriteURL(endpoint)
+		}
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	dryRun := isDryRun(args)
+	if outputPath != "" || endpoint == "" {
+		ext := ".prom"
+		if format == metricsFormatInflux {
+			ext = ".influx"
+		}
+		var err error
+		outputPath, err = resolveOutputPath(outputPath, outputStem(filePath), ext, dryRun)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, data)
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	metrics, err := sessionMetrics(data, targetFPS, rules)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var payload, contentType, method string
+	if format == metricsFormatPrometheus {
+		payload, contentType, method = prometheusPayload(metrics), "text/plain; version=0.0.4", http.MethodPut
+	} else {
+		payload, contentType, method = influxPayload(metrics, job, data.SessionName, labels, timestamp), "text/plain; charset=utf-8", http.MethodPost
+	}
+
+	p95 := metrics.FrameTime[0].Value
+	for _, v := range metrics.FrameTime {
+		if v.Name == "p95" {
+			p95 = v.Value
+		}
+	}
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"format":          format,
+		"job":             job,
+		"labels":          labels,
+		"targetFPS":       targetFPS,
+		"targetFPSSource": targetSource,
+		"metrics":         metrics,
+	}
+	summary := fmt.Sprintf("Score %.0f, p95 frame time %.2fms and %d subsystems as %s metrics", metrics.Score, p95, len(metrics.Subsystems), format)
+	if metrics.Frames == 0 {
+		summary = fmt.Sprintf("Score %.0f, average frame time %.2fms and %d subsystems as %s metrics (no per-frame data for percentiles)", metrics.Score, p95, len(metrics.Subsystems), format)
+	}
+	if format == metricsFormatInflux {
+		output["timestamp"] = timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	if outputPath != "" {
+		write, err := writeFile(outputPath, []byte(payload), dryRun)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		attachWrites(output, []FileWrite{write}, dryRun)
+		output["outputPath"] = outputPath
+		summary = fmt.Sprintf("%s %s: %s", wroteVerb(dryRun), outputPath, summary)
+	}
+	if endpoint != "" {
+		output["endpoint"] = endpoint
+		if dryRun {
+			summary += fmt.Sprintf("; would %s them to %s", method, endpoint)
+		} else {
+			if err := sendHTTP(ctx, method, endpoint, contentType, headers, []byte(payload)); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to send metrics to %s: %v", endpoint, err)), nil
+			}
+			summary += fmt.Sprintf("; sent to %s", endpoint)
+		}
+	}
+	output["summary"] = summary
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
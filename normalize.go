@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Normalized hotspots express each function's cost as a percentage of a frame instead of
+// absolute milliseconds, so captures with different frame counts and durations compare
+// directly. The ranking metric (total, self or inclusive) is converted to time per frame
+// with the function's own average, then divided by either the average frame time, which
+// the main thread spans, or the function's own thread's time per frame under the same metric.
+
+// Normalization modes for find_hotspots
+const (
+	normalizeNone       = "none"
+	normalizeMainThread = "main_thread"
+	normalizeOwnThread  = "own_thread"
+)
+
+// perFrameRankingMs converts a function's ranking metric to milliseconds per frame; fn
+// comes from aggregateFrame, so TimeMs is its total time per frame
+func perFrameRankingMs(fn FrameProFunction, rankBy string) float64 {
+	if fn.TotalTimeMs <= 0 {
+		return 0
+	}
+	return fn.TimeMs * rankingTimeMs(fn, rankBy) / fn.TotalTimeMs
+}
+
+// averageFrameMs is the mean frame time, estimated from the average frame without per-frame data
+func averageFrameMs(data *FrameProData) float64 {
+	if len(data.Frames) > 0 {
+		mean, _ := meanStdDev(frameTimes(data))
+		return mean
+	}
+	return frameTimeMs(aggregateFrame(data), threadStages(data))
+}
+
+// hotspotShares returns each function's percentage of the normalization base, keyed by functionKey
+func hotspotShares(data *FrameProData, rankBy, normalize string) map[string]float64 {
+	average := aggregateFrame(data)
+	bases := make(map[int]float64)
+	if normalize == normalizeMainThread {
+		frameMs := averageFrameMs(data)
+		for _, fn := range average.Functions {
+			bases[fn.ThreadID] = frameMs
+		}
+	} else {
+		for _, fn := range average.Functions {
+			bases[fn.ThreadID] += perFrameRankingMs(fn, rankBy)
+		}
+	}
+
+	shares := make(map[string]float64)
+	for _, fn := range average.Functions {
+		if base := bases[fn.ThreadID]; base > 0 {
+			shares[functionKey(fn)] = perFrameRankingMs(fn, rankBy) / base * 100
+		}
+	}
+	return shares
+}
+
+// attachHotspotShares adds the percentage to entries built by hotspotAnalysis
+func attachHotspotShares(entries []map[string]interface{}, shares map[string]float64, normalize string) {
+	field := "percentOfFrame"
+	if normalize == normalizeOwnThread {
+		field = "percentOfThread"
+	}
+	for _, entry := range entries {
+		entry[field] = shares[fmt.Sprintf("%s:%d", entry["functionName"], entry["threadId"])]
+	}
+}
+
+// summarizeHotspotShares is summarizeHotspots in percentages instead of milliseconds
+func summarizeHotspotShares(hotspots []FrameProFunction, shares map[string]float64, normalize, rankBy string) string {
+	if len(hotspots) == 0 {
+		return "No functions recorded"
+	}
+	base := "of frame time"
+	if normalize == normalizeOwnThread {
+		base = "of its thread"
+	}
+	parts := []string{}
+	for i, fn := range hotspots {
+		if i == 3 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s on %s (%.1f%% %s, %s time)", fn.FunctionName, fn.ThreadName, shares[functionKey(fn)], base, rankBy))
+	}
+	return "Top hotspots: " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// OpenTelemetry exports turn a capture into OTLP traces in the OTLP/JSON encoding: one
+// trace per frame with a root span for the frame, a span per thread under it and the
+// thread's scopes (with their children) under that. Scopes with a recorded start (StartMs)
+// keep it; the rest run back to back from the start of their frame, as in speedscope
+// exports. The first frame starts at start_time, by default chosen so that the last frame
+// ends at the time of the export, where trace search finds it. Trace and span IDs are
+// derived from the session name, so exporting the same capture twice gives the same IDs.
+// With an endpoint the traces are POSTed to its /v1/traces in batches of otelBatchSpans.
+
+const (
+	otelBatchSpans  = 5000
+	otelSendTimeout = 30 * time.Second
+	otelSpanKind    = 1 // SPAN_KIND_INTERNAL
+)
+
+// OTLPTraces is an OTLP/JSON ExportTraceServiceRequest
+type OTLPTraces struct {
+	ResourceSpans []OTLPResourceSpans `json:"resourceSpans"`
+}
+
+// OTLPResourceSpans is the spans of one resource (the capture)
+type OTLPResourceSpans struct {
+	Resource   OTLPResource     `json:"resource"`
+	ScopeSpans []OTLPScopeSpans `json:"scopeSpans"`
+}
+
+// OTLPResource describes where spans come from
+type OTLPResource struct {
+	Attributes []OTLPAttribute `json:"attributes"`
+}
+
+// OTLPScopeSpans is the spans produced by one instrumentation scope
+type OTLPScopeSpans struct {
+	Scope OTLPScope  `json:"scope"`
+	Spans []OTLPSpan `json:"spans"`
+}
+
+// OTLPScope names the instrumentation that produced spans
+type OTLPScope struct {
+	Name string `json:"name"`
+}
+
+// OTLPSpan is one span; IDs are hex and times are nanoseconds since the Unix epoch
+type OTLPSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []OTLPAttribute `json:"attributes,omitempty"`
+}
+
+// OTLPAttribute is a key with a string, integer or double value
+type OTLPAttribute struct {
+	Key   string    `json:"key"`
+	Value OTLPValue `json:"value"`
+}
+
+// OTLPValue holds one of the attribute value kinds
+type OTLPValue struct {
+	String *string  `json:"stringValue,omitempty"`
+	Int    *string  `json:"intValue,omitempty"` // int64 as a string, as OTLP/JSON encodes it
+	Double *float64 `json:"doubleValue,omitempty"`
+}
+
+// otelString, otelInt and otelDouble build attributes
+func otelString(key, value string) OTLPAttribute {
+	return OTLPAttribute{Key: key, Value: OTLPValue{String: &value}}
+}
+
+func otelInt(key string, value int) OTLPAttribute {
+	text := fmt.Sprint(value)
+	return OTLPAttribute{Key: key, Value: OTLPValue{Int: &text}}
+}
+
+func otelDouble(key string, value float64) OTLPAttribute {
+	return OTLPAttribute{Key: key, Value: OTLPValue{Double: &value}}
+}
+
+// otelExporter lays scopes out as spans
+type otelExporter struct {
+	seed   uint64
+	spans  int
+	base   time.Time
+	frames [][]OTLPSpan // Spans by frame, so batches keep traces whole
+}
+
+// spanID returns the next span ID
+func (e *otelExporter) spanID() string {
+	e.spans++
+	return fmt.Sprintf("%016x", e.seed^uint64(e.spans)*0x9e3779b97f4a7c15)
+}
+
+// nanos converts a time in the capture to nanoseconds since the epoch
+func (e *otelExporter) nanos(ms float64) string {
+	return fmt.Sprint(e.base.Add(time.Duration(ms * float64(time.Millisecond))).UnixNano())
+}
+
+// addScope adds a scope at a time and its children back to back inside it, and returns its end
+func (e *otelExporter) addScope(spans *[]OTLPSpan, traceID, parent string, fn FrameProFunction, at float64) float64 {
+	end := at + fn.TimeMs
+	id := e.spanID()
+	*spans = append(*spans, OTLPSpan{
+		TraceID: traceID, SpanID: id, ParentSpanID: parent, Name: fn.FunctionName, Kind: otelSpanKind,
+		Start: e.nanos(at), End: e.nanos(end),
+		Attributes: []OTLPAttribute{
+			otelString("code.function", fn.FunctionName),
+			otelString("thread.name", fn.ThreadName),
+			otelInt("thread.id", fn.ThreadID),
+			otelInt("framepro.count", fn.Count),
+		},
+	})
+	child := at
+	for _, c := range fn.Children {
+		if c.StartMs != nil && fn.StartMs != nil {
+			e.addScope(spans, traceID, id, c, at-*fn.StartMs+*c.StartMs)
+			continue
+		}
+		c.TimeMs = min(c.TimeMs, end-child) // Children that overrun their parent are clipped to it
+		if c.TimeMs > 0 {
+			child = e.addScope(spans, traceID, id, c, child)
+		}
+	}
+	return end
+}
+
+// otelFrameLength is how long a frame lasts: its frame time or its busiest thread, whichever is longer
+func otelFrameLength(frame FrameProFrame, stages map[int]string) float64 {
+	length := frameTimeMs(frame, stages)
+	for _, total := range frameThreadTotals(frame) {
+		length = max(length, total.TimeMs)
+	}
+	return length
+}
+
+// build converts frames into one trace per frame
+func (e *otelExporter) build(data *FrameProData, frames []FrameProFrame) {
+	stages := threadStages(data)
+	at := 0.0
+	for _, frame := range frames {
+		length := otelFrameLength(frame, stages)
+		traceID := fmt.Sprintf("%016x%016x", e.seed, uint64(frame.FrameNumber)+1)
+		frameID := e.spanID()
+		spans := []OTLPSpan{{
+			TraceID: traceID, SpanID: frameID, Name: fmt.Sprintf("Frame %d", frame.FrameNumber), Kind: otelSpanKind,
+			Start: e.nanos(at), End: e.nanos(at + length),
+			Attributes: []OTLPAttribute{
+				otelInt("framepro.frame", frame.FrameNumber),
+				otelDouble("framepro.frame_time_ms", frameTimeMs(frame, stages)),
+			},
+		}}
+
+		threadSpans := make(map[int]string)
+		cursor := make(map[int]float64)
+		for _, fn := range frame.Functions {
+			if fn.TimeMs <= 0 {
+				continue
+			}
+			parent, exists := threadSpans[fn.ThreadID]
+			if !exists {
+				parent = e.spanID()
+				threadSpans[fn.ThreadID] = parent
+				spans = append(spans, OTLPSpan{
+					TraceID: traceID, SpanID: parent, ParentSpanID: frameID, Name: fn.ThreadName, Kind: otelSpanKind,
+					Start: e.nanos(at), End: e.nanos(at + length),
+					Attributes: []OTLPAttribute{otelString("thread.name", fn.ThreadName), otelInt("thread.id", fn.ThreadID)},
+				})
+			}
+			if fn.StartMs != nil {
+				e.addScope(&spans, traceID, parent, fn, at+*fn.StartMs)
+				continue
+			}
+			cursor[fn.ThreadID] = e.addScope(&spans, traceID, parent, fn, at+cursor[fn.ThreadID]) - at
+		}
+		e.frames = append(e.frames, spans)
+		at += length
+	}
+}
+
+// batches splits the spans into OTLP requests of whole frames, about otelBatchSpans spans each
+func (e *otelExporter) batches(resource OTLPResource) []OTLPTraces {
+	batches := []OTLPTraces{}
+	var spans []OTLPSpan
+	flush := func() {
+		if len(spans) > 0 {
+			batches = append(batches, OTLPTraces{ResourceSpans: []OTLPResourceSpans{{
+				Resource:   resource,
+				ScopeSpans: []OTLPScopeSpans{{Scope: OTLPScope{Name: "framepro-mcp"}, Spans: spans}},
+			}}})
+			spans = nil
+		}
+	}
+	for _, frame := range e.frames {
+		if len(spans) > 0 && len(spans)+len(frame) > otelBatchSpans {
+			flush()
+		}
+		spans = append(spans, frame...)
+	}
+	flush()
+	return batches
+}
+
+// otlpTracesURL adds the OTLP/HTTP traces path to an endpoint given without one
+func otlpTracesURL(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q: expected an http(s) URL such as http://localhost:4318", endpoint)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = "/v1/traces"
+	}
+	return parsed.String(), nil
+}
+
+// sendHTTP sends one request body to an export endpoint and fails on any non-2xx status
+func sendHTTP(ctx context.Context, method, endpoint, contentType string, headers map[string]string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, otelSendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// parseHeaders reads the extra HTTP headers of an export, such as Authorization
+func parseHeaders(args map[string]interface{}) (map[string]string, error) {
+	headers := make(map[string]string)
+	raw, _ := args["headers"].(map[string]interface{})
+	for key, value := range raw {
+		text, isText := value.(string)
+		if !isText {
+			return nil, fmt.Errorf("header %q must be a string", key)
+		}
+		headers[key] = text
+	}
+	return headers, nil
+}
+
+func exportOTelHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	outputPath, _ := args["output_path"].(string)
+	endpoint, _ := args["endpoint"].(string)
+	serviceName, _ := args["service_name"].(string)
+	if serviceName == "" {
+		serviceName = "framepro"
+	}
+	headers, err := parseHeaders(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var startTime time.Time
+	if text, _ := args["start_time"].(string); text != "" {
+		parsed, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid start_time %q: expected RFC 3339, e.g. 2025-10-09T03:33:23Z", text)), nil
+		}
+		startTime = parsed
+	}
+	if endpoint != "" {
+		var err error
+		if endpoint, err = otlpTracesURL(endpoint); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	dryRun := isDryRun(args)
+	if outputPath != "" || endpoint == "" {
+		var err error
+		outputPath, err = resolveOutputPath(outputPath, outputStem(filePath), ".otlp.json", dryRun)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	frames := data.Frames
+	analysis := []string{}
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+		analysis = append(analysis, "No per-frame data: exported the average frame as one trace")
+	}
+	stages := threadStages(data)
+	var length float64
+	for _, frame := range frames {
+		length += otelFrameLength(frame, stages)
+	}
+	if startTime.IsZero() {
+		startTime = time.Now().Add(-time.Duration(length * float64(time.Millisecond)))
+	}
+	seed := fnv.New64a()
+	seed.Write([]byte(data.SessionName))
+	e := &otelExporter{seed: seed.Sum64() | 1, base: startTime}
+	e.build(data, frames)
+
+	resource := OTLPResource{Attributes: []OTLPAttribute{
+		otelString("service.name", serviceName),
+		otelString("framepro.session", data.SessionName),
+		otelString("framepro.file", filePath),
+	}}
+	batches := e.batches(resource)
+	spans := 0
+	for _, frame := range e.frames {
+		spans += len(frame)
+	}
+
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"serviceName": serviceName,
+		"traces":      len(e.frames),
+		"spans":       spans,
+		"startTime":   startTime.UTC().Format(time.RFC3339Nano),
+		"durationMs":  length,
+	}
+	summary := fmt.Sprintf("%d frames as %d traces with %d spans", len(e.frames), len(e.frames), spans)
+	if outputPath != "" {
+		merged := OTLPTraces{ResourceSpans: []OTLPResourceSpans{}}
+		for _, batch := range batches {
+			merged.ResourceSpans = append(merged.ResourceSpans, batch.ResourceSpans...)
+		}
+		content, err := json.Marshal(merged)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to encode OTLP traces: %v", err)), nil
+		}
+		write, err := writeFile(outputPath, content, dryRun)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		attachWrites(output, []FileWrite{write}, dryRun)
+		output["outputPath"] = outputPath
+		summary = fmt.Sprintf("%s %s: %s", wroteVerb(dryRun), outputPath, summary)
+	}
+	if endpoint != "" {
+		output["endpoint"] = endpoint
+		output["requests"] = len(batches)
+		if dryRun {
+			summary += fmt.Sprintf("; would send them to %s in %d requests", endpoint, len(batches))
+		} else {
+			sent := 0
+			for i, batch := range batches {
+				body, err := json.Marshal(batch)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("Failed to encode OTLP traces: %v", err)), nil
+				}
+				if err := sendHTTP(ctx, http.MethodPost, endpoint, "application/json", headers, body); err != nil {
+					message := fmt.Sprintf("Failed to send request %d of %d to %s: %v", i+1, len(batches), endpoint, err)
+					if i > 0 { // Those traces were delivered; a retry sends them again
+						message += fmt.Sprintf(". The first %d requests (%d of %d spans) were already sent", i, sent, spans)
+					}
+					return mcp.NewToolResultError(message), nil
+				}
+				sent += len(batch.ResourceSpans[0].ScopeSpans[0].Spans)
+			}
+			summary += fmt.Sprintf("; sent to %s in %d requests", endpoint, len(batches))
+		}
+	}
+	analysis = append(analysis, "Each frame is a trace: Frame span, one span per thread, then the thread's scopes. Scopes without recorded start times run back to back from the start of their frame")
+	output["analysis"] = analysis
+	output["summary"] = summary
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Outlier filtering ranks the frames in the window by frame time and either drops the
+// fastest and slowest outlier_percent of them (trim) or scales every function in those
+// frames so the frame time is clamped to the nearest kept frame (winsorize). A debugger
+// break or alt-tab then can't dominate averages computed from the capture.
+
+const (
+	outlierTrim       = "trim"
+	outlierWinsorize  = "winsorize"
+	maxOutlierPercent = 25
+)
+
+// OutlierFilter describes the outlier handling applied to an analysis
+type OutlierFilter struct {
+	Mode           string  `json:"mode"`
+	Percent        float64 `json:"percent"` // Per tail
+	LowMs          float64 `json:"lowMs"`   // Fastest frame time kept
+	HighMs         float64 `json:"highMs"`  // Slowest frame time kept
+	FramesAffected int     `json:"framesAffected"`
+}
+
+// parseOutlierFilter reads outlier_percent and outlier_mode; it returns nil when none was requested
+func parseOutlierFilter(args map[string]interface{}) (*OutlierFilter, error) {
+	percent, ok := args["outlier_percent"].(float64)
+	if !ok || percent == 0 {
+		return nil, nil
+	}
+	if percent < 0 || percent > maxOutlierPercent {
+		return nil, fmt.Errorf("outlier_percent must be between 0 and %d", maxOutlierPercent)
+	}
+	mode, _ := args["outlier_mode"].(string)
+	if mode == "" {
+		mode = outlierTrim
+	}
+	if mode != outlierTrim && mode != outlierWinsorize {
+		return nil, fmt.Errorf("outlier_mode must be '%s' or '%s'", outlierTrim, outlierWinsorize)
+	}
+	return &OutlierFilter{Mode: mode, Percent: percent}, nil
+}
+
+// applyOutlierFilter trims or winsorizes the fastest and slowest frames in data
+func applyOutlierFilter(data *FrameProData, filter *OutlierFilter) []FrameProFrame {
+	times := frameTimes(data)
+	order := make([]int, len(times))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return times[order[a]] < times[order[b]]
+	})
+
+	tail := int(float64(len(times)) * filter.Percent / 100)
+	if 2*tail >= len(times) {
+		tail = (len(times) - 1) / 2
+	}
+	filter.FramesAffected = 2 * tail
+	filter.LowMs = times[order[tail]]
+	filter.HighMs = times[order[len(order)-1-tail]]
+
+	outliers := make(map[int]bool)
+	for _, i := range append(order[:tail:tail], order[len(order)-tail:]...) {
+		outliers[i] = true
+	}
+
+	frames := []FrameProFrame{}
+	for i, frame := range data.Frames {
+		if !outliers[i] {
+			frames = append(frames, frame)
+			continue
+		}
+		if filter.Mode == outlierTrim {
+			continue
+		}
+		// The frame time is a sum or maximum of function times, so scaling every
+		// scope by the same factor clamps it to the bound
+		bound := max(min(times[i], filter.HighMs), filter.LowMs)
+		scale := 1.0
+		if times[i] > 0 {
+			scale = bound / times[i]
+		}
+		frame.Functions = scaleScopes(frame.Functions, scale)
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// scaleScopes copies scopes with their times, and those of their children, scaled by
+// the same factor, so nested scopes still add up to their parents
+func scaleScopes(functions []FrameProFunction, scale float64) []FrameProFunction {
+	if functions == nil {
+		return nil
+	}
+	scaled := make([]FrameProFunction, len(functions))
+	for i, fn := range functions {
+		fn.TimeMs *= scale
+		fn.SelfTimeMs *= scale
+		fn.InclusiveTimeMs *= scale
+		fn.Children = scaleScopes(fn.Children, scale)
+		scaled[i] = fn
+	}
+	return scaled
+}
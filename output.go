@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Every tool builds its full (L3) result; lower detail levels are derived from it
+// here, so a quick answer and a deep dive can never drift apart.
+//
+//	L0 - headline: up to three sentences of facts
+//	L1 - headline, top-level values and the first 3 entries of each list (values only)
+//	L2 - headline and everything, with lists capped at 10 entries
+//	L3 - the full result (default)
+
+const (
+	detailL1ListLimit = 3
+	detailL2ListLimit = 10
+)
+
+// withOutputOptions adds the parameters shared by every tool to its input schema
+func withOutputOptions(tool mcp.Tool) mcp.Tool {
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = make(map[string]any)
+	}
+	tool.InputSchema.Properties["detail_level"] = map[string]any{
+		"type":        "string",
+		"enum":        []string{"L0", "L1", "L2", "L3"},
+		"description": "Output detail: 'L0' headline only, 'L1' key values and top entries, 'L2' capped lists, 'L3' full detail (default: 'L3')",
+	}
+	tool.InputSchema.Properties["output_format"] = map[string]any{
+		"type":        "string",
+		"enum":        []string{"json", "csv", "markdown"},
+		"description": "'csv' also writes one table of the result (hotspots, issues, regressions, ...) to a CSV file for spreadsheets; 'markdown' returns the result as headings, tables and bullet lists for chat (default: 'json')",
+	}
+	tool.InputSchema.Properties["csv_table"] = map[string]any{
+		"type":        "string",
+		"description": "Result list to write as CSV, e.g. 'regressions' (default: the longest list; the result names the others in csvTables)",
+	}
+	tool.InputSchema.Properties["csv_path"] = map[string]any{
+		"type":        "string",
+		"description": "Where to write the CSV, relative to the output directory (default: <tool>_<table>-<timestamp>.csv there)",
+	}
+	tool.InputSchema.Properties["dry_run"] = map[string]any{
+		"type":        "boolean",
+		"description": "Write nothing: return each file the call would write (the CSV, exports, annotation or feedback stores) with its path, size and the start of its content (default: false)",
+	}
+	return tool
+}
+
+// parseDetailLevel accepts "L0".."L3" or 0..3
+func parseDetailLevel(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 3, nil
+	case float64:
+		if v >= 0 && v <= 3 && v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		switch strings.ToUpper(strings.TrimSpace(v)) {
+		case "", "L3", "3":
+			return 3, nil
+		case "L0", "0":
+			return 0, nil
+		case "L1", "1":
+			return 1, nil
+		case "L2", "2":
+			return 2, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid detail_level '%v': expected 'L0', 'L1', 'L2' or 'L3'", value)
+}
+
+// outputMiddleware writes successful JSON tool results as CSV when asked, reduces them to
+// the requested detail level and renders them as Markdown when asked
+func outputMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, _ := request.Params.Arguments.(map[string]interface{})
+		level, err := parseDetailLevel(args["detail_level"])
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		format, err := parseOutputFormat(args["output_format"])
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError || level == 3 && format == "json" {
+			return result, err
+		}
+
+		for i, content := range result.Content {
+			text, ok := content.(mcp.TextContent)
+			if !ok {
+				continue
+			}
+			var full map[string]interface{}
+			if json.Unmarshal([]byte(text.Text), &full) != nil {
+				continue // Not a JSON object result; leave untouched
+			}
+			if format == "csv" {
+				written, err := writeResultCSV(request.Params.Name, full, args)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				for key, value := range written {
+					full[key] = value
+				}
+			}
+			if level < 3 {
+				full = applyDetailLevel(full, level)
+			}
+			if format == "markdown" {
+				result.Content[i] = mcp.NewTextContent(renderMarkdown(request.Params.Name, full))
+				continue
+			}
+			encoded, _ := json.MarshalIndent(full, "", "  ")
+			result.Content[i] = mcp.NewTextContent(string(encoded))
+		}
+		return result, nil
+	}
+}
+
+// applyDetailLevel derives the L0-L2 view of a full result
+func applyDetailLevel(full map[string]interface{}, level int) map[string]interface{} {
+	reduced := map[string]interface{}{
+		"detailLevel": fmt.Sprintf("L%d", level),
+		"headline":    headline(full),
+	}
+	// What a call wrote, or would write in a dry run, and its caveats are reported at every level
+	for _, key := range []string{"dryRun", "writes", "warnings"} {
+		if value, found := full[key]; found {
+			reduced[key] = value
+		}
+	}
+	if level == 0 {
+		return reduced
+	}
+
+	omitted := make(map[string]int)
+	for key, value := range full {
+		if _, kept := reduced[key]; kept {
+			continue
+		}
+		switch v := value.(type) {
+		case []interface{}:
+			limit := detailL2ListLimit
+			if level == 1 {
+				limit = detailL1ListLimit
+			}
+			if len(v) > limit {
+				omitted[key] = len(v) - limit
+				v = v[:limit]
+			}
+			if level == 1 {
+				for i := range v {
+					v[i] = scalarsOnly(v[i])
+				}
+			}
+			reduced[key] = v
+		case map[string]interface{}:
+			if level == 1 {
+				reduced[key] = scalarsOnly(v)
+			} else {
+				reduced[key] = v
+			}
+		default:
+			reduced[key] = v
+		}
+	}
+	if len(omitted) > 0 {
+		reduced["omittedEntries"] = omitted
+	}
+	return reduced
+}
+
+// scalarsOnly strips nested lists and objects from an object, keeping its plain values
+func scalarsOnly(value interface{}) interface{} {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	scalars := make(map[string]interface{})
+	for key, v := range object {
+		switch v.(type) {
+		case []interface{}, map[string]interface{}:
+			continue
+		default:
+			scalars[key] = v
+		}
+	}
+	return scalars
+}
+
+// headline builds up to three sentences of facts from a result: its summary and
+// analysis lines when present, otherwise its top-level values
+func headline(full map[string]interface{}) string {
+	sentences := []string{}
+	if summary, ok := full["summary"].(string); ok && summary != "" {
+		sentences = append(sentences, summary)
+	}
+	if analysis, ok := full["analysis"].([]interface{}); ok {
+		for _, line := range analysis {
+			if s, ok := line.(string); ok {
+				sentences = append(sentences, s)
+			}
+		}
+	}
+
+	if len(sentences) == 0 {
+		keys := make([]string, 0, len(full))
+		for key, value := range full {
+			switch value.(type) {
+			case string, float64, bool:
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		facts := []string{}
+		for _, key := range keys {
+			facts = append(facts, fmt.Sprintf("%s: %v", key, full[key]))
+		}
+		if len(facts) > 0 {
+			sentences = append(sentences, strings.Join(facts, ", "))
+		}
+	}
+
+	if len(sentences) > 3 {
+		sentences = sentences[:3]
+	}
+	for i, s := range sentences {
+		if !strings.HasSuffix(s, ".") {
+			sentences[i] = s + "."
+		}
+	}
+	return strings.Join(sentences, " ")
+}
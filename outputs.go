@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Files the tools write (exports, CSV tables, catalogs) go under one output root, set by
+// FRAMEPRO_OUTPUT_DIR and defaulting to framepro-output in the data directory, so they
+// don't land between the captures, and so do the annotation and feedback stores. Output
+// paths are relative to the root; absolute ones and ones leading out of it are rejected.
+// Without an output path a tool names the file after its input and the time it was written,
+// adding -2, -3, ... if that name is taken. Written files are returned as resource links.
+
+// outputDir is the root the tools write their files under
+var outputDir string
+
+var (
+	outputNamesMu sync.Mutex
+	outputNames   = make(map[string]bool) // Names handed out by this server, written or not yet
+)
+
+// setOutputDir sets the output root, by default below the data directory
+func setOutputDir(dir string) {
+	if dir == "" {
+		dir = filepath.Join(dataDir, "framepro-output")
+	}
+	outputDir = dir
+}
+
+// resolveOutputPath places a requested output path under the output root, or names a new
+// file <stem>-<timestamp><ext> there when none was requested
+func resolveOutputPath(requested, stem, ext string, dryRun bool) (string, error) {
+	if requested != "" {
+		if filepath.IsAbs(requested) {
+			return "", fmt.Errorf("output path %s must be relative to the output root %s", requested, outputDir)
+		}
+		path := filepath.Join(outputDir, requested)
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("output path %s leads out of the output root %s", requested, outputDir)
+		}
+		return path, nil
+	}
+
+	outputNamesMu.Lock()
+	defer outputNamesMu.Unlock()
+	base := filepath.Join(outputDir, stem+"-"+time.Now().UTC().Format("20060102-150405"))
+	path := base + ext
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) && !outputNames[path] {
+			break
+		}
+		path = base + "-" + strconv.Itoa(n) + ext
+	}
+	if !dryRun {
+		outputNames[path] = true
+	}
+	return path, nil
+}
+
+// outputStem names output files after their input capture
+func outputStem(inputPath string) string {
+	return strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+}
+
+// outputURI is the resource URI of a written file
+func outputURI(path string) string {
+	if rel, err := filepath.Rel(outputDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		path = filepath.ToSlash(rel)
+	}
+	return "framepro://output/" + escapeSegment(path)
+}
+
+// outputMIMEType guesses a written file's MIME type from its extension
+func outputMIMEType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "application/json"
+	case ".csv":
+		return "text/csv"
+	case ".svg":
+		return "image/svg+xml"
+	}
+	return "text/plain"
+}
+
+// resultWrites lists the files a tool result reports as written; dry runs wrote none
+func resultWrites(result *mcp.CallToolResult) []string {
+	paths := []string{}
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		var output struct {
+			DryRun bool `json:"dryRun"`
+			Writes []struct {
+				Path string `json:"path"`
+			} `json:"writes"`
+		}
+		if json.Unmarshal([]byte(text.Text), &output) != nil || output.DryRun {
+			continue
+		}
+		for _, write := range output.Writes {
+			paths = append(paths, write.Path)
+		}
+	}
+	return paths
+}
+
+// addOutput lists a written file as a resource and returns a link to it
+func (r *SessionResources) addOutput(path string) mcp.ResourceLink {
+	uri := outputURI(path)
+	name := filepath.Base(path)
+	description := fmt.Sprintf("Written to %s", path)
+	mimeType := outputMIMEType(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.opened[uri] {
+		r.opened[uri] = true
+		r.server.AddResource(mcp.NewResource(uri, name,
+			mcp.WithResourceDescription(description),
+			mcp.WithMIMEType(mimeType)), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: mimeType, Text: string(content)}}, nil
+		})
+	}
+	return mcp.NewResourceLink(uri, name, description, mimeType)
+}
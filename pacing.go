@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Frame pacing looks at how evenly frames are delivered rather than how fast.
+// The pacing score is 100 for perfectly even frames and drops by 2 points for every
+// percent the average frame-to-frame change is of the median frame time, so a steady
+// 40fps scores higher than a 55fps capture that alternates between fast and slow frames.
+
+// PacingRun is a stretch of consecutive frames with a jarring frame-to-frame change
+type PacingRun struct {
+	StartFrame int     `json:"startFrame"`
+	EndFrame   int     `json:"endFrame"`
+	Frames     int     `json:"frames"`
+	MaxDeltaMs float64 `json:"maxDeltaMs"`
+}
+
+// pacingScore rates frame delivery from 0 (erratic) to 100 (perfectly even)
+func pacingScore(meanAbsDeltaMs, medianMs float64) float64 {
+	if medianMs <= 0 {
+		return 0
+	}
+	return math.Max(0, 100*(1-2*meanAbsDeltaMs/medianMs))
+}
+
+// pacingRating describes a pacing score
+func pacingRating(score float64) string {
+	switch {
+	case score >= 90:
+		return "smooth"
+	case score >= 70:
+		return "good"
+	case score >= 50:
+		return "noticeable judder"
+	default:
+		return "poor"
+	}
+}
+
+func framePacingHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	jitterMs := 4.0
+	if j, ok := args["jitter_threshold_ms"].(float64); ok && j > 0 {
+		jitterMs = j
+	}
+	topN := 5
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) < 2 {
+		return mcp.NewToolResultError("Frame pacing needs per-frame data with at least 2 frames. Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	meanMs, stdDevMs := meanStdDev(times)
+	median := medianOf(times)
+
+	deltas := make([]float64, len(times)-1)
+	absDeltas := make([]float64, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		deltas[i-1] = times[i] - times[i-1]
+		absDeltas[i-1] = math.Abs(deltas[i-1])
+	}
+	_, deltaStdDev := meanStdDev(deltas)
+	meanAbsDelta, _ := meanStdDev(absDeltas)
+
+	// Runs of consecutive frames whose change from the previous frame exceeds the threshold
+	runs := []PacingRun{}
+	badFrames := 0
+	for i, delta := range absDeltas {
+		if delta <= jitterMs {
+			continue
+		}
+		badFrames++
+		frameNumber := data.Frames[i+1].FrameNumber
+		last := len(runs) - 1
+		if last >= 0 && runs[last].EndFrame == data.Frames[i].FrameNumber {
+			runs[last].EndFrame = frameNumber
+			runs[last].Frames++
+			runs[last].MaxDeltaMs = math.Max(runs[last].MaxDeltaMs, delta)
+			continue
+		}
+		runs = append(runs, PacingRun{StartFrame: frameNumber, EndFrame: frameNumber, Frames: 1, MaxDeltaMs: delta})
+	}
+	longest := PacingRun{}
+	sustained := 0
+	for _, run := range runs {
+		if run.Frames > longest.Frames {
+			longest = run
+		}
+		if run.Frames > 1 {
+			sustained++
+		}
+	}
+
+	worst := append([]PacingRun(nil), runs...)
+	sort.Slice(worst, func(i, j int) bool {
+		if worst[i].Frames != worst[j].Frames {
+			return worst[i].Frames > worst[j].Frames
+		}
+		return worst[i].MaxDeltaMs > worst[j].MaxDeltaMs
+	})
+	if len(worst) > topN {
+		worst = worst[:topN]
+	}
+
+	score := pacingScore(meanAbsDelta, median)
+	rating := pacingRating(score)
+
+	analysis := []string{
+		fmt.Sprintf("Frames change by %.2fms on average from one frame to the next (%.1f%% of the median)", meanAbsDelta, meanAbsDelta/median*100),
+	}
+	if badFrames > 0 {
+		analysis = append(analysis, fmt.Sprintf("%d frame-to-frame changes exceed %.1fms; the longest run lasts %d frames starting at frame %d",
+			badFrames, jitterMs, longest.Frames, longest.StartFrame))
+	}
+
+	output := map[string]interface{}{
+		"file":              filePath,
+		"sessionName":       data.SessionName,
+		"framesAnalyzed":    len(times),
+		"avgFPS":            msToFPS(meanMs),
+		"meanFrameTimeMs":   meanMs,
+		"medianFrameTimeMs": median,
+		"frameTimeStdDevMs": stdDevMs,
+		"frameTimeVariance": stdDevMs * stdDevMs,
+		"meanAbsDeltaMs":    meanAbsDelta,
+		"deltaStdDevMs":     deltaStdDev,
+		"deltaVariance":     deltaStdDev * deltaStdDev,
+		"jitterThresholdMs": jitterMs,
+		"jitteryFrames":     badFrames,
+		"jitteryRuns":       len(runs),
+		"sustainedRuns":     sustained,
+		"longestRun":        longest,
+		"worstRuns":         worst,
+		"pacingScore":       score,
+		"rating":            rating,
+		"analysis":          analysis,
+		"summary": fmt.Sprintf("Pacing score %.0f/100 (%s) at %.1f FPS average, frame time std dev %.2fms",
+			score, rating, msToFPS(meanMs), stdDevMs),
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Linux perf captures are read as text, since perf.data itself depends on the kernel and perf
+// version that wrote it:
+//
+//	perf script     - one block per sample: "comm [pid/]tid [cpu] seconds: [period] event:"
+//	                  then the call chain, leaf first, one "address symbol+offset (dso)" line each
+//	folded stacks   - stackcollapse-perf.pl output: "root;...;leaf count" per stack, no times
+//
+// Samples of a thread become spans covering the sampling interval (the median gap between
+// samples on a thread); consecutive samples extend the spans of the stack prefix they share,
+// as with sampled speedscope profiles, and a gap in a thread's samples closes them. Frames
+// are cut where a frame marker enters the stack, so the marker should be a function called
+// once per frame that doesn't stay on the stack between frames, such as the present or swap
+// call (FRAMEPRO_FRAME_MARKER). perf samples threads while they run, so time blocked in the
+// marker is not seen. Folded stacks have no times: their stacks are laid back to back at
+// FRAMEPRO_PERF_FREQUENCY and load as one frame. The thread whose ID is its process ID is
+// the main thread unless a frame marker's thread is.
+
+const defaultPerfFrequency = 4000 // Hz, perf record's default sampling frequency
+
+// perfFrequency is the sampling frequency folded stacks were recorded at
+var perfFrequency = float64(defaultPerfFrequency)
+
+var (
+	perfHeaderLine = regexp.MustCompile(`^\s*(\S.*?)\s+(?:(-?\d+)/)?(-?\d+)\s+(?:\[\d+\]\s+)?(\d+\.\d+):\s+(?:\d+\s+)?\S+:\s*(.*)$`)
+	perfStackLine  = regexp.MustCompile(`^\s*([0-9a-fA-F]+)\s+(.+?)(?:\s+\(([^()]*)\))?\s*$`)
+	perfOffset     = regexp.MustCompile(`\+0x[0-9a-fA-F]+$`)
+	foldedLine     = regexp.MustCompile(`^(.+) (\d+)$`)
+	foldedThread   = regexp.MustCompile(`^(.*?)-(\d+)(?:/(\d+))?$`) // stackcollapse-perf.pl --pid / --tid
+)
+
+// perfThread is the samples of one thread, in time order
+type perfThread struct {
+	comm    string
+	pid     int // -1 when not recorded
+	samples []perfSample
+}
+
+// perfSample is one stack, root first, at a time in microseconds
+type perfSample struct {
+	at       float64
+	duration float64
+	stack    []string
+}
+
+// setPerfFrequency sets the sampling frequency of folded stacks from FRAMEPRO_PERF_FREQUENCY
+func setPerfFrequency(value string) error {
+	hz, err := strconv.ParseFloat(value, 64)
+	if err != nil || hz <= 0 {
+		return fmt.Errorf("invalid FRAMEPRO_PERF_FREQUENCY %q: expected samples per second", value)
+	}
+	perfFrequency = hz
+	return nil
+}
+
+// perfLines returns the first lines of content that aren't blank or # comments
+func perfLines(content []byte, count int) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() && len(lines) < count {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// isPerfScript reports whether content is perf script output
+func isPerfScript(content []byte) bool {
+	lines := perfLines(content, 1)
+	return len(lines) == 1 && perfHeaderLine.MatchString(lines[0])
+}
+
+// isFoldedStacks reports whether content is folded stacks
+func isFoldedStacks(path string, content []byte) bool {
+	if strings.EqualFold(filepath.Ext(path), ".folded") {
+		return true
+	}
+	lines := perfLines(content, 20)
+	nested := false
+	for _, line := range lines {
+		if !foldedLine.MatchString(line) {
+			return false
+		}
+		nested = nested || strings.Contains(line, ";")
+	}
+	return nested
+}
+
+// perfSymbol names a call chain entry: the symbol without its offset, or the DSO when unresolved
+func perfSymbol(symbol, dso string) string {
+	symbol = perfOffset.ReplaceAllString(symbol, "")
+	if symbol == "[unknown]" && dso != "" && dso != "unknown" {
+		return "[" + filepath.Base(dso) + "]"
+	}
+	return symbol
+}
+
+// decodePerfScript converts perf script output into per-thread spans cut at frame markers
+func decodePerfScript(path string, content []byte) (*FrameProData, error) {
+	threads := make(map[int]*perfThread)
+	var current *perfSample
+	var leafFirst []string
+	flush := func() {
+		if current != nil && len(leafFirst) > 0 {
+			current.stack = make([]string, 0, len(leafFirst))
+			for i := len(leafFirst) - 1; i >= 0; i-- {
+				current.stack = append(current.stack, leafFirst[i])
+			}
+		}
+		current, leafFirst = nil, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			flush()
+			continue
+		}
+		match := perfHeaderLine.FindStringSubmatch(line)
+		if match == nil {
+			if frame := perfStackLine.FindStringSubmatch(line); frame != nil && current != nil {
+				leafFirst = append(leafFirst, perfSymbol(frame[2], frame[3]))
+			}
+			continue
+		}
+		flush()
+		tid, _ := strconv.Atoi(match[3])
+		pid := -1
+		if match[2] != "" {
+			pid, _ = strconv.Atoi(match[2])
+		}
+		if tid <= 0 {
+			continue // The idle task (swapper) or an unknown thread
+		}
+		seconds, _ := strconv.ParseFloat(match[4], 64)
+		thread, exists := threads[tid]
+		if !exists {
+			thread = &perfThread{comm: match[1], pid: pid}
+			threads[tid] = thread
+		}
+		thread.samples = append(thread.samples, perfSample{at: seconds * 1e6})
+		current = &thread.samples[len(thread.samples)-1]
+		// Without call chains (perf record without -g) the sampled symbol ends the header
+		if trailing := perfStackLine.FindStringSubmatch(match[5]); trailing != nil {
+			leafFirst = append(leafFirst, perfSymbol(trailing[2], trailing[3]))
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read perf script output: %w", err)
+	}
+
+	// Every sample covers the sampling interval: the median gap between samples on a thread
+	gaps := []float64{}
+	for _, thread := range threads {
+		thread.samples = slices.DeleteFunc(thread.samples, func(sample perfSample) bool { return len(sample.stack) == 0 })
+		sort.SliceStable(thread.samples, func(a, b int) bool { return thread.samples[a].at < thread.samples[b].at })
+		for i := 1; i < len(thread.samples); i++ {
+			if gap := thread.samples[i].at - thread.samples[i-1].at; gap > 0 {
+				gaps = append(gaps, gap)
+			}
+		}
+	}
+	interval := 1e6 / perfFrequency
+	if len(gaps) > 0 {
+		interval = medianOf(gaps)
+	}
+	for _, thread := range threads {
+		for i := range thread.samples {
+			thread.samples[i].duration = interval
+		}
+	}
+	return perfCapture(path, threads, true)
+}
+
+// decodeFoldedStacks converts folded stacks into one frame of back-to-back samples per thread
+func decodeFoldedStacks(path string, content []byte) (*FrameProData, error) {
+	threads := make(map[int]*perfThread)
+	ids := make(map[string]int)
+	sampleUs := 1e6 / perfFrequency
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		match := foldedLine.FindStringSubmatch(strings.TrimRight(scanner.Text(), "\r"))
+		if match == nil || strings.HasPrefix(match[1], "#") {
+			continue
+		}
+		count, _ := strconv.Atoi(match[2])
+		stack := strings.Split(match[1], ";")
+		if count == 0 || len(stack) == 0 {
+			continue
+		}
+
+		// A leading comm-pid or comm-pid/tid frame names the thread
+		comm, pid, key := "perf", -1, ""
+		if thread := foldedThread.FindStringSubmatch(stack[0]); thread != nil && len(stack) > 1 {
+			comm, key = thread[1], thread[2]
+			pid, _ = strconv.Atoi(thread[2])
+			if thread[3] != "" {
+				key = thread[3]
+			}
+			stack = stack[1:]
+		}
+		id, exists := ids[key]
+		if !exists {
+			id = len(ids) + 1
+			if tid, err := strconv.Atoi(key); err == nil && tid > 0 {
+				id = tid
+			}
+			ids[key] = id
+			threads[id] = &perfThread{comm: comm, pid: pid}
+		}
+		thread := threads[id]
+		at := 0.0
+		if n := len(thread.samples); n > 0 {
+			at = thread.samples[n-1].at + thread.samples[n-1].duration
+		}
+		thread.samples = append(thread.samples, perfSample{at: at, duration: float64(count) * sampleUs, stack: stack})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read folded stacks: %w", err)
+	}
+	return perfCapture(path, threads, false)
+}
+
+// perfCapture turns the samples of every thread into spans and builds the capture; frames are
+// cut at frame markers only when the samples carry real times
+func perfCapture(path string, threads map[int]*perfThread, cutFrames bool) (*FrameProData, error) {
+	// A frame starts where a marker enters a thread's stack; the thread entering it most cuts the frames
+	markers := make(map[int][]float64)
+	if cutFrames {
+		isMarker := make(map[string]bool)
+		markerThread := 0
+		for id, thread := range threads {
+			inMarker, end := false, 0.0
+			for _, sample := range thread.samples {
+				found := false
+				for _, name := range sample.stack {
+					marker, seen := isMarker[name]
+					if !seen {
+						marker = isTraceFrameMarker(name)
+						isMarker[name] = marker
+					}
+					found = found || marker
+				}
+				if found && (!inMarker || sample.at > end+sample.duration/2) {
+					markers[id] = append(markers[id], sample.at)
+				}
+				inMarker, end = found, sample.at+sample.duration
+			}
+			if len(markers[id]) > len(markers[markerThread]) || len(markers[id]) == len(markers[markerThread]) && id < markerThread {
+				markerThread = id
+			}
+		}
+		markers = map[int][]float64{markerThread: markers[markerThread]}
+	}
+	var boundaries []float64
+	for _, times := range markers {
+		boundaries = times
+	}
+
+	spans := make(map[int][]*traceSpan)
+	threadNames := make(map[int]string)
+	shared := make(map[string]int)
+	for _, thread := range threads {
+		shared[thread.comm]++
+	}
+	for id, thread := range threads {
+		threadNames[id] = thread.comm
+		if shared[thread.comm] > 1 {
+			threadNames[id] = fmt.Sprintf("%s %d", thread.comm, id)
+		}
+
+		// Consecutive samples sharing a stack prefix extend the same spans, up to a gap or a frame boundary
+		stack := []*traceSpan{}
+		end := 0.0
+		next := 0 // First boundary after the previous sample
+		for _, sample := range thread.samples {
+			crossed := false
+			for next < len(boundaries) && boundaries[next] <= sample.at {
+				crossed = crossed || len(stack) > 0
+				next++
+			}
+			common := 0
+			if !crossed && sample.at <= end+sample.duration/2 {
+				for common < len(sample.stack) && common < len(stack) && sample.stack[common] == stack[common].name {
+					common++
+				}
+			}
+			for len(stack) > common {
+				stack[len(stack)-1].end = min(end, sample.at)
+				spans[id] = append(spans[id], stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			for _, name := range sample.stack[common:] {
+				stack = append(stack, &traceSpan{name: name, thread: id, start: sample.at})
+			}
+			end = sample.at + sample.duration
+		}
+		for len(stack) > 0 {
+			stack[len(stack)-1].end = end
+			spans[id] = append(spans[id], stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("%s has no samples with a stack", filepath.Base(path))
+	}
+
+	data := buildTraceCapture(path, spans, threadNames, markers)
+	if len(data.Frames) > 0 {
+		return data, nil // The frame marker's thread is the main thread
+	}
+
+	// Without frames, the busiest thread that is its process's first thread is the main thread
+	mainThread, busiest := 0, -1.0
+	for id, total := range frameThreadTotals(aggregateFrame(data)) {
+		first := threads[id].pid == id
+		if first && (total.TimeMs > busiest || total.TimeMs == busiest && id < mainThread) {
+			mainThread, busiest = id, total.TimeMs
+		}
+	}
+	if mainThread == 0 {
+		for id, total := range frameThreadTotals(aggregateFrame(data)) {
+			if total.TimeMs > busiest || total.TimeMs == busiest && id < mainThread {
+				mainThread, busiest = id, total.TimeMs
+			}
+		}
+	}
+	var setFlags func(functions []FrameProFunction)
+	setFlags = func(functions []FrameProFunction) {
+		for i := range functions {
+			fn := &functions[i]
+			fn.IsMainThread = fn.ThreadID == mainThread
+			if fn.IsMainThread {
+				fn.IsRenderThread, fn.IsWorkerThread = false, false
+			}
+			setFlags(fn.Children)
+		}
+	}
+	setFlags(data.Functions)
+	return data, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Per-second stats cut the capture into intervals of wall-clock time, measured by summing
+// frame times as skip_first_seconds does, and summarize each one: frame time average, min
+// and max, FPS, spikes (frames over spike_factor times the capture's median) and the
+// category that took the most time. Categories are the subsystems of subsystem_breakdown,
+// with unmatched functions grouped by namespace. A frame belongs to the interval it
+// starts in; the last interval may be partial.
+
+// SecondStats is one interval of the capture
+type SecondStats struct {
+	StartSeconds       float64 `json:"startSeconds"`
+	EndSeconds         float64 `json:"endSeconds"`
+	FirstFrame         int     `json:"firstFrame"`
+	LastFrame          int     `json:"lastFrame"`
+	Frames             int     `json:"frames"`
+	AvgFrameTimeMs     float64 `json:"avgFrameTimeMs"`
+	MinFrameTimeMs     float64 `json:"minFrameTimeMs"`
+	MaxFrameTimeMs     float64 `json:"maxFrameTimeMs"`
+	AvgFPS             float64 `json:"avgFPS"`
+	Spikes             int     `json:"spikes"`
+	DominantCategory   string  `json:"dominantCategory,omitempty"`
+	DominantCategoryMs float64 `json:"dominantCategoryMsPerFrame,omitempty"`
+	Partial            bool    `json:"partial,omitempty"` // Shorter than the interval: the end of the capture
+}
+
+func statsPerSecondHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	interval := 1.0
+	if s, ok := args["interval_seconds"].(float64); ok {
+		if s <= 0 {
+			return mcp.NewToolResultError("interval_seconds must be positive"), nil
+		}
+		interval = s
+	}
+	spikeFactor := 2.0
+	if f, ok := args["spike_factor"].(float64); ok && f > 0 {
+		spikeFactor = f
+	}
+	rules, err := parseSubsystems(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	median := medianOf(times)
+	category := make(map[string]string) // Function name -> category
+	seconds := []SecondStats{}
+	var categoryMs map[string]float64
+	var clock float64
+	currentIndex := -1
+	// finish completes the current interval: averages and its dominant category
+	finish := func() {
+		current := &seconds[len(seconds)-1]
+		current.AvgFrameTimeMs /= float64(current.Frames)
+		current.AvgFPS = msToFPS(current.AvgFrameTimeMs)
+		for name, ms := range categoryMs {
+			if ms > current.DominantCategoryMs || ms == current.DominantCategoryMs && name < current.DominantCategory {
+				current.DominantCategory, current.DominantCategoryMs = name, ms
+			}
+		}
+		current.DominantCategoryMs /= float64(current.Frames)
+	}
+	for f, frame := range data.Frames {
+		index := int(clock / 1000 / interval)
+		if index > currentIndex {
+			if len(seconds) > 0 {
+				finish()
+			}
+			currentIndex = index
+			start := float64(index) * interval
+			seconds = append(seconds, SecondStats{StartSeconds: start, EndSeconds: start + interval, FirstFrame: frame.FrameNumber, MinFrameTimeMs: math.Inf(1)})
+			categoryMs = make(map[string]float64)
+		}
+		current := &seconds[len(seconds)-1]
+		current.LastFrame = frame.FrameNumber
+		current.Frames++
+		current.AvgFrameTimeMs += times[f]
+		current.MinFrameTimeMs = math.Min(current.MinFrameTimeMs, times[f])
+		current.MaxFrameTimeMs = math.Max(current.MaxFrameTimeMs, times[f])
+		if times[f] > median*spikeFactor {
+			current.Spikes++
+		}
+		for _, fn := range frame.Functions {
+			name, known := category[fn.FunctionName]
+			if !known {
+				name, _ = subsystemOf(rules, fn.FunctionName, true)
+				category[fn.FunctionName] = name
+			}
+			categoryMs[name] += fn.TimeMs
+		}
+		clock += times[f]
+	}
+	finish()
+	last := &seconds[len(seconds)-1]
+	if end := clock / 1000; end < last.EndSeconds-1e-9 {
+		last.EndSeconds, last.Partial = end, true
+	}
+
+	// The worst interval by spikes, then by its slowest frame; and where the leader changes
+	worst := 0
+	spikes := 0
+	dominantCounts := make(map[string]int)
+	for i, second := range seconds {
+		spikes += second.Spikes
+		dominantCounts[second.DominantCategory]++
+		if second.Spikes > seconds[worst].Spikes || second.Spikes == seconds[worst].Spikes && second.MaxFrameTimeMs > seconds[worst].MaxFrameTimeMs {
+			worst = i
+		}
+	}
+	usual := ""
+	for name, count := range dominantCounts {
+		if count > dominantCounts[usual] || count == dominantCounts[usual] && name < usual {
+			usual = name
+		}
+	}
+	unusual := []string{}
+	for _, second := range seconds {
+		if second.DominantCategory != usual && second.DominantCategory != "" {
+			unusual = append(unusual, fmt.Sprintf("%.0fs: %s", second.StartSeconds, second.DominantCategory))
+		}
+	}
+
+	w := seconds[worst]
+	summary := fmt.Sprintf("%.1fs of capture in %d intervals of %gs: %d spikes in all; the worst interval is %.1fs-%.1fs (frames %d-%d, %d spikes, max %.2fms)",
+		clock/1000, len(seconds), interval, spikes, w.StartSeconds, w.EndSeconds, w.FirstFrame, w.LastFrame, w.Spikes, w.MaxFrameTimeMs)
+	analysis := []string{}
+	if usual != "" {
+		analysis = append(analysis, fmt.Sprintf("%s takes the most time in %d of %d intervals", usual, dominantCounts[usual], len(seconds)))
+	}
+	if len(unusual) > 0 {
+		if len(unusual) > 10 {
+			unusual = append(unusual[:10], fmt.Sprintf("%d more", len(unusual)-10))
+		}
+		analysis = append(analysis, fmt.Sprintf("Another category leads in: %s", strings.Join(unusual, ", ")))
+	}
+	if spikes > 0 {
+		spiky := 0
+		for _, second := range seconds {
+			if second.Spikes > 0 {
+				spiky++
+			}
+		}
+		analysis = append(analysis, fmt.Sprintf("Spikes (over %.1fx the %.2fms median) fall in %d of %d intervals", spikeFactor, median, spiky, len(seconds)))
+	}
+
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"intervalSeconds": interval,
+		"durationSeconds": clock / 1000,
+		"medianFrameMs":   median,
+		"spikeThreshold":  median * spikeFactor,
+		"totalSpikes":     spikes,
+		"worstInterval":   w,
+		"intervals":       seconds,
+		"analysis":        analysis,
+		"summary":         summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
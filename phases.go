@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Session phases split a capture that spans loading, menus and gameplay with simple
+// change-point detection: at each frame the window of frames before it is compared with
+// the window after it. A boundary is placed where the mean frame time shifts by more
+// than time_shift_percent, or where the set of active functions (those recorded in at
+// least half of a window's frames) changes by more than function_change_percent.
+// Adjacent phases whose medians and active sets end up alike are merged again, so a
+// burst of spikes doesn't split a phase.
+
+// Phase labels, guessed from function names and frame times
+const (
+	phaseLoading  = "loading"
+	phaseMenu     = "menu"
+	phaseGameplay = "gameplay"
+)
+
+// SessionPhase is a stretch of consecutive frames with a consistent profile
+type SessionPhase struct {
+	Index             int                  `json:"index"`
+	StartFrame        int                  `json:"startFrame"`
+	EndFrame          int                  `json:"endFrame"`
+	Frames            int                  `json:"frames"`
+	Label             string               `json:"label"`
+	LabelReason       string               `json:"labelReason"`
+	FrameTimes        FrameTimePercentiles `json:"frameTimes"`
+	ActiveFunctions   int                  `json:"activeFunctions"`
+	TopFunctions      []FunctionCost       `json:"topFunctions"`
+	DistinctFunctions []string             `json:"distinctFunctions"` // Active here but in no other phase
+	start, end        int                  // Indexes into data.Frames, end exclusive
+	active            map[string]bool
+}
+
+// FunctionCost is a function's average cost over a set of frames
+type FunctionCost struct {
+	FunctionName      string  `json:"functionName"`
+	ThreadName        string  `json:"threadName"`
+	AvgTimePerFrameMs float64 `json:"avgTimePerFrameMs"`
+}
+
+// isLoadingScope reports whether a scope loads, streams or compiles content
+func isLoadingScope(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, "workload") || strings.Contains(lower, "unload") {
+		return false
+	}
+	for _, word := range []string{"load", "stream", "compileshader", "shadercompil", "creategpuprogram", "decompress"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMenuScope reports whether a scope belongs to a menu or front end
+func isMenuScope(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range []string{"menu", "frontend", "front end", "lobby", "pause"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeFunctions returns the function names recorded in at least half of frames
+func activeFunctions(frames []FrameProFrame) map[string]bool {
+	seen := make(map[string]int)
+	for _, frame := range frames {
+		names := make(map[string]bool)
+		for _, fn := range frame.Functions {
+			names[fn.FunctionName] = true
+		}
+		for name := range names {
+			seen[name]++
+		}
+	}
+	active := make(map[string]bool)
+	for name, count := range seen {
+		if count*2 >= len(frames) {
+			active[name] = true
+		}
+	}
+	return active
+}
+
+// setDistance is the Jaccard distance between two function sets: 0 identical, 1 disjoint
+func setDistance(a, b map[string]bool) float64 {
+	union := len(a)
+	shared := 0
+	for name := range b {
+		if a[name] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return 1 - float64(shared)/float64(union)
+}
+
+// relativeShift is the change between two frame times as a fraction of the larger
+func relativeShift(a, b float64) float64 {
+	if a <= 0 && b <= 0 {
+		return 0
+	}
+	return math.Abs(a-b) / math.Max(a, b)
+}
+
+// splitPhases finds phase boundaries and returns the phases as index ranges
+func splitPhases(frames []FrameProFrame, times []float64, windowFrames int, timeShift, functionChange float64) []*SessionPhase {
+	// score is at least 1 where the windows either side of frame i differ enough to split
+	score := func(i int) float64 {
+		before, after := frames[i-windowFrames:i], frames[i:i+windowFrames]
+		meanBefore, _ := meanStdDev(times[i-windowFrames : i])
+		meanAfter, _ := meanStdDev(times[i : i+windowFrames])
+		shift := relativeShift(meanBefore, meanAfter)
+		change := setDistance(activeFunctions(before), activeFunctions(after))
+		return math.Max(shift/timeShift, change/functionChange)
+	}
+
+	phases := []*SessionPhase{}
+	start := 0
+	for i := windowFrames; i+windowFrames <= len(frames); i++ {
+		if i-start < windowFrames || score(i) < 1 {
+			continue
+		}
+		// Place the boundary at the strongest change within the next window
+		best, bestScore := i, score(i)
+		for j := i + 1; j < i+windowFrames && j+windowFrames <= len(frames); j++ {
+			if s := score(j); s > bestScore {
+				best, bestScore = j, s
+			}
+		}
+		phases = append(phases, &SessionPhase{start: start, end: best})
+		start = best
+		i = best
+	}
+	phases = append(phases, &SessionPhase{start: start, end: len(frames)})
+
+	// Merge neighbours that are alike as a whole, so one noisy window doesn't split a phase
+	merged := []*SessionPhase{phases[0]}
+	for _, phase := range phases[1:] {
+		last := merged[len(merged)-1]
+		shift := relativeShift(medianOf(times[last.start:last.end]), medianOf(times[phase.start:phase.end]))
+		change := setDistance(activeFunctions(frames[last.start:last.end]), activeFunctions(frames[phase.start:phase.end]))
+		if shift < timeShift && change < functionChange {
+			last.end = phase.end
+			continue
+		}
+		merged = append(merged, phase)
+	}
+	return merged
+}
+
+// labelPhase guesses what a phase is from its scopes and frame times
+func labelPhase(phase *SessionPhase, frames []FrameProFrame, captureMedianMs float64, largestActive int) (string, string) {
+	var total, loading float64
+	menuScopes := []string{}
+	for _, frame := range frames {
+		for _, fn := range frame.Functions {
+			total += fn.TimeMs
+			if isLoadingScope(fn.FunctionName) {
+				loading += fn.TimeMs
+			}
+		}
+	}
+	for name := range phase.active {
+		if isMenuScope(name) {
+			menuScopes = append(menuScopes, name)
+		}
+	}
+	sort.Strings(menuScopes)
+
+	if total > 0 && loading/total >= 0.10 {
+		return phaseLoading, fmt.Sprintf("%.0f%% of recorded time is in load, stream or shader compile scopes", loading/total*100)
+	}
+	if phase.FrameTimes.P50Ms > captureMedianMs*2 && phase.FrameTimes.P95Ms > phase.FrameTimes.P50Ms*1.5 {
+		return phaseLoading, fmt.Sprintf("Median frame time %.2fms is over twice the capture median (%.2fms) and erratic", phase.FrameTimes.P50Ms, captureMedianMs)
+	}
+	if len(menuScopes) > 0 {
+		return phaseMenu, fmt.Sprintf("Menu scopes are active: %s", strings.Join(menuScopes, ", "))
+	}
+	if largestActive > 0 && len(phase.active)*2 <= largestActive {
+		return phaseMenu, fmt.Sprintf("Only %d functions active, under half of the busiest phase (%d)", len(phase.active), largestActive)
+	}
+	return phaseGameplay, fmt.Sprintf("Full function set (%d active) at %.2fms median frame time", len(phase.active), phase.FrameTimes.P50Ms)
+}
+
+func sessionPhasesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	minPhaseFrames := 30
+	if n, ok := args["min_phase_frames"].(float64); ok && n >= 2 {
+		minPhaseFrames = int(n)
+	}
+	timeShift := 0.30
+	if p, ok := args["time_shift_percent"].(float64); ok && p > 0 {
+		timeShift = p / 100
+	}
+	functionChange := 0.40
+	if p, ok := args["function_change_percent"].(float64); ok && p > 0 {
+		functionChange = p / 100
+	}
+	topN := 5
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	phases := []*SessionPhase{{start: 0, end: len(data.Frames)}}
+	if len(data.Frames) >= minPhaseFrames*2 {
+		phases = splitPhases(data.Frames, times, minPhaseFrames, timeShift, functionChange)
+	}
+
+	threadNames := make(map[string]string)
+	largestActive := 0
+	for i, phase := range phases {
+		frames := data.Frames[phase.start:phase.end]
+		phase.Index = i + 1
+		phase.StartFrame = frames[0].FrameNumber
+		phase.EndFrame = frames[len(frames)-1].FrameNumber
+		phase.Frames = len(frames)
+		phase.FrameTimes = computeFrameTimePercentiles(times[phase.start:phase.end])
+		phase.active = activeFunctions(frames)
+		phase.ActiveFunctions = len(phase.active)
+		largestActive = max(largestActive, len(phase.active))
+
+		for _, frame := range frames {
+			for _, fn := range frame.Functions {
+				threadNames[functionKey(fn)] = fn.ThreadName
+			}
+		}
+		phase.TopFunctions = []FunctionCost{}
+		for key, avg := range avgFunctionTimes(frames) {
+			name := functionNameFromKey(key)
+			phase.TopFunctions = append(phase.TopFunctions, FunctionCost{FunctionName: name, ThreadName: threadNames[key], AvgTimePerFrameMs: avg})
+		}
+		sort.Slice(phase.TopFunctions, func(a, b int) bool {
+			return phase.TopFunctions[a].AvgTimePerFrameMs > phase.TopFunctions[b].AvgTimePerFrameMs
+		})
+		if len(phase.TopFunctions) > topN {
+			phase.TopFunctions = phase.TopFunctions[:topN]
+		}
+	}
+
+	captureMedian := medianOf(times)
+	analysis := []string{}
+	for _, phase := range phases {
+		phase.DistinctFunctions = []string{}
+		for name := range phase.active {
+			distinct := true
+			for _, other := range phases {
+				if other != phase && other.active[name] {
+					distinct = false
+					break
+				}
+			}
+			if distinct && len(phases) > 1 {
+				phase.DistinctFunctions = append(phase.DistinctFunctions, name)
+			}
+		}
+		sort.Strings(phase.DistinctFunctions)
+		if len(phase.DistinctFunctions) > 10 {
+			phase.DistinctFunctions = phase.DistinctFunctions[:10]
+		}
+
+		phase.Label, phase.LabelReason = labelPhase(phase, data.Frames[phase.start:phase.end], captureMedian, largestActive)
+		analysis = append(analysis, fmt.Sprintf("Phase %d (%s, frames %d-%d): %d frames at %.2fms median, %.2fms p95 (%.1f FPS)",
+			phase.Index, phase.Label, phase.StartFrame, phase.EndFrame, phase.Frames,
+			phase.FrameTimes.P50Ms, phase.FrameTimes.P95Ms, phase.FrameTimes.AvgFPS))
+	}
+
+	summary := fmt.Sprintf("Capture is one %s phase of %d frames at %.2fms median frame time",
+		phases[0].Label, phases[0].Frames, phases[0].FrameTimes.P50Ms)
+	if len(phases) > 1 {
+		labels := make([]string, len(phases))
+		for i, phase := range phases {
+			labels[i] = phase.Label
+		}
+		summary = fmt.Sprintf("Capture splits into %d phases: %s. Analyze one with frame_range to keep the others out of its statistics",
+			len(phases), strings.Join(labels, ", "))
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"framesAnalyzed": len(data.Frames),
+		"phaseCount":     len(phases),
+		"phases":         phases,
+		"analysis":       analysis,
+		"summary":        summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
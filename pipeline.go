@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Pipeline stages split a frame by what the work does rather than which thread ran it, for
+// renderers that simulate, prepare, submit and present on several threads at once. Stages
+// come from the stages argument, a project file named by FRAMEPRO_STAGES, or the built-in
+// four; a scope counts for the first stage whose pattern matches it, with its whole time,
+// and its children are not searched further. With the built-in stages, render-thread work
+// no pattern claims counts for prepare and RHI-thread work for submit; time still left on
+// no stage (waits aside) is reported as unstaged. With start times (StartMs) the stages'
+// spans in each frame are intersected to measure how much they overlap; without them
+// overlap is only known as a lower bound: stage time beyond the frame time must have run
+// in parallel.
+
+// projectPipelineStages are the stages declared in the FRAMEPRO_STAGES file, if any
+var projectPipelineStages []SubsystemBudget
+
+// defaultPipelineStages are used when neither the argument nor the project declares stages
+var defaultPipelineStages = []SubsystemBudget{
+	{Name: "simulate", Pattern: `Tick|Update|Physics|Simulat|Animation|Gameplay|Script`},
+	{Name: "prepare", Pattern: `Prepare|Cull|Visibility|Gather|Extract|InitViews|BuildCommand`},
+	{Name: "submit", Pattern: `Submit|RHI|Draw|Dispatch|CommandList|ExecuteCommand`},
+	{Name: "present", Pattern: `Present|SwapBuffers|SwapChain|Flip|VSync`},
+}
+
+// PipelineStageRow is one stage's time per frame
+type PipelineStageRow struct {
+	Name             string   `json:"name"`
+	Pattern          string   `json:"pattern"`
+	AvgMs            float64  `json:"avgMs"`
+	P95Ms            float64  `json:"p95Ms"`
+	MaxMs            float64  `json:"maxMs"`
+	PercentOfFrame   float64  `json:"percentOfFrame"`
+	FramesPresent    int      `json:"framesPresent"`
+	Threads          []string `json:"threads,omitempty"`
+	MatchedFunctions []string `json:"matchedFunctions,omitempty"`
+}
+
+// PipelineFrame is one frame split by stage
+type PipelineFrame struct {
+	FrameNumber int                `json:"frame"`
+	FrameTimeMs float64            `json:"frameTimeMs"`
+	Stages      map[string]float64 `json:"stages"`
+	OverlapMs   float64            `json:"overlapMs"`  // Stage time that ran in parallel with another stage
+	UnstagedMs  float64            `json:"unstagedMs"` // Work no stage claimed, waits aside
+}
+
+// StageOverlap is how much two stages ran at the same time
+type StageOverlap struct {
+	Stages            [2]string `json:"stages"`
+	AvgOverlapMs      float64   `json:"avgOverlapMs,omitempty"` // Needs start times
+	FramesOverlapping int       `json:"framesOverlapping,omitempty"`
+	SharedThreads     []string  `json:"sharedThreads,omitempty"` // Threads running both, where they serialize
+}
+
+// loadProjectPipelineStages reads the stages declared for a project: [{"name": ..., "pattern": ...}]
+func loadProjectPipelineStages(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read pipeline stages: %w", err)
+	}
+	var stages []SubsystemBudget
+	if err := json.Unmarshal(content, &stages); err != nil {
+		return fmt.Errorf("failed to parse pipeline stages %s: %w", path, err)
+	}
+	for i, stage := range stages {
+		if stage.Name == "" || stage.Pattern == "" {
+			return fmt.Errorf("stage %d in %s needs a name and a pattern", i+1, path)
+		}
+	}
+	compiled, err := compileBudgets(stages)
+	if err != nil {
+		return err
+	}
+	projectPipelineStages = compiled
+	return nil
+}
+
+// parsePipelineStages reads the stages argument, else the project's stages, else the
+// built-in ones, and says which it used
+func parsePipelineStages(args map[string]interface{}) ([]SubsystemBudget, string, error) {
+	raw, ok := args["stages"].([]interface{})
+	if !ok || len(raw) == 0 {
+		if len(projectPipelineStages) > 0 {
+			return projectPipelineStages, "project", nil
+		}
+		stages, err := compileBudgets(append([]SubsystemBudget(nil), defaultPipelineStages...))
+		return stages, "built-in", err
+	}
+	stages := []SubsystemBudget{}
+	for i, entry := range raw {
+		spec, _ := entry.(map[string]interface{})
+		name, _ := spec["name"].(string)
+		pattern, _ := spec["pattern"].(string)
+		if name == "" || pattern == "" {
+			return nil, "", fmt.Errorf("stage %d needs a name and a pattern", i+1)
+		}
+		stages = append(stages, SubsystemBudget{Name: name, Pattern: pattern})
+	}
+	stages, err := compileBudgets(stages)
+	return stages, "argument", err
+}
+
+// pipelineThreadStages maps render threads to the built-in stage their unclaimed work
+// belongs to: submit for RHI threads, prepare for the others
+func pipelineThreadStages(data *FrameProData, stages []SubsystemBudget) map[int]int {
+	index := make(map[string]int)
+	for i, stage := range stages {
+		index[stage.Name] = i
+	}
+	threads := make(map[int]int)
+	for _, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			if !fn.IsRenderThread {
+				continue
+			}
+			if strings.Contains(strings.ToLower(fn.ThreadName), "rhi") {
+				threads[fn.ThreadID] = index["submit"]
+			} else {
+				threads[fn.ThreadID] = index["prepare"]
+			}
+		}
+	}
+	return threads
+}
+
+// pipelineScopes adds the outermost scopes matching each stage to the stage's time by
+// thread and, for scopes with a start time, to its intervals. Time of unmatched scopes
+// that no child claims goes to their thread's stage, if it has one; unmatched leaves are
+// added to unclaimed by thread instead when it is given, for the caller to settle. It
+// returns how many staged scopes had no start time, and the staged time of the scopes.
+func pipelineScopes(functions []FrameProFunction, stages []SubsystemBudget, threadStages map[int]int, times []map[int]float64, intervals [][]gpuInterval, matched []map[string]bool, unclaimed map[int][]FrameProFunction) (int, []float64) {
+	untimed := 0
+	staged := make([]float64, len(functions))
+	for i, fn := range functions {
+		index := matchBudget(stages, fn.FunctionName)
+		if index < 0 {
+			childUntimed, childStaged := pipelineScopes(fn.Children, stages, threadStages, times, intervals, matched, nil)
+			untimed += childUntimed
+			for _, ms := range childStaged {
+				staged[i] += ms
+			}
+			stage, ok := threadStages[fn.ThreadID]
+			if !ok || isWaitScope(fn.FunctionName) || fn.TimeMs <= staged[i] {
+				continue
+			}
+			if unclaimed != nil && len(fn.Children) == 0 {
+				unclaimed[fn.ThreadID] = append(unclaimed[fn.ThreadID], fn)
+				staged[i] = fn.TimeMs
+				continue
+			}
+			times[stage][fn.ThreadID] += fn.TimeMs - staged[i]
+			matched[stage][fn.FunctionName] = true
+			if fn.StartMs != nil && staged[i] == 0 {
+				intervals[stage] = append(intervals[stage], gpuInterval{*fn.StartMs, *fn.StartMs + fn.TimeMs})
+			} else {
+				untimed++ // Only part of the span is this stage's
+			}
+			staged[i] = fn.TimeMs
+			continue
+		}
+		staged[i] = fn.TimeMs
+		times[index][fn.ThreadID] += fn.TimeMs
+		matched[index][fn.FunctionName] = true
+		if fn.StartMs != nil {
+			intervals[index] = append(intervals[index], gpuInterval{*fn.StartMs, *fn.StartMs + fn.TimeMs})
+		} else if fn.TimeMs > 0 {
+			untimed++
+		}
+	}
+	return untimed, staged
+}
+
+func pipelineStagesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	stages, source, err := parsePipelineStages(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	var threadStages map[int]int
+	if source == "built-in" {
+		threadStages = pipelineThreadStages(data, stages)
+	}
+	threadNames := make(map[int]string)
+	series := make([][]float64, len(stages)) // [stage][frame]
+	stageThreads := make([]map[int]bool, len(stages))
+	matched := make([]map[string]bool, len(stages))
+	for i := range stages {
+		series[i] = make([]float64, len(data.Frames))
+		stageThreads[i] = make(map[int]bool)
+		matched[i] = make(map[string]bool)
+	}
+	pairOverlap := make(map[[2]int]float64)
+	pairFrames := make(map[[2]int]int)
+	untimed := 0
+	overlaps := make([]float64, len(data.Frames))
+	unstaged := make([]float64, len(data.Frames)) // Work on no stage, per frame
+	frames := []PipelineFrame{}
+	for f, frame := range data.Frames {
+		byThread := make([]map[int]float64, len(stages))
+		for i := range stages {
+			byThread[i] = make(map[int]float64)
+		}
+		intervals := make([][]gpuInterval, len(stages))
+		unclaimed := make(map[int][]FrameProFunction)
+		frameUntimed, staged := pipelineScopes(frame.Functions, stages, threadStages, byThread, intervals, matched, unclaimed)
+		untimed += frameUntimed
+		// Flat exports list enclosing scopes such as RenderFrame next to the scopes they
+		// enclose, so only unclaimed time beyond what the thread's stages claimed is new
+		for id, scopes := range unclaimed {
+			var ms float64
+			for _, fn := range scopes {
+				ms += fn.TimeMs
+			}
+			for i := range stages {
+				ms -= byThread[i][id]
+			}
+			if ms <= 0 {
+				continue
+			}
+			stage := threadStages[id]
+			byThread[stage][id] += ms
+			for _, fn := range scopes {
+				matched[stage][fn.FunctionName] = true
+			}
+			untimed++
+		}
+		for i, fn := range frame.Functions {
+			threadNames[fn.ThreadID] = fn.ThreadName
+			if !isWaitScope(fn.FunctionName) {
+				unstaged[f] += max(fn.TimeMs-staged[i], 0)
+			}
+		}
+
+		row := PipelineFrame{FrameNumber: frame.FrameNumber, FrameTimeMs: times[f], Stages: make(map[string]float64)}
+		var stageSum float64
+		all := []gpuInterval{}
+		for i, stage := range stages {
+			for id, ms := range byThread[i] {
+				series[i][f] += ms
+				stageThreads[i][id] = true
+			}
+			row.Stages[stage.Name] = series[i][f]
+			stageSum += series[i][f]
+			intervals[i] = mergeIntervals(intervals[i])
+			all = append(all, intervals[i]...)
+			for j := 0; j < i; j++ {
+				if overlap := intervalOverlap(intervals[j], intervals[i]); overlap > 0 {
+					pairOverlap[[2]int{j, i}] += overlap
+					pairFrames[[2]int{j, i}]++
+				}
+			}
+		}
+		if frameUntimed == 0 {
+			var busy, span float64
+			for _, list := range intervals {
+				for _, interval := range list {
+					busy += interval.end - interval.start
+				}
+			}
+			for _, interval := range mergeIntervals(all) {
+				span += interval.end - interval.start
+			}
+			row.OverlapMs = busy - span
+		} else {
+			row.OverlapMs = max(stageSum-times[f], 0) // Lower bound
+		}
+		overlaps[f] = row.OverlapMs
+		frames = append(frames, row)
+	}
+	timing := "recorded"
+	if untimed > 0 {
+		timing = "estimated" // Lower bounds from stage time beyond the frame time
+	}
+
+	avgFrame, _ := meanStdDev(times)
+	rows := []PipelineStageRow{}
+	missing := []string{}
+	var stagedMs float64
+	for i, stage := range stages {
+		row := PipelineStageRow{Name: stage.Name, Pattern: stage.Pattern}
+		sorted := append([]float64(nil), series[i]...)
+		sort.Float64s(sorted)
+		row.AvgMs, _ = meanStdDev(series[i])
+		row.P95Ms = percentile(sorted, 95)
+		row.MaxMs = sorted[len(sorted)-1]
+		if avgFrame > 0 {
+			row.PercentOfFrame = row.AvgMs / avgFrame * 100
+		}
+		for _, ms := range series[i] {
+			if ms > 0 {
+				row.FramesPresent++
+			}
+		}
+		if row.FramesPresent == 0 {
+			missing = append(missing, stage.Name)
+		}
+		for id := range stageThreads[i] {
+			row.Threads = append(row.Threads, threadNames[id])
+		}
+		sort.Strings(row.Threads)
+		for name := range matched[i] {
+			row.MatchedFunctions = append(row.MatchedFunctions, name)
+		}
+		sort.Strings(row.MatchedFunctions)
+		stagedMs += row.AvgMs
+		rows = append(rows, row)
+	}
+	for _, name := range missing {
+		for _, frame := range frames {
+			delete(frame.Stages, name) // A stage nothing matched has no time to report
+		}
+	}
+	for f := range frames {
+		frames[f].UnstagedMs = unstaged[f]
+	}
+	avgUnstaged, _ := meanStdDev(unstaged)
+
+	// Stage pairs: measured overlap, and the threads where the two can only take turns
+	pairs := []StageOverlap{}
+	serialized := []string{}
+	for i := range stages {
+		for j := 0; j < i; j++ {
+			pair := StageOverlap{Stages: [2]string{stages[j].Name, stages[i].Name}, FramesOverlapping: pairFrames[[2]int{j, i}]}
+			if timing == "recorded" {
+				pair.AvgOverlapMs = pairOverlap[[2]int{j, i}] / float64(len(frames))
+			}
+			for id := range stageThreads[i] {
+				if stageThreads[j][id] {
+					pair.SharedThreads = append(pair.SharedThreads, threadNames[id])
+				}
+			}
+			sort.Strings(pair.SharedThreads)
+			if len(pair.SharedThreads) > 0 && rows[i].AvgMs > 0 && rows[j].AvgMs > 0 {
+				serialized = append(serialized, fmt.Sprintf("%s and %s on %s", stages[j].Name, stages[i].Name, strings.Join(pair.SharedThreads, ", ")))
+			}
+			if pair.AvgOverlapMs > 0 || pair.FramesOverlapping > 0 || len(pair.SharedThreads) > 0 {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].AvgOverlapMs > pairs[b].AvgOverlapMs })
+	avgOverlap, _ := meanStdDev(overlaps)
+
+	parts := []string{}
+	heaviest := 0
+	for i, row := range rows {
+		if row.FramesPresent > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.2fms", row.Name, row.AvgMs))
+		}
+		if row.AvgMs > rows[heaviest].AvgMs {
+			heaviest = i
+		}
+	}
+	if avgUnstaged >= 0.01 {
+		parts = append(parts, fmt.Sprintf("unstaged %.2fms", avgUnstaged))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no scope matched a stage")
+	}
+	summary := fmt.Sprintf("Average %.2fms frame by pipeline stage: %s; %.2fms of stage time overlapped per frame (%s)", avgFrame, strings.Join(parts, ", "), avgOverlap, timing)
+
+	analysis := []string{}
+	suggestions := []string{}
+	if avgFrame > 0 && stagedMs > 0 {
+		analysis = append(analysis, fmt.Sprintf("Stages add up to %.2fms per frame, %.2fx the frame time; %s is the heaviest at %.0f%% of the frame",
+			stagedMs, stagedMs/avgFrame, rows[heaviest].Name, rows[heaviest].PercentOfFrame))
+	}
+	if timing == "recorded" && len(pairs) > 0 && pairs[0].AvgOverlapMs > 0 {
+		analysis = append(analysis, fmt.Sprintf("%s and %s overlap the most: %.2fms per frame", pairs[0].Stages[0], pairs[0].Stages[1], pairs[0].AvgOverlapMs))
+	} else if timing == "estimated" {
+		analysis = append(analysis, "Scopes have no start times (StartMs), so overlap is a lower bound: stage time beyond the frame time must have run in parallel")
+	}
+	if len(serialized) > 0 {
+		analysis = append(analysis, fmt.Sprintf("Stages sharing threads can only take turns there: %s", strings.Join(serialized, "; ")))
+		suggestions = append(suggestions, "Move stages that share a thread onto their own threads (e.g. submit on a dedicated RHI thread) so they can overlap with the next frame's simulation")
+	}
+	if avgUnstaged >= 0.01 {
+		analysis = append(analysis, fmt.Sprintf("%.2fms of work per frame matched no stage; matchedFunctions shows what each stage claimed", avgUnstaged))
+	}
+	if len(missing) > 0 {
+		analysis = append(analysis, fmt.Sprintf("No scope matched %s in this capture, so they are left out rather than reported as 0ms", strings.Join(missing, ", ")))
+		suggestions = append(suggestions, "Declare the engine's stage functions with stages or FRAMEPRO_STAGES; search_functions shows the names in this build")
+	}
+
+	reported := []PipelineStageRow{}
+	for _, row := range rows {
+		if row.FramesPresent > 0 {
+			reported = append(reported, row)
+		}
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"framesAnalyzed": len(data.Frames),
+		"avgFrameTimeMs": avgFrame,
+		"stageSource":    source,
+		"stages":         reported,
+		"unstagedMs":     avgUnstaged,
+		"missingStages":  missing,
+		"overlap": map[string]interface{}{
+			"timing":       timing,
+			"avgOverlapMs": avgOverlap,
+			"pairs":        pairs,
+		},
+		"frames":      frames,
+		"suggestions": suggestions,
+		"analysis":    analysis,
+		"summary":     summary,
+	}
+	if avgUnstaged >= 0.01 {
+		addWarning(output, warnUnstagedTime, fmt.Sprintf("%.2fms of work per frame (%.0f%% of the frame) matched no pipeline stage and is reported as unstaged", avgUnstaged, avgUnstaged/avgFrame*100))
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// pprof profiles (Go's runtime/pprof, go tool pprof) are gzipped protocol buffers of
+// profile.proto: samples hold a stack of location IDs, leaf first, and one value per
+// sample type. The CPU time (or, failing that, the first time-valued sample type) of every
+// stack is added along its path, giving a hierarchical call tree per thread. Go profiles
+// have no threads, so samples are split by a "thread" label when present and otherwise go
+// to one thread. A profile has no frames either: it is imported as a single frame covering
+// the whole profile, so per-frame times are totals over the profile.
+
+// pprofUnitsToMs converts the time units of pprof sample types to milliseconds
+var pprofUnitsToMs = map[string]float64{
+	"nanoseconds": 1e-6, "microseconds": 1e-3, "milliseconds": 1, "seconds": 1000,
+}
+
+// errTruncatedProto reports a protocol buffer that ends inside a field
+var errTruncatedProto = errors.New("truncated protocol buffer")
+
+// protoField is one field of a protocol buffer message
+type protoField struct {
+	number int
+	varint uint64 // Wire type 0
+	bytes  []byte // Wire type 2
+	wire   int
+}
+
+// protoFields splits a protocol buffer message into its fields
+func protoFields(message []byte) ([]protoField, error) {
+	fields := []protoField{}
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return nil, errTruncatedProto
+		}
+		message = message[n:]
+		field := protoField{number: int(key >> 3), wire: int(key & 7)}
+		switch field.wire {
+		case 0:
+			field.varint, n = binary.Uvarint(message)
+			if n <= 0 {
+				return nil, errTruncatedProto
+			}
+			message = message[n:]
+		case 1:
+			if len(message) < 8 {
+				return nil, errTruncatedProto
+			}
+			message = message[8:]
+		case 2:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return nil, errTruncatedProto
+			}
+			field.bytes = message[n : n+int(length)]
+			message = message[n+int(length):]
+		case 5:
+			if len(message) < 4 {
+				return nil, errTruncatedProto
+			}
+			message = message[4:]
+		default:
+			return nil, fmt.Errorf("unsupported protocol buffer wire type %d", field.wire)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// values returns a repeated integer field, packed or not
+func (f protoField) values() ([]uint64, error) {
+	if f.wire == 0 {
+		return []uint64{f.varint}, nil
+	}
+	values := []uint64{}
+	for packed := f.bytes; len(packed) > 0; {
+		value, n := binary.Uvarint(packed)
+		if n <= 0 {
+			return nil, errTruncatedProto
+		}
+		values = append(values, value)
+		packed = packed[n:]
+	}
+	return values, nil
+}
+
+// pprofSample is a stack (leaf first) with its values and thread label
+type pprofSample struct {
+	locations []uint64
+	values    []int64
+	thread    string
+}
+
+// pprofLocation is a code address with the functions at it, innermost (inlined) first
+type pprofLocation struct {
+	address   uint64
+	functions []uint64
+}
+
+// maxDecompressedCapture caps how much a gzipped capture may expand to
+const maxDecompressedCapture = 1 << 30
+
+// isGzip reports whether content is gzip-compressed
+func isGzip(content []byte) bool {
+	return bytes.HasPrefix(content, []byte{0x1f, 0x8b})
+}
+
+// gunzipCapture decompresses a gzipped capture, failing once it expands past
+// maxDecompressedCapture
+func gunzipCapture(content []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress capture: %w", err)
+	}
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxDecompressedCapture+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress capture: %w", err)
+	}
+	if len(decompressed) > maxDecompressedCapture {
+		return nil, fmt.Errorf("capture expands to more than %d MB when decompressed, more than can be loaded", maxDecompressedCapture>>20)
+	}
+	return decompressed, nil
+}
+
+// pprofWireTypes are the wire types of profile.proto's fields by number: messages and
+// strings (2) or integers (0); comment may be packed
+var pprofWireTypes = map[int][]int{1: {2}, 2: {2}, 3: {2}, 4: {2}, 5: {2}, 6: {2}, 7: {0}, 8: {0},
+	9: {0}, 10: {0}, 11: {2}, 12: {0}, 13: {0, 2}, 14: {0}}
+
+// isPprofMessage reports whether a protocol buffer starts like a profile: its first
+// field is one of profile.proto's with the wire type declared for it
+func isPprofMessage(prefix []byte) bool {
+	key, n := binary.Uvarint(prefix)
+	if n <= 0 {
+		return false
+	}
+	if _, n = binary.Uvarint(prefix[n:]); n <= 0 {
+		return false // Both integers and lengths start with a varint
+	}
+	for _, wire := range pprofWireTypes[int(key>>3)] {
+		if wire == int(key&7) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPprof reports whether content is a pprof profile: gzipped, as Go writes them, or raw
+// with a pprof extension. Other gzipped captures are told apart by their decompressed start.
+func isPprof(path string, content []byte) bool {
+	if isGzip(content) {
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return false
+		}
+		prefix := make([]byte, 16)
+		n, _ := io.ReadFull(reader, prefix)
+		return isPprofMessage(prefix[:n])
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pprof", ".prof", ".pb":
+		return true
+	case ".gz":
+		return strings.HasSuffix(strings.ToLower(path), ".pb.gz")
+	}
+	return false
+}
+
+// decodePprof converts a pprof profile into a hierarchical call tree, one frame for the whole profile
+func decodePprof(path string, content []byte) (*FrameProData, error) {
+	if isGzip(content) {
+		var err error
+		if content, err = gunzipCapture(content); err != nil {
+			return nil, err
+		}
+	}
+	fields, err := protoFields(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pprof profile: %w", err)
+	}
+
+	var strs []string
+	var sampleTypes [][2]uint64 // type, unit string indexes
+	var samples []pprofSample
+	locations := make(map[uint64]pprofLocation)
+	functions := make(map[uint64]uint64) // Function -> name string index
+	threadKey := -1
+	for _, field := range fields {
+		if field.number == 6 {
+			strs = append(strs, string(field.bytes))
+			if string(field.bytes) == "thread" {
+				threadKey = len(strs) - 1
+			}
+		}
+	}
+	str := func(index uint64) string {
+		if index < uint64(len(strs)) {
+			return strs[index]
+		}
+		return ""
+	}
+
+	for _, field := range fields {
+		if field.wire != 2 || field.number != 1 && field.number != 2 && field.number != 4 && field.number != 5 {
+			continue
+		}
+		message, err := protoFields(field.bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pprof profile: %w", err)
+		}
+		switch field.number {
+		case 1: // ValueType
+			var valueType [2]uint64
+			for _, f := range message {
+				if f.number == 1 || f.number == 2 {
+					valueType[f.number-1] = f.varint
+				}
+			}
+			sampleTypes = append(sampleTypes, valueType)
+		case 2: // Sample
+			var sample pprofSample
+			for _, f := range message {
+				switch f.number {
+				case 1:
+					ids, err := f.values()
+					if err != nil {
+						return nil, err
+					}
+					sample.locations = append(sample.locations, ids...)
+				case 2:
+					values, err := f.values()
+					if err != nil {
+						return nil, err
+					}
+					for _, value := range values {
+						sample.values = append(sample.values, int64(value))
+					}
+				case 3:
+					label, err := protoFields(f.bytes)
+					if err != nil {
+						return nil, err
+					}
+					var key, value uint64
+					for _, l := range label {
+						switch l.number {
+						case 1:
+							key = l.varint
+						case 2:
+							value = l.varint
+						}
+					}
+					if threadKey >= 0 && key == uint64(threadKey) {
+						sample.thread = str(value)
+					}
+				}
+			}
+			samples = append(samples, sample)
+		case 4: // Location
+			var id, address uint64
+			lines := []uint64{}
+			for _, f := range message {
+				switch f.number {
+				case 1:
+					id = f.varint
+				case 3:
+					address = f.varint
+				case 4:
+					line, err := protoFields(f.bytes)
+					if err != nil {
+						return nil, err
+					}
+					for _, l := range line {
+						if l.number == 1 {
+							lines = append(lines, l.varint)
+						}
+					}
+				}
+			}
+			locations[id] = pprofLocation{address: address, functions: lines}
+		case 5: // Function
+			var id, name uint64
+			for _, f := range message {
+				switch f.number {
+				case 1:
+					id = f.varint
+				case 2:
+					name = f.varint
+				}
+			}
+			functions[id] = name
+		}
+	}
+
+	// CPU time if recorded, else the first time-valued sample type
+	valueIndex, scale := -1, 0.0
+	for i, sampleType := range sampleTypes {
+		toMs, isTime := pprofUnitsToMs[str(sampleType[1])]
+		if isTime && (valueIndex < 0 || str(sampleType[0]) == "cpu") {
+			valueIndex, scale = i, toMs
+		}
+	}
+	countIndex := -1 // Samples per stack, when recorded
+	for i, sampleType := range sampleTypes {
+		if str(sampleType[0]) == "samples" && str(sampleType[1]) == "count" {
+			countIndex = i
+		}
+	}
+	if valueIndex < 0 {
+		return nil, fmt.Errorf("%s has no time-valued sample type (only %s); import CPU profiles", filepath.Base(path), pprofSampleTypes(sampleTypes, str))
+	}
+
+	threadIDs := make(map[string]int)
+	frame := FrameProFrame{FrameNumber: 0}
+	for _, sample := range samples {
+		if valueIndex >= len(sample.values) || sample.values[valueIndex] == 0 || len(sample.locations) == 0 {
+			continue
+		}
+		thread := sample.thread
+		if thread == "" {
+			thread = "Go"
+		}
+		id, exists := threadIDs[thread]
+		if !exists {
+			id = len(threadIDs) + 1
+			threadIDs[thread] = id
+		}
+
+		// Root first: the last location is the outermost caller, and within a location
+		// the last line is the function the others were inlined into
+		stack := []string{}
+		for i := len(sample.locations) - 1; i >= 0; i-- {
+			location := locations[sample.locations[i]]
+			if len(location.functions) == 0 {
+				stack = append(stack, fmt.Sprintf("0x%x", location.address)) // Not symbolized
+			}
+			for j := len(location.functions) - 1; j >= 0; j-- {
+				stack = append(stack, str(functions[location.functions[j]]))
+			}
+		}
+		ms := float64(sample.values[valueIndex]) * scale
+		count := 1
+		if countIndex >= 0 && countIndex < len(sample.values) {
+			count = int(sample.values[countIndex])
+		}
+		into := &frame.Functions
+		for _, name := range stack {
+			index := -1
+			for i, fn := range *into {
+				if fn.FunctionName == name && fn.ThreadID == id {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				*into = append(*into, FrameProFunction{FunctionName: name, ThreadID: id, ThreadName: thread})
+				index = len(*into) - 1
+			}
+			fn := &(*into)[index]
+			fn.TimeMs += ms
+			fn.Count += count
+			into = &fn.Children
+		}
+	}
+	if len(frame.Functions) == 0 {
+		return nil, fmt.Errorf("%s has no samples with %s", filepath.Base(path), str(sampleTypes[valueIndex][0]))
+	}
+
+	// One thread is treated as the main thread; with thread labels, the busiest one
+	mainThread, busiest := 0, -1.0
+	for id, total := range frameThreadTotals(frame) {
+		if total.TimeMs > busiest || total.TimeMs == busiest && id < mainThread {
+			mainThread, busiest = id, total.TimeMs
+		}
+	}
+	var setFlags func(functions []FrameProFunction)
+	setFlags = func(functions []FrameProFunction) {
+		for i := range functions {
+			fn := &functions[i]
+			fn.IsMainThread = fn.ThreadID == mainThread
+			fn.IsWorkerThread = !fn.IsMainThread && isWorkerThreadName(fn.ThreadName)
+			setFlags(fn.Children)
+		}
+	}
+	setFlags(frame.Functions)
+
+	data := &FrameProData{
+		SessionName: strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), filepath.Ext(strings.TrimSuffix(path, ".gz"))),
+		TotalFrames: 1,
+	}
+	for _, fn := range frame.Functions {
+		addFrameScope(&data.Functions, fn)
+	}
+	averageScopes(data.Functions, 1)
+	return data, nil
+}
+
+// pprofSampleTypes lists sample types as type/unit for error messages
+func pprofSampleTypes(sampleTypes [][2]uint64, str func(uint64) string) string {
+	names := []string{}
+	for _, sampleType := range sampleTypes {
+		names = append(names, str(sampleType[0])+"/"+str(sampleType[1]))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Some exporters record, per scope, how often its thread was switched out
+// (ContextSwitches) and for how long it was runnable but not running (PreemptedMs),
+// optionally with what ran instead (PreemptedBy). Preemption inside a scope is also part
+// of its parent's, so only top-level scopes of hierarchical frames are summed. A thread
+// losing far more time to preemption in spike frames than in normal ones was likely
+// pushed off its core, often by a background process on the capture machine.
+
+const preemptionSpikeRatio = 2.0 // Spike-frame preemption over normal-frame preemption to flag a thread
+
+// ThreadPreemption is the preemption of one thread in spike and normal frames
+type ThreadPreemption struct {
+	ThreadName                string         `json:"threadName"`
+	Stage                     string         `json:"stage"`
+	AvgContextSwitches        float64        `json:"avgContextSwitchesPerFrame"`
+	SpikeAvgContextSwitches   float64        `json:"spikeAvgContextSwitches"`
+	AvgPreemptedMs            float64        `json:"avgPreemptedMsNormalFrames"`
+	SpikeAvgPreemptedMs       float64        `json:"avgPreemptedMsSpikeFrames"`
+	SpikeFramesPreempted      int            `json:"spikeFramesPreempted"`
+	ShareOfSpikeExcessPercent float64        `json:"shareOfSpikeExcessPercent"` // Preempted time over the spike frames' time above the median
+	PreemptedBy               []string       `json:"preemptedBy,omitempty"`
+	Flagged                   bool           `json:"flagged"`
+	Evidence                  *IssueEvidence `json:"evidence,omitempty"`
+}
+
+// PreemptedSpike is one spike frame with the thread that lost the most time in it
+type PreemptedSpike struct {
+	FrameNumber int      `json:"frame"`
+	FrameTimeMs float64  `json:"frameTimeMs"`
+	ExcessMs    float64  `json:"excessMs"` // Above the median frame time
+	ThreadName  string   `json:"threadName"`
+	PreemptedMs float64  `json:"preemptedMs"`
+	Switches    int      `json:"contextSwitches"`
+	PreemptedBy []string `json:"preemptedBy,omitempty"`
+}
+
+// framePreemption sums the context switches and preempted time of each thread in a frame
+func framePreemption(frame FrameProFrame) (map[int]int, map[int]float64, map[int]map[string]bool) {
+	switches := make(map[int]int)
+	preempted := make(map[int]float64)
+	by := make(map[int]map[string]bool)
+	for _, fn := range frame.Functions {
+		if fn.ContextSwitches != nil {
+			switches[fn.ThreadID] += *fn.ContextSwitches
+		}
+		if fn.PreemptedMs != nil {
+			preempted[fn.ThreadID] += *fn.PreemptedMs
+		}
+		for _, name := range fn.PreemptedBy {
+			if by[fn.ThreadID] == nil {
+				by[fn.ThreadID] = make(map[string]bool)
+			}
+			by[fn.ThreadID][name] = true
+		}
+	}
+	return switches, preempted, by
+}
+
+func preemptionHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	factor := 2.0
+	if f, ok := args["threshold_factor"].(float64); ok && f > 0 {
+		factor = f
+	}
+	minPreemptedMs := 1.0
+	if ms, ok := args["min_preempted_ms"].(float64); ok && ms >= 0 {
+		minPreemptedMs = ms
+	}
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	stages := threadStages(data)
+	times := frameTimes(data)
+	median := medianOf(times)
+	threshold := median * factor
+
+	type threadSums struct {
+		name                              string
+		switches, spikeSwitches           int
+		preempted, spikePreempted, excess float64
+		spikeFrames                       map[int]float64
+		by                                map[string]int
+	}
+	threads := make(map[int]*threadSums)
+	spikeCount := 0
+	var spikeExcess float64
+	spikes := []PreemptedSpike{}
+	for i, frame := range data.Frames {
+		switches, preempted, by := framePreemption(frame)
+		spike := times[i] > threshold
+		if spike {
+			spikeCount++
+			spikeExcess += times[i] - median
+		}
+		worst := PreemptedSpike{FrameNumber: frame.FrameNumber, FrameTimeMs: times[i], ExcessMs: times[i] - median}
+		for _, fn := range frame.Functions {
+			if _, recorded := switches[fn.ThreadID]; !recorded {
+				if _, recorded = preempted[fn.ThreadID]; !recorded {
+					continue
+				}
+			}
+			if threads[fn.ThreadID] == nil {
+				threads[fn.ThreadID] = &threadSums{name: fn.ThreadName, spikeFrames: make(map[int]float64), by: make(map[string]int)}
+			}
+		}
+		for id, thread := range threads {
+			ms := preempted[id]
+			if !spike {
+				thread.switches += switches[id]
+				thread.preempted += ms
+				continue
+			}
+			thread.spikeSwitches += switches[id]
+			thread.spikePreempted += ms
+			if ms >= minPreemptedMs {
+				thread.spikeFrames[frame.FrameNumber] = ms
+				for name := range by[id] {
+					thread.by[name]++
+				}
+			}
+			if ms > worst.PreemptedMs {
+				worst.ThreadName, worst.PreemptedMs, worst.Switches = thread.name, ms, switches[id]
+				worst.PreemptedBy = worst.PreemptedBy[:0]
+				for name := range by[id] {
+					worst.PreemptedBy = append(worst.PreemptedBy, name)
+				}
+				sort.Strings(worst.PreemptedBy)
+			}
+		}
+		if spike && worst.PreemptedMs >= minPreemptedMs {
+			spikes = append(spikes, worst)
+		}
+	}
+	if len(threads) == 0 {
+		return mcp.NewToolResultError("This capture has no context-switch data (ContextSwitches or PreemptedMs) on its per-frame scopes. FramePro's standard JSON exports don't record it; preemption analysis needs an exporter that writes scheduler data per scope"), nil
+	}
+
+	normalCount := len(data.Frames) - spikeCount
+	preemption := []*ThreadPreemption{}
+	for id, thread := range threads {
+		result := &ThreadPreemption{
+			ThreadName:           thread.name,
+			Stage:                stages[id],
+			SpikeFramesPreempted: len(thread.spikeFrames),
+		}
+		if normalCount > 0 {
+			result.AvgContextSwitches = float64(thread.switches) / float64(normalCount)
+			result.AvgPreemptedMs = thread.preempted / float64(normalCount)
+		}
+		if spikeCount > 0 {
+			result.SpikeAvgContextSwitches = float64(thread.spikeSwitches) / float64(spikeCount)
+			result.SpikeAvgPreemptedMs = thread.spikePreempted / float64(spikeCount)
+		}
+		if spikeExcess > 0 {
+			result.ShareOfSpikeExcessPercent = thread.spikePreempted / spikeExcess * 100
+		}
+		for name := range thread.by {
+			result.PreemptedBy = append(result.PreemptedBy, name)
+		}
+		sort.Slice(result.PreemptedBy, func(i, j int) bool {
+			a, b := result.PreemptedBy[i], result.PreemptedBy[j]
+			if thread.by[a] != thread.by[b] {
+				return thread.by[a] > thread.by[b]
+			}
+			return a < b
+		})
+		result.Flagged = result.SpikeAvgPreemptedMs >= minPreemptedMs && result.SpikeAvgPreemptedMs >= result.AvgPreemptedMs*preemptionSpikeRatio
+		if result.SpikeFramesPreempted > 0 {
+			frames := thread.spikeFrames
+			result.Evidence = frameEvidence(data, func(frame FrameProFrame) float64 {
+				return frames[frame.FrameNumber]
+			}, minPreemptedMs)
+		}
+		preemption = append(preemption, result)
+	}
+	sort.Slice(preemption, func(i, j int) bool {
+		if preemption[i].SpikeAvgPreemptedMs != preemption[j].SpikeAvgPreemptedMs {
+			return preemption[i].SpikeAvgPreemptedMs > preemption[j].SpikeAvgPreemptedMs
+		}
+		return preemption[i].ThreadName < preemption[j].ThreadName
+	})
+	sort.Slice(spikes, func(i, j int) bool {
+		return spikes[i].PreemptedMs > spikes[j].PreemptedMs
+	})
+	preemptedSpikes := len(spikes)
+	if len(spikes) > topN {
+		spikes = spikes[:topN]
+	}
+
+	analysis := []string{}
+	suggestions := []string{}
+	external := make(map[string]bool)
+	for _, thread := range preemption {
+		if !thread.Flagged {
+			continue
+		}
+		line := fmt.Sprintf("%s lost %.2fms per spike frame to preemption vs %.2fms in normal frames (%.1f vs %.1f context switches), %.0f%% of the spikes' time above the median",
+			thread.ThreadName, thread.SpikeAvgPreemptedMs, thread.AvgPreemptedMs, thread.SpikeAvgContextSwitches, thread.AvgContextSwitches, thread.ShareOfSpikeExcessPercent)
+		if len(thread.PreemptedBy) > 0 {
+			line += fmt.Sprintf("; preempted by %s", strings.Join(thread.PreemptedBy[:min(len(thread.PreemptedBy), 3)], ", "))
+			for _, name := range thread.PreemptedBy {
+				external[name] = true
+			}
+		}
+		analysis = append(analysis, line)
+	}
+	if len(external) > 0 {
+		names := []string{}
+		for name := range external {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		suggestions = append(suggestions, fmt.Sprintf("Close or lower the priority of %s on the capture machine and re-capture before treating these spikes as regressions", strings.Join(names, ", ")))
+	} else if len(analysis) > 0 {
+		suggestions = append(suggestions, "Check the capture machine for background processes (updaters, indexers, antivirus scans) and re-capture before treating these spikes as regressions")
+	}
+	for _, thread := range preemption {
+		if thread.Flagged && thread.Stage == stageMain {
+			suggestions = append(suggestions, fmt.Sprintf("Raise the priority of %s or reserve it a core so the OS schedules other work elsewhere", thread.ThreadName))
+		}
+	}
+
+	summary := fmt.Sprintf("No thread is preempted much more in the %d spike frames (over %.2fms) than in normal frames", spikeCount, threshold)
+	if spikeCount == 0 {
+		summary = fmt.Sprintf("No spike frames over %.2fms (%.1fx the median) to check for preemption", threshold, factor)
+	} else if len(analysis) > 0 {
+		summary = fmt.Sprintf("%d threads were heavily preempted in spike frames; %d of %d spike frames lost at least %.1fms on one thread", len(analysis), preemptedSpikes, spikeCount, minPreemptedMs)
+	}
+
+	output := map[string]interface{}{
+		"file":             filePath,
+		"sessionName":      data.SessionName,
+		"framesAnalyzed":   len(data.Frames),
+		"medianFrameMs":    median,
+		"spikeThresholdMs": threshold,
+		"spikeFrames":      spikeCount,
+		"threads":          preemption,
+		"worstSpikes":      spikes,
+		"suggestions":      suggestions,
+		"analysis":         analysis,
+		"summary":          summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
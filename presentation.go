@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Present timing comes per frame under Present, with PresentMon's column names, when a
+// capture was merged with PresentMon output. Frame times only say how long the CPU took;
+// presentation says what the player saw:
+//
+//	dropped present  - the frame was rendered but never displayed
+//	late flip        - the frame finished rendering at least one refresh before it was shown
+//	display hitch    - the display kept a frame for extra refreshes although the CPU frame
+//	                   fit the refresh interval, so the hitch isn't a CPU problem
+//	tearing risk     - presents without vsync (sync interval 0, tearing allowed) whose
+//	                   display changes fall between refreshes
+
+const (
+	lateFlipTolerance     = 0.1 // Share of a refresh interval a flip may be late without counting
+	displayHitchRefreshes = 1.5 // Display change interval, in refreshes, that counts as a display hitch
+)
+
+// FramePresent is the PresentMon timing of one frame's present
+type FramePresent struct {
+	TimeInSeconds          float64 `json:"TimeInSeconds,omitempty"`          // When Present was called
+	MsBetweenPresents      float64 `json:"MsBetweenPresents,omitempty"`      // Since the previous Present call
+	MsBetweenDisplayChange float64 `json:"MsBetweenDisplayChange,omitempty"` // Since the previous frame was displayed
+	MsInPresentAPI         float64 `json:"MsInPresentAPI,omitempty"`
+	MsUntilRenderComplete  float64 `json:"MsUntilRenderComplete,omitempty"` // From Present until the GPU finished the frame
+	MsUntilDisplayed       float64 `json:"MsUntilDisplayed,omitempty"`      // From Present until the frame was shown
+	Dropped                bool    `json:"Dropped,omitempty"`
+	SyncInterval           *int    `json:"SyncInterval,omitempty"`
+	PresentMode            string  `json:"PresentMode,omitempty"`
+	AllowsTearing          bool    `json:"AllowsTearing,omitempty"`
+}
+
+// PresentIssueFrame is one frame with a presentation problem
+type PresentIssueFrame struct {
+	FrameNumber     int     `json:"frame"`
+	Issue           string  `json:"issue"`
+	CPUFrameMs      float64 `json:"cpuFrameMs"`
+	DisplayChangeMs float64 `json:"displayChangeMs,omitempty"`
+	ReadyToFlipMs   float64 `json:"readyToFlipMs,omitempty"` // Render complete until displayed
+}
+
+func presentationHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	refreshHz, _ := args["refresh_hz"].(float64)
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	presented := []int{} // Frame indexes with present data
+	displayIntervals := []float64{}
+	vsyncIntervals := []float64{} // Only vsynced display changes land on refreshes
+	for i, frame := range data.Frames {
+		if frame.Present == nil {
+			continue
+		}
+		presented = append(presented, i)
+		if !frame.Present.Dropped && frame.Present.MsBetweenDisplayChange > 0 {
+			displayIntervals = append(displayIntervals, frame.Present.MsBetweenDisplayChange)
+			if frame.Present.SyncInterval == nil || *frame.Present.SyncInterval != 0 {
+				vsyncIntervals = append(vsyncIntervals, frame.Present.MsBetweenDisplayChange)
+			}
+		}
+	}
+	if len(presented) == 0 {
+		return mcp.NewToolResultError("This capture has no present timing (Present on its frames). Merge PresentMon output (MsBetweenDisplayChange, MsUntilRenderComplete, MsUntilDisplayed, Dropped, ...) into the frames to analyze presentation"), nil
+	}
+
+	// Unless given, the refresh interval is the shortest common vsynced display change
+	// interval: frames shown for extra refreshes only lengthen the rest
+	refreshSource := "refresh_hz"
+	if refreshHz <= 0 {
+		refreshSource = "default (no vsynced presents to estimate from)"
+		refreshHz = 60
+		sort.Float64s(vsyncIntervals)
+		if len(vsyncIntervals) > 0 {
+			if base := percentile(vsyncIntervals, 10); base > 0 {
+				refreshSource = "estimated from display change intervals"
+				refreshHz = math.Round(1000 / base)
+			}
+		}
+	}
+	refreshMs := 1000 / refreshHz
+
+	issues := []PresentIssueFrame{}
+	counts := make(map[string]int)
+	tearingCapable := 0
+	var readyToFlip []float64
+	add := func(issue string, i int, present *FramePresent, readyMs float64) {
+		counts[issue]++
+		issues = append(issues, PresentIssueFrame{
+			FrameNumber:     data.Frames[i].FrameNumber,
+			Issue:           issue,
+			CPUFrameMs:      times[i],
+			DisplayChangeMs: present.MsBetweenDisplayChange,
+			ReadyToFlipMs:   readyMs,
+		})
+	}
+	for _, i := range presented {
+		present := data.Frames[i].Present
+		if present.Dropped {
+			add("dropped", i, present, 0)
+			continue
+		}
+		noVSync := present.SyncInterval != nil && *present.SyncInterval == 0
+		tearing := present.AllowsTearing && noVSync
+		if tearing {
+			tearingCapable++
+			// A display change between refreshes means the flip landed mid-scanout
+			if refreshes := present.MsBetweenDisplayChange / refreshMs; present.MsBetweenDisplayChange > 0 && math.Abs(refreshes-math.Round(refreshes)) > lateFlipTolerance {
+				add("tearing", i, present, 0)
+			}
+		}
+		if present.MsUntilDisplayed > 0 && present.MsUntilRenderComplete > 0 {
+			ready := present.MsUntilDisplayed - present.MsUntilRenderComplete
+			readyToFlip = append(readyToFlip, ready)
+			if !noVSync && ready > refreshMs*(1+lateFlipTolerance) {
+				add("late-flip", i, present, ready)
+			}
+		}
+		if present.MsBetweenDisplayChange > refreshMs*displayHitchRefreshes && times[i] <= refreshMs {
+			add("display-hitch", i, present, 0)
+		}
+	}
+
+	var avgReady, p95Ready float64
+	if len(readyToFlip) > 0 {
+		avgReady, _ = meanStdDev(readyToFlip)
+		sort.Float64s(readyToFlip)
+		p95Ready = percentile(readyToFlip, 95)
+	}
+	sortedIntervals := append([]float64(nil), displayIntervals...)
+	sort.Float64s(sortedIntervals)
+	intervals := map[string]interface{}{"count": len(sortedIntervals)}
+	if len(sortedIntervals) > 0 {
+		intervals["medianMs"] = percentile(sortedIntervals, 50)
+		intervals["p95Ms"] = percentile(sortedIntervals, 95)
+		intervals["maxMs"] = sortedIntervals[len(sortedIntervals)-1]
+	}
+
+	analysis := []string{}
+	suggestions := []string{}
+	if counts["dropped"] > 0 {
+		analysis = append(analysis, fmt.Sprintf("%d of %d presents were dropped: rendered but never displayed", counts["dropped"], len(presented)))
+		suggestions = append(suggestions, "Dropped presents waste GPU time: cap the frame rate to the refresh rate or use a flip model with fewer queued frames")
+	}
+	if counts["late-flip"] > 0 {
+		analysis = append(analysis, fmt.Sprintf("%d frames flipped late: ready at least one refresh (%.2fms) before they were shown; render complete to display averages %.2fms (p95 %.2fms)",
+			counts["late-flip"], refreshMs, avgReady, p95Ready))
+		suggestions = append(suggestions, "Late flips add latency without a CPU or GPU cause: reduce the swap chain's queued frames (maximum frame latency) or check for composition (windowed mode) instead of independent flip")
+	}
+	if counts["display-hitch"] > 0 {
+		analysis = append(analysis, fmt.Sprintf("%d display hitches where the CPU frame fit the %.2fms refresh but the display kept the previous frame for extra refreshes; frame times alone would not show them",
+			counts["display-hitch"], refreshMs))
+	}
+	if tearingCapable > 0 {
+		line := fmt.Sprintf("%d presents allow tearing (sync interval 0)", tearingCapable)
+		if counts["tearing"] > 0 {
+			line += fmt.Sprintf("; %d of them changed the display between refreshes and likely tore", counts["tearing"])
+			suggestions = append(suggestions, "Enable vsync or VRR (G-Sync/FreeSync) to avoid tearing, or cap the frame rate just below the refresh rate with VRR")
+		}
+		analysis = append(analysis, line)
+	}
+	modes := make(map[string]int)
+	for _, i := range presented {
+		if mode := data.Frames[i].Present.PresentMode; mode != "" {
+			modes[mode]++
+		}
+	}
+	modeNames := make([]string, 0, len(modes))
+	for mode := range modes {
+		modeNames = append(modeNames, mode)
+	}
+	sort.Strings(modeNames)
+	for _, mode := range modeNames {
+		if strings.Contains(strings.ToLower(mode), "composed") {
+			analysis = append(analysis, fmt.Sprintf("%d presents went through the compositor (%s), which adds a frame of latency", modes[mode], mode))
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].DisplayChangeMs+issues[i].ReadyToFlipMs > issues[j].DisplayChangeMs+issues[j].ReadyToFlipMs
+	})
+	if len(issues) > topN {
+		issues = issues[:topN]
+	}
+
+	summary := fmt.Sprintf("Presentation is clean across %d presents at %.0f Hz", len(presented), refreshHz)
+	if problems := counts["dropped"] + counts["late-flip"] + counts["display-hitch"] + counts["tearing"]; problems > 0 {
+		summary = fmt.Sprintf("%d presentation problems across %d presents at %.0f Hz: %d dropped, %d late flips, %d display hitches, %d likely tears",
+			problems, len(presented), refreshHz, counts["dropped"], counts["late-flip"], counts["display-hitch"], counts["tearing"])
+	}
+
+	output := map[string]interface{}{
+		"file":                   filePath,
+		"sessionName":            data.SessionName,
+		"framesAnalyzed":         len(data.Frames),
+		"framesWithPresent":      len(presented),
+		"refreshHz":              refreshHz,
+		"refreshSource":          refreshSource,
+		"displayIntervals":       intervals,
+		"avgRenderToDisplayMs":   avgReady,
+		"p95RenderToDisplayMs":   p95Ready,
+		"issueCounts":            counts,
+		"presentModes":           modes,
+		"worstFrames":            issues,
+		"tearingCapablePresents": tearingCapable,
+		"suggestions":            suggestions,
+		"analysis":               analysis,
+		"summary":                summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Budget projections fit a line through each subsystem's average time per frame across
+// the captures in a directory, dated by file modification time as in search_history, and
+// extrapolate when the line crosses the budget. Only steady growth (correlation of at
+// least minProjectionCorrelation) is projected.
+
+const minProjectionCorrelation = 0.5
+
+// CapturePoint is one capture's value in a projection
+type CapturePoint struct {
+	File  string  `json:"file"`
+	Date  string  `json:"date"`
+	Value float64 `json:"valueMs"`
+}
+
+// BudgetProjection projects when a subsystem or function will exceed its budget
+type BudgetProjection struct {
+	Name          string         `json:"name"`
+	Pattern       string         `json:"pattern,omitempty"`
+	BudgetMs      float64        `json:"budgetMs"`
+	CurrentMs     float64        `json:"currentMs"` // Fitted value at the latest capture
+	MsPerWeek     float64        `json:"msPerWeek"`
+	Correlation   float64        `json:"correlation"`
+	Status        string         `json:"status"`
+	DaysToBudget  float64        `json:"daysToBudget,omitempty"`
+	BreachDate    string         `json:"projectedBreachDate,omitempty"`
+	Projection    string         `json:"projection"`
+	CapturePoints []CapturePoint `json:"captures"`
+}
+
+// humanDuration rounds a number of days to the unit a person would use
+func humanDuration(days float64) string {
+	switch {
+	case days < 2:
+		return fmt.Sprintf("~%.0f hours", math.Max(1, days*24))
+	case days < 14:
+		return fmt.Sprintf("~%.0f days", days)
+	case days < 60:
+		return fmt.Sprintf("~%.0f weeks", days/7)
+	case days < 730:
+		return fmt.Sprintf("~%.0f months", days/30)
+	default:
+		return fmt.Sprintf("~%.0f years", days/365)
+	}
+}
+
+// projectBudget fits points (days since the first capture, ms) and projects the breach of budgetMs
+func projectBudget(projection *BudgetProjection, days, values []float64, latest time.Time) {
+	slope, intercept, r := linearFit(days, values)
+	last := days[len(days)-1]
+	projection.CurrentMs = intercept + slope*last
+	projection.MsPerWeek = slope * 7
+	projection.Correlation = r
+
+	switch {
+	case projection.CurrentMs > projection.BudgetMs:
+		projection.Status = "over budget"
+		projection.Projection = fmt.Sprintf("%s is already over its %.2fms budget at %.2fms", projection.Name, projection.BudgetMs, projection.CurrentMs)
+	case slope <= 0:
+		projection.Status = "not growing"
+		projection.Projection = fmt.Sprintf("%s is not growing (%.2fms of %.2fms)", projection.Name, projection.CurrentMs, projection.BudgetMs)
+	case r < minProjectionCorrelation:
+		projection.Status = "no steady trend"
+		projection.Projection = fmt.Sprintf("%s varies between captures without a steady trend (r=%.2f); no projection", projection.Name, r)
+	default:
+		projection.Status = "growing"
+		projection.DaysToBudget = (projection.BudgetMs - projection.CurrentMs) / slope
+		projection.BreachDate = latest.Add(time.Duration(projection.DaysToBudget * 24 * float64(time.Hour))).Format("2006-01-02")
+		projection.Projection = fmt.Sprintf("%s will blow its %.2fms budget in %s at the current rate (%.2fms now, %+.3fms per week)",
+			projection.Name, projection.BudgetMs, humanDuration(projection.DaysToBudget), projection.CurrentMs, projection.MsPerWeek)
+	}
+}
+
+func projectBudgetsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	directory, _ := args["directory"].(string)
+	if directory == "" {
+		directory = dataDir
+	}
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		pattern = "*.json"
+	}
+	targetFPS := 60.0
+	if fps, ok := args["target_fps"].(float64); ok && fps > 0 {
+		targetFPS = fps
+	}
+	functionName, _ := args["function"].(string)
+	functionBudget, _ := args["budget_ms"].(float64)
+	if functionName != "" && functionBudget <= 0 {
+		return mcp.NewToolResultError("budget_ms is required with function"), nil
+	}
+	budgets, err := parseBudgets(args, targetFPS)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	filter, err := parseSessionFilter(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	files, err := listCaptureFiles(directory, pattern)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// One value per capture and projection: either the requested function or every budget
+	projections := []*BudgetProjection{}
+	if functionName != "" {
+		projections = append(projections, &BudgetProjection{Name: functionName, BudgetMs: functionBudget})
+	} else {
+		for _, budget := range budgets {
+			projections = append(projections, &BudgetProjection{Name: budget.Name, Pattern: budget.Pattern, BudgetMs: budget.BudgetMs})
+		}
+	}
+	days := []float64{}
+	values := make([][]float64, len(projections))
+	skipped := []string{}
+	var first, latest time.Time
+	read := len(files)
+	for i, file := range files {
+		if ctx.Err() != nil { // Out of time or canceled: report the captures read so far
+			read = i
+			break
+		}
+		data, err := loadFrameProData(file.Path)
+		if err != nil {
+			skipped = append(skipped, filepath.Base(file.Path))
+			continue
+		}
+		if _, selected := filter.match(file.Path, data); !selected {
+			continue
+		}
+		if len(days) == 0 {
+			first = file.Modified
+		}
+		latest = file.Modified
+		days = append(days, file.Modified.Sub(first).Hours()/24)
+
+		point := CapturePoint{File: filepath.Base(file.Path), Date: file.Modified.Format(time.RFC3339)}
+		if functionName != "" {
+			point.Value = mergeFunctionsByName(data.Functions)[functionName].AvgTimePerFrameMs
+			values[0] = append(values[0], point.Value)
+			projections[0].CapturePoints = append(projections[0].CapturePoints, point)
+			continue
+		}
+		times, _ := subsystemFrameTimes(data, budgets)
+		for i := range budgets {
+			point.Value, _ = meanStdDev(times[i])
+			values[i] = append(values[i], point.Value)
+			projections[i].CapturePoints = append(projections[i].CapturePoints, point)
+		}
+	}
+	if len(days) < 3 && read < len(files) {
+		return mcp.NewToolResultError(fmt.Sprintf("Timed out after reading %d of %d captures, with %d matching; projections need at least 3. Narrow the pattern or raise timeout_seconds", read, len(files), len(days))), nil
+	}
+	if len(days) < 3 {
+		return mcp.NewToolResultError(fmt.Sprintf("Projections need at least 3 captures; found %d matching '%s' in %s", len(days), pattern, directory)), nil
+	}
+	if days[len(days)-1] == 0 {
+		return mcp.NewToolResultError("All captures have the same date; projections need captures taken over time"), nil
+	}
+
+	analysis := []string{}
+	growing := 0
+	for i, projection := range projections {
+		projectBudget(projection, days, values[i], latest)
+		if projection.Status == "growing" {
+			growing++
+		}
+	}
+	sort.SliceStable(projections, func(i, j int) bool {
+		rank := map[string]int{"growing": 0, "over budget": 1, "no steady trend": 2, "not growing": 3}
+		if rank[projections[i].Status] != rank[projections[j].Status] {
+			return rank[projections[i].Status] < rank[projections[j].Status]
+		}
+		return projections[i].DaysToBudget < projections[j].DaysToBudget
+	})
+	for _, projection := range projections {
+		if projection.Status == "over budget" || projection.Status == "growing" {
+			analysis = append(analysis, projection.Projection)
+		}
+	}
+
+	summary := fmt.Sprintf("Nothing is growing toward its budget across %d captures", len(days))
+	if growing > 0 {
+		soonest := projections[0] // Growing projections sort first, soonest breach first
+		summary = fmt.Sprintf("%d of %d budgets are growing toward their limit across %d captures; first to break is %s in %s",
+			growing, len(projections), len(days), soonest.Name, humanDuration(soonest.DaysToBudget))
+	}
+
+	output := map[string]interface{}{
+		"directory":   directory,
+		"pattern":     pattern,
+		"captures":    len(days),
+		"firstDate":   first.Format(time.RFC3339),
+		"latestDate":  latest.Format(time.RFC3339),
+		"projections": projections,
+		"analysis":    analysis,
+		"summary":     summary,
+	}
+	if len(skipped) > 0 {
+		output["skippedFiles"] = skipped
+	}
+	if read < len(files) {
+		attachPartial(ctx, output, read, len(files), "capture files")
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Refactors rename functions between captures, which a comparison would otherwise report as a
+// removed function plus an unrelated new one. A rename map (old name -> new name) from the
+// renames argument or a project file named by FRAMEPRO_RENAMES renames the baseline's
+// scopes before the captures are compared, so both sides pair up; renamed entries keep the
+// old name in renamedFrom.
+
+// projectRenames are the renames declared in the FRAMEPRO_RENAMES file, if any
+var projectRenames map[string]string
+
+// RenameUse is one rename that applied to the baseline
+type RenameUse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// loadProjectRenames reads the project's rename map: {"OldName": "NewName", ...}
+func loadProjectRenames(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read renames: %w", err)
+	}
+	var renames map[string]string
+	if err := json.Unmarshal(content, &renames); err != nil {
+		return fmt.Errorf("failed to parse renames %s: %w", path, err)
+	}
+	for from, to := range renames {
+		if from == "" || to == "" {
+			return fmt.Errorf("rename %q -> %q in %s needs both names", from, to, path)
+		}
+	}
+	projectRenames = renames
+	return nil
+}
+
+// renamesSchema describes the renames parameter
+func renamesSchema() mcp.ToolOption {
+	return mcp.WithObject("renames",
+		mcp.Description("Functions renamed since the baseline, old name to new name, e.g. {\"UPhysicsSystem::Step\": \"Physics::Step\"}. Added to the FRAMEPRO_RENAMES project file, overriding its entries"))
+}
+
+// parseRenames merges the renames argument into the project's renames
+func parseRenames(args map[string]interface{}) (map[string]string, error) {
+	renames := make(map[string]string)
+	for from, to := range projectRenames {
+		renames[from] = to
+	}
+	raw, _ := args["renames"].(map[string]interface{})
+	for from, value := range raw {
+		to, _ := value.(string)
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("rename %q needs a new function name", from)
+		}
+		renames[from] = to
+	}
+	return renames, nil
+}
+
+// applyRenames renames the scopes of a capture and returns the renames that matched a scope
+func applyRenames(data *FrameProData, renames map[string]string) []RenameUse {
+	if len(renames) == 0 {
+		return nil
+	}
+	used := make(map[string]bool)
+	var rename func(functions []FrameProFunction)
+	rename = func(functions []FrameProFunction) {
+		for i := range functions {
+			if to, found := renames[functions[i].FunctionName]; found {
+				used[functions[i].FunctionName] = true
+				functions[i].FunctionName = to
+			}
+			rename(functions[i].Children)
+		}
+	}
+	rename(data.Functions)
+	rename(data.Tree)
+	for f := range data.Frames {
+		rename(data.Frames[f].Functions)
+	}
+
+	uses := []RenameUse{}
+	for from := range used {
+		uses = append(uses, RenameUse{From: from, To: renames[from]})
+	}
+	sort.Slice(uses, func(i, j int) bool { return uses[i].From < uses[j].From })
+	return uses
+}
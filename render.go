@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Render-thread scopes are grouped by what they do, from their names:
+//
+//	present - handing the frame to the GPU: present, swap chain, fence and vsync waits
+//	wait    - other waits, usually for the main thread to hand over the next frame
+//	state   - pipeline state changes: Set*/Bind* calls, PSOs, shaders, render targets
+//	submit  - command list recording and submission
+//	draw    - draw calls, passes and scene rendering
+//	other   - anything else the render thread does
+//
+// Present and wait scopes are not render work, so only the rest counts as busy time.
+
+// Render scope categories
+const (
+	renderPresent = "present"
+	renderWait    = "wait"
+	renderState   = "state"
+	renderSubmit  = "submit"
+	renderDraw    = "draw"
+	renderOther   = "other"
+)
+
+var renderCategories = []string{renderDraw, renderSubmit, renderState, renderOther, renderPresent, renderWait}
+
+// renderCategory classifies a render-thread scope by name
+func renderCategory(name string) string {
+	lower := strings.ToLower(name)
+	hasAny := func(words ...string) bool {
+		for _, word := range words {
+			if strings.Contains(lower, word) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case isGPUWaitScope(name) || isVSyncScope(name):
+		return renderPresent
+	case isWaitScope(name):
+		return renderWait
+	case hasAny("setstate", "statechange", "pipelinestate", "pso", "bind", "setshader", "settexture", "setrendertarget", "setviewport", "setconstant", "setvertex", "setindex"):
+		return renderState
+	case hasAny("submit", "execute", "commandlist", "commandbuffer", "cmdlist", "cmdbuffer", "flush", "rhi"):
+		return renderSubmit
+	case hasAny("draw", "render", "pass", "mesh", "instanc", "dispatch", "scene", "shadow", "cull", "occlusion"):
+		return renderDraw
+	}
+	return renderOther
+}
+
+// RenderCategoryStats is the time of one scope category on the render thread
+type RenderCategoryStats struct {
+	Category        string   `json:"category"`
+	AvgMsPerFrame   float64  `json:"avgMsPerFrame"`
+	PercentOfThread float64  `json:"percentOfRenderThread"`
+	TopFunctions    []string `json:"topFunctions"`
+}
+
+// StateChangeFunction is a render-thread state-change scope
+type StateChangeFunction struct {
+	FunctionName      string  `json:"functionName"`
+	AvgTimePerFrameMs float64 `json:"avgTimePerFrameMs"`
+	AvgCallsPerFrame  float64 `json:"avgCallsPerFrame"`
+	AvgTimePerCallUs  float64 `json:"avgTimePerCallUs"`
+}
+
+// RenderFrame compares the render thread's busy time with the budget and the main thread
+type RenderFrame struct {
+	FrameNumber    int     `json:"frame"`
+	RenderBusyMs   float64 `json:"renderBusyMs"`
+	MainBusyMs     float64 `json:"mainBusyMs"`
+	PresentMs      float64 `json:"presentMs"`
+	TopFunction    string  `json:"topFunction"`
+	OverBudget     bool    `json:"overBudget"`
+	SlowerThanMain bool    `json:"slowerThanMain"` // Render work took longer than main-thread work
+}
+
+func renderThreadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, data)
+	budgetMs := 1000 / targetFPS
+	if ms, ok := args["budget_ms"].(float64); ok && ms > 0 {
+		budgetMs = ms
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	stages := threadStages(data)
+	categoryMs := make(map[string]float64)
+	functionMs := make(map[string]float64)
+	functionCalls := make(map[string]float64)
+	renderThreads := make(map[string]bool)
+	frames := []RenderFrame{}
+	overBudget, slowerThanMain, limiting := 0, 0, 0
+	var totalMs float64
+	for _, frame := range data.Frames {
+		rf := RenderFrame{FrameNumber: frame.FrameNumber}
+		scopes := make(map[string]float64)
+		for _, fn := range frame.Functions {
+			switch stages[fn.ThreadID] {
+			case stageMain:
+				if !isWaitScope(fn.FunctionName) {
+					rf.MainBusyMs += fn.TimeMs
+				}
+				continue
+			case stageRender:
+			default:
+				continue
+			}
+			renderThreads[fn.ThreadName] = true
+			category := renderCategory(fn.FunctionName)
+			categoryMs[category] += fn.TimeMs
+			functionMs[fn.FunctionName] += fn.TimeMs
+			functionCalls[fn.FunctionName] += float64(fn.Count)
+			totalMs += fn.TimeMs
+			switch category {
+			case renderPresent:
+				rf.PresentMs += fn.TimeMs
+			case renderWait:
+			default:
+				rf.RenderBusyMs += fn.TimeMs
+				scopes[fn.FunctionName] += fn.TimeMs
+			}
+		}
+		var topMs float64
+		for name, ms := range scopes {
+			if ms > topMs || ms == topMs && name < rf.TopFunction {
+				rf.TopFunction, topMs = name, ms
+			}
+		}
+		rf.OverBudget = rf.RenderBusyMs > budgetMs
+		rf.SlowerThanMain = rf.RenderBusyMs > rf.MainBusyMs
+		if rf.OverBudget {
+			overBudget++
+		}
+		if rf.SlowerThanMain {
+			slowerThanMain++
+			if rf.OverBudget {
+				limiting++
+			}
+		}
+		frames = append(frames, rf)
+	}
+	if len(renderThreads) == 0 {
+		return mcp.NewToolResultError("No render thread found. Render threads are recognized by the IsRenderThread flag or 'Render'/'RHI' in the thread name"), nil
+	}
+	frameCount := float64(len(data.Frames))
+
+	// Time per category, with the functions that make it up
+	byCategory := make(map[string][]string)
+	for name := range functionMs {
+		category := renderCategory(name)
+		byCategory[category] = append(byCategory[category], name)
+	}
+	breakdown := []RenderCategoryStats{}
+	for _, category := range renderCategories {
+		names := byCategory[category]
+		if len(names) == 0 {
+			continue
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return functionMs[names[i]] > functionMs[names[j]]
+		})
+		if len(names) > 3 {
+			names = names[:3]
+		}
+		stats := RenderCategoryStats{Category: category, AvgMsPerFrame: categoryMs[category] / frameCount, TopFunctions: names}
+		if totalMs > 0 {
+			stats.PercentOfThread = categoryMs[category] / totalMs * 100
+		}
+		breakdown = append(breakdown, stats)
+	}
+
+	stateChanges := []StateChangeFunction{}
+	for _, name := range byCategory[renderState] {
+		change := StateChangeFunction{
+			FunctionName:      name,
+			AvgTimePerFrameMs: functionMs[name] / frameCount,
+			AvgCallsPerFrame:  functionCalls[name] / frameCount,
+		}
+		if functionCalls[name] > 0 {
+			change.AvgTimePerCallUs = functionMs[name] / functionCalls[name] * 1000
+		}
+		stateChanges = append(stateChanges, change)
+	}
+	sort.Slice(stateChanges, func(i, j int) bool {
+		return stateChanges[i].AvgTimePerFrameMs > stateChanges[j].AvgTimePerFrameMs
+	})
+	if len(stateChanges) > topN {
+		stateChanges = stateChanges[:topN]
+	}
+
+	worst := append([]RenderFrame(nil), frames...)
+	sort.SliceStable(worst, func(i, j int) bool {
+		return worst[i].RenderBusyMs > worst[j].RenderBusyMs
+	})
+	if len(worst) > topN {
+		worst = worst[:topN]
+	}
+
+	var busyMs, mainMs float64
+	for _, rf := range frames {
+		busyMs += rf.RenderBusyMs
+		mainMs += rf.MainBusyMs
+	}
+	threadNames := []string{}
+	for name := range renderThreads {
+		threadNames = append(threadNames, name)
+	}
+	sort.Strings(threadNames)
+
+	analysis := []string{}
+	for _, stats := range breakdown {
+		analysis = append(analysis, fmt.Sprintf("%s: %.2fms per frame (%.1f%% of the render thread), mostly %s",
+			stats.Category, stats.AvgMsPerFrame, stats.PercentOfThread, stats.TopFunctions[0]))
+	}
+	if len(stateChanges) > 0 {
+		analysis = append(analysis, fmt.Sprintf("Heaviest state change: %s, %.2fms per frame over %.0f calls (%.1fus per call)",
+			stateChanges[0].FunctionName, stateChanges[0].AvgTimePerFrameMs, stateChanges[0].AvgCallsPerFrame, stateChanges[0].AvgTimePerCallUs))
+	}
+	analysis = append(analysis, fmt.Sprintf("Render work took longer than main-thread work in %d of %d frames; %d of those were also over budget, so the render thread set the frame time",
+		slowerThanMain, len(frames), limiting))
+	if len(worst) > 0 && worst[0].OverBudget {
+		analysis = append(analysis, fmt.Sprintf("Worst frame %d: %.2fms of render work against %.2fms on the main thread, mostly %s",
+			worst[0].FrameNumber, worst[0].RenderBusyMs, worst[0].MainBusyMs, worst[0].TopFunction))
+	}
+
+	summary := fmt.Sprintf("The render thread finishes within the %.2fms budget in every frame (%.2fms average)", budgetMs, busyMs/frameCount)
+	if overBudget > 0 {
+		summary = fmt.Sprintf("The render thread is over the %.2fms budget in %d of %d frames (%.2fms average against %.2fms on the main thread)",
+			budgetMs, overBudget, len(frames), busyMs/frameCount, mainMs/frameCount)
+	}
+
+	output := map[string]interface{}{
+		"file":                 filePath,
+		"sessionName":          data.SessionName,
+		"renderThreads":        threadNames,
+		"budgetMs":             budgetMs,
+		"targetFPS":            targetFPS,
+		"targetFPSSource":      targetSource,
+		"framesAnalyzed":       len(frames),
+		"avgRenderBusyMs":      busyMs / frameCount,
+		"avgMainBusyMs":        mainMs / frameCount,
+		"overBudgetFrames":     overBudget,
+		"slowerThanMainFrames": slowerThanMain,
+		"renderLimitedFrames":  limiting,
+		"breakdown":            breakdown,
+		"stateChangeFunctions": stateChanges,
+		"worstFrames":          worst,
+		"analysis":             analysis,
+		"summary":              summary,
+	}
+	if len(stateChanges) == 0 {
+		addWarning(output, warnMissingScopes, "No state-change scopes (Set*/Bind*/PSO) were recorded; instrument them to see state-change cost")
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
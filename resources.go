@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Captures can be browsed as MCP resources, one level per read:
+//
+//	framepro://sessions                                          captures in the data directory
+//	framepro://session/{file}                                    session summary and threads
+//	framepro://session/{file}/thread/{thread}                    a thread's top functions
+//	framepro://session/{file}/thread/{thread}/function/{function} a function's worst frames
+//	framepro://session/{file}/frame/{frame}                      every function in one frame
+//
+// Every entry links to the level below with a "uri" field. Path segments are percent-encoded.
+// Captures opened by a tool call are also listed as concrete resources.
+
+const (
+	resourceTopFunctions = 20
+	resourceWorstFrames  = 20
+)
+
+// escapeSegment percent-encodes everything but unreserved characters, which is all a
+// simple {var} template expression matches ("::" in function names included)
+func escapeSegment(segment string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(segment) {
+		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || strings.IndexByte("-._~", b) >= 0 {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// sessionURI builds a resource URI from escaped path segments
+func sessionURI(file string, segments ...string) string {
+	uri := "framepro://session/" + escapeSegment(file)
+	for _, segment := range segments {
+		uri += "/" + escapeSegment(segment)
+	}
+	return uri
+}
+
+// resourceArgument returns an unescaped URI template variable
+func resourceArgument(request mcp.ReadResourceRequest, name string) string {
+	value := request.Params.Arguments[name]
+	if values, ok := value.([]string); ok && len(values) > 0 {
+		value = values[0]
+	}
+	text, _ := value.(string)
+	if unescaped, err := url.PathUnescape(text); err == nil {
+		return unescaped
+	}
+	return text
+}
+
+// jsonResource wraps a value as the JSON contents of a resource
+func jsonResource(uri string, value interface{}) []mcp.ResourceContents {
+	text, _ := json.MarshalIndent(value, "", "  ")
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: string(text)}}
+}
+
+// SessionResources lists the captures opened by tool calls as concrete resources
+type SessionResources struct {
+	server *server.MCPServer
+	mu     sync.Mutex
+	opened map[string]bool
+}
+
+func newSessionResources() *SessionResources {
+	return &SessionResources{opened: make(map[string]bool)}
+}
+
+// middleware registers the captures of every successful tool call, and links the files it wrote
+func (r *SessionResources) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError || r.server == nil {
+			return result, err
+		}
+		for _, file := range captureArguments(request) {
+			r.open(file)
+		}
+		for _, path := range resultWrites(result) {
+			result.Content = append(result.Content, r.addOutput(path))
+		}
+		return result, err
+	}
+}
+
+// open adds a capture to the resource list the first time it is used
+func (r *SessionResources) open(file string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.opened[file] {
+		return
+	}
+	r.opened[file] = true
+	r.server.AddResource(mcp.NewResource(sessionURI(file), "Session "+filepath.Base(file),
+		mcp.WithResourceDescription("Opened capture: summary and threads, linking to top functions and frames"),
+		mcp.WithMIMEType("application/json")), sessionResourceHandler)
+}
+
+// registerResources adds the session list and the browsing templates
+func registerResources(s *server.MCPServer) {
+	s.AddResource(mcp.NewResource("framepro://sessions", "FramePro captures",
+		mcp.WithResourceDescription("Captures in the data directory, each linking to its session resource"),
+		mcp.WithMIMEType("application/json")), sessionsResourceHandler)
+	s.AddResourceTemplate(mcp.NewResourceTemplate("framepro://session/{file}", "Session",
+		mcp.WithTemplateDescription("Session summary and threads of a capture (file relative to the data directory)"),
+		mcp.WithTemplateMIMEType("application/json")), sessionResourceHandler)
+	s.AddResourceTemplate(mcp.NewResourceTemplate("framepro://session/{file}/thread/{thread}", "Thread",
+		mcp.WithTemplateDescription("Top functions of one thread, by thread ID"),
+		mcp.WithTemplateMIMEType("application/json")), threadResourceHandler)
+	s.AddResourceTemplate(mcp.NewResourceTemplate("framepro://session/{file}/thread/{thread}/function/{function}", "Function",
+		mcp.WithTemplateDescription("Metrics and worst frames of one function on one thread"),
+		mcp.WithTemplateMIMEType("application/json")), functionResourceHandler)
+	s.AddResourceTemplate(mcp.NewResourceTemplate("framepro://session/{file}/frame/{frame}", "Frame",
+		mcp.WithTemplateDescription("Every function recorded in one frame, by thread"),
+		mcp.WithTemplateMIMEType("application/json")), frameResourceHandler)
+}
+
+func sessionsResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	files, err := listCaptureFiles(dataDir, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	sessions := []map[string]interface{}{}
+	for i := len(files) - 1; i >= 0; i-- { // Newest first
+		name := filepath.Base(files[i].Path)
+		sessions = append(sessions, map[string]interface{}{
+			"file":     name,
+			"modified": files[i].Modified.Format(time.RFC3339),
+			"uri":      sessionURI(name),
+		})
+	}
+	return jsonResource(request.Params.URI, map[string]interface{}{
+		"directory": dataDir,
+		"sessions":  sessions,
+	}), nil
+}
+
+func sessionResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	file := resourceArgument(request, "file")
+	if file == "" {
+		file = strings.TrimPrefix(request.Params.URI, "framepro://session/")
+		file, _ = url.PathUnescape(file)
+	}
+	data, err := loadFrameProData(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load FramePro data: %w", err)
+	}
+
+	type threadEntry struct {
+		ThreadID          int     `json:"threadId"`
+		ThreadName        string  `json:"threadName"`
+		Stage             string  `json:"stage"`
+		Functions         int     `json:"functions"`
+		TotalTimeMs       float64 `json:"totalTimeMs"`
+		AvgTimePerFrameMs float64 `json:"avgTimePerFrameMs"`
+		URI               string  `json:"uri"`
+	}
+	stages := threadStages(data)
+	byThread := make(map[int]*threadEntry)
+	for _, fn := range data.Functions {
+		entry, exists := byThread[fn.ThreadID]
+		if !exists {
+			entry = &threadEntry{ThreadID: fn.ThreadID, ThreadName: fn.ThreadName, Stage: stages[fn.ThreadID],
+				URI: sessionURI(file, "thread", strconv.Itoa(fn.ThreadID))}
+			byThread[fn.ThreadID] = entry
+		}
+		entry.Functions++
+		entry.TotalTimeMs += fn.TotalTimeMs
+		entry.AvgTimePerFrameMs += fn.AvgTimePerFrameMs
+	}
+	threads := []*threadEntry{}
+	for _, entry := range byThread {
+		threads = append(threads, entry)
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].TotalTimeMs > threads[j].TotalTimeMs
+	})
+
+	session := map[string]interface{}{
+		"file":           file,
+		"sessionName":    data.SessionName,
+		"totalFrames":    data.TotalFrames,
+		"totalFunctions": data.TotalFunctions,
+		"hasFrameData":   len(data.Frames) > 0,
+		"threads":        threads,
+	}
+	if len(data.Frames) > 0 {
+		session["frameTimes"] = computeFrameTimePercentiles(frameTimes(data))
+		session["firstFrameUri"] = sessionURI(file, "frame", strconv.Itoa(data.Frames[0].FrameNumber))
+	}
+	return jsonResource(request.Params.URI, session), nil
+}
+
+func threadResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	file := resourceArgument(request, "file")
+	threadID, err := strconv.Atoi(resourceArgument(request, "thread"))
+	if err != nil {
+		return nil, fmt.Errorf("thread must be a numeric thread ID")
+	}
+	data, err := loadFrameProData(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load FramePro data: %w", err)
+	}
+
+	functions := []FrameProFunction{}
+	threadName := ""
+	for _, fn := range data.Functions {
+		if fn.ThreadID == threadID {
+			functions = append(functions, fn)
+			threadName = fn.ThreadName
+		}
+	}
+	if len(functions) == 0 {
+		return nil, fmt.Errorf("no functions recorded on thread %d", threadID)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].TotalTimeMs > functions[j].TotalTimeMs
+	})
+	total := len(functions)
+	if len(functions) > resourceTopFunctions {
+		functions = functions[:resourceTopFunctions]
+	}
+
+	entries := []map[string]interface{}{}
+	for _, fn := range functions {
+		entries = append(entries, map[string]interface{}{
+			"functionName":      fn.FunctionName,
+			"totalTimeMs":       fn.TotalTimeMs,
+			"avgTimePerFrameMs": fn.AvgTimePerFrameMs,
+			"maxTimePerFrameMs": fn.MaxTimePerFrameMs,
+			"totalCount":        fn.TotalCount,
+			"uri":               sessionURI(file, "thread", strconv.Itoa(threadID), "function", fn.FunctionName),
+		})
+	}
+	return jsonResource(request.Params.URI, map[string]interface{}{
+		"file":           file,
+		"threadId":       threadID,
+		"threadName":     threadName,
+		"totalFunctions": total,
+		"topFunctions":   entries,
+		"sessionUri":     sessionURI(file),
+	}), nil
+}
+
+func functionResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	file := resourceArgument(request, "file")
+	functionName := resourceArgument(request, "function")
+	threadID, err := strconv.Atoi(resourceArgument(request, "thread"))
+	if err != nil {
+		return nil, fmt.Errorf("thread must be a numeric thread ID")
+	}
+	data, err := loadFrameProData(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load FramePro data: %w", err)
+	}
+
+	var function *FrameProFunction
+	for i := range data.Functions {
+		if data.Functions[i].FunctionName == functionName && data.Functions[i].ThreadID == threadID {
+			function = &data.Functions[i]
+			break
+		}
+	}
+	if function == nil {
+		return nil, fmt.Errorf("function '%s' not found on thread %d", functionName, threadID)
+	}
+
+	type frameEntry struct {
+		Frame  int     `json:"frame"`
+		TimeMs float64 `json:"timeMs"`
+		URI    string  `json:"uri"`
+	}
+	frames := []frameEntry{}
+	for _, frame := range data.Frames {
+		if ms, found := functionFrameTimeMs(frame, functionName, threadID); found {
+			frames = append(frames, frameEntry{Frame: frame.FrameNumber, TimeMs: ms, URI: sessionURI(file, "frame", strconv.Itoa(frame.FrameNumber))})
+		}
+	}
+	framesPresent := len(frames)
+	sort.Slice(frames, func(i, j int) bool {
+		return frames[i].TimeMs > frames[j].TimeMs
+	})
+	if len(frames) > resourceWorstFrames {
+		frames = frames[:resourceWorstFrames]
+	}
+
+	contents := map[string]interface{}{
+		"file":          file,
+		"function":      function,
+		"framesPresent": framesPresent,
+		"worstFrames":   frames,
+		"threadUri":     sessionURI(file, "thread", strconv.Itoa(threadID)),
+	}
+	if known := lookupKnownIssue(functionName); known != nil {
+		contents["knownIssue"] = known
+	}
+	return jsonResource(request.Params.URI, contents), nil
+}
+
+func frameResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	file := resourceArgument(request, "file")
+	frameNumber, err := strconv.Atoi(resourceArgument(request, "frame"))
+	if err != nil {
+		return nil, fmt.Errorf("frame must be a frame number")
+	}
+	data, err := loadFrameProData(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load FramePro data: %w", err)
+	}
+	if len(data.Frames) == 0 {
+		return nil, fmt.Errorf("no per-frame data in this file (Frames array is empty)")
+	}
+	index, found := findFrame(data, frameNumber)
+	if !found {
+		return nil, fmt.Errorf("frame %d not found", frameNumber)
+	}
+	frame := data.Frames[index]
+
+	functions := make(map[int][]map[string]interface{})
+	for _, fn := range topFrameFunctions(frame, 0) {
+		functions[fn.ThreadID] = append(functions[fn.ThreadID], map[string]interface{}{
+			"function": fn.FunctionName,
+			"timeMs":   fn.TimeMs,
+			"count":    fn.Count,
+			"uri":      sessionURI(file, "thread", strconv.Itoa(fn.ThreadID), "function", fn.FunctionName),
+		})
+	}
+	threads := []map[string]interface{}{}
+	for id, total := range frameThreadTotals(frame) {
+		threads = append(threads, map[string]interface{}{
+			"threadId":   id,
+			"threadName": total.ThreadName,
+			"totalMs":    total.TimeMs,
+			"functions":  functions[id],
+		})
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i]["totalMs"].(float64) > threads[j]["totalMs"].(float64)
+	})
+
+	contents := map[string]interface{}{
+		"file":        file,
+		"frame":       frame.FrameNumber,
+		"frameTimeMs": frameTimeMs(frame, threadStages(data)),
+		"threads":     threads,
+		"sessionUri":  sessionURI(file),
+	}
+	if index > 0 {
+		contents["previousUri"] = sessionURI(file, "frame", strconv.Itoa(data.Frames[index-1].FrameNumber))
+	}
+	if index+1 < len(data.Frames) {
+		contents["nextUri"] = sessionURI(file, "frame", strconv.Itoa(data.Frames[index+1].FrameNumber))
+	}
+	return jsonResource(request.Params.URI, contents), nil
+}
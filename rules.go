@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The rule catalog lists everything the analyzer checks under a stable ID: the
+// analyze_performance rules (whose findings carry the ID as ruleId), the known-issue
+// database, subsystem budgets, derived metrics with a budget and the thresholds of the
+// detection tools. IDs are built from names and patterns, not positions, so they stay the
+// same as rules are added. A project can turn rules off by ID in a file named by
+// FRAMEPRO_RULES; tools note the disabled rules they would have applied in disabledRules.
+
+// Rule sources
+const (
+	ruleSourcePerformance = "analyze_performance"
+	ruleSourceKnownIssue  = "known-issue"
+	ruleSourceBudget      = "budget"
+	ruleSourceMetric      = "derived-metric"
+	ruleSourceDetector    = "detector"
+)
+
+// CatalogRule is one entry of the rule catalog
+type CatalogRule struct {
+	ID              string                 `json:"id"`
+	Source          string                 `json:"source"`
+	Tool            string                 `json:"tool,omitempty"`
+	Category        string                 `json:"category,omitempty"`
+	Severity        string                 `json:"severity,omitempty"`
+	Thresholds      map[string]interface{} `json:"thresholds,omitempty"`
+	Description     string                 `json:"description"`
+	Origin          string                 `json:"origin"` // "built-in" or the project file it was loaded from
+	Enabled         bool                   `json:"enabled"`
+	ProjectSeverity string                 `json:"projectSeverity,omitempty"` // Given to all its findings by the project's severity rules
+}
+
+// RuleConfig is the FRAMEPRO_RULES project file
+type RuleConfig struct {
+	Disabled   []string       `json:"disabled"`   // Rule IDs, or patterns with * and ? such as "known/unity/*"
+	Severities []SeverityRule `json:"severities"` // First match wins
+}
+
+// SeverityRule gives the findings matching all of its conditions a severity
+type SeverityRule struct {
+	Rule     string `json:"rule,omitempty"`     // Rule ID or pattern
+	Category string `json:"category,omitempty"` // Finding category, e.g. "Call Frequency"
+	Thread   string `json:"thread,omitempty"`   // Thread name pattern, e.g. "*Audio*"
+	Severity string `json:"severity"`
+	re       *regexp.Regexp
+}
+
+// severities are the levels findings can have, most severe first
+var severities = []string{"critical", "high", "medium", "low", "info"}
+
+var (
+	disabledRules []string       // Rule ID patterns the project turned off
+	severityRules []SeverityRule // The project's severity policy
+)
+
+// loadRuleConfig reads the rules a project turns off; every entry must name a rule
+func loadRuleConfig(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read rule configuration: %w", err)
+	}
+	var config RuleConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return fmt.Errorf("failed to parse rule configuration %s: %w", file, err)
+	}
+	catalog := ruleCatalog()
+	for _, pattern := range config.Disabled {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid rule pattern %q in %s: %w", pattern, file, err)
+		}
+		matched := false
+		for _, rule := range catalog {
+			ok, _ := path.Match(pattern, rule.ID)
+			matched = matched || ok
+		}
+		if !matched {
+			return fmt.Errorf("%q in %s matches no rule; export_rule_catalog lists the rule IDs", pattern, file)
+		}
+	}
+	for i := range config.Severities {
+		rule := &config.Severities[i]
+		if !slices.Contains(severities, rule.Severity) {
+			return fmt.Errorf("severity rule %d in %s: severity %q is not one of %s", i+1, file, rule.Severity, strings.Join(severities, ", "))
+		}
+		if rule.Rule == "" && rule.Category == "" && rule.Thread == "" {
+			return fmt.Errorf("severity rule %d in %s needs a rule, category or thread to match", i+1, file)
+		}
+		if _, err := path.Match(rule.Rule, ""); err != nil {
+			return fmt.Errorf("invalid rule pattern %q in %s: %w", rule.Rule, file, err)
+		}
+		if rule.Thread != "" {
+			if rule.re, err = compileFunctionPattern(rule.Thread, false); err != nil {
+				return fmt.Errorf("severity rule %d in %s: %w", i+1, file, err)
+			}
+		}
+	}
+	disabledRules = config.Disabled
+	severityRules = config.Severities
+	return nil
+}
+
+// matches reports whether a finding meets all of a severity rule's conditions
+func (r SeverityRule) matches(ruleID, category, thread string) bool {
+	if r.Rule != "" {
+		if ok, _ := path.Match(r.Rule, ruleID); !ok {
+			return false
+		}
+	}
+	if r.Category != "" && !strings.EqualFold(r.Category, category) {
+		return false
+	}
+	return r.re == nil || r.re.MatchString(thread)
+}
+
+// remapSeverities applies the project's severity rules, keeping the default severity in Remapped
+func remapSeverities(issues []PerformanceIssue) []PerformanceIssue {
+	for i := range issues {
+		issue := &issues[i]
+		for _, rule := range severityRules {
+			if !rule.matches(issue.RuleID, issue.Category, issue.Thread) {
+				continue
+			}
+			if rule.Severity != issue.Severity {
+				issue.Remapped, issue.Severity = issue.Severity, rule.Severity
+			}
+			break
+		}
+	}
+	return issues
+}
+
+// ruleEnabled reports whether the project left a rule on
+func ruleEnabled(id string) bool {
+	for _, pattern := range disabledRules {
+		if ok, _ := path.Match(pattern, id); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// knownIssueRuleID is the rule ID of a known-issue entry
+func knownIssueRuleID(issue KnownIssue) string {
+	return "known/" + ruleSlug(issue.Engine) + "/" + ruleSlug(issue.Pattern)
+}
+
+// budgetRuleID is the rule ID of a default subsystem budget
+func budgetRuleID(budget SubsystemBudget) string {
+	return "budget/" + ruleSlug(budget.Name)
+}
+
+// enabledIssues drops the issues raised by disabled rules
+func enabledIssues(issues []PerformanceIssue) []PerformanceIssue {
+	kept := []PerformanceIssue{}
+	for _, issue := range issues {
+		if issue.RuleID == "" || ruleEnabled(issue.RuleID) {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+// performanceRules are the checks of analyze_performance, in the order they run
+var performanceRules = []CatalogRule{
+	{ID: "data/capture-quality", Category: "Data Quality", Severity: "critical",
+		Description: "The capture has no functions or no frames, so findings that depend on them are missing"},
+	{ID: "perf/cpu-hotspot", Category: "CPU Hotspot", Severity: "high; critical over the critical total or on the main thread",
+		Thresholds:  map[string]interface{}{"totalMs": 100, "criticalTotalMs": 500},
+		Description: "A function's total time over the capture is excessive"},
+	{ID: "perf/call-frequency", Category: "Call Frequency", Severity: "medium",
+		Thresholds:  map[string]interface{}{"calls": 10000, "totalMs": 50},
+		Description: "A function is called very often and the calls add up"},
+	{ID: "perf/frame-spike", Category: "Frame Spike", Severity: "high",
+		Thresholds:  map[string]interface{}{"maxPerFrameMs": 16.67, "minCalls": 100},
+		Description: "A function's worst frame is longer than a whole 60 FPS frame"},
+	{ID: "perf/function-saturation", Category: "Thread Saturation", Severity: "critical",
+		Thresholds:  map[string]interface{}{"utilizationPercent": 95, "minTotalMs": 100},
+		Description: "A single function keeps its thread almost fully busy"},
+	{ID: "perf/main-thread-spike", Category: "Frame Spike - Main Thread", Severity: "critical",
+		Thresholds:  map[string]interface{}{"maxPerFrameMs": 33},
+		Description: "A main thread function's worst frame is longer than a 30 FPS frame"},
+	{ID: "perf/main-thread-over-budget", Category: "Frame Performance", Severity: "high",
+		Thresholds:  map[string]interface{}{"maxPerFrameMs": 16.67},
+		Description: "A main thread function's worst frame is longer than a 60 FPS frame"},
+	{ID: "perf/inconsistent-performance", Category: "Inconsistent Performance", Severity: "medium",
+		Thresholds:  map[string]interface{}{"maxToAvgRatio": 5, "minAvgMs": 1},
+		Description: "A function's worst frame is many times its average"},
+	{ID: "perf/session-info", Category: "Session Info", Severity: "info",
+		Description: "Frames and functions captured; always reported"},
+	{ID: "perf/thread-saturation", Category: "Thread Saturation", Severity: "medium; high on the main or render thread",
+		Thresholds:  map[string]interface{}{"utilizationPercent": 90},
+		Description: "A thread runs at capacity"},
+	{ID: "perf/thread-balance", Category: "Thread Balance", Severity: "medium",
+		Thresholds:  map[string]interface{}{"mainToRenderRatio": 2},
+		Description: "Main and render thread time differ by more than the ratio either way"},
+}
+
+// detectorRules are the default thresholds of the detection tools
+func detectorRules() []CatalogRule {
+	return []CatalogRule{
+		{ID: "detect/hitch", Tool: "detect_hitches",
+			Thresholds:  map[string]interface{}{"thresholdFactor": 2.0, "sustainedFrames": 3},
+			Description: "A frame over the factor times the median frame time is a hitch; a run of them is a sustained slowdown"},
+		{ID: "detect/contamination", Tool: "detect_contamination",
+			Thresholds: map[string]interface{}{"thresholdFactor": 2.0, "threadExcessShare": contaminationThreadExcess,
+				"stalledThreadShare": contaminationThreadShare, "minThreadMs": contaminationMinThreadMs, "uniformGapSpread": uniformGapSpread},
+			Description: "A spike frame in which most active threads grew together is a capture-machine stall"},
+		{ID: "detect/preemption", Tool: "analyze_preemption",
+			Thresholds:  map[string]interface{}{"thresholdFactor": 2.0, "minPreemptedMs": 1.0, "spikeRatio": preemptionSpikeRatio},
+			Description: "A thread preempted much more in spike frames than in normal frames"},
+		{ID: "detect/presentation", Tool: "analyze_presentation",
+			Thresholds:  map[string]interface{}{"lateFlipTolerance": lateFlipTolerance, "displayHitchRefreshes": displayHitchRefreshes},
+			Description: "Dropped presents, late flips, display hitches and tearing from present timing"},
+		{ID: "detect/gpu-queue-serialization", Tool: "analyze_gpu_queues",
+			Thresholds:  map[string]interface{}{"minHiddenShare": minHiddenShare, "minAsyncQueueMs": minAsyncQueueMs, "serializedFrameShare": serializedFrameOK},
+			Description: "Async compute or copy work that doesn't overlap graphics work"},
+		{ID: "detect/smt-contention", Tool: "analyze_core_affinity",
+			Thresholds:  map[string]interface{}{"heavyThreadBudgetShare": heavyThreadBudgetShare, "sharedFrameShare": smtSharedFrameShare},
+			Description: "Two heavy threads sharing a physical core in over-budget frames"},
+		{ID: "detect/core-hopping", Tool: "analyze_core_affinity",
+			Thresholds:  map[string]interface{}{"migrationsPerFrame": hoppingMigrationsPerFrame, "pinnedCoreShare": pinnedCoreShare},
+			Description: "The main thread migrating between cores, or threads pinned to one core"},
+		{ID: "detect/boundness", Tool: "analyze_frame_times",
+			Thresholds:  map[string]interface{}{"gpuWaitShare": gpuWaitShare, "vsyncWaitShare": vsyncWaitShare, "vsyncTolerance": vsyncTolerance},
+			Description: "Frames classified as CPU-bound, GPU-bound or vsync-limited from their waits"},
+		{ID: "detect/spike-explanation", Tool: "correlate_spikes",
+			Thresholds:  map[string]interface{}{"explainShare": explainShare, "coSpikeOverlap": coSpikeOverlap},
+			Description: "A function explains a hitch when it covers a share of the hitch's excess"},
+	}
+}
+
+// ruleSlug turns a name or pattern into an ID segment
+var ruleSlugUnsafe = regexp.MustCompile(`[^a-z0-9*?:.]+`)
+
+func ruleSlug(name string) string {
+	return strings.Trim(ruleSlugUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// ruleCatalog lists every rule the analyzer applies with the current configuration
+func ruleCatalog() []CatalogRule {
+	rules := []CatalogRule{}
+	for _, rule := range performanceRules {
+		rule.Source, rule.Tool, rule.Origin = ruleSourcePerformance, "analyze_performance", "built-in"
+		rules = append(rules, rule)
+	}
+
+	builtin := make(map[string]bool)
+	for _, issue := range builtinKnownIssues {
+		builtin[issue.Engine+"|"+issue.Pattern] = true
+	}
+	for _, issue := range knownIssues {
+		origin := "built-in"
+		if !builtin[issue.Engine+"|"+issue.Pattern] {
+			origin = os.Getenv("FRAMEPRO_KNOWN_ISSUES")
+		}
+		rules = append(rules, CatalogRule{
+			ID: knownIssueRuleID(issue), Source: ruleSourceKnownIssue, Tool: "find_hotspots",
+			Category: issue.Engine, Severity: "info", Thresholds: map[string]interface{}{"pattern": issue.Pattern},
+			Description: issue.Explanation, Origin: origin,
+		})
+	}
+
+	for _, budget := range defaultBudgets {
+		rules = append(rules, CatalogRule{
+			ID: budgetRuleID(budget), Source: ruleSourceBudget, Tool: "check_budgets", Category: budget.Name,
+			Thresholds:  map[string]interface{}{"pattern": budget.Pattern, "budgetMsAt60FPS": budget.BudgetMs},
+			Description: fmt.Sprintf("Default %s budget, scaled to target_fps; replaced by the budgets argument", budget.Name), Origin: "built-in",
+		})
+	}
+
+	for _, metric := range projectMetrics {
+		if metric.Budget == nil {
+			continue
+		}
+		rules = append(rules, CatalogRule{
+			ID: "metric/" + metric.Name, Source: ruleSourceMetric, Tool: "check_budgets",
+			Thresholds:  map[string]interface{}{"expression": metric.Expression, "budget": *metric.Budget},
+			Description: fmt.Sprintf("Derived metric %s must stay within its budget", metric.Name), Origin: os.Getenv("FRAMEPRO_METRICS"),
+		})
+	}
+
+	for _, rule := range detectorRules() {
+		rule.Source, rule.Origin = ruleSourceDetector, "built-in"
+		rules = append(rules, rule)
+	}
+	for i := range rules {
+		rules[i].Enabled = ruleEnabled(rules[i].ID)
+		for _, severity := range severityRules {
+			if severity.Thread == "" && severity.matches(rules[i].ID, rules[i].Category, "") {
+				rules[i].ProjectSeverity = severity.Severity
+				break
+			}
+		}
+	}
+	return rules
+}
+
+// toolDisabledRules lists the disabled rules a tool applies, and whether the tool is a
+// detector with all of its rules disabled
+func toolDisabledRules(tool string) ([]string, bool) {
+	disabled := []string{}
+	off := true
+	for _, rule := range ruleCatalog() {
+		if rule.Tool != tool {
+			continue
+		}
+		if !rule.Enabled {
+			disabled = append(disabled, rule.ID)
+		}
+		off = off && !rule.Enabled && rule.Source == ruleSourceDetector
+	}
+	return disabled, off && len(disabled) > 0
+}
+
+// rulesMiddleware notes the disabled rules in the results of the tools applying them. A
+// detector whose rules are all disabled isn't run.
+func rulesMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if len(disabledRules) == 0 {
+			return next(ctx, request)
+		}
+		disabled, off := toolDisabledRules(request.Params.Name)
+		if len(disabled) == 0 {
+			return next(ctx, request)
+		}
+		if off {
+			output := map[string]interface{}{
+				"disabledRules": disabled,
+				"summary":       fmt.Sprintf("%s is turned off for this project: %s disabled in %s", request.Params.Name, strings.Join(disabled, ", "), os.Getenv("FRAMEPRO_RULES")),
+			}
+			result, _ := json.MarshalIndent(output, "", "  ")
+			return mcp.NewToolResultText(string(result)), nil
+		}
+
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+		for i, content := range result.Content {
+			text, ok := content.(mcp.TextContent)
+			if !ok {
+				continue
+			}
+			var output map[string]interface{}
+			if json.Unmarshal([]byte(text.Text), &output) != nil {
+				continue
+			}
+			output["disabledRules"] = disabled
+			noted, _ := json.MarshalIndent(output, "", "  ")
+			result.Content[i] = mcp.NewTextContent(string(noted))
+		}
+		return result, nil
+	}
+}
+
+func ruleCatalogHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	source, _ := args["source"].(string)
+	outputPath, _ := args["output_path"].(string)
+
+	rules := []CatalogRule{}
+	counts := make(map[string]int)
+	for _, rule := range ruleCatalog() {
+		if source != "" && rule.Source != source {
+			continue
+		}
+		rules = append(rules, rule)
+		counts[rule.Source]++
+	}
+	if len(rules) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("No rules from source '%s': expected %s, %s, %s, %s or %s", source,
+			ruleSourcePerformance, ruleSourceKnownIssue, ruleSourceBudget, ruleSourceMetric, ruleSourceDetector)), nil
+	}
+
+	sources := []string{}
+	for name, count := range counts {
+		sources = append(sources, fmt.Sprintf("%d %s", count, name))
+	}
+	sort.Strings(sources)
+	summary := fmt.Sprintf("%d rules: %s", len(rules), strings.Join(sources, ", "))
+	disabled := 0
+	for _, rule := range rules {
+		if !rule.Enabled {
+			disabled++
+		}
+	}
+	if disabled > 0 {
+		summary += fmt.Sprintf("; %d disabled by %s", disabled, os.Getenv("FRAMEPRO_RULES"))
+	}
+
+	output := map[string]interface{}{
+		"rules":   rules,
+		"counts":  counts,
+		"summary": summary,
+	}
+	if outputPath != "" {
+		dryRun := isDryRun(args)
+		outputPath, err := resolveOutputPath(outputPath, "", "", dryRun)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		content, _ := json.MarshalIndent(rules, "", "  ")
+		write, err := writeFile(outputPath, content, dryRun)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		output["outputPath"] = outputPath
+		output["summary"] = summary + "; written to " + outputPath
+		if dryRun {
+			output["summary"] = summary + "; would be written to " + outputPath
+		}
+		attachWrites(output, []FileWrite{write}, dryRun)
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The profile score rolls four sub-scores from 0 to 100 into one weighted number that can
+// be tracked build over build:
+//
+//	fps       average and 1% low FPS against the target, half each (average only without frames)
+//	pacing    the frame pacing score of analyze_frame_pacing (needs per-frame data)
+//	hotspots  1 minus the Herfindahl index of per-function time shares: 100 when time is
+//	          spread evenly, low when a few functions dominate the frame
+//	balance   average busy share of the active threads relative to the busiest one
+//
+// Sub-scores that can't be computed are left out and the remaining weights rescaled.
+
+// Score components
+const (
+	scoreFPS      = "fps"
+	scorePacing   = "pacing"
+	scoreHotspots = "hotspots"
+	scoreBalance  = "balance"
+)
+
+var defaultScoreWeights = map[string]float64{
+	scoreFPS:      0.3,
+	scorePacing:   0.3,
+	scoreHotspots: 0.2,
+	scoreBalance:  0.2,
+}
+
+// SubScore is one component of the profile score
+type SubScore struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"` // After rescaling for missing components
+	Detail string  `json:"detail"`
+}
+
+// scoreGrade turns a score into a letter grade
+func scoreGrade(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// hotspotConcentration returns the Herfindahl index of function time shares and the top function.
+// Self times are used when the export has them, so parent scopes don't count their children.
+func hotspotConcentration(functions []FrameProFunction) (float64, FrameProFunction, float64) {
+	rankBy := "total"
+	if hasSelfTimes(functions) {
+		rankBy = "self"
+	}
+	var total float64
+	for _, fn := range functions {
+		total += rankingTimeMs(fn, rankBy)
+	}
+	var index, topShare float64
+	var top FrameProFunction
+	for _, fn := range functions {
+		if total <= 0 {
+			break
+		}
+		share := rankingTimeMs(fn, rankBy) / total
+		index += share * share
+		if share > topShare {
+			top, topShare = fn, share
+		}
+	}
+	return index, top, topShare
+}
+
+// profileScore computes the sub-scores of a capture and their weighted total
+func profileScore(data *FrameProData, targetFPS float64, weights map[string]float64) ([]SubScore, float64, error) {
+	scores := []SubScore{}
+
+	// Frame rate against the target
+	frames := data.Frames
+	if len(frames) > 0 {
+		stats := computeFrameTimePercentiles(frameTimes(data))
+		avgPart := math.Min(1, stats.AvgFPS/targetFPS)
+		lowPart := math.Min(1, stats.OnePercentLowFPS/targetFPS)
+		scores = append(scores, SubScore{Name: scoreFPS, Score: 50*avgPart + 50*lowPart,
+			Detail: fmt.Sprintf("%.1f FPS average, %.1f FPS 1%% low against %.0f FPS", stats.AvgFPS, stats.OnePercentLowFPS, targetFPS)})
+	} else {
+		frames = []FrameProFrame{aggregateFrame(data)}
+		avgFPS := msToFPS(frameTimeMs(frames[0], threadStages(data)))
+		scores = append(scores, SubScore{Name: scoreFPS, Score: 100 * math.Min(1, avgFPS/targetFPS),
+			Detail: fmt.Sprintf("%.1f FPS estimated from average times against %.0f FPS (no 1%% low without per-frame data)", avgFPS, targetFPS)})
+	}
+
+	// Frame pacing
+	if len(data.Frames) >= 2 {
+		times := frameTimes(data)
+		absDeltas := make([]float64, len(times)-1)
+		for i := 1; i < len(times); i++ {
+			absDeltas[i-1] = math.Abs(times[i] - times[i-1])
+		}
+		meanAbsDelta, _ := meanStdDev(absDeltas)
+		median := medianOf(times)
+		scores = append(scores, SubScore{Name: scorePacing, Score: pacingScore(meanAbsDelta, median),
+			Detail: fmt.Sprintf("frames change by %.2fms on average from one frame to the next (%.1f%% of the median)", meanAbsDelta, meanAbsDelta/median*100)})
+	}
+
+	// Hotspot concentration, meaningless for a single (root) scope
+	if len(data.Functions) > 1 {
+		index, top, topShare := hotspotConcentration(data.Functions)
+		scores = append(scores, SubScore{Name: scoreHotspots, Score: 100 * (1 - index),
+			Detail: fmt.Sprintf("%s on %s is the largest hotspot at %.0f%% of recorded time", top.FunctionName, top.ThreadName, topShare*100)})
+	}
+
+	// Thread balance over the threads doing any work
+	var busiest *ThreadUtilization
+	var busySum float64
+	active := 0
+	for _, thread := range threadUtilization(data, frames) {
+		if thread.BusyPercent <= 0 {
+			continue
+		}
+		active++
+		busySum += thread.BusyPercent
+		if busiest == nil || thread.BusyPercent > busiest.BusyPercent {
+			busiest = thread
+		}
+	}
+	if active > 1 {
+		mean := busySum / float64(active)
+		scores = append(scores, SubScore{Name: scoreBalance, Score: 100 * mean / busiest.BusyPercent,
+			Detail: fmt.Sprintf("%d active threads average %.0f%% busy; %s is the busiest at %.0f%%", active, mean, busiest.ThreadName, busiest.BusyPercent)})
+	}
+
+	var totalWeight float64
+	for _, sub := range scores {
+		totalWeight += weights[sub.Name]
+	}
+	if totalWeight <= 0 {
+		return nil, 0, errors.New("all weights of the available sub-scores are zero")
+	}
+	var score float64
+	for i := range scores {
+		scores[i].Weight = weights[scores[i].Name] / totalWeight
+		score += scores[i].Score * scores[i].Weight
+	}
+	return scores, score, nil
+}
+
+func scoreProfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	weights := make(map[string]float64)
+	for name, weight := range defaultScoreWeights {
+		weights[name] = weight
+	}
+	if custom, ok := args["weights"].(map[string]interface{}); ok {
+		for name, value := range custom {
+			weight, isNumber := value.(float64)
+			if _, known := defaultScoreWeights[name]; !known || !isNumber || weight < 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid weight '%s': weights are non-negative numbers for fps, pacing, hotspots and balance", name)), nil
+			}
+			weights[name] = weight
+		}
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, data)
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	scores, score, err := profileScore(data, targetFPS, weights)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	grade := scoreGrade(score)
+
+	omitted := []string{}
+	for _, name := range []string{scoreFPS, scorePacing, scoreHotspots, scoreBalance} {
+		found := false
+		for _, sub := range scores {
+			found = found || sub.Name == name
+		}
+		if !found {
+			omitted = append(omitted, name)
+		}
+	}
+
+	// The weakest sub-scores first: they are where the score is lost
+	weakest := append([]SubScore(nil), scores...)
+	sort.Slice(weakest, func(i, j int) bool {
+		return weakest[i].Score < weakest[j].Score
+	})
+	analysis := []string{}
+	for _, sub := range weakest {
+		if sub.Score >= 90 {
+			continue
+		}
+		analysis = append(analysis, fmt.Sprintf("%s scores %.0f: %s", sub.Name, sub.Score, sub.Detail))
+	}
+
+	parts := []string{}
+	for _, sub := range scores {
+		parts = append(parts, fmt.Sprintf("%s %.0f", sub.Name, sub.Score))
+	}
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"targetFPS":       targetFPS,
+		"targetFPSSource": targetSource,
+		"score":           math.Round(score*10) / 10,
+		"grade":           grade,
+		"subScores":       scores,
+		"analysis":        analysis,
+		"summary":         fmt.Sprintf("Performance score %.0f/100 (%s): %s", score, grade, strings.Join(parts, ", ")),
+	}
+	if len(omitted) > 0 {
+		output["omittedSubScores"] = omitted
+		addWarning(output, warnSubScoresOmitted, "Sub-scores that need per-frame data, several functions or several active threads were left out and the other weights rescaled; compare scores only between captures with the same sub-scores")
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
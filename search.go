@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// compileFunctionPattern compiles a function name pattern. Patterns using only the
+// wildcards * and ? (e.g. "*Physics*") are matched against the whole name; anything
+// else is treated as a regular expression (e.g. "^UWorld::").
+func compileFunctionPattern(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	expr := pattern
+	if isWildcardPattern(pattern) {
+		expr = regexp.QuoteMeta(pattern)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		expr = "^" + expr + "$"
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	return re, nil
+}
+
+// isWildcardPattern reports whether a pattern uses * or ? wildcards and no other regex syntax
+func isWildcardPattern(pattern string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return false
+	}
+	return !strings.ContainsAny(pattern, `^$[](){}|+\`) && !strings.Contains(pattern, ".*")
+}
+
+func searchFunctionsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		return mcp.NewToolResultError("pattern is required"), nil
+	}
+	caseSensitive, _ := args["case_sensitive"].(bool)
+	limit := 50
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
+	re, err := compileFunctionPattern(pattern, caseSensitive)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var allTime, matchedTime float64
+	matches := []FrameProFunction{}
+	threads := make(map[string]bool)
+	for _, fn := range data.Functions {
+		allTime += fn.TotalTimeMs
+		if re.MatchString(fn.FunctionName) {
+			matches = append(matches, fn)
+			matchedTime += fn.TotalTimeMs
+			threads[fn.ThreadName] = true
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].TotalTimeMs > matches[j].TotalTimeMs
+	})
+	total := len(matches)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	results := make([]map[string]interface{}, len(matches))
+	for i, fn := range matches {
+		results[i] = map[string]interface{}{
+			"function":          fn.FunctionName,
+			"threadName":        fn.ThreadName,
+			"threadId":          fn.ThreadID,
+			"totalTimeMs":       fn.TotalTimeMs,
+			"avgTimePerFrameMs": fn.AvgTimePerFrameMs,
+			"maxTimePerFrameMs": fn.MaxTimePerFrameMs,
+			"totalCount":        fn.TotalCount,
+			"avgCountPerFrame":  fn.AvgCountPerFrame,
+		}
+	}
+
+	percentOfAll := 0.0
+	if allTime > 0 {
+		percentOfAll = matchedTime / allTime * 100
+	}
+
+	output := map[string]interface{}{
+		"file":               filePath,
+		"pattern":            pattern,
+		"regex":              re.String(),
+		"totalMatches":       total,
+		"returned":           len(results),
+		"matchedTotalMs":     matchedTime,
+		"percentOfAllTime":   percentOfAll,
+		"threadsWithMatches": len(threads),
+		"matches":            results,
+		"summary": fmt.Sprintf("%d functions match '%s' on %d threads, %.2fms total (%.1f%% of all recorded time)",
+			total, pattern, len(threads), matchedTime, percentOfAll),
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Session-name patterns such as "{level}_{build}_{run}" split capture names into fields,
+// so captures can be selected (where) and grouped (group_by) without listing files. A
+// pattern is matched against the capture's session name, then against its file name
+// without the extension. Fields match lazily: "Forest_Night_1042_3" gives level "Forest".
+
+var sessionFieldPattern = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// SessionPattern is a compiled session-name pattern
+type SessionPattern struct {
+	Raw    string
+	Fields []string
+	re     *regexp.Regexp
+}
+
+// parseSessionPattern compiles a pattern of literal text and {field} placeholders
+func parseSessionPattern(pattern string) (*SessionPattern, error) {
+	p := &SessionPattern{Raw: pattern}
+	expr := strings.Builder{}
+	expr.WriteString("(?i)^")
+	last := 0
+	for _, match := range sessionFieldPattern.FindAllStringSubmatchIndex(pattern, -1) {
+		field := pattern[match[2]:match[3]]
+		for _, existing := range p.Fields {
+			if existing == field {
+				return nil, fmt.Errorf("session_pattern '%s' uses {%s} twice", pattern, field)
+			}
+		}
+		p.Fields = append(p.Fields, field)
+		expr.WriteString(regexp.QuoteMeta(pattern[last:match[0]]))
+		expr.WriteString("(.+?)")
+		last = match[1]
+	}
+	if len(p.Fields) == 0 {
+		return nil, fmt.Errorf("session_pattern '%s' has no {field} placeholders, e.g. '{level}_{build}_{run}'", pattern)
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+	expr.WriteString("$")
+	p.re = regexp.MustCompile(expr.String())
+	return p, nil
+}
+
+// match returns the fields of name, or nil when the name doesn't follow the pattern
+func (p *SessionPattern) match(name string) map[string]string {
+	values := p.re.FindStringSubmatch(name)
+	if values == nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	for i, field := range p.Fields {
+		fields[field] = values[i+1]
+	}
+	return fields
+}
+
+// SessionFilter selects captures by the fields of their session name
+type SessionFilter struct {
+	Pattern *SessionPattern
+	Where   map[string]string
+}
+
+// withSessionFilter adds the session_pattern and where parameters to a tool
+func withSessionFilter(tool mcp.Tool) mcp.Tool {
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = make(map[string]any)
+	}
+	tool.InputSchema.Properties["session_pattern"] = map[string]any{
+		"type":        "string",
+		"description": "Pattern splitting session or file names into fields, e.g. '{level}_{build}_{run}'. Captures that don't follow it are skipped",
+	}
+	tool.InputSchema.Properties["where"] = map[string]any{
+		"type":                 "object",
+		"additionalProperties": map[string]any{"type": "string"},
+		"description":          "Only use captures whose session_pattern fields have these values, e.g. {\"level\": \"Forest\"}. Values may use * wildcards",
+	}
+	return tool
+}
+
+// parseSessionFilter reads session_pattern and where; it returns nil when no pattern is given
+func parseSessionFilter(args map[string]interface{}) (*SessionFilter, error) {
+	pattern, _ := args["session_pattern"].(string)
+	where, _ := args["where"].(map[string]interface{})
+	if pattern == "" {
+		if len(where) > 0 {
+			return nil, fmt.Errorf("where needs a session_pattern naming its fields, e.g. '{level}_{build}_{run}'")
+		}
+		return nil, nil
+	}
+	compiled, err := parseSessionPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	filter := &SessionFilter{Pattern: compiled, Where: make(map[string]string)}
+	for field, value := range where {
+		if !filter.hasField(field) {
+			return nil, fmt.Errorf("where field '%s' is not in session_pattern '%s'", field, pattern)
+		}
+		filter.Where[field] = fmt.Sprint(value)
+	}
+	return filter, nil
+}
+
+// hasField reports whether the pattern defines field
+func (f *SessionFilter) hasField(field string) bool {
+	for _, name := range f.Pattern.Fields {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the fields of a capture and whether it is selected. Without a filter
+// every capture is selected and has no fields.
+func (f *SessionFilter) match(path string, data *FrameProData) (map[string]string, bool) {
+	if f == nil {
+		return nil, true
+	}
+	fields := f.Pattern.match(data.SessionName)
+	if fields == nil {
+		fields = f.Pattern.match(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	}
+	if fields == nil {
+		return nil, false
+	}
+	for field, want := range f.Where {
+		if matched, _ := filepath.Match(strings.ToLower(want), strings.ToLower(fields[field])); !matched {
+			return fields, false
+		}
+	}
+	return fields, true
+}
+
+// lessFieldValue orders field values numerically when both are numbers, e.g. builds
+func lessFieldValue(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// SessionGroup is the captures sharing one value of the group_by field
+type SessionGroup struct {
+	Value          string   `json:"value"`
+	Captures       int      `json:"captures"`
+	Files          []string `json:"files"`
+	AvgFrameTimeMs float64  `json:"avgFrameTimeMs"` // Mean over the captures
+}
+
+func listCapturesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	directory, _ := args["directory"].(string)
+	if directory == "" {
+		directory = dataDir
+	}
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		pattern = "*.json"
+	}
+	filter, err := parseSessionFilter(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	groupBy, _ := args["group_by"].(string)
+	if groupBy != "" && (filter == nil || !filter.hasField(groupBy)) {
+		return mcp.NewToolResultError(fmt.Sprintf("group_by '%s' must be a field of session_pattern", groupBy)), nil
+	}
+
+	files, err := listCaptureFiles(directory, pattern)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	captures := []map[string]interface{}{}
+	groups := make(map[string]*SessionGroup)
+	skipped := []string{}
+	unmatched := 0
+	read := len(files)
+	for i, file := range files {
+		if ctx.Err() != nil { // Out of time or canceled: report the captures read so far
+			read = i
+			break
+		}
+		data, err := loadFrameProData(file.Path)
+		if err != nil {
+			skipped = append(skipped, filepath.Base(file.Path))
+			continue
+		}
+		fields, selected := filter.match(file.Path, data)
+		if !selected {
+			if fields == nil {
+				unmatched++
+			}
+			continue
+		}
+		frameMs := averageFrameMs(data)
+		capture := map[string]interface{}{
+			"file":           filepath.Base(file.Path),
+			"sessionName":    data.SessionName,
+			"date":           file.Modified.Format(time.RFC3339),
+			"frames":         len(data.Frames),
+			"totalFrames":    data.TotalFrames,
+			"avgFrameTimeMs": frameMs,
+		}
+		if fields != nil {
+			capture["fields"] = fields
+		}
+		captures = append(captures, capture)
+
+		if groupBy != "" {
+			group, exists := groups[fields[groupBy]]
+			if !exists {
+				group = &SessionGroup{Value: fields[groupBy], Files: []string{}}
+				groups[fields[groupBy]] = group
+			}
+			group.Captures++
+			group.Files = append(group.Files, filepath.Base(file.Path))
+			group.AvgFrameTimeMs += frameMs
+		}
+	}
+
+	output := map[string]interface{}{
+		"directory": directory,
+		"pattern":   pattern,
+		"captures":  captures,
+		"summary":   fmt.Sprintf("%d captures in %s", len(captures), directory),
+	}
+	if filter != nil {
+		output["sessionPattern"] = filter.Pattern.Raw
+		output["unmatchedFiles"] = unmatched
+		if len(filter.Where) > 0 {
+			output["where"] = filter.Where
+		}
+	}
+	if groupBy != "" {
+		grouped := []*SessionGroup{}
+		for _, group := range groups {
+			group.AvgFrameTimeMs /= float64(group.Captures)
+			grouped = append(grouped, group)
+		}
+		sort.Slice(grouped, func(i, j int) bool {
+			return lessFieldValue(grouped[i].Value, grouped[j].Value)
+		})
+		analysis := []string{}
+		for _, group := range grouped {
+			analysis = append(analysis, fmt.Sprintf("%s %s: %d captures, %.2fms average frame", groupBy, group.Value, group.Captures, group.AvgFrameTimeMs))
+		}
+		output["groupBy"] = groupBy
+		output["groups"] = grouped
+		output["analysis"] = analysis
+		output["summary"] = fmt.Sprintf("%d captures in %d groups by %s", len(captures), len(grouped), groupBy)
+	}
+	if len(skipped) > 0 {
+		output["skippedFiles"] = skipped
+	}
+	if read < len(files) {
+		attachPartial(ctx, output, read, len(files), "capture files")
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
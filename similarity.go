@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// PastRegression is a regression of a function between two consecutive historical captures
+type PastRegression struct {
+	Function            string              `json:"function"`
+	BaselineFile        string              `json:"baselineFile"`
+	BaselineSession     string              `json:"baselineSession"`
+	CurrentFile         string              `json:"currentFile"`
+	CurrentSession      string              `json:"currentSession"`
+	Date                string              `json:"date"`
+	PercentChange       float64             `json:"percentChange"`
+	AvgTimeDiffMs       float64             `json:"avgTimeDiffMs"`
+	Similarity          float64             `json:"similarity"` // 1.0 = same magnitude
+	BaselineAnnotations []SessionAnnotation `json:"baselineAnnotations,omitempty"`
+	CurrentAnnotations  []SessionAnnotation `json:"currentAnnotations,omitempty"`
+}
+
+// regressionQuery describes the regression to look up in history
+type regressionQuery struct {
+	Function      string
+	Contains      bool
+	PercentChange float64
+	HasPercent    bool
+	AvgDiffMs     float64
+	HasDiff       bool
+	Tolerance     float64
+}
+
+func similarRegressionsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	query := regressionQuery{Tolerance: 0.5}
+	query.Function, _ = args["function"].(string)
+	if query.Function == "" {
+		return mcp.NewToolResultError("function is required"), nil
+	}
+	query.Contains, _ = args["contains"].(bool)
+	query.PercentChange, query.HasPercent = args["percent_change"].(float64)
+	query.AvgDiffMs, query.HasDiff = args["avg_time_diff_ms"].(float64)
+	if !query.HasPercent && !query.HasDiff {
+		return mcp.NewToolResultError("Either percent_change or avg_time_diff_ms is required to describe the regression magnitude"), nil
+	}
+	if t, ok := args["tolerance"].(float64); ok && t > 0 {
+		query.Tolerance = t
+	}
+	directory, _ := args["directory"].(string)
+	if directory == "" {
+		directory = dataDir
+	}
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		pattern = "*.json"
+	}
+
+	filter, err := parseSessionFilter(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	files, err := listCaptureFiles(directory, pattern)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Replay the history as consecutive comparisons, oldest first
+	matches := []PastRegression{}
+	comparisons := 0
+	var previous *FrameProData
+	var previousFile CaptureFile
+	read := len(files)
+	for i, file := range files {
+		if ctx.Err() != nil { // Out of time or canceled: report the captures read so far
+			read = i
+			break
+		}
+		data, err := loadFrameProData(file.Path)
+		if err != nil {
+			continue
+		}
+		if _, selected := filter.match(file.Path, data); !selected {
+			continue
+		}
+		if previous != nil {
+			comparisons++
+			matches = append(matches, matchRegressions(previous, data, previousFile, file, query)...)
+		}
+		previous = data
+		previousFile = file
+	}
+
+	// Regressions with recorded resolutions are the useful ones, then the closest in magnitude
+	sort.Slice(matches, func(i, j int) bool {
+		ai := len(matches[i].BaselineAnnotations)+len(matches[i].CurrentAnnotations) > 0
+		aj := len(matches[j].BaselineAnnotations)+len(matches[j].CurrentAnnotations) > 0
+		if ai != aj {
+			return ai
+		}
+		return matches[i].Similarity > matches[j].Similarity
+	})
+
+	annotated := 0
+	for _, m := range matches {
+		if len(m.BaselineAnnotations)+len(m.CurrentAnnotations) > 0 {
+			annotated++
+		}
+	}
+
+	output := map[string]interface{}{
+		"function":           query.Function,
+		"directory":          directory,
+		"comparisonsScanned": comparisons,
+		"tolerance":          query.Tolerance,
+		"similarRegressions": matches,
+		"summary": fmt.Sprintf("Found %d similar past regressions in %d historical comparisons, %d with recorded annotations",
+			len(matches), comparisons, annotated),
+	}
+	if read < len(files) {
+		attachPartial(ctx, output, read, len(files), "capture files")
+	}
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// matchRegressions returns the regressions of matching functions between two captures whose
+// magnitude is within tolerance (relative) of the requested regression
+func matchRegressions(baseline, current *FrameProData, baselineFile, currentFile CaptureFile, query regressionQuery) []PastRegression {
+	baselineFuncs := mergeFunctionsByName(baseline.Functions)
+	matches := []PastRegression{}
+
+	for name, currentFn := range mergeFunctionsByName(current.Functions) {
+		if !matchesFunctionName(name, query.Function, query.Contains) {
+			continue
+		}
+		baselineFn, exists := baselineFuncs[name]
+		if !exists {
+			continue
+		}
+
+		pastPercent := (currentFn.TotalTimeMs - baselineFn.TotalTimeMs) / (baselineFn.TotalTimeMs + 0.001) * 100
+		pastDiff := currentFn.AvgTimePerFrameMs - baselineFn.AvgTimePerFrameMs
+		if pastPercent <= 0 && pastDiff <= 0 {
+			continue // Not a regression
+		}
+
+		// Similarity is 1 minus the relative magnitude difference, averaged over the given measures
+		var similarity float64
+		measures := 0
+		if query.HasPercent {
+			similarity += 1 - math.Abs(pastPercent-query.PercentChange)/math.Max(math.Abs(query.PercentChange), 0.001)
+			measures++
+		}
+		if query.HasDiff {
+			similarity += 1 - math.Abs(pastDiff-query.AvgDiffMs)/math.Max(math.Abs(query.AvgDiffMs), 0.001)
+			measures++
+		}
+		similarity /= float64(measures)
+		if similarity < 1-query.Tolerance {
+			continue
+		}
+
+		matches = append(matches, PastRegression{
+			Function:            name,
+			BaselineFile:        filepath.Base(baselineFile.Path),
+			BaselineSession:     baseline.SessionName,
+			CurrentFile:         filepath.Base(currentFile.Path),
+			CurrentSession:      current.SessionName,
+			Date:                currentFile.Modified.Format(time.RFC3339),
+			PercentChange:       pastPercent,
+			AvgTimeDiffMs:       pastDiff,
+			Similarity:          similarity,
+			BaselineAnnotations: sessionAnnotations(baseline.SessionName),
+			CurrentAnnotations:  sessionAnnotations(current.SessionName),
+		})
+	}
+	return matches
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// speedscope (https://www.speedscope.app) files hold one profile per thread over a shared
+// frame (function name) table. Evented profiles open and close frames at times; sampled
+// profiles list stacks with weights, laid out one after another. Both import as nested
+// spans cut into frames like Chrome traces. Exports place each thread's scopes back to back
+// from the start of their frame, since FramePro exports carry durations but no start times.
+
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+// SpeedscopeFile is a speedscope document
+type SpeedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Shared             SpeedscopeShared    `json:"shared"`
+	Profiles           []SpeedscopeProfile `json:"profiles"`
+	Name               string              `json:"name,omitempty"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter,omitempty"`
+}
+
+// SpeedscopeShared holds the frames referenced by index from every profile
+type SpeedscopeShared struct {
+	Frames []SpeedscopeFrame `json:"frames"`
+}
+
+// SpeedscopeFrame is a function in speedscope's frame table
+type SpeedscopeFrame struct {
+	Name string `json:"name"`
+}
+
+// SpeedscopeProfile is one thread of a speedscope file
+type SpeedscopeProfile struct {
+	Type       string            `json:"type"` // "evented" or "sampled"
+	Name       string            `json:"name"`
+	Unit       string            `json:"unit"`
+	StartValue float64           `json:"startValue"`
+	EndValue   float64           `json:"endValue"`
+	Events     []SpeedscopeEvent `json:"events,omitempty"`
+	Samples    [][]int           `json:"samples,omitempty"`
+	Weights    []float64         `json:"weights,omitempty"`
+}
+
+// SpeedscopeEvent opens (O) or closes (C) a frame at a time
+type SpeedscopeEvent struct {
+	Type  string  `json:"type"`
+	Frame int     `json:"frame"`
+	At    float64 `json:"at"`
+}
+
+// speedscopeUnitsToMicros converts the time units of speedscope profiles to microseconds
+var speedscopeUnitsToMicros = map[string]float64{
+	"nanoseconds": 0.001, "microseconds": 1, "milliseconds": 1000, "seconds": 1e6,
+}
+
+// isSpeedscope reports whether a JSON document is a speedscope file
+func isSpeedscope(trimmed []byte) bool {
+	return len(trimmed) > 0 && trimmed[0] == '{' && bytes.Contains(trimmed, []byte("speedscope.app/file-format-schema"))
+}
+
+// decodeSpeedscope converts a speedscope file into hierarchical per-frame and aggregate functions
+func decodeSpeedscope(path string, content []byte) (*FrameProData, error) {
+	var file SpeedscopeFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse speedscope file: %w", err)
+	}
+
+	spans := make(map[int][]*traceSpan)
+	threadNames := make(map[int]string)
+	markers := make(map[int][]float64)
+	name := func(frame int) (string, error) {
+		if frame < 0 || frame >= len(file.Shared.Frames) {
+			return "", fmt.Errorf("speedscope frame index %d out of range", frame)
+		}
+		return file.Shared.Frames[frame].Name, nil
+	}
+	for i, profile := range file.Profiles {
+		scale, known := speedscopeUnitsToMicros[profile.Unit]
+		if !known {
+			return nil, fmt.Errorf("speedscope profile %q is measured in %q; only time units can be imported", profile.Name, profile.Unit)
+		}
+		thread := i + 1
+		threadNames[thread] = profile.Name
+		if profile.Name == "" {
+			threadNames[thread] = fmt.Sprintf("Thread %d", thread)
+		}
+		add := func(span *traceSpan) {
+			spans[thread] = append(spans[thread], span)
+			if isTraceFrameMarker(span.name) {
+				markers[thread] = append(markers[thread], span.start)
+			}
+		}
+
+		switch profile.Type {
+		case "evented":
+			open := []*traceSpan{}
+			for _, event := range profile.Events {
+				at := event.At * scale
+				switch event.Type {
+				case "O":
+					fn, err := name(event.Frame)
+					if err != nil {
+						return nil, err
+					}
+					open = append(open, &traceSpan{name: fn, thread: thread, start: at})
+				case "C":
+					if len(open) > 0 {
+						span := open[len(open)-1]
+						open = open[:len(open)-1]
+						span.end = at
+						add(span)
+					}
+				}
+			}
+		case "sampled":
+			// Consecutive samples sharing a stack prefix extend the same spans
+			at := profile.StartValue * scale
+			stack := []*traceSpan{}
+			frames := []int{}
+			for s, sample := range profile.Samples {
+				common := 0
+				for common < len(sample) && common < len(frames) && sample[common] == frames[common] {
+					common++
+				}
+				for len(stack) > common {
+					stack[len(stack)-1].end = at
+					add(stack[len(stack)-1])
+					stack, frames = stack[:len(stack)-1], frames[:len(frames)-1]
+				}
+				for _, frame := range sample[common:] {
+					fn, err := name(frame)
+					if err != nil {
+						return nil, err
+					}
+					stack = append(stack, &traceSpan{name: fn, thread: thread, start: at})
+					frames = append(frames, frame)
+				}
+				if s < len(profile.Weights) {
+					at += profile.Weights[s] * scale
+				}
+			}
+			for len(stack) > 0 {
+				stack[len(stack)-1].end = at
+				add(stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("%s has no evented or sampled speedscope profiles with time units", filepath.Base(path))
+	}
+	return buildTraceCapture(path, spans, threadNames, markers), nil
+}
+
+// speedscopeExporter lays FramePro scopes out as speedscope events in milliseconds
+type speedscopeExporter struct {
+	file    SpeedscopeFile
+	frameOf map[string]int
+}
+
+// frameIndex returns the index of a function in the shared frame table, adding it if new
+func (e *speedscopeExporter) frameIndex(name string) int {
+	index, exists := e.frameOf[name]
+	if !exists {
+		index = len(e.file.Shared.Frames)
+		e.frameOf[name] = index
+		e.file.Shared.Frames = append(e.file.Shared.Frames, SpeedscopeFrame{Name: name})
+	}
+	return index
+}
+
+// addScope places a scope at a time, its children back to back inside it, and returns its end
+func (e *speedscopeExporter) addScope(profile *SpeedscopeProfile, fn FrameProFunction, at float64) float64 {
+	frame := e.frameIndex(fn.FunctionName)
+	end := at + fn.TimeMs
+	profile.Events = append(profile.Events, SpeedscopeEvent{Type: "O", Frame: frame, At: at})
+	child := at
+	for _, c := range fn.Children {
+		if c.TimeMs == 0 {
+			c.TimeMs = c.AvgTimePerFrameMs // Children of the average frame
+		}
+		// Children that overrun their parent are clipped to it so events stay nested
+		c.TimeMs = min(c.TimeMs, end-child)
+		if c.TimeMs > 0 {
+			child = e.addScope(profile, c, child)
+		}
+	}
+	profile.Events = append(profile.Events, SpeedscopeEvent{Type: "C", Frame: frame, At: end})
+	return end
+}
+
+// buildSpeedscope converts frames into one evented speedscope profile per thread plus a
+// Frames track marking where each frame starts
+func buildSpeedscope(data *FrameProData, frames []FrameProFrame) SpeedscopeFile {
+	e := &speedscopeExporter{
+		file: SpeedscopeFile{
+			Schema:   speedscopeSchema,
+			Name:     data.SessionName,
+			Exporter: "framepro-mcp",
+		},
+		frameOf: make(map[string]int),
+	}
+	stages := threadStages(data)
+
+	profiles := make(map[int]*SpeedscopeProfile)
+	order := []int{}
+	frameTrack := &SpeedscopeProfile{Type: "evented", Name: "Frames", Unit: "milliseconds"}
+	at := 0.0
+	for _, frame := range frames {
+		// A frame lasts as long as its frame time or its busiest thread, whichever is longer
+		length := frameTimeMs(frame, stages)
+		for _, total := range frameThreadTotals(frame) {
+			length = max(length, total.TimeMs)
+		}
+		marker := e.frameIndex(fmt.Sprintf("Frame %d", frame.FrameNumber))
+		frameTrack.Events = append(frameTrack.Events,
+			SpeedscopeEvent{Type: "O", Frame: marker, At: at},
+			SpeedscopeEvent{Type: "C", Frame: marker, At: at + length})
+
+		cursor := make(map[int]float64)
+		for _, fn := range frame.Functions {
+			if fn.TimeMs <= 0 {
+				continue
+			}
+			profile, exists := profiles[fn.ThreadID]
+			if !exists {
+				profile = &SpeedscopeProfile{Type: "evented", Name: fn.ThreadName, Unit: "milliseconds"}
+				profiles[fn.ThreadID] = profile
+				order = append(order, fn.ThreadID)
+			}
+			cursor[fn.ThreadID] = e.addScope(profile, fn, at+cursor[fn.ThreadID]) - at
+		}
+		at += length
+	}
+
+	frameTrack.EndValue = at
+	e.file.Profiles = append(e.file.Profiles, *frameTrack)
+	for _, id := range order {
+		profile := profiles[id]
+		profile.EndValue = at
+		if stages[id] == stageMain && e.file.ActiveProfileIndex == 0 {
+			e.file.ActiveProfileIndex = len(e.file.Profiles)
+		}
+		e.file.Profiles = append(e.file.Profiles, *profile)
+	}
+	return e.file
+}
+
+func exportSpeedscopeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	outputPath, _ := args["output_path"].(string)
+	dryRun := isDryRun(args)
+	outputPath, err := resolveOutputPath(outputPath, outputStem(filePath), ".speedscope.json", dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	frames := data.Frames
+	note := fmt.Sprintf("Exported %d frames; scopes run back to back from the start of each frame because FramePro exports have no start times", len(frames))
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+		note = "No per-frame data: exported the average frame. Scopes run back to back because FramePro exports have no start times"
+	}
+	file := buildSpeedscope(data, frames)
+
+	content, err := json.Marshal(file)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode speedscope file: %v", err)), nil
+	}
+	write, err := writeFile(outputPath, content, dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	events := 0
+	for _, profile := range file.Profiles {
+		events += len(profile.Events)
+	}
+	output := map[string]interface{}{
+		"file":        filePath,
+		"sessionName": data.SessionName,
+		"outputPath":  outputPath,
+		"profiles":    len(file.Profiles),
+		"functions":   len(file.Shared.Frames),
+		"events":      events,
+		"bytes":       len(content),
+		"analysis":    []string{note},
+		"summary":     fmt.Sprintf("%s %s (%d threads); open it at https://www.speedscope.app", wroteVerb(dryRun), outputPath, len(file.Profiles)-1),
+	}
+	attachWrites(output, []FileWrite{write}, dryRun)
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
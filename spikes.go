@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Correlated spikes compare every function's per-frame cost with the spike signal: how far
+// each frame is over the median frame time (0 for frames at or under it). Hitches are frames
+// over threshold_factor times the median, as in detect_hitches. A function explains a hitch
+// when its excess over its own average covers at least explainShare of the hitch's excess.
+// Functions that explain mostly the same hitches are grouped as spiking together.
+
+const (
+	explainShare    = 0.25 // Share of a hitch's excess a function must cover to explain it
+	coSpikeOverlap  = 0.5  // Jaccard overlap of explained hitches to group two functions
+	maxSpikeSuspect = 20   // Candidates considered for grouping
+)
+
+// SpikeCorrelation relates one function to the capture's frame-time spikes
+type SpikeCorrelation struct {
+	FunctionName     string  `json:"functionName"`
+	ThreadName       string  `json:"threadName"`
+	Correlation      float64 `json:"correlation"` // With the spike signal
+	AvgMs            float64 `json:"avgMs"`
+	AvgInHitchesMs   float64 `json:"avgInHitchesMs"`
+	HitchesExplained int     `json:"hitchesExplained"`
+	HitchCoverage    float64 `json:"hitchCoveragePercent"`
+	ExcessShare      float64 `json:"excessSharePercent"` // Share of all hitch excess time
+	series           []float64
+	explained        map[int]bool
+}
+
+// SpikeGroup is a set of functions that spike in the same hitches
+type SpikeGroup struct {
+	Functions     []string `json:"functions"`
+	Hitches       int      `json:"hitches"`
+	HitchCoverage float64  `json:"hitchCoveragePercent"`
+	ExcessShare   float64  `json:"excessSharePercent"`
+	ExampleFrames []int    `json:"exampleFrames"`
+}
+
+// hitchOverlap is the Jaccard overlap of two sets of hitch indexes
+func hitchOverlap(a, b map[int]bool) float64 {
+	union := len(a)
+	shared := 0
+	for i := range b {
+		if a[i] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// joinNames lists names as "A, B and C"
+func joinNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+func correlateSpikesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	factor := 2.0
+	if f, ok := args["threshold_factor"].(float64); ok && f > 0 {
+		factor = f
+	}
+	minCorrelation := 0.5
+	if c, ok := args["min_correlation"].(float64); ok && c > 0 {
+		minCorrelation = c
+	}
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) < 2 {
+		return mcp.NewToolResultError("Spike correlation needs per-frame data with at least 2 frames. Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	median := medianOf(times)
+	signal := make([]float64, len(times))
+	hitches := []int{}
+	var totalExcess float64
+	for i, t := range times {
+		signal[i] = max(t-median, 0)
+		if t > median*factor {
+			hitches = append(hitches, i)
+			totalExcess += signal[i]
+		}
+	}
+
+	// Per-frame cost of every function, 0 in frames where it wasn't recorded
+	byKey := make(map[string]*SpikeCorrelation)
+	for i, frame := range data.Frames {
+		for _, fn := range frame.Functions {
+			key := functionKey(fn)
+			c, exists := byKey[key]
+			if !exists {
+				c = &SpikeCorrelation{FunctionName: fn.FunctionName, ThreadName: fn.ThreadName, series: make([]float64, len(times)), explained: make(map[int]bool)}
+				byKey[key] = c
+			}
+			c.series[i] += fn.TimeMs
+		}
+	}
+
+	suspects := []*SpikeCorrelation{}
+	for _, c := range byKey {
+		c.AvgMs, _ = meanStdDev(c.series)
+		_, _, c.Correlation = linearFit(c.series, signal)
+		var excess float64
+		for h, i := range hitches {
+			c.AvgInHitchesMs += c.series[i] / float64(len(hitches))
+			fnExcess := c.series[i] - c.AvgMs
+			if fnExcess <= 0 {
+				continue
+			}
+			excess += min(fnExcess, signal[i])
+			if fnExcess >= signal[i]*explainShare {
+				c.explained[h] = true
+			}
+		}
+		c.HitchesExplained = len(c.explained)
+		if len(hitches) > 0 {
+			c.HitchCoverage = float64(c.HitchesExplained) / float64(len(hitches)) * 100
+		}
+		if totalExcess > 0 {
+			c.ExcessShare = excess / totalExcess * 100
+		}
+		if c.Correlation >= minCorrelation || c.HitchesExplained > 0 {
+			suspects = append(suspects, c)
+		}
+	}
+	sort.Slice(suspects, func(i, j int) bool {
+		if suspects[i].ExcessShare != suspects[j].ExcessShare {
+			return suspects[i].ExcessShare > suspects[j].ExcessShare
+		}
+		return suspects[i].Correlation > suspects[j].Correlation
+	})
+	if len(suspects) > maxSpikeSuspect {
+		suspects = suspects[:maxSpikeSuspect]
+	}
+
+	// Group functions that explain mostly the same hitches (union-find over the suspects)
+	parent := make([]int, len(suspects))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range suspects {
+		for j := i + 1; j < len(suspects); j++ {
+			if len(suspects[i].explained) > 0 && hitchOverlap(suspects[i].explained, suspects[j].explained) >= coSpikeOverlap {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+	members := make(map[int][]*SpikeCorrelation)
+	order := []int{}
+	for i, c := range suspects {
+		if c.HitchesExplained == 0 {
+			continue
+		}
+		root := find(i)
+		if members[root] == nil {
+			order = append(order, root)
+		}
+		members[root] = append(members[root], c)
+	}
+
+	groups := []SpikeGroup{}
+	for _, root := range order {
+		group := SpikeGroup{ExampleFrames: []int{}}
+		covered := make(map[int]bool)
+		for _, c := range members[root] {
+			group.Functions = append(group.Functions, c.FunctionName)
+			for h := range c.explained {
+				covered[h] = true
+			}
+		}
+		var excess float64
+		for h, i := range hitches {
+			var groupExcess float64
+			for _, c := range members[root] {
+				groupExcess += max(c.series[i]-c.AvgMs, 0)
+			}
+			excess += min(groupExcess, signal[i])
+			if covered[h] && len(group.ExampleFrames) < 5 {
+				group.ExampleFrames = append(group.ExampleFrames, data.Frames[i].FrameNumber)
+			}
+		}
+		group.Hitches = len(covered)
+		group.HitchCoverage = float64(len(covered)) / float64(len(hitches)) * 100
+		if totalExcess > 0 {
+			group.ExcessShare = excess / totalExcess * 100
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ExcessShare > groups[j].ExcessShare
+	})
+
+	analysis := []string{}
+	for _, group := range groups {
+		if len(groups) > 1 && len(analysis) >= 3 {
+			break
+		}
+		if len(group.Functions) > 1 {
+			analysis = append(analysis, fmt.Sprintf("%s spike together and are responsible for %.0f%% of hitch time, appearing in %d of %d hitches",
+				joinNames(group.Functions), group.ExcessShare, group.Hitches, len(hitches)))
+		} else {
+			analysis = append(analysis, fmt.Sprintf("%s spikes alone and is responsible for %.0f%% of hitch time, appearing in %d of %d hitches",
+				group.Functions[0], group.ExcessShare, group.Hitches, len(hitches)))
+		}
+	}
+
+	listed := suspects
+	if len(listed) > topN {
+		listed = listed[:topN]
+	}
+
+	summary := fmt.Sprintf("No hitches over %.1fx the %.2fms median frame time", factor, median)
+	if len(hitches) > 0 {
+		summary = fmt.Sprintf("%d hitches over %.1fx the %.2fms median frame time; no function stands out in them", len(hitches), factor, median)
+		if len(groups) > 0 {
+			summary = fmt.Sprintf("%d hitches over %.1fx the %.2fms median frame time, explained by %d groups of functions that spike together",
+				len(hitches), factor, median, len(groups))
+		}
+	}
+
+	output := map[string]interface{}{
+		"file":             filePath,
+		"sessionName":      data.SessionName,
+		"framesAnalyzed":   len(times),
+		"medianFrameMs":    median,
+		"hitchThresholdMs": median * factor,
+		"hitches":          len(hitches),
+		"hitchExcessMs":    totalExcess,
+		"functions":        listed,
+		"groups":           groups,
+		"analysis":         analysis,
+		"summary":          summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// FrameTimePercentiles holds the smoothness metrics the games industry reports for a capture.
+// "Lows" are the average of the slowest N% of frames, expressed as both frame time and FPS.
+type FrameTimePercentiles struct {
+	FrameCount            int     `json:"frameCount"`
+	AvgFrameTimeMs        float64 `json:"avgFrameTimeMs"`
+	AvgFPS                float64 `json:"avgFPS"`
+	P50Ms                 float64 `json:"p50Ms"`
+	P90Ms                 float64 `json:"p90Ms"`
+	P95Ms                 float64 `json:"p95Ms"`
+	P99Ms                 float64 `json:"p99Ms"`
+	OnePercentLowMs       float64 `json:"onePercentLowMs"`
+	OnePercentLowFPS      float64 `json:"onePercentLowFPS"`
+	PointOnePercentLowMs  float64 `json:"pointOnePercentLowMs"`
+	PointOnePercentLowFPS float64 `json:"pointOnePercentLowFPS"`
+}
+
+// percentile returns the p-th percentile (0-100) of an ascending sorted slice,
+// interpolating linearly between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// slowestAverage returns the average of the slowest fraction of an ascending sorted slice.
+// At least one value is always included, so short captures still report a low.
+func slowestAverage(sorted []float64, fraction float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	n := int(math.Ceil(float64(len(sorted)) * fraction))
+	if n < 1 {
+		n = 1
+	}
+	var sum float64
+	for _, v := range sorted[len(sorted)-n:] {
+		sum += v
+	}
+	return sum / float64(n)
+}
+
+// msToFPS converts a frame time to frames per second
+func msToFPS(ms float64) float64 {
+	if ms <= 0 {
+		return 0
+	}
+	return 1000.0 / ms
+}
+
+// computeFrameTimePercentiles computes percentile and low statistics for a set of frame times
+func computeFrameTimePercentiles(times []float64) FrameTimePercentiles {
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, t := range sorted {
+		sum += t
+	}
+	stats := FrameTimePercentiles{FrameCount: len(sorted)}
+	if len(sorted) == 0 {
+		return stats
+	}
+
+	stats.AvgFrameTimeMs = sum / float64(len(sorted))
+	stats.AvgFPS = msToFPS(stats.AvgFrameTimeMs)
+	stats.P50Ms = percentile(sorted, 50)
+	stats.P90Ms = percentile(sorted, 90)
+	stats.P95Ms = percentile(sorted, 95)
+	stats.P99Ms = percentile(sorted, 99)
+	stats.OnePercentLowMs = slowestAverage(sorted, 0.01)
+	stats.OnePercentLowFPS = msToFPS(stats.OnePercentLowMs)
+	stats.PointOnePercentLowMs = slowestAverage(sorted, 0.001)
+	stats.PointOnePercentLowFPS = msToFPS(stats.PointOnePercentLowMs)
+	return stats
+}
+
+// meanStdDev returns the mean and population standard deviation of values
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)))
+}
+
+// linearFit fits y = slope*x + intercept by least squares and returns the correlation
+// coefficient r, which is 0 when either series is constant
+func linearFit(xs, ys []float64) (slope, intercept, r float64) {
+	n := float64(len(xs))
+	if len(xs) < 2 {
+		if len(ys) == 1 {
+			return 0, ys[0], 0
+		}
+		return 0, 0, 0
+	}
+	meanX, stdX := meanStdDev(xs)
+	meanY, stdY := meanStdDev(ys)
+	var covariance float64
+	for i := range xs {
+		covariance += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	covariance /= n
+	if stdX == 0 {
+		return 0, meanY, 0
+	}
+	slope = covariance / (stdX * stdX)
+	intercept = meanY - slope*meanX
+	if stdY > 0 {
+		r = covariance / (stdX * stdY)
+	}
+	return slope, intercept, r
+}
+
+// tCritical95 returns the two-sided 95% critical value of Student's t distribution with
+// df degrees of freedom, from a table between the listed points and the normal value beyond
+func tCritical95(df float64) float64 {
+	table := []struct{ df, t float64 }{
+		{1, 12.706}, {2, 4.303}, {3, 3.182}, {4, 2.776}, {5, 2.571}, {6, 2.447}, {7, 2.365},
+		{8, 2.306}, {9, 2.262}, {10, 2.228}, {12, 2.179}, {15, 2.131}, {20, 2.086},
+		{25, 2.060}, {30, 2.042}, {40, 2.021}, {60, 2.000}, {120, 1.980},
+	}
+	if df < 1 {
+		return table[0].t
+	}
+	for i, entry := range table {
+		if df == entry.df {
+			return entry.t
+		}
+		if df < entry.df {
+			prev := table[i-1]
+			return prev.t + (entry.t-prev.t)*(df-prev.df)/(entry.df-prev.df)
+		}
+	}
+	return 1.960
+}
+
+// ksTest returns the two-sample Kolmogorov-Smirnov statistic (the largest gap between the
+// empirical distributions) and its asymptotic p-value
+func ksTest(a, b []float64) (d, p float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 1
+	}
+	x := append([]float64(nil), a...)
+	y := append([]float64(nil), b...)
+	sort.Float64s(x)
+	sort.Float64s(y)
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		value := math.Min(x[i], y[j])
+		for i < len(x) && x[i] == value {
+			i++
+		}
+		for j < len(y) && y[j] == value {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/float64(len(x))-float64(j)/float64(len(y))))
+	}
+
+	// Kolmogorov distribution with the usual small-sample correction
+	n := float64(len(x)) * float64(len(y)) / float64(len(x)+len(y))
+	lambda := (math.Sqrt(n) + 0.12 + 0.11/math.Sqrt(n)) * d
+	if lambda < 0.2 {
+		return d, 1
+	}
+	sign := 1.0
+	for k := 1.0; k <= 100; k++ {
+		term := sign * math.Exp(-2*k*k*lambda*lambda)
+		p += term
+		if math.Abs(term) < 1e-10 {
+			break
+		}
+		sign = -sign
+	}
+	return d, math.Min(1, math.Max(0, 2*p))
+}
+
+// earthMoverDistance returns the 1-Wasserstein distance between two samples: the average
+// amount each value must move to turn one distribution into the other, in their unit
+func earthMoverDistance(a, b []float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	x := append([]float64(nil), a...)
+	y := append([]float64(nil), b...)
+	sort.Float64s(x)
+	sort.Float64s(y)
+	var distance, last float64
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		var value float64
+		switch {
+		case j == len(y) || i < len(x) && x[i] <= y[j]:
+			value = x[i]
+		default:
+			value = y[j]
+		}
+		if i > 0 || j > 0 {
+			distance += math.Abs(float64(i)/float64(len(x))-float64(j)/float64(len(y))) * (value - last)
+		}
+		for i < len(x) && x[i] == value {
+			i++
+		}
+		for j < len(y) && y[j] == value {
+			j++
+		}
+		last = value
+	}
+	return distance
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Subsystem rollups group functions by name rules (see parseSubsystems), first match wins.
+// Functions matching no rule are grouped by namespace, the part of the name before the
+// first "::", or collected under "Other". Times are per frame, using the chosen ranking
+// metric; as with budgets, nested scopes in the same subsystem count twice with 'total'.
+
+const subsystemOther = "Other"
+
+// SubsystemThread is one thread's share of a subsystem
+type SubsystemThread struct {
+	ThreadID      int     `json:"threadId"`
+	ThreadName    string  `json:"threadName"`
+	AvgMsPerFrame float64 `json:"avgMsPerFrame"`
+}
+
+// SubsystemRollup is the time of one subsystem, in total and per thread
+type SubsystemRollup struct {
+	Name           string            `json:"name"`
+	Rule           string            `json:"rule"`
+	AvgMsPerFrame  float64           `json:"avgMsPerFrame"`
+	PercentOfFrame float64           `json:"percentOfFrame"`
+	P95Ms          float64           `json:"p95Ms,omitempty"`
+	MaxMs          float64           `json:"maxMs,omitempty"`
+	Functions      int               `json:"functions"`
+	Threads        []SubsystemThread `json:"threads"`
+	TopFunctions   []FunctionCost    `json:"topFunctions"`
+	threads        map[int]*SubsystemThread
+	functions      []FunctionCost
+}
+
+// subsystemOf names the subsystem of a function and the rule that put it there
+func subsystemOf(rules []SubsystemBudget, functionName string, byNamespace bool) (string, string) {
+	if index := matchBudget(rules, functionName); index >= 0 {
+		return rules[index].Name, rules[index].Pattern
+	}
+	if namespace, _, found := strings.Cut(functionName, "::"); found && byNamespace && namespace != "" {
+		return namespace, "namespace"
+	}
+	return subsystemOther, ""
+}
+
+func subsystemBreakdownHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	rankBy, _ := args["rank_by"].(string)
+	if rankBy == "" {
+		rankBy = "total"
+	}
+	if rankBy != "total" && rankBy != "self" && rankBy != "inclusive" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid rank_by '%s': expected 'total', 'self' or 'inclusive'", rankBy)), nil
+	}
+	byNamespace := true
+	if b, ok := args["group_unmatched_by_namespace"].(bool); ok {
+		byNamespace = b
+	}
+	topN := 3
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+	rules, err := parseSubsystems(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	note := ""
+	if rankBy == "self" && !hasSelfTimes(data.Functions) {
+		note = "This export has no SelfTimeMs data; rolled up by total time instead"
+		rankBy = "total"
+	}
+
+	// Average time per frame of every subsystem, per thread
+	frameMs := averageFrameMs(data)
+	rollups := make(map[string]*SubsystemRollup)
+	threadTotals := make(map[int]map[string]float64)
+	threadNames := make(map[int]string)
+	for _, fn := range aggregateFrame(data).Functions {
+		ms := perFrameRankingMs(fn, rankBy)
+		name, rule := subsystemOf(rules, fn.FunctionName, byNamespace)
+		rollup, exists := rollups[name]
+		if !exists {
+			rollup = &SubsystemRollup{Name: name, Rule: rule, threads: make(map[int]*SubsystemThread)}
+			rollups[name] = rollup
+		}
+		rollup.AvgMsPerFrame += ms
+		rollup.Functions++
+		rollup.functions = append(rollup.functions, FunctionCost{FunctionName: fn.FunctionName, ThreadName: fn.ThreadName, AvgTimePerFrameMs: ms})
+		thread, exists := rollup.threads[fn.ThreadID]
+		if !exists {
+			thread = &SubsystemThread{ThreadID: fn.ThreadID, ThreadName: fn.ThreadName}
+			rollup.threads[fn.ThreadID] = thread
+		}
+		thread.AvgMsPerFrame += ms
+
+		if threadTotals[fn.ThreadID] == nil {
+			threadTotals[fn.ThreadID] = make(map[string]float64)
+		}
+		threadTotals[fn.ThreadID][name] += ms
+		threadNames[fn.ThreadID] = fn.ThreadName
+	}
+
+	// Spread across frames, when the per-frame times use the same metric
+	if len(data.Frames) > 0 && rankBy == "total" {
+		series := make(map[string][]float64)
+		for f, frame := range data.Frames {
+			for _, fn := range frame.Functions {
+				name, _ := subsystemOf(rules, fn.FunctionName, byNamespace)
+				if series[name] == nil {
+					series[name] = make([]float64, len(data.Frames))
+				}
+				series[name][f] += fn.TimeMs
+			}
+		}
+		for name, times := range series {
+			if rollup, exists := rollups[name]; exists {
+				stats := computeFrameTimePercentiles(times)
+				rollup.P95Ms = stats.P95Ms
+				for _, ms := range times {
+					rollup.MaxMs = max(rollup.MaxMs, ms)
+				}
+			}
+		}
+	}
+
+	subsystems := []*SubsystemRollup{}
+	for _, rollup := range rollups {
+		if frameMs > 0 {
+			rollup.PercentOfFrame = rollup.AvgMsPerFrame / frameMs * 100
+		}
+		for _, thread := range rollup.threads {
+			rollup.Threads = append(rollup.Threads, *thread)
+		}
+		sort.Slice(rollup.Threads, func(i, j int) bool {
+			return rollup.Threads[i].AvgMsPerFrame > rollup.Threads[j].AvgMsPerFrame
+		})
+		sort.Slice(rollup.functions, func(i, j int) bool {
+			return rollup.functions[i].AvgTimePerFrameMs > rollup.functions[j].AvgTimePerFrameMs
+		})
+		rollup.TopFunctions = rollup.functions
+		if len(rollup.TopFunctions) > topN {
+			rollup.TopFunctions = rollup.TopFunctions[:topN]
+		}
+		subsystems = append(subsystems, rollup)
+	}
+	sort.Slice(subsystems, func(i, j int) bool {
+		return subsystems[i].AvgMsPerFrame > subsystems[j].AvgMsPerFrame
+	})
+
+	threads := []map[string]interface{}{}
+	for id, totals := range threadTotals {
+		var total float64
+		for _, ms := range totals {
+			total += ms
+		}
+		threads = append(threads, map[string]interface{}{
+			"threadId":      id,
+			"threadName":    threadNames[id],
+			"avgMsPerFrame": total,
+			"subsystems":    totals,
+		})
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i]["avgMsPerFrame"].(float64) > threads[j]["avgMsPerFrame"].(float64)
+	})
+
+	analysis := []string{}
+	for i, rollup := range subsystems {
+		if i == 3 {
+			break
+		}
+		analysis = append(analysis, fmt.Sprintf("%s takes %.2fms per frame (%.0f%% of the frame), mostly on %s (%.2fms)",
+			rollup.Name, rollup.AvgMsPerFrame, rollup.PercentOfFrame, rollup.Threads[0].ThreadName, rollup.Threads[0].AvgMsPerFrame))
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"rankBy":         rankBy,
+		"avgFrameTimeMs": frameMs,
+		"subsystems":     subsystems,
+		"threads":        threads,
+		"analysis":       analysis,
+		"summary":        fmt.Sprintf("%d subsystems over %d threads against a %.2fms average frame", len(subsystems), len(threads), frameMs),
+	}
+	if note != "" {
+		addWarning(output, warnNoSelfTimes, note)
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+// Without a target_fps argument the target frame rate is read from the capture: a game
+// locked to a frame rate presents most frames close to its budget, so the frame times
+// cluster near 8.3, 16.7 or 33.3ms. The standard rate whose budget holds the largest share
+// of frames within targetClusterTolerance wins if that share is at least
+// targetClusterMinShare; an uncapped or unsteady capture keeps the 60 FPS default.
+
+const (
+	defaultTargetFPS       = 60.0
+	targetClusterTolerance = 0.1 // Fraction of the budget either side
+	targetClusterMinShare  = 0.3
+)
+
+var standardTargetFPS = []float64{120, 60, 30}
+
+// detectTargetFPS finds the standard frame rate the frame times cluster at, and the share
+// of frames near its budget
+func detectTargetFPS(times []float64) (float64, float64, bool) {
+	if len(times) == 0 {
+		return 0, 0, false
+	}
+	best, bestShare := 0.0, 0.0
+	for _, fps := range standardTargetFPS {
+		budget := 1000 / fps
+		near := 0
+		for _, t := range times {
+			if t >= budget*(1-targetClusterTolerance) && t <= budget*(1+targetClusterTolerance) {
+				near++
+			}
+		}
+		if share := float64(near) / float64(len(times)); share > bestShare {
+			best, bestShare = fps, share
+		}
+	}
+	if bestShare < targetClusterMinShare {
+		return 0, bestShare, false
+	}
+	return best, bestShare, true
+}
+
+// targetFPSFor returns the target_fps argument, else the capture's detected target, else
+// 60, with where it came from: "argument", "detected" or "default"
+func targetFPSFor(args map[string]interface{}, data *FrameProData) (float64, string) {
+	if fps, ok := args["target_fps"].(float64); ok && fps > 0 {
+		return fps, "argument"
+	}
+	if data != nil && len(data.Frames) > 0 {
+		if fps, _, ok := detectTargetFPS(frameTimes(data)); ok {
+			return fps, "detected"
+		}
+	}
+	return defaultTargetFPS, "default"
+}
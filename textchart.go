@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Text charts are render_chart's charts for clients that can't show images, drawn with
+// Unicode block characters so they read in any monospaced tool result:
+//
+//	frame_times - a sparkline, one column per group of frames showing the group's worst
+//	              frame, over a row marking groups above the 60 (-) and 30 (!) FPS budgets
+//	hotspots    - one bar per function, in eighths of a character
+//	histogram   - one bar per bin
+//
+// Width is counted in characters.
+
+const (
+	textChartDefaultWidth = 60
+	textChartMinWidth     = 20
+	textChartMaxWidth     = 400
+)
+
+var (
+	sparkBlocks   = []rune("▁▂▃▄▅▆▇█")
+	partialBlocks = []rune(" ▏▎▍▌▋▊▉")
+)
+
+// sparkline draws values from 0 to top in one row of block characters
+func sparkline(values []float64, top float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if top > 0 {
+			level = int(math.Min(v/top, 1) * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[max(level, 0)])
+	}
+	return b.String()
+}
+
+// textBar draws a bar of value/top times width characters, in eighths
+func textBar(value, top float64, width int) string {
+	if top <= 0 || value <= 0 {
+		return ""
+	}
+	eighths := int(math.Round(math.Min(value/top, 1) * float64(width*8)))
+	bar := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		bar += string(partialBlocks[eighths%8])
+	}
+	return bar
+}
+
+// textFrameTimeChart draws the frame times as a sparkline of the worst frame per column
+func textFrameTimeChart(title string, numbers []int, times []float64, width int) string {
+	columns := min(width, len(times))
+	worst := make([]float64, columns)
+	for i, t := range times {
+		column := i * columns / len(times)
+		worst[column] = math.Max(worst[column], t)
+	}
+	top := chartBudget60Ms
+	for _, t := range times {
+		top = math.Max(top, t)
+	}
+
+	var budgets strings.Builder
+	for _, t := range worst {
+		switch {
+		case t > chartBudget30Ms:
+			budgets.WriteRune('!')
+		case t > chartBudget60Ms:
+			budgets.WriteRune('-')
+		default:
+			budgets.WriteRune(' ')
+		}
+	}
+	framesPerColumn := ""
+	if columns < len(times) {
+		framesPerColumn = fmt.Sprintf(", worst of %.1f frames per column", float64(len(times))/float64(columns))
+	}
+	first, last := fmt.Sprintf("frame %d", numbers[0]), fmt.Sprintf("frame %d", numbers[len(numbers)-1])
+	axis := first + strings.Repeat(" ", max(columns-len(first)-len(last), 1)) + last
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n0 to %.1fms%s\n", title, top, framesPerColumn)
+	fmt.Fprintf(&b, "%s\n%s\n%s\n", sparkline(worst, top), strings.TrimRight(budgets.String(), " "), axis)
+	b.WriteString("- over 16.7ms (60 FPS), ! over 33.3ms (30 FPS)\n")
+	return b.String()
+}
+
+// textBarChart draws labeled bars with their values, giving labels up to a third of the width
+func textBarChart(title string, labels []string, values []float64, unit string, width int) string {
+	labelWidth := 0
+	for _, label := range labels {
+		labelWidth = max(labelWidth, len([]rune(label)))
+	}
+	labelWidth = min(labelWidth, width/3)
+	top := 0.0
+	for _, v := range values {
+		top = math.Max(top, v)
+	}
+	barWidth := max(width-labelWidth-12, 5)
+
+	var b strings.Builder
+	b.WriteString(title + "\n")
+	for i, label := range labels {
+		label = truncateLabel(label, labelWidth)
+		fmt.Fprintf(&b, "%s%s  %s %s%s\n", label, strings.Repeat(" ", labelWidth-len([]rune(label))), textBar(values[i], top, barWidth), chartLabel(math.Round(values[i]*100)/100), unit)
+	}
+	return b.String()
+}
+
+// textHotspotChart draws the hotspot bars
+func textHotspotChart(title string, bars []ChartBar, width int) string {
+	labels, values := make([]string, len(bars)), make([]float64, len(bars))
+	for i, bar := range bars {
+		labels[i], values[i] = bar.Label, bar.Value
+	}
+	return textBarChart(title, labels, values, "ms", width)
+}
+
+// textHistogramChart draws one bar per bin, leaving out empty bins past the last frame
+func textHistogramChart(title string, buckets []HistogramBucket, width int) string {
+	last := 0
+	for i, bucket := range buckets {
+		if bucket.Count > 0 {
+			last = i
+		}
+	}
+	labels, values := []string{}, []float64{}
+	for i, bucket := range buckets[:last+1] {
+		label := fmt.Sprintf("%s-%sms", chartLabel(bucket.MinMs), chartLabel(bucket.MaxMs))
+		if i == len(buckets)-1 {
+			label = fmt.Sprintf("%sms+", chartLabel(bucket.MinMs)) // The overflow bin
+		}
+		if bucket.MaxMs > chartBudget30Ms {
+			label += " !"
+		} else if bucket.MaxMs > chartBudget60Ms {
+			label += " -"
+		}
+		labels = append(labels, label)
+		values = append(values, float64(bucket.Count))
+	}
+	return textBarChart(title, labels, values, "", width)
+}
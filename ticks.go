@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Tick decomposition splits every frame by the engine's declared top-level ticks (World
+// tick, render frame, audio update, ...) instead of by whatever happens to be hot, so the
+// same rows come back for every capture. A scope matching a tick counts with its whole
+// time and its children are not searched further, so nested ticks don't count twice; the
+// main thread's time outside all ticks is reported as unanchored. Ticks come from the
+// ticks argument or from a project file named by FRAMEPRO_TICKS.
+
+const (
+	unanchoredTick         = "Unanchored (main thread)"
+	unanchoredShareWarning = 20 // Percent of the main thread outside the ticks that suggests a missing tick
+)
+
+// projectTicks are the ticks declared in the FRAMEPRO_TICKS file, if any
+var projectTicks []SubsystemBudget
+
+// TickRow is one declared tick's time per frame
+type TickRow struct {
+	Name             string   `json:"name"`
+	Pattern          string   `json:"pattern"`
+	AvgMs            float64  `json:"avgMs"`
+	P95Ms            float64  `json:"p95Ms"`
+	MaxMs            float64  `json:"maxMs"`
+	PercentOfFrame   float64  `json:"percentOfFrame"`
+	FramesPresent    int      `json:"framesPresent"`
+	Threads          []string `json:"threads,omitempty"`
+	MatchedFunctions []string `json:"matchedFunctions,omitempty"`
+	SpikeGrowthMs    float64  `json:"spikeGrowthMs"` // Average above its median in the slowest 10% of frames
+}
+
+// TickFrame is one frame split by tick
+type TickFrame struct {
+	FrameNumber  int                `json:"frame"`
+	FrameTimeMs  float64            `json:"frameTimeMs"`
+	Ticks        map[string]float64 `json:"ticks"`
+	UnanchoredMs float64            `json:"unanchoredMainMs"`
+}
+
+// loadProjectTicks reads the ticks declared for a project: [{"name": ..., "pattern": ...}]
+func loadProjectTicks(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read ticks: %w", err)
+	}
+	var ticks []SubsystemBudget
+	if err := json.Unmarshal(content, &ticks); err != nil {
+		return fmt.Errorf("failed to parse ticks %s: %w", path, err)
+	}
+	for i, tick := range ticks {
+		if tick.Name == "" || tick.Pattern == "" {
+			return fmt.Errorf("tick %d in %s needs a name and a pattern", i+1, path)
+		}
+	}
+	compiled, err := compileBudgets(ticks)
+	if err != nil {
+		return err
+	}
+	projectTicks = compiled
+	return nil
+}
+
+// ticksSchema describes the ticks parameter
+func ticksSchema() mcp.ToolOption {
+	return mcp.WithArray("ticks",
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":    map[string]any{"type": "string"},
+				"pattern": map[string]any{"type": "string"},
+			},
+			"required": []string{"name", "pattern"},
+		}),
+		mcp.Description("The engine's top-level tick functions in report order, e.g. [{\"name\": \"World\", \"pattern\": \"World::Tick\"}, {\"name\": \"Render\", \"pattern\": \"RenderFrame\"}]. Patterns use * and ? wildcards or a regular expression, case-insensitive (default: the FRAMEPRO_TICKS project file)"))
+}
+
+// parseTicks reads the ticks argument, or takes the project's declared ticks
+func parseTicks(args map[string]interface{}) ([]SubsystemBudget, error) {
+	raw, ok := args["ticks"].([]interface{})
+	if !ok || len(raw) == 0 {
+		if len(projectTicks) == 0 {
+			return nil, fmt.Errorf("no ticks declared: pass ticks ([{\"name\": \"World\", \"pattern\": \"World::Tick\"}, ...]) or point FRAMEPRO_TICKS at a JSON file with them")
+		}
+		return projectTicks, nil
+	}
+	ticks := []SubsystemBudget{}
+	for i, entry := range raw {
+		spec, _ := entry.(map[string]interface{})
+		name, _ := spec["name"].(string)
+		pattern, _ := spec["pattern"].(string)
+		if name == "" || pattern == "" {
+			return nil, fmt.Errorf("tick %d needs a name and a pattern", i+1)
+		}
+		ticks = append(ticks, SubsystemBudget{Name: name, Pattern: pattern})
+	}
+	return compileBudgets(ticks)
+}
+
+// tickScopes adds the time of the outermost scopes matching each tick to times, by tick and
+// thread, and records the matched function names
+func tickScopes(functions []FrameProFunction, ticks []SubsystemBudget, times []map[int]float64, matched []map[string]bool) {
+	for _, fn := range functions {
+		if index := matchBudget(ticks, fn.FunctionName); index >= 0 {
+			times[index][fn.ThreadID] += fn.TimeMs
+			matched[index][fn.FunctionName] = true
+			continue
+		}
+		tickScopes(fn.Children, ticks, times, matched)
+	}
+}
+
+func tickDecompositionHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	ticks, err := parseTicks(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	times := frameTimes(data)
+	mainThreads := mainThreadIDs(data)
+	threadNames := make(map[int]string)
+	series := make([][]float64, len(ticks)) // [tick][frame]
+	tickThreads := make([]map[int]bool, len(ticks))
+	matched := make([]map[string]bool, len(ticks))
+	for i := range ticks {
+		series[i] = make([]float64, len(data.Frames))
+		tickThreads[i] = make(map[int]bool)
+		matched[i] = make(map[string]bool)
+	}
+	unanchored := make([]float64, len(data.Frames))
+	mainTotals := make([]float64, len(data.Frames))
+	frames := []TickFrame{}
+	for f, frame := range data.Frames {
+		byThread := make([]map[int]float64, len(ticks))
+		for i := range ticks {
+			byThread[i] = make(map[int]float64)
+		}
+		tickScopes(frame.Functions, ticks, byThread, matched)
+
+		var mainTotal, mainAnchored float64
+		for id, total := range frameThreadTotals(frame) {
+			threadNames[id] = total.ThreadName
+			if mainThreads[id] {
+				mainTotal += total.TimeMs
+			}
+		}
+		row := TickFrame{FrameNumber: frame.FrameNumber, FrameTimeMs: times[f], Ticks: make(map[string]float64)}
+		for i, tick := range ticks {
+			for id, ms := range byThread[i] {
+				series[i][f] += ms
+				tickThreads[i][id] = true
+				if mainThreads[id] {
+					mainAnchored += ms
+				}
+			}
+			row.Ticks[tick.Name] = series[i][f]
+		}
+		mainTotals[f] = mainTotal
+		unanchored[f] = max(mainTotal-mainAnchored, 0)
+		row.UnanchoredMs = unanchored[f]
+		frames = append(frames, row)
+	}
+
+	avgFrame, _ := meanStdDev(times)
+	// The slowest 10% of frames show which tick grows when the frame does
+	slowest := make([]int, len(times))
+	for i := range slowest {
+		slowest[i] = i
+	}
+	sort.SliceStable(slowest, func(a, b int) bool { return times[slowest[a]] > times[slowest[b]] })
+	slowest = slowest[:max(len(slowest)/10, 1)]
+
+	rows := []TickRow{}
+	missing := []string{}
+	spikeDriver := -1
+	for i, tick := range ticks {
+		row := TickRow{Name: tick.Name, Pattern: tick.Pattern}
+		sorted := append([]float64(nil), series[i]...)
+		sort.Float64s(sorted)
+		row.AvgMs, _ = meanStdDev(series[i])
+		row.P95Ms = percentile(sorted, 95)
+		row.MaxMs = sorted[len(sorted)-1]
+		if avgFrame > 0 {
+			row.PercentOfFrame = row.AvgMs / avgFrame * 100
+		}
+		for _, ms := range series[i] {
+			if ms > 0 {
+				row.FramesPresent++
+			}
+		}
+		if row.FramesPresent == 0 {
+			missing = append(missing, tick.Name)
+		}
+		for id := range tickThreads[i] {
+			row.Threads = append(row.Threads, threadNames[id])
+		}
+		sort.Strings(row.Threads)
+		for name := range matched[i] {
+			row.MatchedFunctions = append(row.MatchedFunctions, name)
+		}
+		sort.Strings(row.MatchedFunctions)
+		median := medianOf(series[i])
+		for _, f := range slowest {
+			row.SpikeGrowthMs += (series[i][f] - median) / float64(len(slowest))
+		}
+		if row.SpikeGrowthMs > 0 && (spikeDriver < 0 || row.SpikeGrowthMs > rows[spikeDriver].SpikeGrowthMs) {
+			spikeDriver = i
+		}
+		rows = append(rows, row)
+	}
+	avgUnanchored, _ := meanStdDev(unanchored)
+	mainAvg, _ := meanStdDev(mainTotals)
+
+	parts := []string{}
+	for _, row := range rows {
+		parts = append(parts, fmt.Sprintf("%s %.2fms", row.Name, row.AvgMs))
+	}
+	summary := fmt.Sprintf("Average %.2fms frame by tick: %s, %s %.2fms", avgFrame, strings.Join(parts, ", "), unanchoredTick, avgUnanchored)
+
+	analysis := []string{}
+	suggestions := []string{}
+	if spikeDriver >= 0 {
+		analysis = append(analysis, fmt.Sprintf("%s grows the most in the slowest 10%% of frames (+%.2fms above its median)", rows[spikeDriver].Name, rows[spikeDriver].SpikeGrowthMs))
+	}
+	if len(missing) > 0 {
+		analysis = append(analysis, fmt.Sprintf("No scope matched %s in this capture; they report 0ms so decompositions stay comparable", strings.Join(missing, ", ")))
+		suggestions = append(suggestions, "Check the patterns of ticks that match nothing against search_functions; the engine may name them differently in this build")
+	}
+	if mainAvg > 0 && avgUnanchored/mainAvg*100 > unanchoredShareWarning {
+		analysis = append(analysis, fmt.Sprintf("%.0f%% of the main thread's time is outside the declared ticks", avgUnanchored/mainAvg*100))
+		suggestions = append(suggestions, "Declare the remaining top-level main thread functions as ticks (get_call_tree shows them) so less time is unanchored")
+	}
+
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"framesAnalyzed":  len(data.Frames),
+		"avgFrameTimeMs":  avgFrame,
+		"ticks":           rows,
+		"avgUnanchoredMs": avgUnanchored,
+		"missingTicks":    missing,
+		"frames":          frames,
+		"suggestions":     suggestions,
+		"analysis":        analysis,
+		"summary":         summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ThreadTimelineStats summarizes one thread across all frames of the timeline
+type ThreadTimelineStats struct {
+	ThreadID      int     `json:"threadId"`
+	ThreadName    string  `json:"threadName"`
+	IsMainThread  bool    `json:"isMainThread"`
+	AvgTimeMs     float64 `json:"avgTimeMs"`
+	MaxTimeMs     float64 `json:"maxTimeMs"`
+	MaxFrame      int     `json:"maxFrame"`
+	FramesPresent int     `json:"framesPresent"`
+}
+
+// SpikeCulprit is a function that took notably longer than usual in a spike frame
+type SpikeCulprit struct {
+	FunctionName string  `json:"function"`
+	ThreadName   string  `json:"threadName"`
+	TimeMs       float64 `json:"timeMs"`
+	AvgTimeMs    float64 `json:"avgTimeMs"`
+	ExcessMs     float64 `json:"excessMs"`
+}
+
+func frameTimelineHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	spikeFactor := 2.0
+	if f, ok := args["spike_factor"].(float64); ok && f > 0 {
+		spikeFactor = f
+	}
+	topN := 3
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+	includeFrames, _ := args["include_frames"].(bool)
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	mainThreads := mainThreadIDs(data)
+	times := frameTimes(data)
+	medianFrameTime := medianOf(times)
+	spikeThreshold := medianFrameTime * spikeFactor
+
+	// Average cost of each function per frame, used to attribute spikes
+	avgTimes := avgFunctionTimes(data.Frames)
+
+	threadStats := make(map[int]*ThreadTimelineStats)
+	spikes := []map[string]interface{}{}
+	timeline := []map[string]interface{}{}
+
+	read := len(data.Frames)
+	for i, frame := range data.Frames {
+		if ctx.Err() != nil { // Out of time or canceled: report the frames scanned so far
+			read = i
+			break
+		}
+		totals := frameThreadTotals(frame)
+		perThread := make(map[string]float64)
+		for id, total := range totals {
+			stats, exists := threadStats[id]
+			if !exists {
+				stats = &ThreadTimelineStats{ThreadID: id, ThreadName: total.ThreadName, IsMainThread: mainThreads[id]}
+				threadStats[id] = stats
+			}
+			stats.AvgTimeMs += total.TimeMs
+			stats.FramesPresent++
+			if total.TimeMs > stats.MaxTimeMs {
+				stats.MaxTimeMs = total.TimeMs
+				stats.MaxFrame = frame.FrameNumber
+			}
+			perThread[total.ThreadName] = total.TimeMs
+		}
+
+		if includeFrames {
+			timeline = append(timeline, map[string]interface{}{
+				"frame":       frame.FrameNumber,
+				"frameTimeMs": times[i],
+				"threads":     perThread,
+			})
+		}
+
+		if times[i] > spikeThreshold {
+			spikes = append(spikes, map[string]interface{}{
+				"frame":         frame.FrameNumber,
+				"frameTimeMs":   times[i],
+				"overMedianMs":  times[i] - medianFrameTime,
+				"ratioToMedian": times[i] / (medianFrameTime + 0.001),
+				"causedBy":      spikeCulprits(frame, avgTimes, topN),
+			})
+		}
+	}
+
+	threads := make([]*ThreadTimelineStats, 0, len(threadStats))
+	for _, stats := range threadStats {
+		stats.AvgTimeMs /= float64(read)
+		threads = append(threads, stats)
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].AvgTimeMs > threads[j].AvgTimeMs
+	})
+
+	// Worst spikes first
+	sort.Slice(spikes, func(i, j int) bool {
+		return spikes[i]["frameTimeMs"].(float64) > spikes[j]["frameTimeMs"].(float64)
+	})
+
+	output := map[string]interface{}{
+		"file":              filePath,
+		"sessionName":       data.SessionName,
+		"framesAnalyzed":    read,
+		"medianFrameTimeMs": medianFrameTime,
+		"spikeThresholdMs":  spikeThreshold,
+		"spikeCount":        len(spikes),
+		"spikes":            spikes,
+		"threads":           threads,
+		"summary": fmt.Sprintf("%d of %d frames exceeded %.2fms (%.1fx the median frame time of %.2fms)",
+			len(spikes), read, spikeThreshold, spikeFactor, medianFrameTime),
+	}
+	if includeFrames {
+		output["timeline"] = timeline
+	}
+	if read < len(data.Frames) {
+		attachPartial(ctx, output, read, len(data.Frames), "frames")
+	}
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// spikeCulprits returns the functions of a frame that exceeded their usual per-frame cost the most
+func spikeCulprits(frame FrameProFrame, avgTimes map[string]float64, topN int) []SpikeCulprit {
+	culprits := []SpikeCulprit{}
+	for _, fn := range frame.Functions {
+		avg := avgTimes[functionKey(fn)]
+		excess := fn.TimeMs - avg
+		if excess <= 0 {
+			continue
+		}
+		culprits = append(culprits, SpikeCulprit{
+			FunctionName: fn.FunctionName,
+			ThreadName:   fn.ThreadName,
+			TimeMs:       fn.TimeMs,
+			AvgTimeMs:    avg,
+			ExcessMs:     excess,
+		})
+	}
+
+	sort.Slice(culprits, func(i, j int) bool {
+		return culprits[i].ExcessMs > culprits[j].ExcessMs
+	})
+	if len(culprits) > topN {
+		culprits = culprits[:topN]
+	}
+	return culprits
+}
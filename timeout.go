@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Tools that scan many captures or frames run under a timeout so a pathological input
+// can't keep the client waiting: at the deadline they stop scanning and return what they
+// have, flagged as partial. The timeout is FRAMEPRO_TOOL_TIMEOUT, a default with optional
+// per-tool values such as "2m,search_history=5m,list_captures=30s" ("0" turns it off), or
+// the call's timeout_seconds. A call the client cancels stops the same way, flagged as
+// canceled rather than timed out. Handlers are never abandoned, and tools not registered
+// with withTimeout (including every tool that writes files or sends requests) get no deadline.
+
+const defaultToolTimeout = 2 * time.Minute
+
+var (
+	toolTimeout  = defaultToolTimeout
+	toolTimeouts = make(map[string]time.Duration) // Per-tool overrides by tool name
+	timeoutTools = make(map[string]bool)          // Tools that return partial results at the deadline
+)
+
+// withTimeout adds timeout_seconds to a tool that checks its context while scanning and
+// returns a partial result when it ends
+func withTimeout(tool mcp.Tool) mcp.Tool {
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = make(map[string]any)
+	}
+	tool.InputSchema.Properties["timeout_seconds"] = map[string]any{
+		"type":        "number",
+		"description": "Seconds to scan before returning what was scanned, flagged as partial; 0 for no limit (default: FRAMEPRO_TOOL_TIMEOUT, else 120)",
+	}
+	timeoutTools[tool.Name] = true
+	return tool
+}
+
+// setToolTimeouts parses FRAMEPRO_TOOL_TIMEOUT: a default and/or tool=duration entries
+func setToolTimeouts(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, text, perTool := strings.Cut(entry, "=")
+		if !perTool {
+			text = name
+		}
+		duration, err := parseTimeout(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("invalid FRAMEPRO_TOOL_TIMEOUT entry %q: expected a duration such as 90s or 2m, optionally after a tool name and '='", entry)
+		}
+		if perTool {
+			toolTimeouts[strings.TrimSpace(name)] = duration
+		} else {
+			toolTimeout = duration
+		}
+	}
+	return nil
+}
+
+// parseTimeout reads a Go duration, or a plain number of seconds
+func parseTimeout(text string) (time.Duration, error) {
+	duration, err := time.ParseDuration(text)
+	if seconds, numErr := strconv.ParseFloat(text, 64); numErr == nil {
+		duration, err = time.Duration(seconds*float64(time.Second)), nil
+	}
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+	return duration, nil
+}
+
+// timeoutFor returns the timeout of a call, zero for none
+func timeoutFor(request mcp.CallToolRequest) time.Duration {
+	args, _ := request.Params.Arguments.(map[string]interface{})
+	if seconds, ok := args["timeout_seconds"].(float64); ok && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if timeout, ok := toolTimeouts[request.Params.Name]; ok {
+		return timeout
+	}
+	return toolTimeout
+}
+
+// timeoutMiddleware puts a deadline on the context of calls to tools registered with
+// withTimeout. The handler always runs to its end, so the deadline only shortens its scan.
+func timeoutMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		timeout := timeoutFor(request)
+		if !timeoutTools[request.Params.Name] || timeout <= 0 {
+			return next(ctx, request)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return next(ctx, request)
+	}
+}
+
+// Causes of a partial result
+const (
+	partialTimeout  = "timeout"
+	partialCanceled = "canceled"
+)
+
+// attachPartial flags a result cut short by the timeout, or by the client canceling the
+// call, covering done of total items
+func attachPartial(ctx context.Context, output map[string]interface{}, done, total int, items string) {
+	output["partial"] = true
+	if errors.Is(ctx.Err(), context.Canceled) {
+		output["partialCause"] = partialCanceled
+		output["partialReason"] = fmt.Sprintf("The call was canceled after %d of %d %s; the result covers those only", done, total, items)
+	} else {
+		output["partialCause"] = partialTimeout
+		output["partialReason"] = fmt.Sprintf("The tool timed out after %d of %d %s; the result covers those only. Narrow the input or raise timeout_seconds for the rest", done, total, items)
+	}
+	if summary, ok := output["summary"].(string); ok {
+		output["summary"] = fmt.Sprintf("Partial result (%d of %d %s): %s", done, total, items, summary)
+	}
+}
+
+// timeoutToolNames lists the tools that honor the timeout, for capabilities
+func timeoutToolNames() []string {
+	names := make([]string, 0, len(timeoutTools))
+	for name := range timeoutTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// timeoutStrings formats per-tool timeouts for capabilities
+func timeoutStrings(timeouts map[string]time.Duration) map[string]string {
+	formatted := make(map[string]string)
+	for name, timeout := range timeouts {
+		formatted[name] = timeout.String()
+	}
+	return formatted
+}
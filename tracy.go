@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Binary Tracy captures (.tracy) are not decoded (see decodeCapture); zones are read
+// from tracy-csvexport output instead, which comes in two shapes:
+//
+//	statistics (default)  - one row per zone: name, src_file, src_line, total_ns, counts,
+//	                        mean_ns, min_ns, max_ns, ... aggregated over the capture
+//	unwrapped (-u)        - one row per zone: name, src_file, src_line, ns_since_start,
+//	                        exec_time_ns, thread; zones nest by time per thread
+//
+// Frame marks are not exported, so unwrapped zones are cut into frames at zones named like
+// frame markers (Frame, BeginFrame, ...), as in Chrome traces; without one, and for
+// statistics, the capture loads as a single frame.
+
+// tracyMagics are the first bytes of .tracy captures: LZ4, Zstd and the newer file header
+var tracyMagics = [][]byte{{'t', 'l', 'Z', 4}, {'t', 'Z', 's', 't'}, {'t', 'r', 0xfd, 'P'}}
+
+// isTracyCapture reports whether content is a binary Tracy capture
+func isTracyCapture(path string, content []byte) bool {
+	if strings.EqualFold(filepath.Ext(path), ".tracy") {
+		return true
+	}
+	for _, magic := range tracyMagics {
+		if bytes.HasPrefix(content, magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTracyCSV reports whether content is tracy-csvexport output
+func isTracyCSV(path string, content []byte) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".csv") {
+		return false
+	}
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	return bytes.HasPrefix(bytes.TrimPrefix(line, []byte("\xef\xbb\xbf")), []byte("name,src_file,src_line,"))
+}
+
+// decodeTracyCSV converts tracy-csvexport output into the data model
+func decodeTracyCSV(path string, content []byte) (*FrameProData, error) {
+	rows, err := readCSV(path, content)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	cell := func(row []string, name string) string {
+		if i, found := columns[name]; found && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	number := func(row []string, name string) float64 {
+		value, _ := strconv.ParseFloat(cell(row, name), 64)
+		return value
+	}
+
+	if _, unwrapped := columns["exec_time_ns"]; unwrapped {
+		threadIDs := make(map[string]int)
+		threadNames := make(map[int]string)
+		spans := make(map[int][]*traceSpan)
+		markers := make(map[int][]float64)
+		for _, row := range rows[1:] {
+			name := cell(row, "name")
+			if name == "" {
+				continue
+			}
+			key := cell(row, "thread")
+			thread, exists := threadIDs[key]
+			if !exists {
+				thread = len(threadIDs) + 1
+				threadIDs[key] = thread
+				threadNames[thread] = key
+				if _, err := strconv.ParseUint(key, 10, 64); err == nil || key == "" {
+					threadNames[thread] = "Thread " + key
+				}
+			}
+			start := number(row, "ns_since_start") / 1000 // Nanoseconds to microseconds
+			span := &traceSpan{name: name, thread: thread, start: start, end: start + number(row, "exec_time_ns")/1000}
+			spans[thread] = append(spans[thread], span)
+			if isTraceFrameMarker(name) {
+				markers[thread] = append(markers[thread], span.start)
+			}
+		}
+		if len(spans) == 0 {
+			return nil, fmt.Errorf("%s has no zones", filepath.Base(path))
+		}
+		return buildTraceCapture(path, spans, threadNames, markers), nil
+	}
+
+	if _, found := columns["total_ns"]; !found {
+		return nil, fmt.Errorf("%s has neither total_ns (statistics) nor exec_time_ns (unwrapped zones) columns", filepath.Base(path))
+	}
+	// Statistics have no threads or frames: every zone is on one thread in one frame
+	data := &FrameProData{SessionName: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), TotalFrames: 1}
+	for _, row := range rows[1:] {
+		name := cell(row, "name")
+		if name == "" {
+			continue
+		}
+		totalMs := number(row, "total_ns") / 1e6
+		count := int(number(row, "counts"))
+		data.Functions = append(data.Functions, FrameProFunction{
+			FunctionName:      name,
+			ThreadID:          1,
+			ThreadName:        "Tracy",
+			TotalTimeMs:       totalMs,
+			TotalCount:        count,
+			AvgTimePerFrameMs: totalMs,
+			AvgCountPerFrame:  float64(count),
+			MaxTimePerFrameMs: totalMs,
+			MaxCountPerFrame:  count,
+			IsMainThread:      true,
+		})
+	}
+	if len(data.Functions) == 0 {
+		return nil, fmt.Errorf("%s has no zones", filepath.Base(path))
+	}
+	data.TotalFunctions = len(data.Functions)
+	return data, nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Trends within a session average frame time and per-function time over windows of
+// window_frames frames, sliding by half a window, and fit a line through the window
+// averages. A cost that changes by more than min_growth_percent from the first window to
+// the last along a steady line (correlation of at least minTrendCorrelation) is growing
+// or shrinking; one that changes as much without a steady line is erratic.
+
+// Trend classes
+const (
+	trendGrowing   = "growing"
+	trendShrinking = "shrinking"
+	trendStable    = "stable"
+	trendErratic   = "erratic"
+)
+
+const minTrendCorrelation = 0.7
+
+// TrendWindow is the moving average over one window of frames
+type TrendWindow struct {
+	StartFrame int     `json:"startFrame"`
+	EndFrame   int     `json:"endFrame"`
+	AvgMs      float64 `json:"avgMs"`
+}
+
+// CostTrend is the fitted trend of a frame time or function cost over the session
+type CostTrend struct {
+	Metric           string    `json:"metric,omitempty"` // Derived metric, see metrics.go
+	FunctionName     string    `json:"functionName,omitempty"`
+	ThreadName       string    `json:"threadName,omitempty"`
+	AvgMs            float64   `json:"avgMs"`
+	FirstWindowMs    float64   `json:"firstWindowMs"` // Fitted value at the first window
+	LastWindowMs     float64   `json:"lastWindowMs"`  // Fitted value at the last window
+	MsPer1000Frames  float64   `json:"msPer1000Frames"`
+	GrowthPercent    float64   `json:"growthPercent"`
+	Correlation      float64   `json:"correlation"`
+	Trend            string    `json:"trend"`
+	WindowAveragesMs []float64 `json:"windowAveragesMs,omitempty"`
+}
+
+// fitTrend fits a line through window averages and classifies it
+func fitTrend(windowStarts, averages []float64, minGrowthPercent float64) CostTrend {
+	slope, intercept, r := linearFit(windowStarts, averages)
+	mean, _ := meanStdDev(averages)
+	trend := CostTrend{
+		AvgMs:           mean,
+		FirstWindowMs:   intercept + slope*windowStarts[0],
+		LastWindowMs:    intercept + slope*windowStarts[len(windowStarts)-1],
+		MsPer1000Frames: slope * 1000,
+		Correlation:     r,
+		Trend:           trendStable,
+	}
+	if mean > 0 {
+		trend.GrowthPercent = (trend.LastWindowMs - trend.FirstWindowMs) / mean * 100
+	}
+	if math.Abs(trend.GrowthPercent) >= minGrowthPercent {
+		switch {
+		case r >= minTrendCorrelation:
+			trend.Trend = trendGrowing
+		case r <= -minTrendCorrelation:
+			trend.Trend = trendShrinking
+		default:
+			trend.Trend = trendErratic
+		}
+	}
+	return trend
+}
+
+func sessionTrendsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	windowFrames := 60
+	if n, ok := args["window_frames"].(float64); ok && n >= 2 {
+		windowFrames = int(n)
+	}
+	minGrowth := 20.0
+	if p, ok := args["min_growth_percent"].(float64); ok && p > 0 {
+		minGrowth = p
+	}
+	minAvgMs := 0.05
+	if m, ok := args["min_avg_ms"].(float64); ok && m >= 0 {
+		minAvgMs = m
+	}
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	metrics, err := parseMetrics(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+	step := max(windowFrames/2, 1)
+	if len(data.Frames) < windowFrames+2*step {
+		return mcp.NewToolResultError(fmt.Sprintf("Trends need at least 3 windows: %d frames is too few for window_frames %d", len(data.Frames), windowFrames)), nil
+	}
+
+	// Moving averages of frame time and of every function, one entry per window
+	times := frameTimes(data)
+	frameWindows := []TrendWindow{}
+	starts := []float64{}
+	frameAverages := []float64{}
+	functionAverages := make(map[string][]float64)
+	threadNames := make(map[string]string)
+	for start := 0; start+windowFrames <= len(data.Frames); start += step {
+		frames := data.Frames[start : start+windowFrames]
+		avg, _ := meanStdDev(times[start : start+windowFrames])
+		frameWindows = append(frameWindows, TrendWindow{StartFrame: frames[0].FrameNumber, EndFrame: frames[len(frames)-1].FrameNumber, AvgMs: avg})
+		starts = append(starts, float64(frames[0].FrameNumber))
+		frameAverages = append(frameAverages, avg)
+
+		index := len(starts) - 1
+		for _, frame := range frames {
+			for _, fn := range frame.Functions {
+				key := functionKey(fn)
+				series, exists := functionAverages[key]
+				if !exists {
+					threadNames[key] = fn.ThreadName
+				}
+				for len(series) <= index {
+					series = append(series, 0)
+				}
+				series[index] += fn.TimeMs / float64(windowFrames)
+				functionAverages[key] = series
+			}
+		}
+	}
+
+	frameTrend := fitTrend(starts, frameAverages, minGrowth)
+
+	// Derived metrics trend over the same windows; their values need not be milliseconds
+	metricTrends := []CostTrend{}
+	metricValues, _ := evaluateMetrics(data, metrics)
+	for i, metric := range metrics {
+		averages := []float64{}
+		for start := 0; start+windowFrames <= len(data.Frames); start += step {
+			avg, _ := meanStdDev(metricValues[i][start : start+windowFrames])
+			averages = append(averages, avg)
+		}
+		trend := fitTrend(starts, averages, minGrowth)
+		trend.Metric = metric.Name
+		if trend.Trend == trendGrowing || trend.Trend == trendShrinking {
+			trend.WindowAveragesMs = averages
+		}
+		metricTrends = append(metricTrends, trend)
+	}
+	groups := map[string][]CostTrend{}
+	for key, series := range functionAverages {
+		for len(series) < len(starts) {
+			series = append(series, 0) // Not recorded in the last windows
+		}
+		trend := fitTrend(starts, series, minGrowth)
+		if trend.AvgMs < minAvgMs {
+			continue
+		}
+		trend.FunctionName, trend.ThreadName = functionNameFromKey(key), threadNames[key]
+		if trend.Trend == trendGrowing || trend.Trend == trendShrinking {
+			trend.WindowAveragesMs = series
+		}
+		groups[trend.Trend] = append(groups[trend.Trend], trend)
+	}
+
+	// Growing and shrinking by the size of the change, stable and erratic by cost
+	for class, trends := range groups {
+		byChange := class == trendGrowing || class == trendShrinking
+		sort.Slice(trends, func(i, j int) bool {
+			if byChange {
+				return math.Abs(trends[i].LastWindowMs-trends[i].FirstWindowMs) > math.Abs(trends[j].LastWindowMs-trends[j].FirstWindowMs)
+			}
+			return trends[i].AvgMs > trends[j].AvgMs
+		})
+	}
+	listed := func(class string) []CostTrend {
+		trends := groups[class]
+		if trends == nil {
+			return []CostTrend{}
+		}
+		if len(trends) > topN {
+			return trends[:topN]
+		}
+		return trends
+	}
+
+	analysis := []string{
+		fmt.Sprintf("Frame time is %s: %.2fms at the start, %.2fms at the end (%+.2fms per 1000 frames)",
+			frameTrend.Trend, frameTrend.FirstWindowMs, frameTrend.LastWindowMs, frameTrend.MsPer1000Frames),
+	}
+	for _, trend := range listed(trendGrowing) {
+		analysis = append(analysis, fmt.Sprintf("%s on %s grows steadily from %.2fms to %.2fms per frame (%+.0f%%, r=%.2f)",
+			trend.FunctionName, trend.ThreadName, trend.FirstWindowMs, trend.LastWindowMs, trend.GrowthPercent, trend.Correlation))
+	}
+
+	for _, trend := range metricTrends {
+		if trend.Trend == trendGrowing || trend.Trend == trendShrinking {
+			analysis = append(analysis, fmt.Sprintf("Metric %s is %s from %.3g to %.3g (%+.0f%%, r=%.2f)",
+				trend.Metric, trend.Trend, trend.FirstWindowMs, trend.LastWindowMs, trend.GrowthPercent, trend.Correlation))
+		}
+	}
+
+	summary := fmt.Sprintf("No function cost grows steadily over the session (%d stable, %d erratic)",
+		len(groups[trendStable]), len(groups[trendErratic]))
+	if growing := groups[trendGrowing]; len(growing) > 0 {
+		summary = fmt.Sprintf("%d functions grow steadily over the session; the largest is %s, from %.2fms to %.2fms per frame",
+			len(growing), growing[0].FunctionName, growing[0].FirstWindowMs, growing[0].LastWindowMs)
+	}
+
+	output := map[string]interface{}{
+		"file":             filePath,
+		"sessionName":      data.SessionName,
+		"framesAnalyzed":   len(data.Frames),
+		"windowFrames":     windowFrames,
+		"windowStep":       step,
+		"frameTimeTrend":   frameTrend,
+		"frameTimeWindows": frameWindows,
+		"growing":          listed(trendGrowing),
+		"shrinking":        listed(trendShrinking),
+		"erratic":          listed(trendErratic),
+		"stable":           listed(trendStable),
+		"counts": map[string]int{
+			trendGrowing:   len(groups[trendGrowing]),
+			trendShrinking: len(groups[trendShrinking]),
+			trendErratic:   len(groups[trendErratic]),
+			trendStable:    len(groups[trendStable]),
+		},
+		"analysis": analysis,
+		"summary":  summary,
+	}
+	if len(metrics) > 0 {
+		output["metricTrends"] = metricTrends
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
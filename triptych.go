@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// A frame triptych puts the best, median and worst frames of a capture side by side.
+// Functions are ranked by how much more they cost in the worst frame than in the best,
+// which shows where the variance comes from.
+
+// TriptychFrame is one of the three selected frames
+type TriptychFrame struct {
+	Frame       int                `json:"frame"`
+	FrameTimeMs float64            `json:"frameTimeMs"`
+	Threads     map[string]float64 `json:"threads"`
+}
+
+// TriptychRow is a function's time in each of the three frames
+type TriptychRow struct {
+	FunctionName     string  `json:"functionName"`
+	ThreadName       string  `json:"threadName"`
+	BestMs           float64 `json:"bestMs"`
+	MedianMs         float64 `json:"medianMs"`
+	WorstMs          float64 `json:"worstMs"`
+	WorstMinusBestMs float64 `json:"worstMinusBestMs"`
+	ShareOfSpread    float64 `json:"percentOfSpread"` // Of the frame time difference between worst and best
+}
+
+// triptychFrame describes a frame by its thread totals
+func triptychFrame(frame FrameProFrame, frameMs float64) TriptychFrame {
+	threads := make(map[string]float64)
+	for _, total := range frameThreadTotals(frame) {
+		threads[total.ThreadName] += total.TimeMs
+	}
+	return TriptychFrame{Frame: frame.FrameNumber, FrameTimeMs: frameMs, Threads: threads}
+}
+
+func frameTriptychHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	// Frames ordered by frame time; the first fastest, median and last slowest are picked
+	times := frameTimes(data)
+	order := make([]int, len(times))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return times[order[i]] < times[order[j]]
+	})
+	best, median, worst := order[0], order[len(order)/2], order[len(order)-1]
+
+	rows := make(map[string]*TriptychRow)
+	row := func(fn FrameProFunction) *TriptychRow {
+		key := functionKey(fn)
+		if rows[key] == nil {
+			rows[key] = &TriptychRow{FunctionName: fn.FunctionName, ThreadName: fn.ThreadName}
+		}
+		return rows[key]
+	}
+	for _, fn := range data.Frames[best].Functions {
+		row(fn).BestMs += fn.TimeMs
+	}
+	for _, fn := range data.Frames[median].Functions {
+		row(fn).MedianMs += fn.TimeMs
+	}
+	for _, fn := range data.Frames[worst].Functions {
+		row(fn).WorstMs += fn.TimeMs
+	}
+
+	spreadMs := times[worst] - times[best]
+	functions := []TriptychRow{}
+	for _, r := range rows {
+		r.WorstMinusBestMs = r.WorstMs - r.BestMs
+		if spreadMs > 0 {
+			r.ShareOfSpread = r.WorstMinusBestMs / spreadMs * 100
+		}
+		functions = append(functions, *r)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].WorstMinusBestMs > functions[j].WorstMinusBestMs
+	})
+	if len(functions) > topN {
+		functions = functions[:topN]
+	}
+
+	frames := map[string]TriptychFrame{
+		"best":   triptychFrame(data.Frames[best], times[best]),
+		"median": triptychFrame(data.Frames[median], times[median]),
+		"worst":  triptychFrame(data.Frames[worst], times[worst]),
+	}
+
+	analysis := []string{
+		fmt.Sprintf("Best frame %d took %.2fms, median frame %d %.2fms and worst frame %d %.2fms",
+			data.Frames[best].FrameNumber, times[best], data.Frames[median].FrameNumber, times[median], data.Frames[worst].FrameNumber, times[worst]),
+	}
+	for i, fn := range functions {
+		if i == 3 || fn.WorstMinusBestMs <= 0 {
+			break
+		}
+		analysis = append(analysis, fmt.Sprintf("%s on %s: %.2fms best, %.2fms median, %.2fms worst (%.0f%% of the spread)",
+			fn.FunctionName, fn.ThreadName, fn.BestMs, fn.MedianMs, fn.WorstMs, fn.ShareOfSpread))
+	}
+
+	summary := fmt.Sprintf("All %d frames take %.2fms; there is no variance to explain", len(times), times[best])
+	if spreadMs > 0 {
+		summary = fmt.Sprintf("The worst frame is %.2fms slower than the best (%.1fx)", spreadMs, times[worst]/max(times[best], 0.001))
+		if len(functions) > 0 && functions[0].WorstMinusBestMs > 0 {
+			summary += fmt.Sprintf("; %s accounts for %.0f%% of the difference", functions[0].FunctionName, functions[0].ShareOfSpread)
+		}
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"framesAnalyzed": len(times),
+		"spreadMs":       spreadMs,
+		"frames":         frames,
+		"functions":      functions,
+		"analysis":       analysis,
+		"summary":        summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Unity profiler captures are saved as binary .data files, which are not decoded (see
+// decodeCapture). They are read from a JSON export instead, written by an editor script
+// walking ProfilerDriver's frame data views:
+//
+//	{"unityVersion": "2022.3.10f1", "frames": [{"frameIndex": 120, "frameStartTimeNs": ...,
+//	  "threads": [{"threadName": "Main Thread", "threadGroupName": "",
+//	    "samples": [{"name": "PlayerLoop", "startTimeNs": ..., "timeMs": 14.2, "childrenCount": 3}, ...]}]}]}
+//
+// Samples come depth-first with childrenCount, as RawFrameDataView lists them, or nested in
+// children. Every Unity frame is one frame: PlayerLoop (EditorLoop in the editor) is the main
+// thread's root scope, "Main Thread" is the main thread, "Render Thread" the render thread and
+// job system threads (Job group, Worker N) are worker threads.
+
+// UnityProfile is a Unity profiler JSON export
+type UnityProfile struct {
+	UnityVersion string       `json:"unityVersion"`
+	Frames       []UnityFrame `json:"frames"`
+}
+
+// UnityFrame is one profiler frame with its threads
+type UnityFrame struct {
+	FrameIndex  int           `json:"frameIndex"`
+	StartTimeNs *float64      `json:"frameStartTimeNs,omitempty"`
+	Threads     []UnityThread `json:"threads"`
+}
+
+// UnityThread is one thread's samples in a frame
+type UnityThread struct {
+	ThreadName      string        `json:"threadName"`
+	ThreadGroupName string        `json:"threadGroupName,omitempty"`
+	Samples         []UnitySample `json:"samples"`
+}
+
+// UnitySample is a profiler marker sample
+type UnitySample struct {
+	Name          string        `json:"name"`
+	StartTimeNs   *float64      `json:"startTimeNs,omitempty"`
+	TimeMs        float64       `json:"timeMs"`
+	CallCount     int           `json:"callCount,omitempty"` // Samples merged into this one (hierarchy views)
+	ChildrenCount *int          `json:"childrenCount,omitempty"`
+	Children      []UnitySample `json:"children,omitempty"`
+}
+
+// isUnityData reports whether path is a binary Unity profiler capture
+func isUnityData(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".data")
+}
+
+// isUnityProfile reports whether trimmed is a Unity profiler JSON export
+func isUnityProfile(trimmed []byte) bool {
+	return len(trimmed) > 0 && trimmed[0] == '{' && bytes.Contains(trimmed, []byte(`"threadName"`)) &&
+		bytes.Contains(trimmed, []byte(`"samples"`)) && bytes.Contains(trimmed, []byte(`"frames"`))
+}
+
+// nestUnitySamples turns depth-first samples with children counts into trees; samples
+// without counts keep their nested children
+func nestUnitySamples(samples []UnitySample) []UnitySample {
+	var take func(count int) []UnitySample
+	take = func(count int) []UnitySample {
+		nested := []UnitySample{}
+		for ; count != 0 && len(samples) > 0; count-- {
+			sample := samples[0]
+			samples = samples[1:]
+			if sample.ChildrenCount != nil {
+				sample.Children = take(*sample.ChildrenCount)
+			}
+			nested = append(nested, sample)
+		}
+		return nested
+	}
+	return take(-1)
+}
+
+// decodeUnityProfile converts a Unity profiler JSON export into hierarchical per-frame functions
+func decodeUnityProfile(path string, content []byte) (*FrameProData, error) {
+	var profile UnityProfile
+	if err := json.Unmarshal(content, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse Unity profiler export: %w", err)
+	}
+	if len(profile.Frames) == 0 {
+		return nil, fmt.Errorf("%s has no frames", filepath.Base(path))
+	}
+
+	threadIDs := make(map[string]int)
+	flags := func(fn *FrameProFunction, group string) {
+		name := strings.ToLower(fn.ThreadName)
+		fn.IsMainThread = name == "main thread"
+		fn.IsRenderThread = name == "render thread"
+		fn.IsWorkerThread = !fn.IsMainThread && !fn.IsRenderThread &&
+			(strings.EqualFold(group, "job") || isWorkerThreadName(fn.ThreadName))
+	}
+	var addSample func(into *[]FrameProFunction, sample UnitySample, threadID int, thread UnityThread, frameStart *float64)
+	addSample = func(into *[]FrameProFunction, sample UnitySample, threadID int, thread UnityThread, frameStart *float64) {
+		index := -1
+		for i, fn := range *into {
+			if fn.FunctionName == sample.Name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			fn := FrameProFunction{FunctionName: sample.Name, ThreadID: threadID, ThreadName: thread.ThreadName}
+			if sample.StartTimeNs != nil && frameStart != nil {
+				startMs := (*sample.StartTimeNs - *frameStart) / 1e6
+				fn.StartMs = &startMs
+			}
+			flags(&fn, thread.ThreadGroupName)
+			*into = append(*into, fn)
+			index = len(*into) - 1
+		}
+		fn := &(*into)[index]
+		fn.TimeMs += sample.TimeMs
+		fn.Count += max(sample.CallCount, 1)
+		for _, child := range sample.Children {
+			addSample(&fn.Children, child, threadID, thread, frameStart)
+		}
+	}
+
+	data := &FrameProData{SessionName: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	for _, unityFrame := range profile.Frames {
+		frame := FrameProFrame{FrameNumber: unityFrame.FrameIndex}
+		frameStart := unityFrame.StartTimeNs
+		if frameStart == nil {
+			// Without a frame start, times are relative to the earliest sample
+			for _, thread := range unityFrame.Threads {
+				for _, sample := range thread.Samples {
+					if sample.StartTimeNs != nil && (frameStart == nil || *sample.StartTimeNs < *frameStart) {
+						frameStart = sample.StartTimeNs
+					}
+				}
+			}
+		}
+		for _, thread := range unityFrame.Threads {
+			key := thread.ThreadGroupName + "/" + thread.ThreadName
+			id, exists := threadIDs[key]
+			if !exists {
+				id = len(threadIDs) + 1
+				threadIDs[key] = id
+			}
+			for _, root := range nestUnitySamples(thread.Samples) {
+				// RawFrameDataView roots every thread in a sample named after the thread
+				if root.Name == thread.ThreadName {
+					for _, child := range root.Children {
+						addSample(&frame.Functions, child, id, thread, frameStart)
+					}
+					continue
+				}
+				addSample(&frame.Functions, root, id, thread, frameStart)
+			}
+		}
+		for _, fn := range frame.Functions {
+			addFrameScope(&data.Functions, fn)
+		}
+		data.Frames = append(data.Frames, frame)
+	}
+	data.TotalFrames = len(data.Frames)
+	averageScopes(data.Functions, float64(len(data.Frames)))
+	data.TotalFunctions = len(data.Functions)
+	return data, nil
+}
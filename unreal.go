@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Unreal Engine captures come as CSV in two shapes:
+//
+//	CSV profiler (csvprofile, *.csv)  - one row per frame with FrameTime, <Thread>Time columns
+//	                                    and stat columns such as Exclusive/GameThread/Physics
+//	                                    or GPU/BasePass, all in milliseconds
+//	Timing Insights timing events     - one row per scope exported from a utrace, with
+//	                                    ThreadId, StartTime, EndTime (seconds) and a timer
+//	                                    name; scopes nest by time and frames are cut at
+//	                                    FEngineLoop::Tick like Chrome trace frame markers
+//
+// Both map GameThread to the main thread and RenderThread/RHIThread to render threads.
+// Utrace files converted to Chrome trace JSON load through the Chrome trace import.
+
+// isUnrealCSV reports whether content is a CSV profiler capture or a timing events export
+func isUnrealCSV(path string, content []byte) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".csv") && !strings.EqualFold(filepath.Ext(path), ".tsv") {
+		return false
+	}
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	header := string(line)
+	return strings.Contains(header, "FrameTime") || strings.Contains(header, "StartTime") && strings.Contains(header, "EndTime")
+}
+
+// readCSV reads the rows of a comma- or tab-separated export (by extension)
+func readCSV(path string, content []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("%s has no data rows", filepath.Base(path))
+	}
+	for i := range rows[0] {
+		rows[0][i] = strings.TrimSpace(strings.TrimPrefix(rows[0][i], "\ufeff"))
+	}
+	return rows, nil
+}
+
+// decodeUnrealCSV converts an Unreal CSV export into the data model
+func decodeUnrealCSV(path string, content []byte) (*FrameProData, error) {
+	rows, err := readCSV(path, content)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	if _, found := columns["FrameTime"]; found {
+		return decodeUnrealCSVProfile(path, rows)
+	}
+	return decodeUnrealTimingEvents(path, rows, columns)
+}
+
+// unrealThreadFlags sets the thread flags from Unreal's thread names
+func unrealThreadFlags(fn *FrameProFunction) {
+	name := strings.ToLower(fn.ThreadName)
+	fn.IsMainThread = strings.Contains(name, "gamethread") || strings.Contains(name, "game thread")
+	fn.IsRenderThread = !fn.IsMainThread && fn.GPUQueue == "" && (strings.Contains(name, "render") || strings.Contains(name, "rhi"))
+	fn.IsWorkerThread = !fn.IsMainThread && !fn.IsRenderThread && fn.GPUQueue == "" &&
+		(isWorkerThreadName(fn.ThreadName) || strings.Contains(name, "taskgraph") || strings.Contains(name, "foreground"))
+}
+
+// decodeUnrealCSVProfile maps CSV profiler stat columns to per-frame scopes
+func decodeUnrealCSVProfile(path string, rows [][]string) (*FrameProData, error) {
+	header := rows[0]
+	type stat struct {
+		column         int
+		thread, name   string
+		threadTotalCol bool // <Thread>Time: the thread's whole time, covering what stats miss
+	}
+	stats := []stat{}
+	for i, name := range header {
+		switch {
+		case strings.HasPrefix(name, "Exclusive/"):
+			if parts := strings.SplitN(strings.TrimPrefix(name, "Exclusive/"), "/", 2); len(parts) == 2 {
+				stats = append(stats, stat{column: i, thread: parts[0], name: parts[1]})
+			}
+		case strings.HasPrefix(name, "GPU/"):
+			stats = append(stats, stat{column: i, thread: "GPU", name: strings.TrimPrefix(name, "GPU/")})
+		case name == "GPUTime":
+			stats = append(stats, stat{column: i, thread: "GPU", threadTotalCol: true})
+		case strings.HasSuffix(name, "ThreadTime"):
+			stats = append(stats, stat{column: i, thread: strings.TrimSuffix(name, "Time"), threadTotalCol: true})
+		}
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("%s has no thread time (GameThreadTime), Exclusive/ or GPU/ stat columns", filepath.Base(path))
+	}
+
+	threadIDs := make(map[string]int)
+	threadOf := func(name string) int {
+		id, exists := threadIDs[name]
+		if !exists {
+			id = len(threadIDs) + 1
+			threadIDs[name] = id
+		}
+		return id
+	}
+	data := &FrameProData{SessionName: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	for _, row := range rows[1:] {
+		// Metadata rows ([HasHeaderRowAtEnd], [Platform], ...) and a repeated header end the frames
+		if len(row) == 0 || strings.HasPrefix(row[0], "[") || row[0] == header[0] {
+			break
+		}
+		frame := FrameProFrame{FrameNumber: len(data.Frames)}
+		covered := make(map[string]float64)
+		totals := make(map[string]float64)
+		for _, s := range stats {
+			if s.column >= len(row) {
+				continue
+			}
+			ms, err := strconv.ParseFloat(strings.TrimSpace(row[s.column]), 64)
+			if err != nil || ms <= 0 {
+				continue
+			}
+			if s.threadTotalCol {
+				totals[s.thread] = ms
+				continue
+			}
+			covered[s.thread] += ms
+			frame.Functions = append(frame.Functions, unrealScope(s.name, s.thread, threadOf(s.thread), ms))
+		}
+		// Thread time the stats don't cover becomes one scope, so thread totals match
+		for _, s := range stats {
+			if s.threadTotalCol && totals[s.thread] > covered[s.thread]+0.01 {
+				name := "Untracked"
+				if covered[s.thread] == 0 {
+					name = s.thread
+				}
+				frame.Functions = append(frame.Functions, unrealScope(name, s.thread, threadOf(s.thread), totals[s.thread]-covered[s.thread]))
+			}
+		}
+		data.Frames = append(data.Frames, frame)
+	}
+	if len(data.Frames) == 0 {
+		return nil, fmt.Errorf("%s has no frame rows", filepath.Base(path))
+	}
+	data.TotalFrames = len(data.Frames)
+	data.Functions = functionsFromFrames(nil, data.Frames)
+	data.TotalFunctions = len(data.Functions)
+	return data, nil
+}
+
+// unrealScope builds one per-frame scope of a CSV profiler stat
+func unrealScope(name, thread string, threadID int, ms float64) FrameProFunction {
+	fn := FrameProFunction{FunctionName: name, ThreadID: threadID, ThreadName: thread, TimeMs: ms, Count: 1}
+	if thread == "GPU" {
+		fn.GPUQueue = gpuQueueGraphics
+	}
+	unrealThreadFlags(&fn)
+	return fn
+}
+
+// decodeUnrealTimingEvents nests exported timing events and cuts frames at FEngineLoop::Tick
+func decodeUnrealTimingEvents(path string, rows [][]string, columns map[string]int) (*FrameProData, error) {
+	column := func(names ...string) int {
+		for _, name := range names {
+			if i, found := columns[name]; found {
+				return i
+			}
+		}
+		return -1
+	}
+	threadCol, startCol, endCol := column("ThreadId"), column("StartTime"), column("EndTime")
+	nameCol, timerCol, threadNameCol := column("TimerName", "Name"), column("TimerId"), column("ThreadName")
+	if threadCol < 0 || startCol < 0 || endCol < 0 || nameCol < 0 && timerCol < 0 {
+		return nil, fmt.Errorf("%s needs ThreadId, StartTime, EndTime and TimerName (or TimerId) columns; export timing events with timer names from Unreal Insights", filepath.Base(path))
+	}
+	cell := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	threadIDs := make(map[string]int)
+	threadNames := make(map[int]string)
+	spans := make(map[int][]*traceSpan)
+	markers := make(map[int][]float64)
+	for _, row := range rows[1:] {
+		start, err1 := strconv.ParseFloat(cell(row, startCol), 64)
+		end, err2 := strconv.ParseFloat(cell(row, endCol), 64)
+		if err1 != nil || err2 != nil || end < start {
+			continue
+		}
+		key := cell(row, threadCol)
+		thread, exists := threadIDs[key]
+		if !exists {
+			thread = len(threadIDs) + 1
+			threadIDs[key] = thread
+			threadNames[thread] = "Thread " + key
+		}
+		if name := cell(row, threadNameCol); name != "" {
+			threadNames[thread] = name
+		}
+		name := cell(row, nameCol)
+		if name == "" {
+			name = "Timer " + cell(row, timerCol)
+		}
+		span := &traceSpan{name: name, thread: thread, start: start * 1e6, end: end * 1e6} // Seconds to microseconds
+		spans[thread] = append(spans[thread], span)
+		if isTraceFrameMarker(name) {
+			markers[thread] = append(markers[thread], span.start)
+		}
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("%s has no timing events", filepath.Base(path))
+	}
+	return buildTraceCapture(path, spans, threadNames, markers), nil
+}
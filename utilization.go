@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// A thread's frame is split into busy time (real work), wait time (scopes named
+// like Wait/Sleep/Idle) and idle time (the rest of the frame, nothing recorded).
+
+// ThreadUtilization is the busy/wait/idle split of one thread, averaged per frame
+type ThreadUtilization struct {
+	ThreadID       int     `json:"threadId"`
+	ThreadName     string  `json:"threadName"`
+	IsMainThread   bool    `json:"isMainThread"`
+	IsRenderThread bool    `json:"isRenderThread"`
+	IsWorkerThread bool    `json:"isWorkerThread"`
+	BusyMsPerFrame float64 `json:"busyMsPerFrame"`
+	WaitMsPerFrame float64 `json:"waitMsPerFrame"`
+	IdleMsPerFrame float64 `json:"idleMsPerFrame"`
+	BusyPercent    float64 `json:"busyPercent"`
+	WaitPercent    float64 `json:"waitPercent"`
+	IdlePercent    float64 `json:"idlePercent"`
+	MaxBusyPercent float64 `json:"maxBusyPercent,omitempty"`
+	MaxBusyFrame   int     `json:"maxBusyFrame,omitempty"`
+	MostlyWaiting  bool    `json:"mostlyWaiting"`
+	TopWaitScope   string  `json:"topWaitScope,omitempty"`
+	topWaitScopeMs float64
+	busyFunctions  map[string]float64
+}
+
+// isWaitScope reports whether a scope name indicates the thread is waiting rather than working
+func isWaitScope(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range []string{"wait", "sleep", "idle", "yield"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWorkerThreadName is the name-based fallback for exports without thread flags
+func isWorkerThreadName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "worker") || strings.Contains(lower, "job") || strings.Contains(lower, "task")
+}
+
+// threadUtilization computes the busy/wait/idle split of every thread over the given frames
+func threadUtilization(data *FrameProData, frames []FrameProFrame) []*ThreadUtilization {
+	mainThreads := mainThreadIDs(data)
+	stages := threadStages(data)
+	threads := make(map[int]*ThreadUtilization)
+	for _, fn := range data.Functions {
+		if _, exists := threads[fn.ThreadID]; !exists {
+			threads[fn.ThreadID] = &ThreadUtilization{
+				ThreadID:       fn.ThreadID,
+				ThreadName:     fn.ThreadName,
+				IsMainThread:   fn.IsMainThread || mainThreads[fn.ThreadID],
+				IsRenderThread: fn.IsRenderThread,
+				IsWorkerThread: fn.IsWorkerThread || isWorkerThreadName(fn.ThreadName),
+				busyFunctions:  make(map[string]float64),
+			}
+		}
+	}
+
+	var totalFrameTime float64
+	waitScopes := make(map[int]map[string]float64)
+	for _, frame := range frames {
+		// A frame lasts at least as long as its busiest thread, so no thread exceeds 100%
+		frameTime := frameTimeMs(frame, stages)
+		for _, total := range frameThreadTotals(frame) {
+			if total.TimeMs > frameTime {
+				frameTime = total.TimeMs
+			}
+		}
+		totalFrameTime += frameTime
+
+		busy := make(map[int]float64)
+		for _, fn := range frame.Functions {
+			thread, exists := threads[fn.ThreadID]
+			if !exists {
+				thread = &ThreadUtilization{
+					ThreadID:       fn.ThreadID,
+					ThreadName:     fn.ThreadName,
+					IsMainThread:   mainThreads[fn.ThreadID],
+					IsWorkerThread: isWorkerThreadName(fn.ThreadName),
+					busyFunctions:  make(map[string]float64),
+				}
+				threads[fn.ThreadID] = thread
+			}
+			if isWaitScope(fn.FunctionName) {
+				thread.WaitMsPerFrame += fn.TimeMs
+				if waitScopes[fn.ThreadID] == nil {
+					waitScopes[fn.ThreadID] = make(map[string]float64)
+				}
+				waitScopes[fn.ThreadID][fn.FunctionName] += fn.TimeMs
+				continue
+			}
+			thread.BusyMsPerFrame += fn.TimeMs
+			thread.busyFunctions[fn.FunctionName] += fn.TimeMs
+			busy[fn.ThreadID] += fn.TimeMs
+		}
+
+		if frameTime > 0 {
+			for id, ms := range busy {
+				percent := ms / frameTime * 100
+				if percent > threads[id].MaxBusyPercent {
+					threads[id].MaxBusyPercent = percent
+					threads[id].MaxBusyFrame = frame.FrameNumber
+				}
+			}
+		}
+	}
+
+	result := make([]*ThreadUtilization, 0, len(threads))
+	for id, thread := range threads {
+		if totalFrameTime > 0 {
+			thread.BusyPercent = thread.BusyMsPerFrame / totalFrameTime * 100
+			thread.WaitPercent = thread.WaitMsPerFrame / totalFrameTime * 100
+			thread.IdlePercent = 100 - thread.BusyPercent - thread.WaitPercent
+			if thread.IdlePercent < 0 {
+				thread.IdlePercent = 0 // Nested scopes can record more time than the frame
+			}
+		}
+		n := float64(len(frames))
+		thread.BusyMsPerFrame /= n
+		thread.WaitMsPerFrame /= n
+		thread.IdleMsPerFrame = totalFrameTime / n * thread.IdlePercent / 100
+		for name := range thread.busyFunctions {
+			thread.busyFunctions[name] /= n
+		}
+		for name, ms := range waitScopes[id] {
+			if ms > thread.topWaitScopeMs {
+				thread.TopWaitScope, thread.topWaitScopeMs = name, ms
+			}
+		}
+		thread.MostlyWaiting = thread.WaitMsPerFrame > thread.BusyMsPerFrame
+		result = append(result, thread)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BusyPercent > result[j].BusyPercent
+	})
+	return result
+}
+
+// aggregateFrame builds one average frame from the aggregate scopes (the top-level ones
+// of hierarchical captures), for exports without per-frame data
+func aggregateFrame(data *FrameProData) FrameProFrame {
+	frame := FrameProFrame{FrameNumber: 0}
+	for _, fn := range scopeTree(data) {
+		fn.TimeMs = fn.AvgTimePerFrameMs
+		if fn.TimeMs == 0 && data.TotalFrames > 0 {
+			fn.TimeMs = fn.TotalTimeMs / float64(data.TotalFrames)
+		}
+		frame.Functions = append(frame.Functions, fn)
+	}
+	return frame
+}
+
+// migrationCandidates suggests moving the heaviest work of saturated main/render threads
+// to the least busy worker with enough idle time per frame to absorb it
+func migrationCandidates(threads []*ThreadUtilization, saturatedPercent float64) []map[string]interface{} {
+	workers := []*ThreadUtilization{}
+	for _, thread := range threads {
+		if thread.IsWorkerThread {
+			workers = append(workers, thread)
+		}
+	}
+	candidates := []map[string]interface{}{}
+	if len(workers) == 0 {
+		return candidates
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].BusyPercent < workers[j].BusyPercent
+	})
+	headroom := make(map[int]float64)
+	for _, worker := range workers {
+		headroom[worker.ThreadID] = worker.IdleMsPerFrame + worker.WaitMsPerFrame
+	}
+
+	for _, thread := range threads {
+		if thread.IsWorkerThread || thread.BusyPercent < saturatedPercent {
+			continue
+		}
+		type work struct {
+			name string
+			ms   float64
+		}
+		functions := []work{}
+		for name, ms := range thread.busyFunctions {
+			functions = append(functions, work{name, ms})
+		}
+		sort.Slice(functions, func(i, j int) bool {
+			return functions[i].ms > functions[j].ms
+		})
+		if len(functions) > 3 {
+			functions = functions[:3]
+		}
+
+		for _, fn := range functions {
+			for _, worker := range workers {
+				if headroom[worker.ThreadID] < fn.ms {
+					continue
+				}
+				headroom[worker.ThreadID] -= fn.ms
+				candidates = append(candidates, map[string]interface{}{
+					"function":          fn.name,
+					"fromThread":        thread.ThreadName,
+					"toThread":          worker.ThreadName,
+					"msPerFrame":        fn.ms,
+					"workerBusyPercent": worker.BusyPercent,
+					"suggestion": fmt.Sprintf("Move %s (%.2fms/frame) off %s (%.0f%% busy) to %s (%.0f%% busy)",
+						fn.name, fn.ms, thread.ThreadName, thread.BusyPercent, worker.ThreadName, worker.BusyPercent),
+				})
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+func threadUtilizationHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	saturatedPercent := 80.0
+	if p, ok := args["saturated_percent"].(float64); ok && p > 0 {
+		saturatedPercent = p
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	frames := data.Frames
+	source := "frames"
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+		source = "aggregate"
+	}
+
+	threads := threadUtilization(data, frames)
+	candidates := migrationCandidates(threads, saturatedPercent)
+
+	waiting := []string{}
+	underutilized := []string{}
+	for _, thread := range threads {
+		if thread.MostlyWaiting {
+			line := fmt.Sprintf("%s waits more than it works (%.0f%% waiting, %.0f%% busy)", thread.ThreadName, thread.WaitPercent, thread.BusyPercent)
+			if thread.TopWaitScope != "" {
+				line += fmt.Sprintf(", mostly in %s", thread.TopWaitScope)
+			}
+			waiting = append(waiting, line)
+		}
+		if thread.IsWorkerThread && thread.BusyPercent < 50 {
+			underutilized = append(underutilized, thread.ThreadName)
+		}
+	}
+
+	summary := "No thread data"
+	if len(threads) > 0 {
+		summary = fmt.Sprintf("%s is the busiest thread at %.0f%% of the frame", threads[0].ThreadName, threads[0].BusyPercent)
+		if len(waiting) > 0 {
+			summary += fmt.Sprintf("; %d thread(s) mostly waiting", len(waiting))
+		}
+		if len(underutilized) > 0 {
+			summary += fmt.Sprintf("; underutilized workers: %s", strings.Join(underutilized, ", "))
+		}
+	}
+
+	analysis := append([]string{}, waiting...)
+	for _, candidate := range candidates {
+		analysis = append(analysis, candidate["suggestion"].(string))
+	}
+
+	output := map[string]interface{}{
+		"file":                filePath,
+		"sessionName":         data.SessionName,
+		"source":              source,
+		"framesAnalyzed":      len(data.Frames),
+		"saturatedPercent":    saturatedPercent,
+		"threads":             threads,
+		"waitingThreads":      waiting,
+		"underutilized":       underutilized,
+		"migrationCandidates": candidates,
+		"analysis":            analysis,
+		"summary":             summary,
+	}
+	if source == "aggregate" {
+		addWarning(output, warnNoFrameData, "No per-frame data; utilization is based on average time per frame")
+	}
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// UtilizationShift is the largest change in a thread's busy percentage between consecutive buckets
+type UtilizationShift struct {
+	ThreadName  string  `json:"threadName"`
+	FromPercent float64 `json:"fromPercent"`
+	ToPercent   float64 `json:"toPercent"`
+	AtFrame     int     `json:"atFrame"`
+	Description string  `json:"description"`
+}
+
+func utilizationTimelineHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	bucketFrames := 0
+	if b, ok := args["bucket_frames"].(float64); ok && b >= 1 {
+		bucketFrames = int(b)
+	}
+	threadFilter, _ := args["thread_filter"].(string)
+	minShift := 10.0
+	if m, ok := args["min_shift_percent"].(float64); ok && m >= 0 {
+		minShift = m
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+	if bucketFrames == 0 {
+		// Aim for about 20 buckets
+		bucketFrames = (len(data.Frames) + 19) / 20
+	}
+
+	match := func(name string) bool { return true }
+	if threadFilter != "" {
+		re, err := compileFunctionPattern(threadFilter, false)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		match = re.MatchString
+	}
+
+	buckets := []map[string]interface{}{}
+	series := make(map[string][]float64)
+	bucketStarts := []int{}
+	for start := 0; start < len(data.Frames); start += bucketFrames {
+		end := start + bucketFrames
+		if end > len(data.Frames) {
+			end = len(data.Frames)
+		}
+		frames := data.Frames[start:end]
+
+		busy := make(map[string]float64)
+		for _, thread := range threadUtilization(data, frames) {
+			if match(thread.ThreadName) {
+				busy[thread.ThreadName] = thread.BusyPercent
+			}
+		}
+		for name := range busy {
+			for len(series[name]) < len(bucketStarts) {
+				series[name] = append(series[name], 0) // Thread absent from earlier buckets
+			}
+			series[name] = append(series[name], busy[name])
+		}
+		bucketStarts = append(bucketStarts, frames[0].FrameNumber)
+
+		buckets = append(buckets, map[string]interface{}{
+			"startFrame":  frames[0].FrameNumber,
+			"endFrame":    frames[len(frames)-1].FrameNumber,
+			"busyPercent": busy,
+		})
+	}
+	if len(series) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("No threads match thread_filter '%s'", threadFilter)), nil
+	}
+
+	shifts := []UtilizationShift{}
+	for name, values := range series {
+		var best UtilizationShift
+		for i := 1; i < len(values); i++ {
+			if math.Abs(values[i]-values[i-1]) > math.Abs(best.ToPercent-best.FromPercent) {
+				best = UtilizationShift{ThreadName: name, FromPercent: values[i-1], ToPercent: values[i], AtFrame: bucketStarts[i]}
+			}
+		}
+		if best.ThreadName == "" || math.Abs(best.ToPercent-best.FromPercent) < minShift {
+			continue
+		}
+		best.Description = fmt.Sprintf("%s went from %.0f%% to %.0f%% busy at frame %d", name, best.FromPercent, best.ToPercent, best.AtFrame)
+		shifts = append(shifts, best)
+	}
+	sort.Slice(shifts, func(i, j int) bool {
+		return math.Abs(shifts[i].ToPercent-shifts[i].FromPercent) > math.Abs(shifts[j].ToPercent-shifts[j].FromPercent)
+	})
+
+	analysis := []string{}
+	for _, shift := range shifts {
+		analysis = append(analysis, shift.Description)
+	}
+	summary := fmt.Sprintf("%d buckets of %d frames for %d threads; %d threads shifted by %.0f+ points",
+		len(buckets), bucketFrames, len(series), len(shifts), minShift)
+
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"bucketFrames":    bucketFrames,
+		"minShiftPercent": minShift,
+		"buckets":         buckets,
+		"shifts":          shifts,
+		"analysis":        analysis,
+		"summary":         summary,
+	}
+	if threadFilter != "" {
+		output["threadFilter"] = threadFilter
+	}
+
+	attachFrameWindow(output, window)
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
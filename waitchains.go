@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// A wait chain links a main-thread Wait/Join scope to the thread it most likely waits on.
+// Scopes carry no start times, so overlap is judged per frame: the main thread waited,
+// and another thread was busy for most of the frame. Across frames, wait time that rises
+// with a thread's busy time confirms the dependency.
+
+// isJoinWaitScope reports whether a scope blocks on other threads (Wait*, Join)
+func isJoinWaitScope(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "wait") || strings.Contains(lower, "join")
+}
+
+// WaitChainFrame is one frame where the main thread waited on a saturated thread
+type WaitChainFrame struct {
+	FrameNumber        int      `json:"frame"`
+	FrameTimeMs        float64  `json:"frameTimeMs"`
+	WaitScopes         []string `json:"waitScopes"`
+	WaitMs             float64  `json:"waitMs"`
+	BlockingThread     string   `json:"blockingThread"`
+	BlockingStage      string   `json:"blockingStage"`
+	BusyPercent        float64  `json:"busyPercent"`
+	BlockingFunction   string   `json:"blockingFunction"`
+	BlockingFunctionMs float64  `json:"blockingFunctionMs"`
+}
+
+// WaitBlocker aggregates the frames in which one thread blocked the main thread
+type WaitBlocker struct {
+	ThreadName       string             `json:"threadName"`
+	Stage            string             `json:"stage"`
+	Frames           int                `json:"frames"`
+	WaitMs           float64            `json:"waitMs"`
+	AvgWaitMs        float64            `json:"avgWaitMs"`
+	AvgBusyPercent   float64            `json:"avgBusyPercent"`
+	WaitBusyR        float64            `json:"waitBusyCorrelation"` // Over all frames with a main-thread wait
+	TopFunction      string             `json:"topFunction"`
+	TopFunctionAvgMs float64            `json:"topFunctionAvgMs"`
+	Functions        map[string]float64 `json:"-"`
+	Evidence         *IssueEvidence     `json:"evidence,omitempty"`
+}
+
+func waitChainsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	saturation := 90.0
+	if p, ok := args["saturation_percent"].(float64); ok && p > 0 && p <= 100 {
+		saturation = p
+	}
+	minWaitMs := 0.5
+	if ms, ok := args["min_wait_ms"].(float64); ok && ms >= 0 {
+		minWaitMs = ms
+	}
+	topN := 10
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	stages := threadStages(data)
+	chains := []WaitChainFrame{}
+	blockers := make(map[string]*WaitBlocker)
+	blockerOf := make(map[int]string)
+	waitOf := make(map[int]float64)
+	waitFrames, unexplainedFrames := 0, 0
+	var totalWaitMs, unexplainedMs float64
+
+	// Main-thread wait and per-thread busy time of every waiting frame, for the correlation
+	waitSeries := []float64{}
+	busyByFrame := []map[string]float64{}
+	for _, frame := range data.Frames {
+		var waitMs float64
+		scopes := []string{}
+		busy := make(map[string]float64)
+		functions := make(map[string]map[string]float64)
+		threadStage := make(map[string]string)
+		for _, fn := range frame.Functions {
+			if stages[fn.ThreadID] == stageMain {
+				if isJoinWaitScope(fn.FunctionName) {
+					waitMs += fn.TimeMs
+					if !slices.Contains(scopes, fn.FunctionName) {
+						scopes = append(scopes, fn.FunctionName)
+					}
+				}
+				continue
+			}
+			if isWaitScope(fn.FunctionName) || syncScopeKind(fn.FunctionName) != "" {
+				continue
+			}
+			busy[fn.ThreadName] += fn.TimeMs
+			threadStage[fn.ThreadName] = stages[fn.ThreadID]
+			if functions[fn.ThreadName] == nil {
+				functions[fn.ThreadName] = make(map[string]float64)
+			}
+			functions[fn.ThreadName][fn.FunctionName] += fn.TimeMs
+		}
+		if waitMs < minWaitMs || waitMs == 0 {
+			continue
+		}
+		waitFrames++
+		totalWaitMs += waitMs
+		waitSeries = append(waitSeries, waitMs)
+		busyByFrame = append(busyByFrame, busy)
+
+		// The busiest saturated thread is the likely blocker
+		frameTime := frameTimeMs(frame, stages)
+		blocking, blockingMs := "", 0.0
+		for thread, ms := range busy {
+			if frameTime > 0 && ms/frameTime*100 >= saturation && (ms > blockingMs || ms == blockingMs && thread < blocking) {
+				blocking, blockingMs = thread, ms
+			}
+		}
+		if blocking == "" {
+			unexplainedFrames++
+			unexplainedMs += waitMs
+			continue
+		}
+
+		chain := WaitChainFrame{
+			FrameNumber:    frame.FrameNumber,
+			FrameTimeMs:    frameTime,
+			WaitScopes:     scopes,
+			WaitMs:         waitMs,
+			BlockingThread: blocking,
+			BlockingStage:  threadStage[blocking],
+			BusyPercent:    blockingMs / frameTime * 100,
+		}
+		for name, ms := range functions[blocking] {
+			if ms > chain.BlockingFunctionMs || ms == chain.BlockingFunctionMs && name < chain.BlockingFunction {
+				chain.BlockingFunction, chain.BlockingFunctionMs = name, ms
+			}
+		}
+		chains = append(chains, chain)
+		blockerOf[frame.FrameNumber] = blocking
+		waitOf[frame.FrameNumber] = waitMs
+
+		blocker, exists := blockers[blocking]
+		if !exists {
+			blocker = &WaitBlocker{ThreadName: blocking, Stage: chain.BlockingStage, Functions: make(map[string]float64)}
+			blockers[blocking] = blocker
+		}
+		blocker.Frames++
+		blocker.WaitMs += waitMs
+		blocker.AvgBusyPercent += chain.BusyPercent
+		for name, ms := range functions[blocking] {
+			blocker.Functions[name] += ms
+		}
+	}
+	if waitFrames == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("No main-thread Wait or Join scopes of at least %.2fms in %d frames", minWaitMs, len(data.Frames))), nil
+	}
+
+	ranked := []*WaitBlocker{}
+	for thread, blocker := range blockers {
+		blocker.AvgWaitMs = blocker.WaitMs / float64(blocker.Frames)
+		blocker.AvgBusyPercent /= float64(blocker.Frames)
+		busySeries := make([]float64, len(busyByFrame))
+		for i, busy := range busyByFrame {
+			busySeries[i] = busy[thread]
+		}
+		_, _, blocker.WaitBusyR = linearFit(busySeries, waitSeries)
+		for name, ms := range blocker.Functions {
+			if ms > blocker.TopFunctionAvgMs || ms == blocker.TopFunctionAvgMs && name < blocker.TopFunction {
+				blocker.TopFunction, blocker.TopFunctionAvgMs = name, ms
+			}
+		}
+		blocker.TopFunctionAvgMs /= float64(blocker.Frames)
+		blocker.Evidence = frameEvidence(data, func(frame FrameProFrame) float64 {
+			if blockerOf[frame.FrameNumber] != thread {
+				return 0
+			}
+			return waitOf[frame.FrameNumber]
+		}, 0)
+		ranked = append(ranked, blocker)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].WaitMs > ranked[j].WaitMs
+	})
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].WaitMs > chains[j].WaitMs
+	})
+	totalChains := len(chains)
+	if len(chains) > topN {
+		chains = chains[:topN]
+	}
+
+	analysis := []string{}
+	for _, blocker := range ranked {
+		line := fmt.Sprintf("%s (%s) blocked the main thread in %d frames, %.2fms of waits (%.2fms avg) while %.0f%% busy; top function %s (%.2fms avg)",
+			blocker.ThreadName, blocker.Stage, blocker.Frames, blocker.WaitMs, blocker.AvgWaitMs, blocker.AvgBusyPercent, blocker.TopFunction, blocker.TopFunctionAvgMs)
+		if blocker.WaitBusyR >= 0.5 {
+			line += fmt.Sprintf("; waits grow with its busy time (r=%.2f)", blocker.WaitBusyR)
+		}
+		analysis = append(analysis, line)
+	}
+	if unexplainedFrames > 0 {
+		analysis = append(analysis, fmt.Sprintf("%d frames waited %.2fms with no thread over %.0f%% busy: likely GPU, I/O or vsync waits rather than a thread",
+			unexplainedFrames, unexplainedMs, saturation))
+	}
+
+	summary := fmt.Sprintf("The main thread waited in %d frames (%.2fms) but no thread was over %.0f%% busy at the time", waitFrames, totalWaitMs, saturation)
+	if len(ranked) > 0 {
+		summary = fmt.Sprintf("%s is the likely blocker of main-thread waits in %d of %d waiting frames, mostly in %s",
+			ranked[0].ThreadName, ranked[0].Frames, waitFrames, ranked[0].TopFunction)
+	}
+
+	output := map[string]interface{}{
+		"file":              filePath,
+		"sessionName":       data.SessionName,
+		"framesAnalyzed":    len(data.Frames),
+		"saturationPercent": saturation,
+		"waitFrames":        waitFrames,
+		"totalWaitMs":       totalWaitMs,
+		"blockers":          ranked,
+		"worstWaits":        chains,
+		"chainedFrames":     totalChains,
+		"unexplainedFrames": unexplainedFrames,
+		"unexplainedWaitMs": unexplainedMs,
+		"analysis":          analysis,
+		"summary":           summary,
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Every JSON result carries a warnings list: caveats that change what its numbers mean,
+// such as missing per-frame data or frames left out, each with a stable code so clients
+// can act on them. Handlers add the caveats only they know about with addWarning; the
+// middleware adds the ones that follow from the shared result fields (frameWindow,
+// skippedFiles, partial) and an empty list when there are none.
+
+// Warning codes
+const (
+	warnNoFrameData      = "no-frame-data"
+	warnNoSelfTimes      = "no-self-times"
+	warnNoHierarchy      = "no-hierarchy"
+	warnMissingScopes    = "missing-scopes"
+	warnSubScoresOmitted = "sub-scores-omitted"
+	warnFewCaptures      = "few-captures"
+	warnFramesExcluded   = "frames-excluded"
+	warnContaminated     = "contaminated-frames-excluded"
+	warnOutliers         = "outliers-filtered"
+	warnFilesSkipped     = "files-skipped"
+	warnPartialResult    = "partial-result"
+	warnCanceled         = "canceled"
+	warnUnstagedTime     = "unstaged-time"
+)
+
+// warningCodes lists the codes for capabilities
+var warningCodes = []string{warnNoFrameData, warnNoSelfTimes, warnNoHierarchy, warnMissingScopes, warnSubScoresOmitted,
+	warnFewCaptures, warnFramesExcluded, warnContaminated, warnOutliers, warnFilesSkipped, warnPartialResult, warnCanceled, warnUnstagedTime}
+
+// Warning is one caveat of a result
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// addWarning adds a caveat to a result
+func addWarning(output map[string]interface{}, code, message string) {
+	warnings, _ := output["warnings"].([]Warning)
+	output["warnings"] = append(warnings, Warning{Code: code, Message: message})
+}
+
+// resultWarnings returns the caveats that follow from the shared fields of a result
+func resultWarnings(output map[string]json.RawMessage) []Warning {
+	warnings := []Warning{}
+	var window FrameWindow
+	if json.Unmarshal(output["frameWindow"], &window) == nil {
+		if window.FramesSkipped > 0 {
+			warnings = append(warnings, Warning{warnFramesExcluded, fmt.Sprintf("%d frames outside the frame window were left out", window.FramesSkipped)})
+		}
+		if len(window.ContaminatedExcluded) > 0 {
+			warnings = append(warnings, Warning{warnContaminated, fmt.Sprintf("%d frames disturbed by other activity were excluded", len(window.ContaminatedExcluded))})
+		}
+		if window.Outliers != nil {
+			action := "excluded"
+			if window.Outliers.Mode == outlierWinsorize {
+				action = "clamped"
+			}
+			warnings = append(warnings, Warning{warnOutliers, fmt.Sprintf("%d frames %s as outliers", window.Outliers.FramesAffected, action)})
+		}
+	}
+	var skipped []string
+	if json.Unmarshal(output["skippedFiles"], &skipped) == nil && len(skipped) > 0 {
+		warnings = append(warnings, Warning{warnFilesSkipped, fmt.Sprintf("%d files could not be loaded and were skipped", len(skipped))})
+	}
+	var reason, cause string
+	if json.Unmarshal(output["partialReason"], &reason) == nil && reason != "" {
+		code := warnPartialResult
+		if json.Unmarshal(output["partialCause"], &cause) == nil && cause == partialCanceled {
+			code = warnCanceled
+		}
+		warnings = append(warnings, Warning{code, reason})
+	}
+	return warnings
+}
+
+// warningsMiddleware completes the warnings list of JSON results. Other fields are passed
+// through as they were encoded.
+func warningsMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+		for i, content := range result.Content {
+			text, ok := content.(mcp.TextContent)
+			if !ok {
+				continue
+			}
+			var output map[string]json.RawMessage
+			if json.Unmarshal([]byte(text.Text), &output) != nil {
+				continue // Not a JSON object result; leave untouched
+			}
+			warnings := []Warning{}
+			json.Unmarshal(output["warnings"], &warnings)
+			warnings = append(warnings, resultWarnings(output)...)
+			output["warnings"], _ = json.Marshal(warnings)
+			encoded, _ := json.MarshalIndent(output, "", "  ")
+			result.Content[i] = mcp.NewTextContent(string(encoded))
+		}
+		return result, nil
+	}
+}
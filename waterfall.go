@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// A budget waterfall spends the frame budget one item at a time, most expensive first,
+// and marks the item that pushes the frame over. Items are the functions, or subsystems,
+// of one thread in one frame: threads run in parallel, so only one of them can be laid
+// against the budget. The main thread is used by default, as it spans the frame.
+
+// WaterfallStep is one item of the waterfall with the budget used so far
+type WaterfallStep struct {
+	Name         string  `json:"name"`
+	TimeMs       float64 `json:"timeMs"`
+	CumulativeMs float64 `json:"cumulativeMs"`
+	RemainingMs  float64 `json:"remainingMs"` // Negative once over budget
+	PushedOver   bool    `json:"pushedOver,omitempty"`
+	OverBudget   bool    `json:"overBudget,omitempty"` // Entirely spent beyond the budget
+}
+
+func budgetWaterfallHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	groupBy, _ := args["group_by"].(string)
+	if groupBy == "" {
+		groupBy = "function"
+	}
+	if groupBy != "function" && groupBy != "subsystem" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid group_by '%s': expected 'function' or 'subsystem'", groupBy)), nil
+	}
+	threadPattern, _ := args["thread"].(string)
+	var rules []SubsystemBudget
+	if groupBy == "subsystem" {
+		var err error
+		if rules, err = parseSubsystems(args); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetFPS, targetSource := targetFPSFor(args, data)
+	budgetMs := 1000 / targetFPS
+	if ms, ok := args["budget_ms"].(float64); ok && ms > 0 {
+		budgetMs = ms
+	}
+	if len(data.Frames) == 0 {
+		return mcp.NewToolResultError("No per-frame data in this file (Frames array is empty). Use a *_frame_analysis.json export"), nil
+	}
+
+	// The requested frame, or the worst one
+	times := frameTimes(data)
+	index := 0
+	if number, ok := args["frame"].(float64); ok {
+		found := false
+		if index, found = findFrame(data, int(number)); !found {
+			return mcp.NewToolResultError(fmt.Sprintf("Frame %d not found (capture has frames %d to %d)",
+				int(number), data.Frames[0].FrameNumber, data.Frames[len(data.Frames)-1].FrameNumber)), nil
+		}
+	} else {
+		for i, ms := range times {
+			if ms > times[index] {
+				index = i
+			}
+		}
+	}
+	frame := data.Frames[index]
+
+	// The thread laid against the budget: by name, the main thread, or the busiest
+	totals := frameThreadTotals(frame)
+	if len(totals) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Frame %d recorded no scopes", frame.FrameNumber)), nil
+	}
+	threads := make(map[int]bool)
+	if threadPattern != "" {
+		re, err := compileFunctionPattern(threadPattern, false)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		for id, total := range totals {
+			if re.MatchString(total.ThreadName) {
+				threads[id] = true
+			}
+		}
+		if len(threads) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("No thread in frame %d matches '%s'", frame.FrameNumber, threadPattern)), nil
+		}
+	} else {
+		for id := range mainThreadIDs(data) {
+			if totals[id] != nil {
+				threads[id] = true
+			}
+		}
+		if len(threads) == 0 {
+			busiest, busiestMs := 0, -1.0
+			for id, total := range totals {
+				if total.TimeMs > busiestMs {
+					busiest, busiestMs = id, total.TimeMs
+				}
+			}
+			threads[busiest] = true
+		}
+	}
+	threadNames := []string{}
+	for id := range threads {
+		if totals[id] == nil {
+			continue
+		}
+		threadNames = append(threadNames, totals[id].ThreadName)
+	}
+	sort.Strings(threadNames)
+
+	items := make(map[string]float64)
+	for _, fn := range frame.Functions {
+		if !threads[fn.ThreadID] {
+			continue
+		}
+		name := fn.FunctionName
+		if groupBy == "subsystem" {
+			name, _ = subsystemOf(rules, fn.FunctionName, true)
+		}
+		items[name] += fn.TimeMs
+	}
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if items[names[i]] != items[names[j]] {
+			return items[names[i]] > items[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	steps := []WaterfallStep{}
+	var cumulative float64
+	pushedOver := -1
+	for _, name := range names {
+		before := cumulative
+		cumulative += items[name]
+		step := WaterfallStep{Name: name, TimeMs: items[name], CumulativeMs: cumulative, RemainingMs: budgetMs - cumulative}
+		step.PushedOver = before <= budgetMs && cumulative > budgetMs
+		step.OverBudget = before > budgetMs
+		if step.PushedOver {
+			pushedOver = len(steps)
+		}
+		steps = append(steps, step)
+	}
+
+	threadLabel := strings.Join(threadNames, ", ")
+	analysis := []string{
+		fmt.Sprintf("Frame %d took %.2fms against a %.2fms budget; %s recorded %.2fms", frame.FrameNumber, times[index], budgetMs, threadLabel, cumulative),
+	}
+	summary := fmt.Sprintf("%s fits the %.2fms budget in frame %d with %.2fms to spare", threadLabel, budgetMs, frame.FrameNumber, budgetMs-cumulative)
+	if pushedOver >= 0 {
+		step := steps[pushedOver]
+		overItems := 0
+		var overMs float64
+		for _, later := range steps[pushedOver+1:] {
+			overItems++
+			overMs += later.TimeMs
+		}
+		analysis = append(analysis, fmt.Sprintf("%s (%.2fms) pushes %s over the budget at %.2fms, %.2fms over",
+			step.Name, step.TimeMs, threadLabel, step.CumulativeMs, -step.RemainingMs))
+		if overItems > 0 {
+			analysis = append(analysis, fmt.Sprintf("%d more items add %.2fms entirely beyond the budget", overItems, overMs))
+		}
+		summary = fmt.Sprintf("%s pushes %s over the %.2fms budget in frame %d; the thread ends %.2fms over",
+			step.Name, threadLabel, budgetMs, frame.FrameNumber, cumulative-budgetMs)
+	} else if times[index] > budgetMs {
+		analysis = append(analysis, "The frame is still over budget: another stage is on the critical path. Try thread '*Render*' or analyze_critical_path")
+	}
+
+	output := map[string]interface{}{
+		"file":            filePath,
+		"sessionName":     data.SessionName,
+		"frame":           frame.FrameNumber,
+		"frameTimeMs":     times[index],
+		"budgetMs":        budgetMs,
+		"targetFPS":       targetFPS,
+		"targetFPSSource": targetSource,
+		"threads":         threadNames,
+		"groupBy":         groupBy,
+		"steps":           steps,
+		"analysis":        analysis,
+		"summary":         summary,
+	}
+	if pushedOver >= 0 {
+		output["pushedOverBy"] = steps[pushedOver].Name
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Frame windows exclude warmup (level load, shader compilation) from an analysis.
+// The per-frame data is cut to the window and the aggregate Functions list is
+// rebuilt from the remaining frames, so every statistic ignores the excluded frames.
+// Outlier filtering (see outliers.go) is applied to the frames inside the window, after
+// frames labeled as capture-machine stalls (see contamination.go) are excluded.
+
+// FrameWindow describes the frames an analysis was restricted to
+type FrameWindow struct {
+	FirstFrame    int `json:"firstFrame"`
+	LastFrame     int `json:"lastFrame"`
+	FramesKept    int `json:"framesKept"`
+	FramesSkipped int `json:"framesSkipped"`
+
+	ContaminatedExcluded []int          `json:"contaminatedFramesExcluded,omitempty"`
+	Outliers             *OutlierFilter `json:"outliers,omitempty"`
+}
+
+// withFrameWindow adds the frame window parameters to an analysis tool's input schema
+func withFrameWindow(tool mcp.Tool) mcp.Tool {
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = make(map[string]any)
+	}
+	tool.InputSchema.Properties["skip_first_frames"] = map[string]any{
+		"type":        "number",
+		"description": "Exclude this many frames from the start of the capture, e.g. level load (needs per-frame data)",
+	}
+	tool.InputSchema.Properties["skip_first_seconds"] = map[string]any{
+		"type":        "number",
+		"description": "Exclude the first N seconds of the capture, measured by frame times (needs per-frame data)",
+	}
+	tool.InputSchema.Properties["frame_range"] = map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "number"},
+		"description": "Only analyze frames [first, last] by frame number, inclusive (needs per-frame data)",
+	}
+	tool.InputSchema.Properties["exclude_contaminated"] = map[string]any{
+		"type":        "boolean",
+		"description": "Drop spike frames that look like capture-machine stalls (all threads slowed at once) rather than in-game problems; see detect_contamination (needs per-frame data)",
+	}
+	tool.InputSchema.Properties["outlier_percent"] = map[string]any{
+		"type":        "number",
+		"description": "Handle the fastest and slowest N% of frames (each, up to 25) as outliers, e.g. a debugger break or alt-tab (needs per-frame data)",
+	}
+	tool.InputSchema.Properties["outlier_mode"] = map[string]any{
+		"type":        "string",
+		"enum":        []string{outlierTrim, outlierWinsorize},
+		"description": "'trim' drops outlier frames (default); 'winsorize' clamps their frame time to the nearest kept frame",
+	}
+	return tool
+}
+
+// applyFrameWindow restricts data to the frame window requested in args.
+// It returns nil when no window was requested.
+func applyFrameWindow(data *FrameProData, args map[string]interface{}) (*FrameWindow, error) {
+	skipFrames, hasSkipFrames := args["skip_first_frames"].(float64)
+	skipSeconds, hasSkipSeconds := args["skip_first_seconds"].(float64)
+	frameRange, hasRange := args["frame_range"].([]interface{})
+	excludeContaminated, _ := args["exclude_contaminated"].(bool)
+	outliers, err := parseOutlierFilter(args)
+	if err != nil {
+		return nil, err
+	}
+	if !hasSkipFrames && !hasSkipSeconds && !hasRange && !excludeContaminated && outliers == nil {
+		return nil, nil
+	}
+	if len(data.Frames) == 0 {
+		return nil, fmt.Errorf("skip_first_frames, skip_first_seconds, frame_range, exclude_contaminated and outlier_percent need per-frame data (Frames array is empty). Use a *_frame_analysis.json export")
+	}
+
+	first, last := data.Frames[0].FrameNumber, data.Frames[len(data.Frames)-1].FrameNumber
+	if hasRange {
+		if len(frameRange) != 2 {
+			return nil, fmt.Errorf("frame_range must be [first, last]")
+		}
+		start, ok1 := frameRange[0].(float64)
+		end, ok2 := frameRange[1].(float64)
+		if !ok1 || !ok2 || start > end {
+			return nil, fmt.Errorf("frame_range must be two frame numbers [first, last] with first not after last")
+		}
+		first, last = int(start), int(end)
+	}
+
+	times := frameTimes(data)
+	kept := []FrameProFrame{}
+	var elapsedMs float64
+	for i, frame := range data.Frames {
+		startMs := elapsedMs
+		elapsedMs += times[i]
+		if hasSkipFrames && float64(i) < skipFrames {
+			continue
+		}
+		if hasSkipSeconds && startMs < skipSeconds*1000 {
+			continue
+		}
+		if frame.FrameNumber < first || frame.FrameNumber > last {
+			continue
+		}
+		kept = append(kept, frame)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("the frame window excludes every frame (capture has frames %d to %d)",
+			data.Frames[0].FrameNumber, data.Frames[len(data.Frames)-1].FrameNumber)
+	}
+
+	window := &FrameWindow{
+		FirstFrame:    kept[0].FrameNumber,
+		LastFrame:     kept[len(kept)-1].FrameNumber,
+		FramesKept:    len(kept),
+		FramesSkipped: len(data.Frames) - len(kept),
+	}
+	data.Frames = kept
+	if excludeContaminated {
+		dropped := make(map[int]bool)
+		for _, frame := range detectContamination(data, 2.0) {
+			dropped[frame.FrameNumber] = true
+			window.ContaminatedExcluded = append(window.ContaminatedExcluded, frame.FrameNumber)
+		}
+		clean := []FrameProFrame{}
+		for _, frame := range kept {
+			if !dropped[frame.FrameNumber] {
+				clean = append(clean, frame)
+			}
+		}
+		if len(clean) == 0 {
+			return nil, fmt.Errorf("every frame in the frame window looks like a capture-machine stall; retry without exclude_contaminated")
+		}
+		kept = clean
+		data.Frames = kept
+		window.FirstFrame, window.LastFrame = kept[0].FrameNumber, kept[len(kept)-1].FrameNumber
+		window.FramesKept = len(kept) // FramesSkipped stays the frames outside the window
+	}
+	if outliers != nil {
+		kept = applyOutlierFilter(data, outliers)
+		data.Frames = kept
+		window.FramesKept = len(kept)
+		window.Outliers = outliers
+	}
+	data.TotalFrames = len(kept)
+	data.Functions = functionsFromFrames(data.Functions, kept)
+	data.TotalFunctions = len(data.Functions)
+	if data.Tree != nil {
+		for _, frame := range kept {
+			if isHierarchical(frame.Functions) {
+				data.Tree = scopesFromFrames(kept)
+				break
+			}
+		}
+	}
+	return window, nil
+}
+
+// scopesFromFrames rebuilds the scope hierarchy of a capture from its hierarchical frames
+func scopesFromFrames(frames []FrameProFrame) []FrameProFunction {
+	scopes := []FrameProFunction{}
+	for _, frame := range frames {
+		for _, fn := range frame.Functions {
+			addFrameScope(&scopes, fn)
+		}
+	}
+	averageScopes(scopes, float64(len(frames)))
+	fillHierarchyTimes(scopes)
+	return scopes
+}
+
+// functionsFromFrames rebuilds the aggregate function list from per-frame data.
+// Thread flags and other fields come from the original aggregate entry; self and
+// inclusive times are scaled by the share of the total time that remains.
+func functionsFromFrames(original []FrameProFunction, frames []FrameProFrame) []FrameProFunction {
+	byKey := make(map[string]*FrameProFunction)
+	order := []string{}
+	add := func(fn FrameProFunction) *FrameProFunction {
+		key := functionKey(fn)
+		if existing, ok := byKey[key]; ok {
+			return existing
+		}
+		entry := fn
+		entry.Children = nil
+		entry.TotalTimeMs, entry.TotalCount = 0, 0
+		entry.MaxTimePerFrameMs, entry.MaxCountPerFrame = 0, 0
+		entry.TimeMs, entry.Count = 0, 0
+		byKey[key] = &entry
+		order = append(order, key)
+		return &entry
+	}
+
+	originalTotals := make(map[string]float64)
+	for _, fn := range original {
+		originalTotals[functionKey(fn)] = fn.TotalTimeMs
+		add(fn)
+	}
+	// Nested scopes of hierarchical frames count too, as in the flat list of their capture
+	open := make(map[string]bool)
+	var addScopes func(functions []FrameProFunction)
+	addScopes = func(functions []FrameProFunction) {
+		for _, fn := range functions {
+			if isTraceFrameMarker(fn.FunctionName) {
+				addScopes(fn.Children) // Left out of the flat list, as by flattenScopes
+				continue
+			}
+			key := functionKey(fn)
+			entry := add(fn)
+			if !open[key] { // A scope nested in itself is already in the outer call's time
+				entry.TotalTimeMs += fn.TimeMs
+				if fn.TimeMs > entry.MaxTimePerFrameMs {
+					entry.MaxTimePerFrameMs = fn.TimeMs
+				}
+			}
+			entry.TotalCount += fn.Count
+			if fn.Count > entry.MaxCountPerFrame {
+				entry.MaxCountPerFrame = fn.Count
+			}
+			wasOpen := open[key]
+			open[key] = true
+			addScopes(fn.Children)
+			open[key] = wasOpen
+		}
+	}
+	for _, frame := range frames {
+		addScopes(frame.Functions)
+	}
+
+	functions := []FrameProFunction{}
+	for _, key := range order {
+		entry := byKey[key]
+		if entry.TotalTimeMs == 0 && entry.TotalCount == 0 {
+			continue // Not recorded inside the window
+		}
+		entry.AvgTimePerFrameMs = entry.TotalTimeMs / float64(len(frames))
+		entry.AvgCountPerFrame = float64(entry.TotalCount) / float64(len(frames))
+		if total := originalTotals[key]; total > 0 {
+			share := entry.TotalTimeMs / total
+			entry.SelfTimeMs *= share
+			entry.InclusiveTimeMs *= share
+		}
+		functions = append(functions, *entry)
+	}
+	return functions
+}
+
+// attachFrameWindow adds the applied frame window to a tool result
+func attachFrameWindow(output map[string]interface{}, window *FrameWindow) {
+	if window != nil {
+		output["frameWindow"] = window
+	}
+}
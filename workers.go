@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Worker-pool balance compares the busy time of the worker threads. Over the whole
+// capture a balanced pool has every worker equally busy; within a frame, the busiest
+// worker decides when the pool's work is done, so per-frame imbalance (busiest worker
+// over the pool mean) is what costs frame time even when the totals even out.
+
+// frameImbalanceMean is the busiest worker's load over the pool mean in a typical frame
+// above which the pool is imbalanced within frames
+const frameImbalanceMean = 1.5
+
+// WorkerLoad is the busy time of one worker thread
+type WorkerLoad struct {
+	ThreadName       string   `json:"threadName"`
+	BusyMsPerFrame   float64  `json:"busyMsPerFrame"`
+	MaxBusyMs        float64  `json:"maxBusyMsInFrame,omitempty"`
+	BusyPercent      float64  `json:"busyPercent"`
+	ShareOfPool      float64  `json:"shareOfPoolPercent"`
+	TimesLeastLoaded float64  `json:"timesLeastLoaded,omitempty"`
+	TopJobs          []string `json:"topJobs"`
+	busyFunctions    map[string]float64
+}
+
+// PinnedJob is a job that only ever runs on one worker and dominates it
+type PinnedJob struct {
+	FunctionName    string  `json:"functionName"`
+	ThreadName      string  `json:"threadName"`
+	MsPerFrame      float64 `json:"msPerFrame"`
+	PercentOfWorker float64 `json:"percentOfWorker"`
+}
+
+func workerBalanceHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments format"), nil
+	}
+
+	filePath, _ := args["file_path"].(string)
+	imbalanceRatio := 2.0
+	if r, ok := args["imbalance_ratio"].(float64); ok && r > 1 {
+		imbalanceRatio = r
+	}
+
+	data, err := loadFrameProData(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load FramePro data: %v", err)), nil
+	}
+	window, err := applyFrameWindow(data, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkCapture(data, true); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	frames := data.Frames
+	source := "frames"
+	if len(frames) == 0 {
+		frames = []FrameProFrame{aggregateFrame(data)}
+		source = "aggregate"
+	}
+
+	workers := []*WorkerLoad{}
+	workerIDs := make(map[int]*WorkerLoad)
+	var poolMs float64
+	for _, thread := range threadUtilization(data, frames) {
+		if !thread.IsWorkerThread {
+			continue
+		}
+		worker := &WorkerLoad{
+			ThreadName:     thread.ThreadName,
+			BusyMsPerFrame: thread.BusyMsPerFrame,
+			BusyPercent:    thread.BusyPercent,
+			busyFunctions:  thread.busyFunctions,
+		}
+		workers = append(workers, worker)
+		workerIDs[thread.ThreadID] = worker
+		poolMs += thread.BusyMsPerFrame
+	}
+	if len(workers) < 2 {
+		return mcp.NewToolResultError(fmt.Sprintf("Found %d worker threads; balance needs at least 2. Workers are recognized by the IsWorkerThread flag or 'Worker'/'Job'/'Task' in the thread name", len(workers))), nil
+	}
+
+	// Per-frame imbalance: the busiest worker against the pool mean
+	frameImbalance := []float64{}
+	if source == "frames" {
+		for _, frame := range frames {
+			busy := make(map[*WorkerLoad]float64)
+			var total float64
+			for _, fn := range frame.Functions {
+				worker := workerIDs[fn.ThreadID]
+				if worker == nil || isWaitScope(fn.FunctionName) {
+					continue
+				}
+				busy[worker] += fn.TimeMs
+				total += fn.TimeMs
+			}
+			var busiest float64
+			for worker, ms := range busy {
+				worker.MaxBusyMs = max(worker.MaxBusyMs, ms)
+				busiest = max(busiest, ms)
+			}
+			if total > 0 {
+				frameImbalance = append(frameImbalance, busiest/(total/float64(len(workers))))
+			}
+		}
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].BusyMsPerFrame > workers[j].BusyMsPerFrame
+	})
+	least := workers[len(workers)-1]
+	for _, worker := range workers {
+		if poolMs > 0 {
+			worker.ShareOfPool = worker.BusyMsPerFrame / poolMs * 100
+		}
+		if least.BusyMsPerFrame > 0 {
+			worker.TimesLeastLoaded = worker.BusyMsPerFrame / least.BusyMsPerFrame
+		}
+		jobs := make([]string, 0, len(worker.busyFunctions))
+		for name := range worker.busyFunctions {
+			jobs = append(jobs, name)
+		}
+		sort.Slice(jobs, func(i, j int) bool {
+			return worker.busyFunctions[jobs[i]] > worker.busyFunctions[jobs[j]]
+		})
+		if len(jobs) > 3 {
+			jobs = jobs[:3]
+		}
+		worker.TopJobs = jobs
+	}
+
+	// Jobs that run on a single worker and make up most of its time can't be spread
+	runsOn := make(map[string]int)
+	for _, worker := range workers {
+		for name := range worker.busyFunctions {
+			runsOn[name]++
+		}
+	}
+	pinned := []PinnedJob{}
+	for _, worker := range workers {
+		for name, ms := range worker.busyFunctions {
+			if runsOn[name] == 1 && worker.BusyMsPerFrame > 0 && ms/worker.BusyMsPerFrame >= 0.5 {
+				pinned = append(pinned, PinnedJob{name, worker.ThreadName, ms, ms / worker.BusyMsPerFrame * 100})
+			}
+		}
+	}
+	sort.Slice(pinned, func(i, j int) bool {
+		return pinned[i].MsPerFrame > pinned[j].MsPerFrame
+	})
+
+	busiest := workers[0]
+	overall := busiest.TimesLeastLoaded
+	imbalanced := overall >= imbalanceRatio || least.BusyMsPerFrame == 0 && busiest.BusyMsPerFrame > 0
+	medianFrame, p95Frame := 0.0, 0.0
+	if len(frameImbalance) > 0 {
+		sorted := append([]float64(nil), frameImbalance...)
+		sort.Float64s(sorted)
+		medianFrame, p95Frame = percentile(sorted, 50), percentile(sorted, 95)
+	}
+
+	analysis := []string{}
+	if least.BusyMsPerFrame > 0 {
+		analysis = append(analysis, fmt.Sprintf("%s does %.1fx the work of %s (%.2fms vs %.2fms per frame)",
+			busiest.ThreadName, busiest.TimesLeastLoaded, least.ThreadName, busiest.BusyMsPerFrame, least.BusyMsPerFrame))
+	} else {
+		analysis = append(analysis, fmt.Sprintf("%s never runs a job while %s does %.2fms per frame", least.ThreadName, busiest.ThreadName, busiest.BusyMsPerFrame))
+	}
+	suggestions := []string{}
+	for i, job := range pinned {
+		if i == 3 {
+			break
+		}
+		analysis = append(analysis, fmt.Sprintf("%s only runs on %s and takes %.0f%% of it (%.2fms per frame)", job.FunctionName, job.ThreadName, job.PercentOfWorker, job.MsPerFrame))
+	}
+	if len(pinned) > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Split %s into smaller jobs, or stop pinning it to %s, so other workers can take part of it", pinned[0].FunctionName, pinned[0].ThreadName))
+	}
+	if imbalanced {
+		suggestions = append(suggestions, "Some workers starve over the whole capture: check thread affinity, per-worker queues and whether idle workers can steal jobs")
+	}
+	if len(frameImbalance) > 0 {
+		analysis = append(analysis, fmt.Sprintf("Within a frame the busiest worker carries %.1fx the pool mean (median), %.1fx at p95", medianFrame, p95Frame))
+		if medianFrame >= frameImbalanceMean && !imbalanced {
+			suggestions = append(suggestions, "The load evens out over the capture but not within frames: jobs are too coarse or queued late in the frame; use finer-grained jobs or start them earlier")
+		}
+	}
+	for _, worker := range workers {
+		if worker.BusyPercent < 10 {
+			suggestions = append(suggestions, fmt.Sprintf("Workers such as %s are busy under 10%% of the frame: the pool may have more threads than work", worker.ThreadName))
+			break
+		}
+	}
+
+	summary := fmt.Sprintf("The %d workers are balanced: the busiest does %.1fx the work of the least busy", len(workers), overall)
+	if imbalanced {
+		summary = fmt.Sprintf("The %d-worker pool is imbalanced: %s does %.0f%% of the pool's work", len(workers), busiest.ThreadName, busiest.ShareOfPool)
+	} else if medianFrame >= frameImbalanceMean {
+		summary += fmt.Sprintf(", but within a typical frame the busiest worker carries %.1fx the pool mean", medianFrame)
+	}
+
+	output := map[string]interface{}{
+		"file":           filePath,
+		"sessionName":    data.SessionName,
+		"source":         source,
+		"workers":        workers,
+		"poolBusyMs":     poolMs,
+		"imbalanceRatio": overall,
+		"pinnedJobs":     pinned,
+		"suggestions":    suggestions,
+		"analysis":       analysis,
+		"summary":        summary,
+	}
+	if len(frameImbalance) > 0 {
+		output["frameImbalance"] = map[string]float64{"median": medianFrame, "p95": p95Frame}
+	}
+	if source == "aggregate" {
+		addWarning(output, warnNoFrameData, "No per-frame data; only the average load per worker was compared, not the imbalance within frames")
+	}
+	attachFrameWindow(output, window)
+
+	result, _ := json.MarshalIndent(output, "", "  ")
+
+	return mcp.NewToolResultText(string(result)), nil
+}
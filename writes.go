@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// Tools that write files (exports, CSV tables, the annotation and feedback stores) take a
+// dry_run parameter: they build exactly what they would write, report where and how big it
+// is with the start of its content, and leave the disk untouched.
+
+const dryRunPreviewBytes = 4096 // Content shown in a dry run's preview
+
+// FileWrite is a file a tool wrote, or would write in a dry run
+type FileWrite struct {
+	Path      string `json:"path"`
+	Action    string `json:"action"` // "create" or "overwrite"
+	Bytes     int    `json:"bytes"`
+	Preview   string `json:"preview,omitempty"` // Dry runs only
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// isDryRun reports whether a tool call only previews its writes
+func isDryRun(args map[string]interface{}) bool {
+	dryRun, _ := args["dry_run"].(bool)
+	return dryRun
+}
+
+// writeFile writes content to path unless this is a dry run, and describes the write
+func writeFile(path string, content []byte, dryRun bool) (FileWrite, error) {
+	write := FileWrite{Path: path, Action: "create", Bytes: len(content)}
+	if _, err := os.Stat(path); err == nil {
+		write.Action = "overwrite"
+	}
+	if dryRun {
+		preview := content
+		if len(preview) > dryRunPreviewBytes {
+			preview, write.Truncated = preview[:dryRunPreviewBytes], true
+			for len(preview) > 0 && !utf8.Valid(preview) {
+				preview = preview[:len(preview)-1] // Don't cut a character in half
+			}
+		}
+		write.Preview = string(preview)
+		return write, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return write, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return write, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return write, nil
+}
+
+// wroteVerb starts a summary of the writes: "Wrote", or "Would write" in a dry run
+func wroteVerb(dryRun bool) string {
+	if dryRun {
+		return "Would write"
+	}
+	return "Wrote"
+}
+
+// attachWrites adds the files a tool wrote, or would write, to its result
+func attachWrites(output map[string]interface{}, writes []FileWrite, dryRun bool) {
+	output["writes"] = writes
+	if dryRun {
+		output["dryRun"] = true
+	}
+}